@@ -58,5 +58,5 @@ This provides a menu-driven interface for accessing various tools and utilities.
 
 func init() {
 	cliCmd.Flags().BoolVar(&debugFlag, "debug", false, "Enable debug logging for HTTP requests")
-	cliCmd.Flags().BoolVar(&testFlag, "test", false, "Enable test mode (simulate device responses without HTTP calls)")
+	cliCmd.Flags().BoolVar(&testFlag, "test", false, "Enable test mode (simulate device responses, Bravia TVs use a local simulator)")
 }
@@ -15,6 +15,7 @@
 package cli
 
 import (
+	"fmt"
 	"lucas/internal"
 	"net"
 	"regexp"
@@ -36,6 +37,7 @@ const (
 	setupFieldDeviceType setupField = iota
 	setupFieldHostAddress
 	setupFieldCredential
+	setupFieldPair
 	setupFieldConnect
 	setupFieldConfigureDevices
 )
@@ -61,6 +63,11 @@ type SetupModel struct {
 	connecting      bool
 	connectionError string
 
+	// PIN pairing state, the credential field holds the PIN while pairing
+	pairing       bool
+	pairClientID  string
+	statusMessage string
+
 	// Connected device (when setup complete)
 	device     device.Device
 	deviceInfo device.DeviceInfo
@@ -101,6 +108,9 @@ func (m SetupModel) Update(msg tea.Msg) (SetupModel, tea.Cmd) {
 			return m.handleTabNavigation(msg.String() == "shift+tab"), nil
 
 		case "enter":
+			if m.focusedField == setupFieldPair {
+				return m.handlePair()
+			}
 			if m.focusedField == setupFieldConnect {
 				return m.handleConnect()
 			}
@@ -182,8 +192,12 @@ func (m SetupModel) View() string {
 	b.WriteString(hostStyle.Render(hostText))
 	b.WriteString("\n\n")
 
-	// Credential Input
-	b.WriteString(subtitleStyle.Render("Credential (PSK):"))
+	// Credential Input, which takes the PIN while pairing
+	credentialLabel := "Credential (PSK, or pair with PIN):"
+	if m.pairing {
+		credentialLabel = "PIN shown on the TV:"
+	}
+	b.WriteString(subtitleStyle.Render(credentialLabel))
 	b.WriteString("\n")
 	credStyle := inputStyle
 	showCredCursor := m.focusedField == setupFieldCredential
@@ -195,6 +209,18 @@ func (m SetupModel) View() string {
 	b.WriteString(credStyle.Render(credText))
 	b.WriteString("\n\n")
 
+	// Pair Button
+	pairStyle := buttonStyle
+	if m.focusedField == setupFieldPair {
+		pairStyle = buttonActiveStyle
+	}
+	pairText := "Pair with PIN"
+	if m.pairing {
+		pairText = "Submit PIN"
+	}
+	b.WriteString(pairStyle.Render(pairText))
+	b.WriteString("\n\n")
+
 	// Connect Button
 	connectStyle := buttonStyle
 	if m.focusedField == setupFieldConnect {
@@ -216,6 +242,12 @@ func (m SetupModel) View() string {
 	b.WriteString(configStyle.Render("Configure Devices"))
 	b.WriteString("\n\n")
 
+	// Pairing Status
+	if m.statusMessage != "" {
+		b.WriteString(successStyle.Render(m.statusMessage))
+		b.WriteString("\n\n")
+	}
+
 	// Connection Error
 	if m.connectionError != "" {
 		b.WriteString(errorStyle.Render("Error: " + m.connectionError))
@@ -230,7 +262,7 @@ func (m SetupModel) View() string {
 
 // handleTabNavigation moves between input fields
 func (m SetupModel) handleTabNavigation(reverse bool) SetupModel {
-	fields := []setupField{setupFieldDeviceType, setupFieldHostAddress, setupFieldCredential, setupFieldConnect, setupFieldConfigureDevices}
+	fields := []setupField{setupFieldDeviceType, setupFieldHostAddress, setupFieldCredential, setupFieldPair, setupFieldConnect, setupFieldConfigureDevices}
 
 	currentIndex := -1
 	for i, field := range fields {
@@ -307,6 +339,57 @@ func (m SetupModel) handleConnect() (SetupModel, tea.Cmd) {
 	return m, nil
 }
 
+// handlePair runs Bravia PIN pairing: the first press shows the PIN on the TV,
+// the second submits the PIN typed into the credential field
+func (m SetupModel) handlePair() (SetupModel, tea.Cmd) {
+	if m.hostAddress == "" || !m.IsValidHostAddress(m.hostAddress) {
+		m.connectionError = "A valid host address is required to pair"
+		return m, nil
+	}
+
+	client := bravia.NewBraviaClient(m.hostAddress, "", *internal.NewModeOptions(internal.WithDebug(m.debugMode), internal.WithTest(m.testMode)))
+	m.connectionError = ""
+	m.statusMessage = ""
+
+	if !m.pairing {
+		clientID := bravia.NewClientID()
+		credential, err := client.RequestPIN(clientID)
+		if err != nil {
+			m.connectionError = fmt.Sprintf("Pairing failed: %v", err)
+			return m, nil
+		}
+		if credential != "" {
+			// The TV already knew this client
+			m.credential = credential
+			m.credentialCursor = len(credential)
+			m.statusMessage = "Paired, press Connect"
+			return m, nil
+		}
+
+		m.pairing = true
+		m.pairClientID = clientID
+		m.credential = ""
+		m.credentialCursor = 0
+		m.focusedField = setupFieldCredential
+		m.statusMessage = "Enter the PIN shown on the TV, then press Submit PIN"
+		return m, nil
+	}
+
+	credential, err := client.CompletePairing(m.pairClientID, m.credential)
+	if err != nil {
+		m.connectionError = fmt.Sprintf("Pairing failed: %v", err)
+		return m, nil
+	}
+
+	m.pairing = false
+	m.pairClientID = ""
+	m.credential = credential
+	m.credentialCursor = len(credential)
+	m.focusedField = setupFieldConnect
+	m.statusMessage = "Paired, press Connect"
+	return m, nil
+}
+
 // handleUp handles up arrow key
 func (m SetupModel) handleUp() SetupModel {
 	if m.focusedField == setupFieldDeviceType {
@@ -123,6 +123,8 @@ The gateway provides a central point for managing distributed IoT devices across
 
 		// Initialize Hermes Broker Service
 		brokerService := gateway.NewBrokerService(config.Server.ZMQ.Address, keys, database)
+		brokerService.SetBrokerLimits(config.GetBrokerLimits())
+		brokerService.SetPayloadLimits(config.GetPayloadLimits())
 
 		// Initialize API server with JWT configuration from config
 		apiServer := gateway.NewAPIServer(database, brokerService, keys, config)
@@ -16,22 +16,37 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"lucas/internal"
+	"lucas/internal/hermes"
+	"lucas/internal/homeassistant"
 	"lucas/internal/hub"
 	"lucas/internal/logger"
 )
 
 var (
-	hubConfigPath  string
-	hubDebugFlag   bool
-	hubTestFlag    bool
-	hubGatewayURL  string
-	hubVerboseFlag bool
+	hubConfigPath   string
+	hubDebugFlag    bool
+	hubTestFlag     bool
+	hubGatewayURL   string
+	hubVerboseFlag  bool
+	hubFailoverFlag bool
+
+	hubHAURL      string
+	hubHAToken    string
+	hubHAEntities []string
+
+	hubBrokerAPIKey string
 )
 
 var hubCmd = &cobra.Command{
@@ -134,11 +149,18 @@ The hub provides secure, centralized device control and management.`,
 	},
 }
 
+// hubStatusURL is the health endpoint of the hub daemon's local configuration API
+const hubStatusURL = "http://localhost:8081/health"
+
 var hubStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check hub daemon status",
 	Long:  `Check the status of the running hub daemon.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if showHubStatus(cmd) {
+			return nil
+		}
+
 		cmd.Println("Hub Status Check")
 		cmd.Println("===============")
 		cmd.Println()
@@ -352,6 +374,75 @@ This is automatically done during 'hub init' if a gateway is found.`,
 	},
 }
 
+var hubBrokerCmd = &cobra.Command{
+	Use:   "broker <service NAME | workers [SERVICE] | stats>",
+	Short: "Query the gateway broker",
+	Long: `Query the gateway's Hermes broker through its management interface (mmi.*):
+whether a service has workers, which workers are connected, and broker statistics.
+The hub authenticates with its product key, or with a gateway user's API key given with --api-key.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return queryBroker(cmd, args)
+	},
+}
+
+var hubHomeAssistantCmd = &cobra.Command{
+	Use:   "homeassistant",
+	Short: "Manage Home Assistant entities",
+	Long:  `Import Home Assistant entities as Lucas devices.`,
+}
+
+var hubHomeAssistantImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import Home Assistant entities as devices",
+	Long: `Import selected Home Assistant entities into the hub configuration.
+Without --entity the available entities are listed instead.
+A long-lived access token can be created from the Home Assistant user profile.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if hubHAURL == "" || hubHAToken == "" {
+			return fmt.Errorf("--url and --token are required")
+		}
+
+		if len(hubHAEntities) == 0 {
+			client := homeassistant.NewHAClient(hubHAURL, hubHAToken, internal.FnModeOptions{})
+			states, err := client.States()
+			if err != nil {
+				return fmt.Errorf("failed to list entities: %w", err)
+			}
+			sort.Slice(states, func(i, j int) bool { return states[i].EntityID < states[j].EntityID })
+			for _, state := range states {
+				cmd.Printf("  %-40s %-12s %s\n", state.EntityID, state.State, state.FriendlyName())
+			}
+			cmd.Println("\nRe-run with --entity <entity_id> for each entity to import.")
+			return nil
+		}
+
+		config, err := hub.LoadConfig(hubConfigPath)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		devices, err := hub.ImportHomeAssistantEntities(hubHAURL, hubHAToken, hubHAEntities)
+		if err != nil {
+			return err
+		}
+
+		added, skipped := config.AddDevices(devices)
+		if err := hub.SaveConfig(config, hubConfigPath); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		for _, id := range added {
+			cmd.Printf("✓ Imported %s\n", id)
+		}
+		for _, id := range skipped {
+			cmd.Printf("⚠ Skipped %s (device ID already configured)\n", id)
+		}
+		cmd.Println("Reload the hub to activate imported devices.")
+		return nil
+	},
+}
+
 // initializeHub handles the comprehensive hub initialization process
 func initializeHub(cmd *cobra.Command) error {
 	cmd.Printf("Initializing Lucas Hub...\n")
@@ -464,6 +555,52 @@ func initializeHub(cmd *cobra.Command) error {
 	return nil
 }
 
+// showHubStatus prints the gateways the hub is configured with and, when the daemon is running,
+// which of them it is connected to. Returns false when there was nothing to show
+func showHubStatus(cmd *cobra.Command) bool {
+	config, configErr := hub.LoadConfig(hubConfigPath)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	healthResp, healthErr := makeHTTPRequest(client, hubStatusURL)
+	if configErr != nil && healthErr != nil {
+		return false
+	}
+
+	health, _ := healthResp["data"].(map[string]interface{})
+	activeGateway, _ := health["active_gateway"].(string)
+
+	if healthErr == nil {
+		cmd.Printf("Hub Status: ✓ RUNNING\n")
+		if hubID, ok := health["hub_id"].(string); ok {
+			cmd.Printf("Hub ID: %s\n", hubID)
+		}
+		if activeGateway != "" {
+			cmd.Printf("Active Gateway: %s\n", activeGateway)
+		} else {
+			cmd.Printf("Active Gateway: ✗ not connected\n")
+		}
+	} else {
+		cmd.Printf("Hub Status: ✗ NOT RUNNING (%v)\n", healthErr)
+	}
+
+	if configErr == nil {
+		cmd.Printf("Gateways (%s):\n", hubConfigPath)
+		for i, gateway := range config.Gateway.Endpoints() {
+			role := "failover"
+			if i == 0 {
+				role = "primary"
+			}
+			marker := " "
+			if gateway.Endpoint == activeGateway {
+				marker = "*"
+			}
+			cmd.Printf("  %s %-30s %s\n", marker, gateway.Endpoint, role)
+		}
+	}
+
+	return true
+}
+
 // registerWithGateway handles manual gateway registration
 func registerWithGateway(cmd *cobra.Command) error {
 	// Load configuration to get hub keys
@@ -524,7 +661,11 @@ func registerWithGateway(cmd *cobra.Command) error {
 		cmd.Printf("⚠ Could not retrieve gateway info: %v\n", err)
 	} else {
 		// Update configuration with gateway info
-		config.UpdateGatewayInfo(gatewayInfo.ZMQEndpoint, gatewayInfo.PublicKey)
+		if hubFailoverFlag {
+			config.AddFailoverGateway(gatewayInfo.ZMQEndpoint, gatewayInfo.PublicKey)
+		} else {
+			config.UpdateGatewayInfo(gatewayInfo.ZMQEndpoint, gatewayInfo.PublicKey)
+		}
 
 		// Save updated configuration
 		if err := hub.SaveConfig(config, hubConfigPath); err != nil {
@@ -545,7 +686,7 @@ func init() {
 	// Main hub command flags
 	hubCmd.Flags().StringVarP(&hubConfigPath, "config", "c", "hub.yml", "Path to hub configuration file")
 	hubCmd.Flags().BoolVarP(&hubDebugFlag, "debug", "d", false, "Enable debug logging")
-	hubCmd.Flags().BoolVar(&hubTestFlag, "test", false, "Enable test mode (simulate device responses)")
+	hubCmd.Flags().BoolVar(&hubTestFlag, "test", false, "Enable test mode (simulate device responses, Bravia TVs use a local simulator)")
 
 	// Add subcommands
 	hubCmd.AddCommand(hubStatusCmd)
@@ -553,6 +694,8 @@ func init() {
 	hubCmd.AddCommand(hubInitCmd)
 	hubCmd.AddCommand(hubKeysCmd)
 	hubCmd.AddCommand(hubRegisterCmd)
+	hubCmd.AddCommand(hubHomeAssistantCmd)
+	hubCmd.AddCommand(hubBrokerCmd)
 
 	// Config subcommands
 	hubConfigCmd.AddCommand(hubConfigGenerateCmd)
@@ -569,6 +712,10 @@ func init() {
 	// Register command flags
 	hubRegisterCmd.Flags().StringVar(&hubGatewayURL, "gateway-url", "", "Gateway URL for registration (required)")
 	hubRegisterCmd.Flags().StringVarP(&hubConfigPath, "config", "c", "hub.yml", "Path to hub configuration file")
+	hubRegisterCmd.Flags().BoolVar(&hubFailoverFlag, "failover", false, "Add the gateway as a failover instead of replacing the primary")
+
+	// Status command flags
+	hubStatusCmd.Flags().StringVarP(&hubConfigPath, "config", "c", "hub.yml", "Path to hub configuration file")
 
 	// Keys command flags
 	hubKeysGenerateCmd.Flags().StringVar(&hubGatewayURL, "gateway-url", "", "Gateway URL to register new keys")
@@ -579,10 +726,77 @@ func init() {
 	hubConfigGenerateCmd.Flags().StringVarP(&hubConfigPath, "config", "c", "hub.yml", "Path for generated configuration file")
 	hubConfigValidateCmd.Flags().StringVarP(&hubConfigPath, "config", "c", "hub.yml", "Path to configuration file to validate")
 
+	// Broker subcommand flags
+	hubBrokerCmd.Flags().StringVarP(&hubConfigPath, "config", "c", "hub.yml", "Path to hub configuration file")
+	hubBrokerCmd.Flags().StringVar(&hubBrokerAPIKey, "api-key", "", "Gateway user API key to authenticate with instead of the hub's product key")
+
+	// Home Assistant subcommands
+	hubHomeAssistantCmd.AddCommand(hubHomeAssistantImportCmd)
+	hubHomeAssistantImportCmd.Flags().StringVar(&hubHAURL, "url", "", "Home Assistant URL (e.g., http://homeassistant.local:8123)")
+	hubHomeAssistantImportCmd.Flags().StringVar(&hubHAToken, "token", "", "Home Assistant long-lived access token")
+	hubHomeAssistantImportCmd.Flags().StringSliceVar(&hubHAEntities, "entity", nil, "Entity ID to import (repeatable)")
+	hubHomeAssistantImportCmd.Flags().StringVarP(&hubConfigPath, "config", "c", "hub.yml", "Path to hub configuration file")
+
 	// Add to root command
 	rootCmd.AddCommand(hubCmd)
 }
 
+// queryBroker asks the gateway broker one of its management questions and prints the answer
+func queryBroker(cmd *cobra.Command, args []string) error {
+	query := args[0]
+	var argument string
+	if len(args) > 1 {
+		argument = args[1]
+	}
+	if query == "service" && argument == "" {
+		return fmt.Errorf("service name is required")
+	}
+	if query != "service" && query != "workers" && query != "stats" {
+		return fmt.Errorf("unknown query: %s (use service, workers or stats)", query)
+	}
+
+	config, err := hub.LoadConfig(hubConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	client := hermes.NewClient(config.Gateway.Endpoint, config.Hub.ID+"/cli")
+	token := hubBrokerAPIKey
+	if token == "" {
+		token = config.Hub.ProductKey
+	}
+	client.SetManagementToken(token)
+	if err := client.Start(); err != nil {
+		return fmt.Errorf("failed to connect to gateway broker: %w", err)
+	}
+	defer client.Stop()
+
+	reply, err := client.Management(hermes.MMI_PREFIX+query, []byte(argument), 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("broker query failed: %w", err)
+	}
+
+	switch string(reply) {
+	case hermes.MMI_UNAUTHORIZED:
+		return fmt.Errorf("the gateway refused the query, check that the hub is registered or pass a valid --api-key")
+	case hermes.MMI_NOT_IMPLEMENTED:
+		return fmt.Errorf("the gateway broker does not support %s", hermes.MMI_PREFIX+query)
+	case hermes.MMI_OK:
+		cmd.Printf("Service %s is available\n", argument)
+		return nil
+	case hermes.MMI_NOT_FOUND:
+		cmd.Printf("Service %s has no workers\n", argument)
+		return nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, reply, "", "  "); err != nil {
+		return fmt.Errorf("unexpected broker reply: %s", reply)
+	}
+	cmd.Println(indented.String())
+	return nil
+}
+
 // promptForRegistration asks user if they want to register with gateway now
 func promptForRegistration() bool {
 	fmt.Print("📡 Register with gateway now? [y/N]: ")
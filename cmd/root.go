@@ -53,6 +53,7 @@ func init() {
 	rootCmd.AddCommand(cliCmd)
 	rootCmd.AddCommand(hubCmd)
 	rootCmd.AddCommand(gatewayCmd)
+	rootCmd.AddCommand(simCmd)
 }
 
 // initGatewayCmd initializes the gateway command and its subcommands
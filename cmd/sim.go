@@ -0,0 +1,91 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"lucas/internal/bravia"
+	"lucas/internal/logger"
+)
+
+var (
+	simBraviaAddr    string
+	simBraviaPSK     string
+	simBraviaPIN     string
+	simBraviaLatency time.Duration
+)
+
+var simCmd = &cobra.Command{
+	Use:   "sim",
+	Short: "Run simulated devices",
+	Long: `Run simulated devices on local ports, so the CLI, hub and web UI can be
+exercised without real hardware.`,
+}
+
+var simBraviaCmd = &cobra.Command{
+	Use:   "bravia",
+	Short: "Run a simulated Sony Bravia TV",
+	Long: `Run a simulated Sony Bravia TV serving the IRCC, JSON-RPC and notification APIs.
+Add a bravia device with the simulator's address and pre-shared key to control it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger.SetSilentMode(false)
+		log := logger.New()
+
+		simulator, err := bravia.NewSimulator(bravia.SimulatorOptions{
+			Address: simBraviaAddr,
+			PSK:     simBraviaPSK,
+			PIN:     simBraviaPIN,
+			Latency: simBraviaLatency,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start simulated TV: %w", err)
+		}
+		defer simulator.Close()
+
+		log.Info().
+			Str("address", simulator.Address()).
+			Str("model", bravia.SimulatorModel).
+			Dur("latency", simBraviaLatency).
+			Msg("Simulated Bravia TV started")
+		cmd.Printf("📺 Simulated Bravia TV listening on %s\n", simulator.Address())
+		if simBraviaPSK != "" {
+			cmd.Printf("🔑 Pre-shared key: %s\n", simBraviaPSK)
+		}
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-sigChan
+		log.Info().
+			Str("signal", sig.String()).
+			Msg("Received shutdown signal")
+
+		return nil
+	},
+}
+
+func init() {
+	simBraviaCmd.Flags().StringVar(&simBraviaAddr, "addr", "127.0.0.1:8090", "Address to listen on")
+	simBraviaCmd.Flags().StringVar(&simBraviaPSK, "psk", "0000", "Pre-shared key the TV accepts, empty accepts any key")
+	simBraviaCmd.Flags().StringVar(&simBraviaPIN, "pin", "", "PIN accepted when pairing, empty accepts any PIN")
+	simBraviaCmd.Flags().DurationVar(&simBraviaLatency, "latency", 0, "Delay added before every reply")
+
+	simCmd.AddCommand(simBraviaCmd)
+}
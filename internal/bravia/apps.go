@@ -0,0 +1,374 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"encoding/json"
+	"fmt"
+	"lucas/internal/device"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Control actions for launching apps and switching inputs
+const (
+	ControlActionLaunchApp    device.ControlAction = "launch_app"
+	ControlActionSetInput     device.ControlAction = "set_input"
+	ControlActionInputsStatus device.ControlAction = "get_inputs_status"
+)
+
+// appListTTL is how long a fetched application list is trusted
+const appListTTL = 10 * time.Minute
+
+// MaxInputPort is the highest external input port number accepted
+const MaxInputPort = 4
+
+// Application is an installed app reported by getApplicationList
+type Application struct {
+	Title string `json:"title"`
+	URI   string `json:"uri"`
+	Icon  string `json:"icon,omitempty"`
+}
+
+// ExternalInput is an input reported by getCurrentExternalInputsStatus
+type ExternalInput struct {
+	URI        string `json:"uri"`
+	Title      string `json:"title"`
+	Label      string `json:"label"`
+	Icon       string `json:"icon,omitempty"`
+	Connection bool   `json:"connection"`
+	Status     string `json:"status,omitempty"`
+}
+
+// inputSchemes maps set_input names to extInput URI kinds
+var inputSchemes = map[string]string{
+	"hdmi":             "hdmi",
+	"composite":        "composite",
+	"component":        "component",
+	"screen_mirroring": "widi",
+	"mirroring":        "widi",
+	"widi":             "widi",
+}
+
+// GetApplicationList returns the apps installed on the TV
+func (c *BraviaClient) GetApplicationList() ([]Application, error) {
+	result, err := c.Call(AppControlEndpoint, GetApplicationList, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("getApplicationList returned no application list")
+	}
+
+	var apps []Application
+	if err := json.Unmarshal(result[0], &apps); err != nil {
+		return nil, fmt.Errorf("failed to parse application list: %w", err)
+	}
+	return apps, nil
+}
+
+// SetActiveApp launches the app with the given URI
+func (c *BraviaClient) SetActiveApp(uri string) error {
+	_, err := c.Call(AppControlEndpoint, SetActiveApp, []map[string]string{{"uri": uri}})
+	return err
+}
+
+// SetPlayContent switches to the content or external input with the given URI
+func (c *BraviaClient) SetPlayContent(uri string) error {
+	_, err := c.Call(AVContentEndpoint, SetPlayContent, []map[string]string{{"uri": uri}})
+	return err
+}
+
+// GetExternalInputsStatus returns the TV's external inputs and their connection state
+func (c *BraviaClient) GetExternalInputsStatus() ([]ExternalInput, error) {
+	result, err := c.Call(AVContentEndpoint, GetCurrentExternalInputsStatus, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("getCurrentExternalInputsStatus returned no input list")
+	}
+
+	var inputs []ExternalInput
+	if err := json.Unmarshal(result[0], &inputs); err != nil {
+		return nil, fmt.Errorf("failed to parse input list: %w", err)
+	}
+	return inputs, nil
+}
+
+// InputURI builds the extInput URI for an input kind and port
+func InputURI(kind string, port int) string {
+	return fmt.Sprintf("extInput:%s?port=%d", kind, port)
+}
+
+// appCache holds a device's application list between launches
+type appCache struct {
+	mutex     sync.Mutex
+	apps      []Application
+	fetchedAt time.Time
+}
+
+// find resolves an app title, refreshing the list once when it is stale or has no match
+func (a *appCache) find(client *BraviaClient, title string) (Application, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	fresh := false
+	if a.apps == nil || time.Since(a.fetchedAt) > appListTTL {
+		if err := a.refreshLocked(client); err != nil {
+			return Application{}, err
+		}
+		fresh = true
+	}
+
+	app, err := matchApplication(a.apps, title)
+	if err == errNoApplication && !fresh {
+		// The app may have been installed since the list was fetched
+		if err := a.refreshLocked(client); err != nil {
+			return Application{}, err
+		}
+		app, err = matchApplication(a.apps, title)
+	}
+	return app, err
+}
+
+// refreshLocked fetches the application list, the mutex must be held
+func (a *appCache) refreshLocked(client *BraviaClient) error {
+	apps, err := client.GetApplicationList()
+	if err != nil {
+		return fmt.Errorf("failed to list applications: %w", err)
+	}
+	a.apps = apps
+	a.fetchedAt = time.Now()
+	return nil
+}
+
+var errNoApplication = fmt.Errorf("no application matches")
+
+// matchApplication picks the app whose title best matches query: exact, then prefix, then substring
+func matchApplication(apps []Application, query string) (Application, error) {
+	key := normalizeTitle(query)
+	if key == "" {
+		return Application{}, fmt.Errorf("app title is empty")
+	}
+
+	var prefix, contains []Application
+	for _, app := range apps {
+		title := normalizeTitle(app.Title)
+		switch {
+		case title == key:
+			return app, nil
+		case strings.HasPrefix(title, key):
+			prefix = append(prefix, app)
+		case strings.Contains(title, key):
+			contains = append(contains, app)
+		}
+	}
+
+	for _, matches := range [][]Application{prefix, contains} {
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			titles := make([]string, len(matches))
+			for i, app := range matches {
+				titles[i] = app.Title
+			}
+			return Application{}, fmt.Errorf("app title %q is ambiguous: %s", query, strings.Join(titles, ", "))
+		}
+	}
+	return Application{}, errNoApplication
+}
+
+// normalizeTitle lower-cases a title and drops everything but letters and digits
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// launchApp handles the launch_app control action
+func (br *BraviaRemote) launchApp(params map[string]interface{}) *device.ActionResponse {
+	uri, _ := params["uri"].(string)
+	title, _ := params["app"].(string)
+	if title == "" {
+		title, _ = params["title"].(string)
+	}
+
+	switch {
+	case uri == "" && title == "":
+		return invalidParameters(fmt.Errorf("uri or app parameter is required for launch_app action"))
+	case uri != "" && title != "":
+		return invalidParameters(fmt.Errorf("uri and app parameters are mutually exclusive"))
+	}
+
+	app := Application{URI: uri}
+	if title != "" {
+		found, err := br.apps.find(br.client, title)
+		if err == errNoApplication {
+			return invalidParameters(fmt.Errorf("no installed app matches %q", title))
+		}
+		if err != nil {
+			return &device.ActionResponse{Success: false, Error: err.Error()}
+		}
+		app = found
+	}
+
+	if err := br.client.SetActiveApp(app.URI); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to launch app: %v", err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"title": app.Title,
+			"uri":   app.URI,
+		},
+	}
+}
+
+// setInput handles the set_input control action
+func (br *BraviaRemote) setInput(params map[string]interface{}) *device.ActionResponse {
+	uri, err := inputURIFromParams(params)
+	if err != nil {
+		return invalidParameters(err)
+	}
+
+	if err := br.client.SetPlayContent(uri); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to switch input: %v", err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    map[string]interface{}{"uri": uri},
+	}
+}
+
+// inputsStatus handles the get_inputs_status control action
+func (br *BraviaRemote) inputsStatus() *device.ActionResponse {
+	inputs, err := br.client.GetExternalInputsStatus()
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to get inputs status: %v", err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    map[string]interface{}{"inputs": inputs},
+	}
+}
+
+// inputURIFromParams validates set_input parameters and builds the extInput URI
+func inputURIFromParams(params map[string]interface{}) (string, error) {
+	if raw, exists := params["uri"]; exists {
+		uri, _ := raw.(string)
+		if !strings.HasPrefix(uri, "extInput:") {
+			return "", fmt.Errorf("uri must be an extInput: URI")
+		}
+		return uri, nil
+	}
+
+	name, _ := params["input"].(string)
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("input or uri parameter is required for set_input action")
+	}
+
+	// hdmi2 is shorthand for input hdmi, port 2
+	port := 0
+	if trimmed := strings.TrimRight(name, "0123456789"); trimmed != name {
+		port, _ = strconv.Atoi(name[len(trimmed):])
+		name = trimmed
+	}
+
+	kind, exists := inputSchemes[name]
+	if !exists {
+		return "", fmt.Errorf("unsupported input: %s", name)
+	}
+
+	if value, exists := params["port"]; exists {
+		if port != 0 {
+			return "", fmt.Errorf("port is given twice")
+		}
+		parsed, err := portParam(value)
+		if err != nil {
+			return "", err
+		}
+		port = parsed
+	}
+
+	switch {
+	case kind == "widi":
+		if port > 1 {
+			return "", fmt.Errorf("screen mirroring has no port %d", port)
+		}
+		port = 1
+	case port == 0 && kind == "hdmi":
+		return "", fmt.Errorf("port parameter is required for hdmi input")
+	case port == 0:
+		port = 1
+	}
+
+	if port < 1 || port > MaxInputPort {
+		return "", fmt.Errorf("port must be between 1 and %d", MaxInputPort)
+	}
+	return InputURI(kind, port), nil
+}
+
+// portParam converts a JSON port value to an int
+func portParam(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("port must be a whole number")
+		}
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port: %s", v)
+		}
+		return port, nil
+	default:
+		return 0, fmt.Errorf("invalid port parameter type")
+	}
+}
+
+// invalidParameters wraps a parameter validation error in a failed response
+func invalidParameters(err error) *device.ActionResponse {
+	return &device.ActionResponse{
+		Success: false,
+		Error:   fmt.Sprintf("invalid parameters: %v", err),
+	}
+}
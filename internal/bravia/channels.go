@@ -0,0 +1,470 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"encoding/json"
+	"fmt"
+	"lucas/internal/device"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Control actions for broadcast channels and programme information
+const (
+	ControlActionSources      device.ControlAction = "get_sources"
+	ControlActionChannels     device.ControlAction = "get_channels"
+	ControlActionChannelCount device.ControlAction = "channel_count"
+	ControlActionSetChannel   device.ControlAction = "set_channel"
+	ControlActionProgramInfo  device.ControlAction = "program_info"
+)
+
+// channelListTTL is how long a fetched channel list is trusted
+const channelListTTL = 10 * time.Minute
+
+// ChannelPageSize is how many channels one getContentList request asks for
+const ChannelPageSize = 100
+
+// maxChannelPages bounds paging through one source
+const maxChannelPages = 50
+
+// Channel is a broadcast service reported by getContentList
+type Channel struct {
+	URI    string `json:"uri"`
+	Title  string `json:"title"`
+	Number string `json:"number,omitempty"`
+	Source string `json:"source"`
+	Index  int    `json:"index"`
+}
+
+// ProgramInfo describes the channel and programme being watched
+type ProgramInfo struct {
+	Channel     string `json:"channel"`
+	Number      string `json:"number,omitempty"`
+	URI         string `json:"uri"`
+	Source      string `json:"source"`
+	Program     string `json:"program,omitempty"`
+	Start       string `json:"start,omitempty"`
+	DurationSec int    `json:"duration_sec,omitempty"`
+}
+
+// GetSourceList returns the broadcast sources of the TV's tuners, such as tv:dvbt
+func (c *BraviaClient) GetSourceList() ([]string, error) {
+	result, err := c.Call(AVContentEndpoint, GetSourceList, []map[string]string{{"scheme": "tv"}})
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("getSourceList returned no source list")
+	}
+
+	var entries []struct {
+		Source string `json:"source"`
+	}
+	if err := json.Unmarshal(result[0], &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse source list: %w", err)
+	}
+	sources := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Source != "" {
+			sources = append(sources, entry.Source)
+		}
+	}
+	return sources, nil
+}
+
+// GetContentCount returns the number of channels on a source
+func (c *BraviaClient) GetContentCount(source string) (int, error) {
+	result, err := c.call(AVContentEndpoint, GetContentCount, []map[string]string{{"uri": source}}, "1.1")
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("getContentCount returned no count")
+	}
+
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(result[0], &count); err != nil {
+		return 0, fmt.Errorf("failed to parse content count: %w", err)
+	}
+	return count.Count, nil
+}
+
+// GetChannels pages through the channel list of a source
+func (c *BraviaClient) GetChannels(source string) ([]Channel, error) {
+	var channels []Channel
+	for page := 0; page < maxChannelPages; page++ {
+		params := []map[string]interface{}{{"uri": source, "stIdx": len(channels), "cnt": ChannelPageSize}}
+		result, err := c.call(AVContentEndpoint, GetContentList, params, "1.5")
+		if err != nil {
+			return nil, err
+		}
+		if len(result) == 0 {
+			return nil, fmt.Errorf("getContentList returned no content list")
+		}
+
+		var entries []struct {
+			URI     string `json:"uri"`
+			Title   string `json:"title"`
+			Index   int    `json:"index"`
+			DispNum string `json:"dispNum"`
+		}
+		if err := json.Unmarshal(result[0], &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse content list: %w", err)
+		}
+		for _, entry := range entries {
+			channels = append(channels, Channel{
+				URI:    entry.URI,
+				Title:  entry.Title,
+				Number: entry.DispNum,
+				Source: source,
+				Index:  entry.Index,
+			})
+		}
+		if len(entries) < ChannelPageSize {
+			return channels, nil
+		}
+	}
+	return channels, nil
+}
+
+// GetProgramInfo returns the channel and programme being watched
+func (c *BraviaClient) GetProgramInfo() (ProgramInfo, error) {
+	result, err := c.Call(AVContentEndpoint, GetPlayingContentInfo, nil)
+	if err != nil {
+		return ProgramInfo{}, err
+	}
+	if len(result) == 0 {
+		return ProgramInfo{}, fmt.Errorf("getPlayingContentInfo returned no content")
+	}
+
+	var content struct {
+		URI           string `json:"uri"`
+		Source        string `json:"source"`
+		Title         string `json:"title"`
+		DispNum       string `json:"dispNum"`
+		ProgramTitle  string `json:"programTitle"`
+		StartDateTime string `json:"startDateTime"`
+		DurationSec   int    `json:"durationSec"`
+	}
+	if err := json.Unmarshal(result[0], &content); err != nil {
+		return ProgramInfo{}, fmt.Errorf("failed to parse playing content: %w", err)
+	}
+	if !strings.HasPrefix(content.URI, "tv:") {
+		return ProgramInfo{}, fmt.Errorf("the TV is not showing a broadcast channel")
+	}
+
+	return ProgramInfo{
+		Channel:     content.Title,
+		Number:      content.DispNum,
+		URI:         content.URI,
+		Source:      content.Source,
+		Program:     content.ProgramTitle,
+		Start:       content.StartDateTime,
+		DurationSec: content.DurationSec,
+	}, nil
+}
+
+// channelCache holds a device's channel lists between requests
+type channelCache struct {
+	mutex     sync.Mutex
+	sources   []string
+	channels  []Channel
+	fetchedAt time.Time
+}
+
+// list returns every channel, refetching when the list is stale or refresh is set
+func (cc *channelCache) list(client *BraviaClient, refresh bool) ([]Channel, error) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if refresh || cc.channels == nil || time.Since(cc.fetchedAt) > channelListTTL {
+		if err := cc.refreshLocked(client); err != nil {
+			return nil, err
+		}
+	}
+	return cc.channels, nil
+}
+
+// find resolves a channel number or name, refreshing the list once when it is stale or has no match
+func (cc *channelCache) find(client *BraviaClient, query string) (Channel, error) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	fresh := false
+	if cc.channels == nil || time.Since(cc.fetchedAt) > channelListTTL {
+		if err := cc.refreshLocked(client); err != nil {
+			return Channel{}, err
+		}
+		fresh = true
+	}
+
+	channel, err := matchChannel(cc.channels, query)
+	if err == errNoChannel && !fresh {
+		// A channel scan may have run since the list was fetched
+		if err := cc.refreshLocked(client); err != nil {
+			return Channel{}, err
+		}
+		channel, err = matchChannel(cc.channels, query)
+	}
+	return channel, err
+}
+
+// refreshLocked fetches the channels of every source, the mutex must be held
+func (cc *channelCache) refreshLocked(client *BraviaClient) error {
+	sources, err := client.GetSourceList()
+	if err != nil {
+		return fmt.Errorf("failed to list sources: %w", err)
+	}
+
+	channels := []Channel{}
+	for _, source := range sources {
+		list, err := client.GetChannels(source)
+		if err != nil {
+			return fmt.Errorf("failed to list channels of %s: %w", source, err)
+		}
+		channels = append(channels, list...)
+	}
+	cc.sources = sources
+	cc.channels = channels
+	cc.fetchedAt = time.Now()
+	return nil
+}
+
+var errNoChannel = fmt.Errorf("no channel matches")
+
+// matchChannel picks the channel with the given display number, or whose name best
+// matches query: exact, then prefix, then substring
+func matchChannel(channels []Channel, query string) (Channel, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return Channel{}, fmt.Errorf("channel is empty")
+	}
+
+	// Display numbers are zero padded by some tuners, "1" finds "001"
+	number := strings.TrimLeft(query, "0")
+	for _, channel := range channels {
+		if channel.Number != "" && (channel.Number == query || strings.TrimLeft(channel.Number, "0") == number) {
+			return channel, nil
+		}
+	}
+
+	key := normalizeTitle(query)
+	var prefix, contains []Channel
+	for _, channel := range channels {
+		title := normalizeTitle(channel.Title)
+		switch {
+		case title == key:
+			return channel, nil
+		case strings.HasPrefix(title, key):
+			prefix = append(prefix, channel)
+		case strings.Contains(title, key):
+			contains = append(contains, channel)
+		}
+	}
+
+	for _, matches := range [][]Channel{prefix, contains} {
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			titles := make([]string, len(matches))
+			for i, channel := range matches {
+				titles[i] = channel.Title
+			}
+			return Channel{}, fmt.Errorf("channel %q is ambiguous: %s", query, strings.Join(titles, ", "))
+		}
+	}
+	return Channel{}, errNoChannel
+}
+
+// sourceParam returns the optional tv: source filter
+func sourceParam(params map[string]interface{}) (string, error) {
+	raw, exists := params["source"]
+	if !exists {
+		return "", nil
+	}
+	source, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("source must be a string")
+	}
+	if !strings.HasPrefix(source, "tv:") {
+		return "", fmt.Errorf("source must be a tv: source such as tv:dvbt")
+	}
+	return source, nil
+}
+
+// sources handles the get_sources control action
+func (br *BraviaRemote) sources() *device.ActionResponse {
+	sources, err := br.client.GetSourceList()
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to list sources: %v", err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    map[string]interface{}{"sources": sources},
+	}
+}
+
+// listChannels handles the get_channels control action
+func (br *BraviaRemote) listChannels(params map[string]interface{}) *device.ActionResponse {
+	source, err := sourceParam(params)
+	if err != nil {
+		return invalidParameters(err)
+	}
+	refresh, _ := params["refresh"].(bool)
+
+	channels, err := br.channels.list(br.client, refresh)
+	if err != nil {
+		return &device.ActionResponse{Success: false, Error: err.Error()}
+	}
+	if source != "" {
+		filtered := []Channel{}
+		for _, channel := range channels {
+			if channel.Source == source {
+				filtered = append(filtered, channel)
+			}
+		}
+		channels = filtered
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"channels": channels,
+			"count":    len(channels),
+		},
+	}
+}
+
+// channelCount handles the channel_count control action
+func (br *BraviaRemote) channelCount(params map[string]interface{}) *device.ActionResponse {
+	source, err := sourceParam(params)
+	if err != nil {
+		return invalidParameters(err)
+	}
+
+	sources := []string{source}
+	if source == "" {
+		if sources, err = br.client.GetSourceList(); err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to list sources: %v", err),
+			}
+		}
+	}
+
+	counts := make(map[string]int, len(sources))
+	total := 0
+	for _, source := range sources {
+		count, err := br.client.GetContentCount(source)
+		if err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to count channels of %s: %v", source, err),
+			}
+		}
+		counts[source] = count
+		total += count
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sources": counts,
+			"total":   total,
+		},
+	}
+}
+
+// setChannel handles the set_channel control action
+func (br *BraviaRemote) setChannel(params map[string]interface{}) *device.ActionResponse {
+	uri, _ := params["uri"].(string)
+	query := ""
+	switch value := params["channel"].(type) {
+	case string:
+		query = value
+	case float64:
+		if value != float64(int(value)) {
+			return invalidParameters(fmt.Errorf("channel number must be a whole number"))
+		}
+		query = fmt.Sprintf("%d", int(value))
+	case nil:
+	default:
+		return invalidParameters(fmt.Errorf("channel must be a name or number"))
+	}
+
+	switch {
+	case uri == "" && query == "":
+		return invalidParameters(fmt.Errorf("channel or uri parameter is required for set_channel action"))
+	case uri != "" && query != "":
+		return invalidParameters(fmt.Errorf("channel and uri parameters are mutually exclusive"))
+	case uri != "" && !strings.HasPrefix(uri, "tv:"):
+		return invalidParameters(fmt.Errorf("uri must be a tv: URI"))
+	}
+
+	channel := Channel{URI: uri}
+	if query != "" {
+		found, err := br.channels.find(br.client, query)
+		if err == errNoChannel {
+			return invalidParameters(fmt.Errorf("no channel matches %q", query))
+		}
+		if err != nil {
+			return &device.ActionResponse{Success: false, Error: err.Error()}
+		}
+		channel = found
+	}
+
+	if err := br.client.SetPlayContent(channel.URI); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to switch channel: %v", err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"title":  channel.Title,
+			"number": channel.Number,
+			"uri":    channel.URI,
+		},
+	}
+}
+
+// programInfo handles the program_info control action
+func (br *BraviaRemote) programInfo() *device.ActionResponse {
+	info, err := br.client.GetProgramInfo()
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to get program info: %v", err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    info,
+	}
+}
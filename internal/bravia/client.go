@@ -16,6 +16,7 @@ package bravia
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,8 +24,10 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"sync"
 	"time"
 
+	"lucas/internal/device"
 	"lucas/internal/logger"
 
 	"github.com/rs/zerolog"
@@ -35,9 +38,20 @@ type BraviaClient struct {
 	httpClient *http.Client
 	address    string
 	credential string
+	store      device.ConfigStore
+	authMutex  sync.Mutex
+	saveMutex  sync.Mutex
 	debugMode  bool
-	testMode   bool
-	logger     zerolog.Logger
+
+	// Retry policy and API versions negotiated per method
+	rpcMutex        sync.Mutex
+	retry           RetryPolicy
+	versions        map[BraviaMethod]string
+	apiInfo         map[string]map[BraviaMethod][]string
+	apiInfoAttempts map[string]time.Time
+
+	testMode bool
+	logger   zerolog.Logger
 }
 
 func NewBraviaClient(address string, credential string, options internal.FnModeOptions) *BraviaClient {
@@ -45,31 +59,46 @@ func NewBraviaClient(address string, credential string, options internal.FnModeO
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		address:    address,
-		credential: credential,
-		debugMode:  options.Debug,
-		testMode:   options.Test,
-		logger:     logger.New(),
+		address:         address,
+		credential:      credential,
+		debugMode:       options.Debug,
+		testMode:        options.Test,
+		logger:          logger.New(),
+		retry:           DefaultRetryPolicy,
+		versions:        make(map[BraviaMethod]string),
+		apiInfoAttempts: make(map[string]time.Time),
 	}
 	if options.Debug {
 		logger.SetLevel(logger.LOG_DEBUG)
 
 	}
+	if options.Test {
+		client.useSimulator()
+	}
 
 	return client
 }
 
-// remoteRequest sends an IRCC SOAP request for remote control commands
-func (c *BraviaClient) RemoteRequest(code BraviaRemoteCode) error {
-	// Test mode: simulate successful request without HTTP call
-	if c.testMode {
-		c.logger.Info().
-			Str("code", string(code)).
+// useSimulator points a test mode client at the simulated TV for its address
+func (c *BraviaClient) useSimulator() {
+	simulator, err := testSimulator(c.address, c.credential)
+	if err != nil {
+		c.logger.Error().
+			Err(err).
 			Str("address", c.address).
-			Msg("Test mode: Remote request simulated")
-		return nil
+			Msg("Failed to start simulated Bravia TV")
+		return
 	}
 
+	c.logger.Info().
+		Str("address", c.address).
+		Str("simulator", simulator.Address()).
+		Msg("Test mode: Using simulated Bravia TV")
+	c.address = simulator.Address()
+}
+
+// remoteRequest sends an IRCC SOAP request for remote control commands
+func (c *BraviaClient) RemoteRequest(code BraviaRemoteCode) error {
 	// SOAP envelope for IRCC command
 	soapBody := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
 <s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
@@ -80,6 +109,11 @@ func (c *BraviaClient) RemoteRequest(code BraviaRemoteCode) error {
   </s:Body>
 </s:Envelope>`, string(code))
 
+	return c.sendIRCC(soapBody, true)
+}
+
+// sendIRCC posts an IRCC envelope, re-pairing once if the auth cookie has expired
+func (c *BraviaClient) sendIRCC(soapBody string, allowRepair bool) error {
 	// Build URL
 	url := fmt.Sprintf("http://%s%s", c.address, IRCCEndpoint)
 
@@ -92,7 +126,7 @@ func (c *BraviaClient) RemoteRequest(code BraviaRemoteCode) error {
 	// Set headers
 	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
 	req.Header.Set("SOAPAction", "\"urn:schemas-sony-com:service:IRCC:1#X_SendIRCC\"")
-	req.Header.Set("X-Auth-PSK", c.credential)
+	c.setAuth(req)
 
 	// Log complete HTTP request if debugMode is enabled
 	c.logHTTPRequest(req)
@@ -110,6 +144,10 @@ func (c *BraviaClient) RemoteRequest(code BraviaRemoteCode) error {
 	// Log complete HTTP response if debugMode is enabled
 	c.logHTTPResponse(resp, duration)
 
+	if allowRepair && c.repairAfter(resp) {
+		return c.sendIRCC(soapBody, false)
+	}
+
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
@@ -119,26 +157,23 @@ func (c *BraviaClient) RemoteRequest(code BraviaRemoteCode) error {
 	return nil
 }
 
-// controlRequest sends a JSON API control request
-func (c *BraviaClient) ControlRequest(endpoint BraviaEndpoint, payload BraviaPayload) (*http.Response, error) {
-	// Test mode: simulate successful request without HTTP call
-	if c.testMode {
-		c.logger.Info().
-			Str("endpoint", string(endpoint)).
-			Str("method", payload.Method).
-			Str("address", c.address).
-			Msg("Test mode: Control request simulated")
-
-		// Create a mock response for test mode
-		mockResp := &http.Response{
-			StatusCode: http.StatusOK,
-			Header:     make(http.Header),
-			Body:       io.NopCloser(strings.NewReader(`{"result": [{"status": "success"}]}`)),
-		}
-		mockResp.Header.Set("Content-Type", "application/json")
-		return mockResp, nil
+// ControlRequest sends a JSON API control request and decodes the reply. Error
+// replies are returned as *APIError, and fallback versions are tried in order
+// when the TV rejects payload.Version.
+func (c *BraviaClient) ControlRequest(endpoint BraviaEndpoint, payload BraviaPayload, fallback ...string) (*RPCResponse, error) {
+	version := payload.Version
+	if version == "" {
+		version = "1.0"
 	}
+	params := payload.Params
+	if params == nil {
+		params = []map[string]string{}
+	}
+	return c.rpc(context.Background(), endpoint, payload.ID, BraviaMethod(payload.Method), params, append([]string{version}, fallback...))
+}
 
+// post marshals a JSON-RPC payload and sends it to endpoint, re-pairing once if the auth cookie has expired
+func (c *BraviaClient) post(ctx context.Context, endpoint BraviaEndpoint, payload interface{}, allowRepair bool) (*http.Response, error) {
 	// Marshal payload to JSON
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -149,14 +184,14 @@ func (c *BraviaClient) ControlRequest(endpoint BraviaEndpoint, payload BraviaPay
 	url := fmt.Sprintf("http://%s%s", c.address, string(endpoint))
 
 	// Create request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create control request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Auth-PSK", c.credential)
+	c.setAuth(req)
 
 	// Log complete HTTP request if debugMode is enabled
 	c.logHTTPRequest(req)
@@ -173,9 +208,35 @@ func (c *BraviaClient) ControlRequest(endpoint BraviaEndpoint, payload BraviaPay
 	// Log complete HTTP response if debugMode is enabled
 	c.logHTTPResponse(resp, duration)
 
+	if allowRepair && c.repairAfter(resp) {
+		return c.post(ctx, endpoint, payload, false)
+	}
+
 	return resp, nil
 }
 
+// Call sends a JSON-RPC request and returns the elements of its result array
+func (c *BraviaClient) Call(endpoint BraviaEndpoint, method BraviaMethod, params []map[string]string) ([]json.RawMessage, error) {
+	if params == nil {
+		params = []map[string]string{}
+	}
+	return c.call(endpoint, method, params)
+}
+
+// call sends params, which may carry non-string values, trying versions in order of preference
+func (c *BraviaClient) call(endpoint BraviaEndpoint, method BraviaMethod, params interface{}, versions ...string) ([]json.RawMessage, error) {
+	return c.callContext(context.Background(), endpoint, method, params, versions...)
+}
+
+// callContext is call giving up, retries included, once ctx ends
+func (c *BraviaClient) callContext(ctx context.Context, endpoint BraviaEndpoint, method BraviaMethod, params interface{}, versions ...string) ([]json.RawMessage, error) {
+	response, err := c.rpc(ctx, endpoint, 1, method, params, versions)
+	if err != nil {
+		return nil, err
+	}
+	return response.Result, nil
+}
+
 // Helper method to create a basic payload with default values
 func CreatePayload(id int, method BraviaMethod, params []map[string]string) BraviaPayload {
 	if params == nil {
@@ -205,8 +266,8 @@ func (c *BraviaClient) logHTTPRequest(req *http.Request) {
 
 	// Mask sensitive credential in the dump
 	reqDumpStr := string(reqDump)
-	if c.credential != "" {
-		reqDumpStr = strings.ReplaceAll(reqDumpStr, c.credential, "****")
+	if secret := c.secret(); secret != "" {
+		reqDumpStr = strings.ReplaceAll(reqDumpStr, secret, "****")
 	}
 
 	c.logger.Debug().
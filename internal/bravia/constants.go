@@ -39,7 +39,7 @@ const (
 
 	// Menu Controls
 	Home BraviaRemoteCode = "AAAAAQAAAAEAAABgAw=="
-	Menu BraviaRemoteCode = "AAAAAQAAAAEAAAAbAw==" // Require to update that is not work
+	Menu BraviaRemoteCode = "AAAAAQAAAAEAAAAbAw==" // Not recognised by every model, the TV code table maps menu to ActionMenu/Options
 	Back BraviaRemoteCode = "AAAAAQAAAAEAAABjAw=="
 
 	// Input Controls
@@ -64,30 +64,66 @@ const (
 
 // API Endpoints for Sony Bravia Control
 const (
-	SystemEndpoint      BraviaEndpoint = "/sony/system"
-	AVContentEndpoint   BraviaEndpoint = "/sony/avContent"
-	AudioEndpoint       BraviaEndpoint = "/sony/audio"
-	AppControlEndpoint  BraviaEndpoint = "/sony/appControl"
-	VideoScreenEndpoint BraviaEndpoint = "/sony/videoScreen"
-	EncryptionEndpoint  BraviaEndpoint = "/sony/encryption"
-	IRCCEndpoint        BraviaEndpoint = "/sony/ircc"
+	SystemEndpoint        BraviaEndpoint = "/sony/system"
+	AVContentEndpoint     BraviaEndpoint = "/sony/avContent"
+	AudioEndpoint         BraviaEndpoint = "/sony/audio"
+	AppControlEndpoint    BraviaEndpoint = "/sony/appControl"
+	VideoScreenEndpoint   BraviaEndpoint = "/sony/videoScreen"
+	VideoEndpoint         BraviaEndpoint = "/sony/video"
+	EncryptionEndpoint    BraviaEndpoint = "/sony/encryption"
+	IRCCEndpoint          BraviaEndpoint = "/sony/ircc"
+	AccessControlEndpoint BraviaEndpoint = "/sony/accessControl"
+	GuideEndpoint         BraviaEndpoint = "/sony/guide"
 )
 
 // API Methods for Sony Bravia Control
 const (
 	// System Methods
-	GetPowerStatus       BraviaMethod = "getPowerStatus"
-	GetSystemInformation BraviaMethod = "getSystemInformation"
+	GetPowerStatus          BraviaMethod = "getPowerStatus"
+	GetSystemInformation    BraviaMethod = "getSystemInformation"
+	SetPowerStatus          BraviaMethod = "setPowerStatus"
+	GetRemoteControllerInfo BraviaMethod = "getRemoteControllerInfo"
+	GetNetworkSettings      BraviaMethod = "getNetworkSettings"
+	GetPowerSavingMode      BraviaMethod = "getPowerSavingMode"
+	SetPowerSavingMode      BraviaMethod = "setPowerSavingMode"
 
 	// Audio Methods
 	GetVolumeInformation BraviaMethod = "getVolumeInformation"
 	SetAudioVolume       BraviaMethod = "setAudioVolume"
 	SetAudioMute         BraviaMethod = "setAudioMute"
+	GetSoundSettings     BraviaMethod = "getSoundSettings"
+	SetSoundSettings     BraviaMethod = "setSoundSettings"
+
+	// Video Methods
+	GetPictureQualitySettings BraviaMethod = "getPictureQualitySettings"
+	SetPictureQualitySettings BraviaMethod = "setPictureQualitySettings"
+
+	// Video Screen Methods
+	SetSceneSetting BraviaMethod = "setSceneSetting"
 
 	// AV Content Methods
-	GetPlayingContentInfo BraviaMethod = "getPlayingContentInfo"
-	GetContentList        BraviaMethod = "getContentList"
+	GetPlayingContentInfo          BraviaMethod = "getPlayingContentInfo"
+	GetContentList                 BraviaMethod = "getContentList"
+	SetPlayContent                 BraviaMethod = "setPlayContent"
+	GetSourceList                  BraviaMethod = "getSourceList"
+	GetContentCount                BraviaMethod = "getContentCount"
+	GetCurrentExternalInputsStatus BraviaMethod = "getCurrentExternalInputsStatus"
 
 	// App Control Methods
 	GetApplicationList BraviaMethod = "getApplicationList"
+	SetActiveApp       BraviaMethod = "setActiveApp"
+	GetTextForm        BraviaMethod = "getTextForm"
+	SetTextForm        BraviaMethod = "setTextForm"
+
+	// Guide Methods
+	GetSupportedApiInfo BraviaMethod = "getSupportedApiInfo"
+
+	// Access Control Methods
+	ActRegister BraviaMethod = "actRegister"
+
+	// Notification Methods, sent over the WebSocket API
+	SwitchNotifications      BraviaMethod = "switchNotifications"
+	NotifyPowerStatus        BraviaMethod = "notifyPowerStatus"
+	NotifyVolumeInformation  BraviaMethod = "notifyVolumeInformation"
+	NotifyPlayingContentInfo BraviaMethod = "notifyPlayingContentInfo"
 )
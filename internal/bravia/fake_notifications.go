@@ -0,0 +1,42 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import "fmt"
+
+// FakeNotificationServer is a local Bravia TV serving the WebSocket notification
+// API and the getters used to resynchronise after a reconnect. It is a Simulator
+// driven only through its notification state
+type FakeNotificationServer struct {
+	*Simulator
+}
+
+// NewFakeNotificationServer starts a fake TV on a loopback port that accepts the given pre-shared key
+func NewFakeNotificationServer(psk string) (*FakeNotificationServer, error) {
+	simulator, err := NewSimulator(SimulatorOptions{PSK: psk})
+	if err != nil {
+		return nil, err
+	}
+	return &FakeNotificationServer{Simulator: simulator}, nil
+}
+
+// SetPlayingContent switches to the content's URI and notifies subscribers
+func (f *FakeNotificationServer) SetPlayingContent(content map[string]interface{}) error {
+	uri, _ := content["uri"].(string)
+	if uri == "" {
+		return fmt.Errorf("playing content has no uri")
+	}
+	return f.SetInput(uri)
+}
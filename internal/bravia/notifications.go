@@ -0,0 +1,501 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lucas/internal/device"
+	"lucas/internal/websocket"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OptionNotifications set to "false" keeps the hub from opening notification connections
+const OptionNotifications = "notifications"
+
+// State properties reported in device events
+const (
+	StateStatus         = "status"
+	StatePower          = "power"
+	StateVolume         = "volume"
+	StatePlayingContent = "playing_content"
+)
+
+// Notification reconnect backoff defaults
+const (
+	DefaultNotifyReconnectMin = time.Second
+	DefaultNotifyReconnectMax = 30 * time.Second
+)
+
+// errNotificationsUnsupported means the TV firmware has no notification channel for an endpoint
+var errNotificationsUnsupported = errors.New("notifications not supported")
+
+// notificationChannel is a notification subscribed on one WebSocket endpoint
+type notificationChannel struct {
+	endpoint BraviaEndpoint
+	method   BraviaMethod
+	property string
+}
+
+var notificationChannels = []notificationChannel{
+	{endpoint: SystemEndpoint, method: NotifyPowerStatus, property: StatePower},
+	{endpoint: AudioEndpoint, method: NotifyVolumeInformation, property: StateVolume},
+	{endpoint: AVContentEndpoint, method: NotifyPlayingContentInfo, property: StatePlayingContent},
+}
+
+// notificationMessage is a JSON-RPC request, response or notification on the WebSocket API
+type notificationMessage struct {
+	ID      int               `json:"id,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Version string            `json:"version,omitempty"`
+	Params  []json.RawMessage `json:"params,omitempty"`
+	Result  []json.RawMessage `json:"result,omitempty"`
+	Error   []interface{}     `json:"error,omitempty"`
+}
+
+// notificationName is an entry of the switchNotifications enabled and disabled lists
+type notificationName struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// notificationSwitch is the parameter and result of switchNotifications
+type notificationSwitch struct {
+	Enabled  []notificationName `json:"enabled"`
+	Disabled []notificationName `json:"disabled"`
+}
+
+// notifier holds the notification connections open and caches what they report
+type notifier struct {
+	client *BraviaClient
+
+	mutex        sync.Mutex
+	enabled      bool
+	started      bool
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+	handler      func(device.StateEvent)
+	conns        map[BraviaEndpoint]*websocket.Conn
+	live         map[string]bool
+	unreachable  bool
+	status       string
+	power        string
+	volumes      map[string]map[string]interface{}
+	content      map[string]interface{}
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+}
+
+// newNotifier creates a notifier for the TV behind client
+func newNotifier(client *BraviaClient) *notifier {
+	return &notifier{
+		client:       client,
+		enabled:      true,
+		stopCh:       make(chan struct{}),
+		conns:        make(map[BraviaEndpoint]*websocket.Conn),
+		live:         make(map[string]bool),
+		volumes:      make(map[string]map[string]interface{}),
+		reconnectMin: DefaultNotifyReconnectMin,
+		reconnectMax: DefaultNotifyReconnectMax,
+	}
+}
+
+// SetNotifications turns the notification connections on or off, they are on by default
+func (br *BraviaRemote) SetNotifications(enabled bool) {
+	br.events.mutex.Lock()
+	defer br.events.mutex.Unlock()
+	br.events.enabled = enabled
+}
+
+// SetNotificationBackoff sets the delays between reconnection attempts
+func (br *BraviaRemote) SetNotificationBackoff(min, max time.Duration) {
+	br.events.mutex.Lock()
+	defer br.events.mutex.Unlock()
+	br.events.reconnectMin = min
+	br.events.reconnectMax = max
+}
+
+// StartEvents opens the notification connections and reports state changes to handler
+func (br *BraviaRemote) StartEvents(handler func(device.StateEvent)) {
+	n := br.events
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.started || !n.enabled {
+		return
+	}
+	n.started = true
+	n.handler = handler
+
+	for _, channel := range notificationChannels {
+		n.wg.Add(1)
+		go n.run(channel)
+	}
+}
+
+// StopEvents closes the notification connections and waits for them to finish
+func (br *BraviaRemote) StopEvents() {
+	n := br.events
+	n.mutex.Lock()
+	if !n.started {
+		n.mutex.Unlock()
+		return
+	}
+	select {
+	case <-n.stopCh:
+	default:
+		close(n.stopCh)
+	}
+	for _, conn := range n.conns {
+		conn.Close()
+	}
+	n.mutex.Unlock()
+
+	n.wg.Wait()
+}
+
+// run keeps one notification channel subscribed until StopEvents
+func (n *notifier) run(channel notificationChannel) {
+	defer n.wg.Done()
+
+	n.mutex.Lock()
+	backoff, max := n.reconnectMin, n.reconnectMax
+	n.mutex.Unlock()
+
+	for {
+		connectedAt := time.Now()
+		err := n.listen(channel)
+		n.setLive(channel.property, false)
+
+		select {
+		case <-n.stopCh:
+			return
+		default:
+		}
+
+		if errors.Is(err, errNotificationsUnsupported) {
+			n.client.logger.Info().
+				Str("address", n.client.address).
+				Str("notification", string(channel.method)).
+				Msg("TV does not offer notification, state is read on request")
+			return
+		}
+		if err != nil {
+			n.client.logger.Debug().
+				Err(err).
+				Str("endpoint", string(channel.endpoint)).
+				Dur("retry_in", backoff).
+				Msg("Bravia notification connection lost")
+		}
+
+		// A long lived connection starts the backoff over
+		if time.Since(connectedAt) > max {
+			backoff = n.reconnectMin
+		}
+
+		select {
+		case <-n.stopCh:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// listen subscribes to one channel and applies notifications until the connection drops
+func (n *notifier) listen(channel notificationChannel) error {
+	url := fmt.Sprintf("ws://%s%s", n.client.address, channel.endpoint)
+	conn, resp, err := websocket.Dial(url, &websocket.DialOptions{
+		Header:  n.client.authHeader(),
+		Timeout: 10 * time.Second,
+	})
+	if err != nil {
+		if resp == nil {
+			n.setUnreachable(true)
+			return err
+		}
+		n.setUnreachable(false)
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return errNotificationsUnsupported
+		case n.client.repairAfter(resp):
+			return fmt.Errorf("auth cookie renewed, reconnecting")
+		}
+		resp.Body.Close()
+		return err
+	}
+	defer conn.Close()
+	n.setUnreachable(false)
+
+	n.mutex.Lock()
+	select {
+	case <-n.stopCh:
+		n.mutex.Unlock()
+		return nil
+	default:
+	}
+	n.conns[channel.endpoint] = conn
+	n.mutex.Unlock()
+
+	defer func() {
+		n.mutex.Lock()
+		delete(n.conns, channel.endpoint)
+		n.mutex.Unlock()
+	}()
+
+	if err := switchNotifications(conn, channel); err != nil {
+		return err
+	}
+
+	// Resynchronise state that changed while disconnected
+	n.refresh(channel)
+	n.setLive(channel.property, true)
+
+	n.client.logger.Info().
+		Str("address", n.client.address).
+		Str("notification", string(channel.method)).
+		Msg("Subscribed to Bravia notifications")
+
+	for {
+		var message notificationMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return err
+		}
+		if message.Method != string(channel.method) || len(message.Params) == 0 {
+			continue
+		}
+		n.apply(channel.property, message.Params[0])
+	}
+}
+
+// switchNotifications enables the channel's notification on conn
+func switchNotifications(conn *websocket.Conn, channel notificationChannel) error {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	request := map[string]interface{}{
+		"id":      1,
+		"version": "1.0",
+		"method":  string(SwitchNotifications),
+		"params": []notificationSwitch{{
+			Enabled:  []notificationName{{Name: string(channel.method), Version: "1.0"}},
+			Disabled: []notificationName{},
+		}},
+	}
+	if err := conn.WriteJSON(request); err != nil {
+		return fmt.Errorf("failed to send switchNotifications: %w", err)
+	}
+
+	for {
+		var message notificationMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return fmt.Errorf("failed to read switchNotifications response: %w", err)
+		}
+		if message.ID != 1 {
+			continue
+		}
+		if len(message.Error) > 0 || len(message.Result) == 0 {
+			return errNotificationsUnsupported
+		}
+
+		var result notificationSwitch
+		if err := json.Unmarshal(message.Result[0], &result); err != nil {
+			return fmt.Errorf("failed to parse switchNotifications response: %w", err)
+		}
+		for _, enabled := range result.Enabled {
+			if enabled.Name == string(channel.method) {
+				return nil
+			}
+		}
+		return errNotificationsUnsupported
+	}
+}
+
+// refresh reads the channel's current state over the control API
+func (n *notifier) refresh(channel notificationChannel) {
+	method := map[string]BraviaMethod{
+		StatePower:          GetPowerStatus,
+		StateVolume:         GetVolumeInformation,
+		StatePlayingContent: GetPlayingContentInfo,
+	}[channel.property]
+
+	result, err := n.client.Call(channel.endpoint, method, nil)
+	if err != nil || len(result) == 0 {
+		// getPlayingContentInfo fails while the TV is in standby
+		n.client.logger.Debug().Err(err).Str("method", string(method)).Msg("Failed to refresh Bravia state")
+		return
+	}
+
+	if channel.property != StateVolume {
+		n.apply(channel.property, result[0])
+		return
+	}
+	var targets []json.RawMessage
+	if err := json.Unmarshal(result[0], &targets); err != nil {
+		return
+	}
+	for _, target := range targets {
+		n.apply(StateVolume, target)
+	}
+}
+
+// apply caches a notification parameter and emits an event when the state changed
+func (n *notifier) apply(property string, raw json.RawMessage) {
+	var params map[string]interface{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return
+	}
+
+	n.mutex.Lock()
+	var value interface{}
+	changed := false
+	switch property {
+	case StatePower:
+		status, _ := params["status"].(string)
+		if status == "" {
+			n.mutex.Unlock()
+			return
+		}
+		changed = status != n.power
+		n.power = status
+		value = status
+	case StateVolume:
+		target, _ := params["target"].(string)
+		merged := make(map[string]interface{})
+		for key, v := range n.volumes[target] {
+			merged[key] = v
+		}
+		for key, v := range params {
+			merged[key] = v
+		}
+		changed = !reflect.DeepEqual(merged, n.volumes[target])
+		n.volumes[target] = merged
+		value = n.volumeListLocked()
+	case StatePlayingContent:
+		changed = !reflect.DeepEqual(params, n.content)
+		n.content = params
+		value = params
+	}
+	n.mutex.Unlock()
+
+	if changed {
+		n.emit(property, value)
+	}
+}
+
+// setLive records whether a channel is subscribed and updates the connection status
+func (n *notifier) setLive(property string, live bool) {
+	n.mutex.Lock()
+	n.live[property] = live
+	n.mutex.Unlock()
+	n.updateStatus()
+}
+
+// setUnreachable records whether the last connection attempt reached the TV
+func (n *notifier) setUnreachable(unreachable bool) {
+	n.mutex.Lock()
+	n.unreachable = unreachable
+	n.mutex.Unlock()
+	n.updateStatus()
+}
+
+// updateStatus derives online or offline from the channels and emits a status event on change
+func (n *notifier) updateStatus() {
+	n.mutex.Lock()
+	status := ""
+	for _, live := range n.live {
+		if live {
+			status = "online"
+		}
+	}
+	if status == "" && n.unreachable {
+		status = "offline"
+	}
+	if status == "" || status == n.status {
+		n.mutex.Unlock()
+		return
+	}
+	n.status = status
+	n.mutex.Unlock()
+
+	n.emit(StateStatus, status)
+}
+
+// emit passes a state change to the handler
+func (n *notifier) emit(property string, value interface{}) {
+	n.mutex.Lock()
+	handler := n.handler
+	n.mutex.Unlock()
+
+	if handler != nil {
+		handler(device.StateEvent{Property: property, Value: value, Time: time.Now()})
+	}
+}
+
+// connectionStatus returns "online", "offline" or "" when notifications have not said
+func (n *notifier) connectionStatus() string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.status
+}
+
+// cached answers power_status and volume_info from notification state while the channel is live
+func (n *notifier) cached(action device.ControlAction) (interface{}, bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	switch action {
+	case device.ControlActionPowerStatus:
+		if !n.live[StatePower] || n.power == "" {
+			return nil, false
+		}
+		return device.PowerStatusData(n.power), true
+	case device.ControlActionVolumeInfo:
+		if !n.live[StateVolume] || len(n.volumes) == 0 {
+			return nil, false
+		}
+		return map[string]interface{}{
+			"id":     1,
+			"result": []interface{}{n.volumeListLocked()},
+		}, true
+	}
+	return nil, false
+}
+
+// volumeListLocked returns the cached volume of each target in getVolumeInformation order
+func (n *notifier) volumeListLocked() []interface{} {
+	targets := make([]string, 0, len(n.volumes))
+	for target := range n.volumes {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	list := make([]interface{}, 0, len(targets))
+	for _, target := range targets {
+		entry := make(map[string]interface{}, len(n.volumes[target]))
+		for key, value := range n.volumes[target] {
+			entry[key] = value
+		}
+		list = append(list, entry)
+	}
+	return list
+}
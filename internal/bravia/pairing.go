@@ -0,0 +1,243 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lucas/internal/device"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CookieCredentialPrefix marks a credential obtained by PIN pairing, the rest is <client id>:<auth cookie>
+const CookieCredentialPrefix = "cookie:"
+
+// PairingNickname is the name shown in the TV's list of registered devices
+const PairingNickname = "Lucas"
+
+// authCookieName is the cookie the TV issues on registration
+const authCookieName = "auth"
+
+// ErrPINRequired is returned when the TV displays a PIN that must be entered to pair
+var ErrPINRequired = errors.New("pairing PIN required")
+
+// NewClientID returns a new client ID for registering with a TV
+func NewClientID() string {
+	return "lucas-" + uuid.New().String()
+}
+
+// CookieCredential builds the credential stored for a PIN paired device
+func CookieCredential(clientID, cookie string) string {
+	return CookieCredentialPrefix + clientID + ":" + cookie
+}
+
+// ParseCookieCredential splits a PIN pairing credential into client ID and auth cookie
+func ParseCookieCredential(credential string) (clientID, cookie string, ok bool) {
+	rest, isCookie := strings.CutPrefix(credential, CookieCredentialPrefix)
+	if !isCookie {
+		return "", "", false
+	}
+	clientID, cookie, ok = strings.Cut(rest, ":")
+	return clientID, cookie, ok && clientID != ""
+}
+
+// RequestPIN asks the TV to display a pairing PIN. A TV that already knows
+// the client registers it again without a PIN and the credential is returned.
+func (c *BraviaClient) RequestPIN(clientID string) (string, error) {
+	cookie, err := c.register(clientID, "")
+	if errors.Is(err, ErrPINRequired) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return CookieCredential(clientID, cookie), nil
+}
+
+// CompletePairing registers the client with the PIN shown on the TV and returns the credential
+func (c *BraviaClient) CompletePairing(clientID, pin string) (string, error) {
+	pin = strings.TrimSpace(pin)
+	if pin == "" {
+		return "", fmt.Errorf("PIN is required")
+	}
+	cookie, err := c.register(clientID, pin)
+	if errors.Is(err, ErrPINRequired) {
+		return "", fmt.Errorf("TV rejected the PIN")
+	}
+	if err != nil {
+		return "", err
+	}
+	return CookieCredential(clientID, cookie), nil
+}
+
+// SetCredentialStore sets where a renewed auth cookie is saved
+func (c *BraviaClient) SetCredentialStore(store device.ConfigStore) {
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+	c.store = store
+}
+
+// register calls actRegister, with HTTP basic auth carrying the PIN when one is given
+func (c *BraviaClient) register(clientID, pin string) (string, error) {
+	payload := map[string]interface{}{
+		"id":      8,
+		"version": "1.0",
+		"method":  string(ActRegister),
+		"params": []interface{}{
+			map[string]string{
+				"clientid": clientID,
+				"nickname": PairingNickname,
+				"level":    "private",
+			},
+			[]map[string]string{{"function": "WOL", "value": "yes"}},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	url := fmt.Sprintf("http://%s%s", c.address, AccessControlEndpoint)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create pairing request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if pin != "" {
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+pin)))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send pairing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return "", ErrPINRequired
+	default:
+		text, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("pairing request failed with status %d: %s", resp.StatusCode, string(text))
+	}
+
+	var response struct {
+		Error []interface{} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err == nil && len(response.Error) > 0 {
+		return "", fmt.Errorf("actRegister returned error: %v", response.Error)
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == authCookieName && cookie.Value != "" {
+			return cookie.Value, nil
+		}
+	}
+	return "", fmt.Errorf("TV did not issue an auth cookie")
+}
+
+// setAuth adds the pre-shared key or auth cookie to a request
+func (c *BraviaClient) setAuth(req *http.Request) {
+	c.authMutex.Lock()
+	credential := c.credential
+	c.authMutex.Unlock()
+
+	if _, cookie, ok := ParseCookieCredential(credential); ok {
+		req.AddCookie(&http.Cookie{Name: authCookieName, Value: cookie})
+		return
+	}
+	req.Header.Set("X-Auth-PSK", credential)
+}
+
+// authHeader returns the auth headers for a WebSocket handshake
+func (c *BraviaClient) authHeader() http.Header {
+	req := &http.Request{Header: make(http.Header)}
+	c.setAuth(req)
+	return req.Header
+}
+
+// secret returns the part of the credential sent on the wire
+func (c *BraviaClient) secret() string {
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+	if _, cookie, ok := ParseCookieCredential(c.credential); ok {
+		return cookie
+	}
+	return c.credential
+}
+
+// repairAfter re-registers when resp shows an expired auth cookie, reporting
+// whether the request should be retried. resp is closed when it returns true.
+func (c *BraviaClient) repairAfter(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+
+	clientID, _, ok := ParseCookieCredential(c.credential)
+	if !ok {
+		return false
+	}
+
+	// A registered client is issued a new cookie without a PIN
+	cookie, err := c.register(clientID, "")
+	if err != nil {
+		c.logger.Warn().
+			Err(err).
+			Str("address", c.address).
+			Msg("Auth cookie expired and re-pairing failed, pair the TV again")
+		return false
+	}
+
+	c.credential = CookieCredential(clientID, cookie)
+	if c.store != nil {
+		go c.saveCredential(c.store, c.credential)
+	}
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Auth cookie renewed")
+
+	resp.Body.Close()
+	return true
+}
+
+// saveCredential saves a renewed credential unless a later renewal replaced it. It
+// runs on its own goroutine: the store may wait for the device manager, which in
+// turn may be waiting for the notification goroutine that renewed the cookie
+func (c *BraviaClient) saveCredential(store device.ConfigStore, credential string) {
+	c.saveMutex.Lock()
+	defer c.saveMutex.Unlock()
+
+	c.authMutex.Lock()
+	current := c.credential == credential
+	c.authMutex.Unlock()
+	if !current {
+		return
+	}
+
+	if err := store.SetCredential(credential); err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to save renewed auth cookie")
+	}
+}
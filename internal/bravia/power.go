@@ -0,0 +1,306 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lucas/internal/device"
+	"lucas/internal/wol"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Option keys recognised in the device configuration options map
+const (
+	OptionMAC       = "mac"
+	OptionBroadcast = "broadcast"
+)
+
+// Power state confirmation defaults
+const (
+	DefaultPowerTimeout      = 30 * time.Second
+	DefaultPowerPollInterval = time.Second
+)
+
+// powerRequestBudget bounds setPowerStatus and its retries, leaving the rest of the
+// request's time to the Wake-on-LAN fallback and the state poll
+const powerRequestBudget = 10 * time.Second
+
+// GetPowerStatus returns the TV power status, "active" or "standby"
+func (c *BraviaClient) GetPowerStatus() (string, error) {
+	return c.getPowerStatus(context.Background())
+}
+
+// getPowerStatus is GetPowerStatus giving up, retries included, once ctx ends
+func (c *BraviaClient) getPowerStatus(ctx context.Context) (string, error) {
+	result, err := c.callContext(ctx, SystemEndpoint, GetPowerStatus, []map[string]string{})
+	if err != nil {
+		return "", err
+	}
+	if len(result) == 0 {
+		return "", fmt.Errorf("getPowerStatus returned no status")
+	}
+
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(result[0], &status); err != nil {
+		return "", fmt.Errorf("failed to parse power status: %w", err)
+	}
+	return status.Status, nil
+}
+
+// SetPowerStatus turns the TV on or puts it into standby
+func (c *BraviaClient) SetPowerStatus(on bool) error {
+	return c.setPowerStatus(context.Background(), on)
+}
+
+// setPowerStatus is SetPowerStatus giving up, retries included, once ctx ends
+func (c *BraviaClient) setPowerStatus(ctx context.Context, on bool) error {
+	// The status parameter must be a JSON boolean, which BraviaPayload cannot carry
+	_, err := c.callContext(ctx, SystemEndpoint, SetPowerStatus, []map[string]interface{}{{"status": on}})
+	return err
+}
+
+// MACAddress returns the MAC address of the TV's active network interface
+func (c *BraviaClient) MACAddress() (string, error) {
+	result, err := c.Call(SystemEndpoint, GetSystemInformation, nil)
+	if err == nil && len(result) > 0 {
+		var info struct {
+			MACAddr string `json:"macAddr"`
+		}
+		if json.Unmarshal(result[0], &info) == nil && info.MACAddr != "" {
+			return info.MACAddr, nil
+		}
+	}
+
+	// Older models only report the address per interface
+	result, err = c.Call(SystemEndpoint, GetNetworkSettings, []map[string]string{{"netif": ""}})
+	if err != nil {
+		return "", err
+	}
+	if len(result) == 0 {
+		return "", fmt.Errorf("getNetworkSettings returned no interfaces")
+	}
+
+	var interfaces []struct {
+		Netif    string `json:"netif"`
+		HWAddr   string `json:"hwAddr"`
+		IPAddrV4 string `json:"ipAddrV4"`
+	}
+	if err := json.Unmarshal(result[0], &interfaces); err != nil {
+		return "", fmt.Errorf("failed to parse network settings: %w", err)
+	}
+
+	host, _, splitErr := net.SplitHostPort(c.address)
+	if splitErr != nil {
+		host = c.address
+	}
+	mac := ""
+	for _, iface := range interfaces {
+		if iface.HWAddr == "" {
+			continue
+		}
+		if iface.IPAddrV4 == host {
+			return iface.HWAddr, nil
+		}
+		if mac == "" {
+			mac = iface.HWAddr
+		}
+	}
+	if mac == "" {
+		return "", fmt.Errorf("no interface reports a MAC address")
+	}
+	return mac, nil
+}
+
+// isUnreachable reports whether err means the TV did not answer at all
+func isUnreachable(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// SetConfigStore sets where a discovered MAC address and renewed auth cookies are saved
+func (br *BraviaRemote) SetConfigStore(store device.ConfigStore) {
+	br.client.SetCredentialStore(store)
+
+	br.powerMutex.Lock()
+	defer br.powerMutex.Unlock()
+	br.store = store
+}
+
+// SetWakeOnLAN sets the MAC address and broadcast address used to wake a TV in deep standby
+func (br *BraviaRemote) SetWakeOnLAN(mac, broadcast string) {
+	br.powerMutex.Lock()
+	defer br.powerMutex.Unlock()
+
+	// An invalid MAC is treated as unknown and learned from the TV instead
+	hw, _ := net.ParseMAC(mac)
+	br.mac = hw
+	br.broadcast = broadcast
+}
+
+// SetPowerPolling sets how the power state is confirmed after power_on and power_off
+func (br *BraviaRemote) SetPowerPolling(interval, timeout time.Duration) {
+	br.powerMutex.Lock()
+	defer br.powerMutex.Unlock()
+	br.pollInterval = interval
+	br.powerTimeout = timeout
+}
+
+// setPower switches the TV on or off and waits until the new state is reported.
+// It gives up once ctx ends so the caller is not answered after it stopped waiting
+func (br *BraviaRemote) setPower(ctx context.Context, on bool) *device.ActionResponse {
+	want := device.PowerStatusStandby
+	if on {
+		want = device.PowerStatusActive
+	} else {
+		// The TV may leave the network once off, so learn the MAC while it is still there
+		br.learnMAC()
+	}
+
+	woken := false
+	requestCtx, cancel := context.WithTimeout(ctx, powerRequestBudget)
+	err := br.client.setPowerStatus(requestCtx, on)
+	cancel()
+	switch {
+	case err == nil:
+		if on {
+			br.learnMAC()
+		}
+	case ctx.Err() != nil:
+		// A request cut short by ctx says nothing about whether the TV is reachable
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to set power status: %v", ctx.Err()),
+		}
+	case on && isUnreachable(err):
+		mac, broadcast := br.wakeTarget()
+		if mac == nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("TV is unreachable and no MAC address is known for Wake-on-LAN: %v", err),
+			}
+		}
+		if err := wol.Wake(mac, broadcast); err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("wake-on-lan failed: %v", err),
+			}
+		}
+		woken = true
+		br.client.logger.Info().
+			Str("address", br.client.address).
+			Str("mac", mac.String()).
+			Msg("TV unreachable, sent Wake-on-LAN packet")
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to set power status: %v", err),
+		}
+	}
+
+	if err := br.waitForPower(ctx, want, woken); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("power state %s not confirmed: %v", want, err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    device.PowerStatusData(want),
+	}
+}
+
+// waitForPower polls getPowerStatus until it reports want, the timeout passes or ctx ends
+func (br *BraviaRemote) waitForPower(ctx context.Context, want string, woken bool) error {
+	br.powerMutex.Lock()
+	interval, timeout := br.pollInterval, br.powerTimeout
+	br.powerMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		status, err := br.client.getPowerStatus(ctx)
+		switch {
+		case err == nil && status == want:
+			return nil
+		case err != nil && want == device.PowerStatusStandby && isUnreachable(err) && ctx.Err() == nil:
+			// Without network standby the TV drops off the network once it is off
+			return nil
+		case err == nil && woken && status == device.PowerStatusStandby:
+			// Some models wake into standby and still need to be switched on
+			woken = false
+			if err := br.client.setPowerStatus(ctx, true); err != nil {
+				return err
+			}
+		}
+
+		if err == nil {
+			err = fmt.Errorf("TV reports %s", status)
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// wakeTarget returns the Wake-on-LAN MAC and broadcast address
+func (br *BraviaRemote) wakeTarget() (net.HardwareAddr, string) {
+	br.powerMutex.Lock()
+	defer br.powerMutex.Unlock()
+	return br.mac, br.broadcast
+}
+
+// learnMAC asks the TV for its MAC address and caches it in the device configuration
+func (br *BraviaRemote) learnMAC() {
+	br.powerMutex.Lock()
+	defer br.powerMutex.Unlock()
+	// The simulated TV's address must not end up in the configuration
+	if br.mac != nil || br.client.testMode {
+		return
+	}
+
+	address, err := br.client.MACAddress()
+	if err != nil {
+		br.client.logger.Debug().Err(err).Msg("Failed to read TV MAC address")
+		return
+	}
+	mac, err := net.ParseMAC(address)
+	if err != nil {
+		br.client.logger.Debug().Err(err).Str("mac", address).Msg("TV reported an invalid MAC address")
+		return
+	}
+	br.mac = mac
+
+	if br.store == nil {
+		return
+	}
+	if err := br.store.SetOption(OptionMAC, mac.String()); err != nil {
+		br.client.logger.Warn().Err(err).Msg("Failed to save TV MAC address")
+		return
+	}
+	br.client.logger.Info().
+		Str("address", br.client.address).
+		Str("mac", mac.String()).
+		Msg("Saved TV MAC address for Wake-on-LAN")
+}
@@ -15,18 +15,33 @@
 package bravia
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"lucas/internal"
 	"lucas/internal/device"
+	"net"
 	"strconv"
+	"sync"
+	"time"
 )
 
 // BraviaRemote implements the Device interface for Sony Bravia TVs
 type BraviaRemote struct {
-	client *BraviaClient
-	info   device.DeviceInfo
+	client   *BraviaClient
+	codes    *remoteCodeTable
+	apps     *appCache
+	channels *channelCache
+	events   *notifier
+	info     device.DeviceInfo
+
+	// Power on/off confirmation and Wake-on-LAN fallback
+	powerMutex   sync.Mutex
+	store        device.ConfigStore
+	mac          net.HardwareAddr
+	broadcast    string
+	pollInterval time.Duration
+	powerTimeout time.Duration
 }
 
 // NewBraviaRemote creates a new BraviaRemote device
@@ -37,7 +52,13 @@ func NewBraviaRemote(address, credential string, options *internal.FnModeOptions
 	}
 	client := NewBraviaClient(address, credential, opts)
 	return &BraviaRemote{
-		client: client,
+		client:       client,
+		codes:        &remoteCodeTable{},
+		apps:         &appCache{},
+		channels:     &channelCache{},
+		events:       newNotifier(client),
+		pollInterval: DefaultPowerPollInterval,
+		powerTimeout: DefaultPowerTimeout,
 		info: device.DeviceInfo{
 			ID:      "", // Will be set from configuration
 			Name:    "", // Will be set from configuration
@@ -51,6 +72,10 @@ func NewBraviaRemote(address, credential string, options *internal.FnModeOptions
 				"audio_control",
 				"content_control",
 				"app_control",
+				"input_control",
+				"channel_control",
+				"text_input",
+				"picture_control",
 			},
 		},
 	}
@@ -58,11 +83,20 @@ func NewBraviaRemote(address, credential string, options *internal.FnModeOptions
 
 // GetDeviceInfo returns information about this Bravia device
 func (br *BraviaRemote) GetDeviceInfo() device.DeviceInfo {
-	return br.info
+	info := br.info
+	if status := br.events.connectionStatus(); status != "" {
+		info.Status = status
+	}
+	return info
 }
 
 // Process handles JSON action requests and routes them to appropriate methods
 func (br *BraviaRemote) Process(actionJSON []byte) (*device.ActionResponse, error) {
+	return br.ProcessContext(context.Background(), actionJSON)
+}
+
+// ProcessContext implements device.ContextProcessor, power actions give up once ctx ends
+func (br *BraviaRemote) ProcessContext(ctx context.Context, actionJSON []byte) (*device.ActionResponse, error) {
 	// Parse the action request
 	request, err := parseActionRequest(actionJSON)
 	if err != nil {
@@ -75,7 +109,7 @@ func (br *BraviaRemote) Process(actionJSON []byte) (*device.ActionResponse, erro
 	// Route based on action type
 	switch request.Type {
 	case device.ActionTypeRemote:
-		return br.processRemoteAction(request)
+		return br.processRemoteAction(ctx, request)
 	case device.ActionTypeControl:
 		return br.processControlAction(request)
 	default:
@@ -87,12 +121,17 @@ func (br *BraviaRemote) Process(actionJSON []byte) (*device.ActionResponse, erro
 }
 
 // processRemoteAction handles remote control actions
-func (br *BraviaRemote) processRemoteAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+func (br *BraviaRemote) processRemoteAction(ctx context.Context, request *device.ActionRequest) (*device.ActionResponse, error) {
 	// Convert action string to RemoteAction
 	remoteAction := device.RemoteAction(request.Action)
 
-	// Look up the corresponding BraviaRemoteCode
-	code, exists := remoteActionMap[remoteAction]
+	// Discrete power actions go through the control API so the result can be confirmed
+	if remoteAction == device.RemoteActionPowerOn || remoteAction == device.RemoteActionPowerOff {
+		return br.setPower(ctx, remoteAction == device.RemoteActionPowerOn), nil
+	}
+
+	// Look up the code in the TV's own table, falling back to the static one
+	code, exists := br.codes.resolve(br.client, remoteAction)
 	if !exists {
 		return &device.ActionResponse{
 			Success: false,
@@ -120,6 +159,42 @@ func (br *BraviaRemote) processControlAction(request *device.ActionRequest) (*de
 	// Convert action string to ControlAction
 	controlAction := device.ControlAction(request.Action)
 
+	switch controlAction {
+	case ControlActionRemoteCodes:
+		source, actions := br.codes.actions(br.client)
+		return &device.ActionResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"source":  source,
+				"actions": actions,
+			},
+		}, nil
+	case ControlActionLaunchApp:
+		return br.launchApp(request.Parameters), nil
+	case ControlActionSetInput:
+		return br.setInput(request.Parameters), nil
+	case ControlActionInputsStatus:
+		return br.inputsStatus(), nil
+	case ControlActionSources:
+		return br.sources(), nil
+	case ControlActionChannels:
+		return br.listChannels(request.Parameters), nil
+	case ControlActionChannelCount:
+		return br.channelCount(request.Parameters), nil
+	case ControlActionSetChannel:
+		return br.setChannel(request.Parameters), nil
+	case ControlActionProgramInfo:
+		return br.programInfo(), nil
+	case device.ControlActionPowerStatus, device.ControlActionVolumeInfo:
+		// Notifications keep these current, so the TV is only asked when they are down
+		if data, ok := br.events.cached(controlAction); ok {
+			return &device.ActionResponse{
+				Success: true,
+				Data:    data,
+			}, nil
+		}
+	}
+
 	// Look up the corresponding endpoint and method
 	actionInfo, exists := controlActionMap[controlAction]
 	if !exists {
@@ -138,40 +213,28 @@ func (br *BraviaRemote) processControlAction(request *device.ActionRequest) (*de
 		}, nil
 	}
 
-	payload := CreatePayload(1, actionInfo.method, params)
+	if actionInfo.settings {
+		return br.applySettings(actionInfo, params), nil
+	}
 
-	// Execute the control request
-	resp, err := br.client.ControlRequest(actionInfo.endpoint, payload)
-	if err != nil {
-		return &device.ActionResponse{
-			Success: false,
-			Error:   fmt.Sprintf("control request failed: %v", err),
-		}, nil
+	payload := CreatePayload(1, actionInfo.method, params)
+	var fallback []string
+	if len(actionInfo.versions) > 0 {
+		payload.Version, fallback = actionInfo.versions[0], actionInfo.versions[1:]
 	}
-	defer resp.Body.Close()
 
-	// Read and parse response
-	responseBody, err := io.ReadAll(resp.Body)
+	// Execute the control request, error replies from the TV come back as errors
+	response, err := br.client.ControlRequest(actionInfo.endpoint, payload, fallback...)
 	if err != nil {
 		return &device.ActionResponse{
 			Success: false,
-			Error:   fmt.Sprintf("failed to read response: %v", err),
-		}, nil
-	}
-
-	// Parse JSON response
-	var responseData interface{}
-	if err := json.Unmarshal(responseBody, &responseData); err != nil {
-		// If JSON parsing fails, return raw response
-		return &device.ActionResponse{
-			Success: true,
-			Data:    string(responseBody),
+			Error:   fmt.Sprintf("control request failed: %v", err),
 		}, nil
 	}
 
 	return &device.ActionResponse{
 		Success: true,
-		Data:    responseData,
+		Data:    response.Data(),
 	}, nil
 }
 
@@ -237,6 +300,44 @@ func (br *BraviaRemote) buildControlParameters(action device.ControlAction, requ
 			return nil, fmt.Errorf("parameters are required for set_mute action")
 		}
 
+	case ControlActionSetText:
+		param, err := textParam(requestParams)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
+	case ControlActionGetText:
+		params = append(params, map[string]string{})
+
+	case ControlActionSetScene:
+		param, err := sceneParam(requestParams)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
+	case ControlActionPictureSettings, ControlActionSoundSettings:
+		param, err := settingsTargetParam(requestParams)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
+	case ControlActionSetPictureSetting, ControlActionSetSoundSetting:
+		param, err := settingParam(action, requestParams)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
+	case ControlActionSetPowerSavingMode:
+		param, err := powerSavingParam(requestParams)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+
 	default:
 		// Most actions don't require parameters
 		// If parameters are provided, we can try to convert them
@@ -307,12 +408,24 @@ var remoteActionMap = map[device.RemoteAction]BraviaRemoteCode{
 	device.RemoteActionHDMI2:       HDMI2,
 	device.RemoteActionHDMI3:       HDMI3,
 	device.RemoteActionHDMI4:       HDMI4,
+	device.RemoteActionNum0:        Num0,
+	device.RemoteActionNum1:        Num1,
+	device.RemoteActionNum2:        Num2,
+	device.RemoteActionNum3:        Num3,
+	device.RemoteActionNum4:        Num4,
+	device.RemoteActionNum5:        Num5,
+	device.RemoteActionNum6:        Num6,
+	device.RemoteActionNum7:        Num7,
+	device.RemoteActionNum8:        Num8,
+	device.RemoteActionNum9:        Num9,
 }
 
 // controlActionMap maps ControlAction to endpoint and method
 type controlActionInfo struct {
 	endpoint BraviaEndpoint
 	method   BraviaMethod
+	versions []string // API versions in order of preference, 1.0 when empty
+	settings bool     // parameters are sent as a settings list
 }
 
 var controlActionMap = map[device.ControlAction]controlActionInfo{
@@ -343,9 +456,52 @@ var controlActionMap = map[device.ControlAction]controlActionInfo{
 	device.ControlActionSetVolume: {
 		endpoint: AudioEndpoint,
 		method:   SetAudioVolume,
+		versions: []string{"1.0", "1.2"},
 	},
 	device.ControlActionSetMute: {
 		endpoint: AudioEndpoint,
 		method:   SetAudioMute,
 	},
+	ControlActionGetText: {
+		endpoint: AppControlEndpoint,
+		method:   GetTextForm,
+		versions: []string{"1.1"},
+	},
+	ControlActionSetText: {
+		endpoint: AppControlEndpoint,
+		method:   SetTextForm,
+		versions: []string{"1.1"},
+	},
+	ControlActionSetScene: {
+		endpoint: VideoScreenEndpoint,
+		method:   SetSceneSetting,
+	},
+	ControlActionPictureSettings: {
+		endpoint: VideoEndpoint,
+		method:   GetPictureQualitySettings,
+	},
+	ControlActionSetPictureSetting: {
+		endpoint: VideoEndpoint,
+		method:   SetPictureQualitySettings,
+		settings: true,
+	},
+	ControlActionSoundSettings: {
+		endpoint: AudioEndpoint,
+		method:   GetSoundSettings,
+		versions: []string{"1.1"},
+	},
+	ControlActionSetSoundSetting: {
+		endpoint: AudioEndpoint,
+		method:   SetSoundSettings,
+		versions: []string{"1.1"},
+		settings: true,
+	},
+	ControlActionPowerSaving: {
+		endpoint: SystemEndpoint,
+		method:   GetPowerSavingMode,
+	},
+	ControlActionSetPowerSavingMode: {
+		endpoint: SystemEndpoint,
+		method:   SetPowerSavingMode,
+	},
 }
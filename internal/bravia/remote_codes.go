@@ -0,0 +1,168 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"encoding/json"
+	"fmt"
+	"lucas/internal/device"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ControlActionRemoteCodes lists the remote actions available on the TV
+const ControlActionRemoteCodes device.ControlAction = "remote_codes"
+
+// remoteCodeRetryInterval limits how often a failed code table fetch is retried
+const remoteCodeRetryInterval = 5 * time.Minute
+
+// RemoteCode is a named IRCC code reported by getRemoteControllerInfo
+type RemoteCode struct {
+	Name  string           `json:"name"`
+	Value BraviaRemoteCode `json:"value"`
+}
+
+// GetRemoteControllerInfo fetches the model's named IRCC code list
+func (c *BraviaClient) GetRemoteControllerInfo() ([]RemoteCode, error) {
+	result, err := c.Call(SystemEndpoint, GetRemoteControllerInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	// result is [{"bundled":true,"type":"..."}, [{"name":"...","value":"..."}, ...]]
+	if len(result) < 2 {
+		return nil, fmt.Errorf("getRemoteControllerInfo returned no code list")
+	}
+
+	var codes []RemoteCode
+	if err := json.Unmarshal(result[1], &codes); err != nil {
+		return nil, fmt.Errorf("failed to parse remote code list: %w", err)
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("getRemoteControllerInfo returned an empty code list")
+	}
+	return codes, nil
+}
+
+// remoteCodeTable caches the model's code list, keyed by lower-cased name
+type remoteCodeTable struct {
+	mutex       sync.Mutex
+	codes       map[string]BraviaRemoteCode
+	loaded      bool
+	lastAttempt time.Time
+}
+
+// resolve returns the IRCC code for a remote action
+func (t *remoteCodeTable) resolve(client *BraviaClient, action device.RemoteAction) (BraviaRemoteCode, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.loadLocked(client)
+	if t.loaded {
+		for _, name := range remoteActionAliases[action] {
+			if code, exists := t.codes[strings.ToLower(name)]; exists {
+				return code, true
+			}
+		}
+		if code, exists := t.codes[strings.ToLower(string(action))]; exists {
+			return code, true
+		}
+	}
+
+	// Fall back to the static table when the TV list is unavailable or lacks the action
+	code, exists := remoteActionMap[action]
+	return code, exists
+}
+
+// actions lists every remote action name and where the codes came from
+func (t *remoteCodeTable) actions(client *BraviaClient) (string, []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.loadLocked(client)
+
+	names := make(map[string]bool)
+	for action := range remoteActionMap {
+		names[string(action)] = true
+	}
+	source := "static"
+	if t.loaded {
+		source = "tv"
+		for name := range t.codes {
+			names[name] = true
+		}
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return source, list
+}
+
+// loadLocked fetches the code list on first use, the mutex must be held
+func (t *remoteCodeTable) loadLocked(client *BraviaClient) {
+	if t.loaded || (!t.lastAttempt.IsZero() && time.Since(t.lastAttempt) < remoteCodeRetryInterval) {
+		return
+	}
+	t.lastAttempt = time.Now()
+
+	codes, err := client.GetRemoteControllerInfo()
+	if err != nil {
+		client.logger.Warn().
+			Err(err).
+			Str("address", client.address).
+			Msg("Using static remote code table")
+		return
+	}
+
+	t.codes = make(map[string]BraviaRemoteCode, len(codes))
+	for _, code := range codes {
+		t.codes[strings.ToLower(code.Name)] = code.Value
+	}
+	t.loaded = true
+
+	client.logger.Info().
+		Int("code_count", len(codes)).
+		Str("address", client.address).
+		Msg("Loaded remote code table from TV")
+}
+
+// remoteActionAliases maps standard remote actions to IRCC names, in order of preference
+var remoteActionAliases = map[device.RemoteAction][]string{
+	device.RemoteActionPower:       {"TvPower", "Power"},
+	device.RemoteActionPowerOn:     {"WakeUp"},
+	device.RemoteActionPowerOff:    {"PowerOff"},
+	device.RemoteActionVolumeUp:    {"VolumeUp"},
+	device.RemoteActionVolumeDown:  {"VolumeDown"},
+	device.RemoteActionMute:        {"Mute"},
+	device.RemoteActionChannelUp:   {"ChannelUp"},
+	device.RemoteActionChannelDown: {"ChannelDown"},
+	device.RemoteActionUp:          {"Up"},
+	device.RemoteActionDown:        {"Down"},
+	device.RemoteActionLeft:        {"Left"},
+	device.RemoteActionRight:       {"Right"},
+	device.RemoteActionConfirm:     {"Confirm"},
+	device.RemoteActionHome:        {"Home"},
+	device.RemoteActionMenu:        {"ActionMenu", "Options", "SyncMenu"},
+	device.RemoteActionBack:        {"Return", "Back"},
+	device.RemoteActionInput:       {"Input"},
+	device.RemoteActionHDMI1:       {"Hdmi1"},
+	device.RemoteActionHDMI2:       {"Hdmi2"},
+	device.RemoteActionHDMI3:       {"Hdmi3"},
+	device.RemoteActionHDMI4:       {"Hdmi4"},
+}
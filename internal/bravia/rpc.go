@@ -0,0 +1,435 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes returned in the JSON-RPC error array
+const (
+	ErrorCodeAny                  = 1
+	ErrorCodeTimeout              = 2
+	ErrorCodeIllegalArgument      = 3
+	ErrorCodeIllegalRequest       = 5
+	ErrorCodeIllegalState         = 7
+	ErrorCodeNoSuchMethod         = 12
+	ErrorCodeUnsupportedVersion   = 14
+	ErrorCodeUnsupportedOperation = 15
+	ErrorCodeForbidden            = 403
+	ErrorCodeDisplayOff           = 40005
+)
+
+// Option keys for the per-device retry policy
+const (
+	OptionRetries    = "retries"
+	OptionRetryDelay = "retry_delay"
+	OptionTimeout    = "timeout"
+)
+
+// apiInfoRetryInterval is how long to wait before asking for the supported API list again after a failure
+const apiInfoRetryInterval = 5 * time.Minute
+
+// ErrAuthFailed is returned when the TV rejects the pre-shared key or auth cookie
+var ErrAuthFailed = errors.New("authentication failed")
+
+// errorDescriptions explain the codes a user is likely to run into
+var errorDescriptions = map[int]string{
+	ErrorCodeTimeout:              "the TV timed out",
+	ErrorCodeIllegalArgument:      "illegal argument",
+	ErrorCodeIllegalState:         "illegal state, the TV may be in standby",
+	ErrorCodeNoSuchMethod:         "not supported by this TV",
+	ErrorCodeUnsupportedVersion:   "unsupported API version",
+	ErrorCodeUnsupportedOperation: "unsupported operation",
+	ErrorCodeForbidden:            "forbidden, check the pre-shared key or pair the TV again",
+	ErrorCodeDisplayOff:           "the display is turned off",
+}
+
+// APIError is an error reply from the TV
+type APIError struct {
+	Method  BraviaMethod
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	description := errorDescriptions[e.Code]
+	switch {
+	case description == "":
+		description = e.Message
+	case e.Message != "" && !strings.EqualFold(e.Message, description):
+		description = fmt.Sprintf("%s (%s)", description, e.Message)
+	}
+	return fmt.Sprintf("%s returned error %d: %s", e.Method, e.Code, description)
+}
+
+// Is makes a forbidden reply match ErrAuthFailed
+func (e *APIError) Is(target error) bool {
+	return target == ErrAuthFailed && e.Code == ErrorCodeForbidden
+}
+
+// statusError is a reply with an HTTP status other than 200
+type statusError struct {
+	method     BraviaMethod
+	statusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s failed with status %d", e.method, e.statusCode)
+}
+
+// RPCResponse is a decoded JSON-RPC reply
+type RPCResponse struct {
+	ID     int               `json:"id"`
+	Result []json.RawMessage `json:"result"`
+}
+
+// Data returns the reply in the shape the TV sent it, for action responses
+func (r *RPCResponse) Data() map[string]interface{} {
+	result := make([]interface{}, 0, len(r.Result))
+	for _, raw := range r.Result {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		result = append(result, value)
+	}
+	return map[string]interface{}{
+		"id":     r.ID,
+		"result": result,
+	}
+}
+
+// RetryPolicy controls how control requests are retried after transient failures
+type RetryPolicy struct {
+	Attempts int           // total attempts, at least one
+	Delay    time.Duration // wait before the first retry, doubled for each further retry
+	Timeout  time.Duration // HTTP timeout of each attempt
+}
+
+// DefaultRetryPolicy retries twice, which covers a TV busy waking its network stack
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts: 3,
+	Delay:    200 * time.Millisecond,
+	Timeout:  10 * time.Second,
+}
+
+// ParseRetryPolicy reads the retries, retry_delay and timeout device options
+func ParseRetryPolicy(options map[string]string) (RetryPolicy, error) {
+	policy := DefaultRetryPolicy
+
+	if value := options[OptionRetries]; value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return policy, fmt.Errorf("invalid %s: %s", OptionRetries, value)
+		}
+		policy.Attempts = retries + 1
+	}
+	if value := options[OptionRetryDelay]; value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return policy, fmt.Errorf("invalid %s: %s", OptionRetryDelay, value)
+		}
+		policy.Delay = delay
+	}
+	if value := options[OptionTimeout]; value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return policy, fmt.Errorf("invalid %s: %s", OptionTimeout, value)
+		}
+		policy.Timeout = timeout
+	}
+
+	return policy, nil
+}
+
+// SetRetryPolicy sets how control requests are retried
+func (c *BraviaClient) SetRetryPolicy(policy RetryPolicy) {
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+
+	c.rpcMutex.Lock()
+	defer c.rpcMutex.Unlock()
+	c.retry = policy
+	if policy.Timeout > 0 {
+		c.httpClient.Timeout = policy.Timeout
+	}
+}
+
+// SetRetryPolicy sets how control requests to the TV are retried
+func (br *BraviaRemote) SetRetryPolicy(policy RetryPolicy) {
+	br.client.SetRetryPolicy(policy)
+}
+
+// rpc sends a JSON-RPC request, retrying transient failures and falling back
+// through versions when the TV rejects the preferred one
+func (c *BraviaClient) rpc(ctx context.Context, endpoint BraviaEndpoint, id int, method BraviaMethod, params interface{}, versions []string) (*RPCResponse, error) {
+	if len(versions) == 0 {
+		versions = []string{"1.0"}
+	}
+	version := c.chosenVersion(method, versions)
+	tried := []string{}
+
+	for {
+		payload := map[string]interface{}{
+			"id":      id,
+			"version": version,
+			"method":  string(method),
+			"params":  params,
+		}
+		response, err := c.sendWithRetry(ctx, endpoint, method, payload)
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != ErrorCodeUnsupportedVersion {
+			return response, err
+		}
+
+		tried = append(tried, version)
+		next, negotiateErr := c.negotiate(endpoint, method, tried, versions)
+		if negotiateErr != nil {
+			return nil, negotiateErr
+		}
+		version = next
+	}
+}
+
+// sendWithRetry posts payload, retrying transient failures according to the retry policy
+// until ctx ends
+func (c *BraviaClient) sendWithRetry(ctx context.Context, endpoint BraviaEndpoint, method BraviaMethod, payload interface{}) (*RPCResponse, error) {
+	c.rpcMutex.Lock()
+	policy := c.retry
+	c.rpcMutex.Unlock()
+
+	delay := policy.Delay
+	for attempt := 1; ; attempt++ {
+		response, err := c.send(ctx, endpoint, method, payload)
+		if err == nil || attempt >= policy.Attempts || !retryable(method, err) || ctx.Err() != nil {
+			return response, err
+		}
+
+		c.logger.Debug().
+			Err(err).
+			Str("method", string(method)).
+			Int("attempt", attempt).
+			Dur("retry_in", delay).
+			Msg("Retrying Bravia control request")
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return response, err
+		}
+		delay *= 2
+	}
+}
+
+// send posts payload once and decodes the reply
+func (c *BraviaClient) send(ctx context.Context, endpoint BraviaEndpoint, method BraviaMethod, payload interface{}) (*RPCResponse, error) {
+	resp, err := c.post(ctx, endpoint, payload, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(method, resp)
+}
+
+// decodeResponse turns an HTTP reply into a result or a typed error
+func decodeResponse(method BraviaMethod, resp *http.Response) (*RPCResponse, error) {
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w: the TV rejected the credential for %s (status %d), check the pre-shared key or pair the TV again",
+			ErrAuthFailed, method, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, &statusError{method: method, statusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+
+	var envelope struct {
+		ID     int               `json:"id"`
+		Result []json.RawMessage `json:"result"`
+		Error  []json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+
+	if len(envelope.Error) > 0 {
+		apiErr := &APIError{Method: method, Code: ErrorCodeAny}
+		json.Unmarshal(envelope.Error[0], &apiErr.Code)
+		if len(envelope.Error) > 1 {
+			json.Unmarshal(envelope.Error[1], &apiErr.Message)
+		}
+		return nil, apiErr
+	}
+
+	return &RPCResponse{ID: envelope.ID, Result: envelope.Result}, nil
+}
+
+// retryable reports whether a failed request may succeed when sent again.
+// Only getters are resent when the TV may already have acted on the request.
+func retryable(method BraviaMethod, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == ErrorCodeTimeout
+	}
+
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.statusCode == http.StatusServiceUnavailable ||
+			(status.statusCode >= 500 && isQuery(method))
+	}
+
+	// A failed dial never reached the TV
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && isQuery(method)
+}
+
+// isQuery reports whether a method only reads state
+func isQuery(method BraviaMethod) bool {
+	return strings.HasPrefix(string(method), "get")
+}
+
+// chosenVersion returns the version negotiated earlier for method, or the preferred one
+func (c *BraviaClient) chosenVersion(method BraviaMethod, versions []string) string {
+	c.rpcMutex.Lock()
+	defer c.rpcMutex.Unlock()
+	if version, exists := c.versions[method]; exists && slices.Contains(versions, version) {
+		return version
+	}
+	return versions[0]
+}
+
+// negotiate picks a version of method, not yet tried, that the TV reports supporting
+func (c *BraviaClient) negotiate(endpoint BraviaEndpoint, method BraviaMethod, tried, versions []string) (string, error) {
+	supported, err := c.supportedVersions(endpoint, method)
+	if err != nil {
+		return "", &APIError{
+			Method:  method,
+			Code:    ErrorCodeUnsupportedVersion,
+			Message: fmt.Sprintf("version %s rejected and supported versions unknown: %v", strings.Join(tried, ", "), err),
+		}
+	}
+
+	for _, version := range versions {
+		if !slices.Contains(tried, version) && slices.Contains(supported, version) {
+			c.rpcMutex.Lock()
+			c.versions[method] = version
+			c.rpcMutex.Unlock()
+
+			c.logger.Info().
+				Str("method", string(method)).
+				Str("version", version).
+				Msg("Negotiated Bravia API version")
+			return version, nil
+		}
+	}
+
+	return "", &APIError{
+		Method:  method,
+		Code:    ErrorCodeUnsupportedVersion,
+		Message: fmt.Sprintf("TV supports version %s, lucas speaks %s", strings.Join(supported, ", "), strings.Join(versions, ", ")),
+	}
+}
+
+// supportedVersions returns the versions of method listed by guide.getSupportedApiInfo
+func (c *BraviaClient) supportedVersions(endpoint BraviaEndpoint, method BraviaMethod) ([]string, error) {
+	service := strings.TrimPrefix(string(endpoint), "/sony/")
+
+	c.rpcMutex.Lock()
+	defer c.rpcMutex.Unlock()
+
+	if c.apiInfo == nil || c.apiInfo[service] == nil {
+		if time.Since(c.apiInfoAttempts[service]) < apiInfoRetryInterval {
+			return nil, fmt.Errorf("supported API list unavailable")
+		}
+		c.apiInfoAttempts[service] = time.Now()
+		if err := c.loadAPIInfoLocked(service); err != nil {
+			return nil, err
+		}
+	}
+
+	versions, exists := c.apiInfo[service][method]
+	if !exists {
+		return nil, fmt.Errorf("%s is not listed for service %s", method, service)
+	}
+	return versions, nil
+}
+
+// loadAPIInfoLocked fetches the supported methods and versions of a service
+func (c *BraviaClient) loadAPIInfoLocked(service string) error {
+	payload := map[string]interface{}{
+		"id":      1,
+		"version": "1.0",
+		"method":  string(GetSupportedApiInfo),
+		"params":  []map[string]interface{}{{"services": []string{service}}},
+	}
+	response, err := c.send(context.Background(), GuideEndpoint, GetSupportedApiInfo, payload)
+	if err != nil {
+		return err
+	}
+	if len(response.Result) == 0 {
+		return fmt.Errorf("getSupportedApiInfo returned no services")
+	}
+
+	var services []struct {
+		Service string `json:"service"`
+		APIs    []struct {
+			Name     string `json:"name"`
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"apis"`
+	}
+	if err := json.Unmarshal(response.Result[0], &services); err != nil {
+		return fmt.Errorf("failed to parse supported API list: %w", err)
+	}
+
+	if c.apiInfo == nil {
+		c.apiInfo = make(map[string]map[BraviaMethod][]string)
+	}
+	for _, info := range services {
+		methods := make(map[BraviaMethod][]string, len(info.APIs))
+		for _, api := range info.APIs {
+			for _, version := range api.Versions {
+				methods[BraviaMethod(api.Name)] = append(methods[BraviaMethod(api.Name)], version.Version)
+			}
+		}
+		c.apiInfo[info.Service] = methods
+	}
+	if c.apiInfo[service] == nil {
+		return fmt.Errorf("service %s is not listed by the TV", service)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"context"
+	"fmt"
+	"lucas/internal/device"
+	"strconv"
+	"strings"
+)
+
+// Control actions for text entry, picture, sound and screen settings
+const (
+	ControlActionGetText            device.ControlAction = "get_text"
+	ControlActionSetText            device.ControlAction = "set_text"
+	ControlActionSetScene           device.ControlAction = "set_scene"
+	ControlActionPictureSettings    device.ControlAction = "get_picture_settings"
+	ControlActionSetPictureSetting  device.ControlAction = "set_picture_setting"
+	ControlActionSoundSettings      device.ControlAction = "get_sound_settings"
+	ControlActionSetSoundSetting    device.ControlAction = "set_sound_setting"
+	ControlActionPowerSaving        device.ControlAction = "get_power_saving"
+	ControlActionSetPowerSavingMode device.ControlAction = "set_power_saving"
+)
+
+// MaxTextLength is the longest text accepted by set_text
+const MaxTextLength = 1024
+
+// sceneSettings are the values accepted by setSceneSetting
+var sceneSettings = []string{"auto", "auto24pSync", "general"}
+
+// powerSavingModes maps set_power_saving modes to setPowerSavingMode values
+var powerSavingModes = map[string]string{
+	"off":        "off",
+	"low":        "low",
+	"high":       "high",
+	"pictureoff": "pictureOff",
+	"screen_off": "pictureOff",
+}
+
+// textParam builds the setTextForm parameter
+func textParam(params map[string]interface{}) (map[string]string, error) {
+	raw, exists := params["text"]
+	if !exists {
+		return nil, fmt.Errorf("text parameter is required for set_text action")
+	}
+	text, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("text must be a string")
+	}
+	if len(text) > MaxTextLength {
+		return nil, fmt.Errorf("text is longer than %d bytes", MaxTextLength)
+	}
+	return map[string]string{"text": text}, nil
+}
+
+// sceneParam builds the setSceneSetting parameter
+func sceneParam(params map[string]interface{}) (map[string]string, error) {
+	raw, exists := params["scene"]
+	if !exists {
+		return nil, fmt.Errorf("scene parameter is required for set_scene action")
+	}
+	scene, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("scene must be a string")
+	}
+	for _, value := range sceneSettings {
+		if strings.EqualFold(scene, value) {
+			return map[string]string{"value": value}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported scene: %s (expected one of %s)", scene, strings.Join(sceneSettings, ", "))
+}
+
+// powerSavingParam builds the setPowerSavingMode parameter
+func powerSavingParam(params map[string]interface{}) (map[string]string, error) {
+	raw, exists := params["mode"]
+	if !exists {
+		return nil, fmt.Errorf("mode parameter is required for set_power_saving action")
+	}
+	mode, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("mode must be a string")
+	}
+	value, exists := powerSavingModes[strings.ToLower(mode)]
+	if !exists {
+		return nil, fmt.Errorf("unsupported power saving mode: %s (expected off, low, high or screen_off)", mode)
+	}
+	return map[string]string{"mode": value}, nil
+}
+
+// settingsTargetParam builds the optional target filter of getPictureQualitySettings and getSoundSettings
+func settingsTargetParam(params map[string]interface{}) (map[string]string, error) {
+	// An empty target asks for every setting
+	target := ""
+	if raw, exists := params["target"]; exists {
+		value, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("target must be a string")
+		}
+		target = value
+	}
+	return map[string]string{"target": target}, nil
+}
+
+// settingParam builds one target and value entry for setPictureQualitySettings and setSoundSettings
+func settingParam(action device.ControlAction, params map[string]interface{}) (map[string]string, error) {
+	target, _ := params["target"].(string)
+	if target == "" {
+		return nil, fmt.Errorf("target parameter is required for %s action", action)
+	}
+
+	raw, exists := params["value"]
+	if !exists {
+		return nil, fmt.Errorf("value parameter is required for %s action", action)
+	}
+	var value string
+	switch v := raw.(type) {
+	case string:
+		value = v
+	case bool:
+		value = "off"
+		if v {
+			value = "on"
+		}
+	case float64:
+		if v != float64(int(v)) {
+			return nil, fmt.Errorf("value must be a whole number")
+		}
+		value = strconv.Itoa(int(v))
+	case int:
+		value = strconv.Itoa(v)
+	default:
+		return nil, fmt.Errorf("invalid value parameter type")
+	}
+	if value == "" {
+		return nil, fmt.Errorf("value must not be empty")
+	}
+
+	return map[string]string{"target": target, "value": value}, nil
+}
+
+// applySettings sends setting entries wrapped in the settings list the setters expect
+func (br *BraviaRemote) applySettings(info controlActionInfo, settings []map[string]string) *device.ActionResponse {
+	params := []map[string]interface{}{{"settings": settings}}
+	response, err := br.client.rpc(context.Background(), info.endpoint, 1, info.method, params, info.versions)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("control request failed: %v", err),
+		}
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    response.Data(),
+	}
+}
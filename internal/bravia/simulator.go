@@ -0,0 +1,1231 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"lucas/internal/websocket"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IRCCAction is the name faults and call counts use for IRCC commands
+const IRCCAction BraviaMethod = "X_SendIRCC"
+
+// Simulated TV identity
+const (
+	SimulatorModel = "KD-55X85J"
+	SimulatorMAC   = "02:00:5e:10:00:01"
+)
+
+// SimulatorOptions configures a Simulator
+type SimulatorOptions struct {
+	Address string        // listen address, a loopback port when empty
+	PSK     string        // pre-shared key required in X-Auth-PSK, any key is accepted when empty
+	PIN     string        // PIN accepted by actRegister, any PIN when empty
+	Latency time.Duration // delay before every HTTP reply
+}
+
+// Fault makes the simulator fail requests for a method
+type Fault struct {
+	Status  int    // HTTP status to reply with
+	Code    int    // JSON-RPC error code to reply with when Status is zero
+	Message string // error message of a JSON-RPC error
+	Drop    bool   // close the connection without replying
+	Count   int    // requests to fail, every request when zero
+}
+
+// simVolume is the volume of one audio target
+type simVolume struct {
+	Target    string `json:"target"`
+	Volume    int    `json:"volume"`
+	Mute      bool   `json:"mute"`
+	MaxVolume int    `json:"maxVolume"`
+	MinVolume int    `json:"minVolume"`
+}
+
+// simNotification is a notification waiting to be sent once the state mutex is released
+type simNotification struct {
+	endpoint BraviaEndpoint
+	method   BraviaMethod
+	params   interface{}
+}
+
+// rpcError is a JSON-RPC error reply
+type rpcError struct {
+	code    int
+	message string
+}
+
+// simulatorAPIs lists the methods and versions the simulator serves per endpoint
+var simulatorAPIs = map[BraviaEndpoint]map[BraviaMethod][]string{
+	SystemEndpoint: {
+		GetPowerStatus:          {"1.0"},
+		SetPowerStatus:          {"1.0"},
+		GetSystemInformation:    {"1.0"},
+		GetRemoteControllerInfo: {"1.0"},
+		GetNetworkSettings:      {"1.0"},
+		GetPowerSavingMode:      {"1.0"},
+		SetPowerSavingMode:      {"1.0"},
+	},
+	AudioEndpoint: {
+		GetVolumeInformation: {"1.0"},
+		SetAudioVolume:       {"1.0", "1.2"},
+		SetAudioMute:         {"1.0"},
+		GetSoundSettings:     {"1.1"},
+		SetSoundSettings:     {"1.1"},
+	},
+	VideoEndpoint: {
+		GetPictureQualitySettings: {"1.0"},
+		SetPictureQualitySettings: {"1.0"},
+	},
+	VideoScreenEndpoint: {
+		SetSceneSetting: {"1.0"},
+	},
+	AVContentEndpoint: {
+		GetPlayingContentInfo:          {"1.0"},
+		GetContentList:                 {"1.0", "1.5"},
+		SetPlayContent:                 {"1.0"},
+		GetCurrentExternalInputsStatus: {"1.0", "1.1"},
+		GetSourceList:                  {"1.0"},
+		GetContentCount:                {"1.1"},
+	},
+	AppControlEndpoint: {
+		GetApplicationList: {"1.0"},
+		SetActiveApp:       {"1.0"},
+		GetTextForm:        {"1.1"},
+		SetTextForm:        {"1.1"},
+	},
+	GuideEndpoint: {
+		GetSupportedApiInfo: {"1.0"},
+	},
+	AccessControlEndpoint: {
+		ActRegister: {"1.0"},
+	},
+}
+
+// simulatorApplications is the app list of the simulated TV
+var simulatorApplications = []Application{
+	{Title: "Netflix", URI: "com.sony.dtv.com.netflix.ninja.com.netflix.ninja.MainActivity"},
+	{Title: "YouTube", URI: "com.sony.dtv.com.google.android.youtube.tv.com.google.android.apps.youtube.tv.activity.ShellActivity"},
+	{Title: "Prime Video", URI: "com.sony.dtv.com.amazon.amazonvideo.livingroom.com.amazon.ignition.IgnitionActivity"},
+}
+
+// simChannel is a broadcast channel of the simulated TV with the programme on air
+type simChannel struct {
+	Channel
+	program string
+}
+
+// simulatorChannels is the channel list of the simulated TV, with enough terrestrial
+// channels to need more than one getContentList page
+var simulatorChannels = func() []simChannel {
+	channels := []simChannel{
+		{Channel{Title: "BBC ONE", Number: "001", Source: "tv:dvbt"}, "News at One"},
+		{Channel{Title: "BBC TWO", Number: "002", Source: "tv:dvbt"}, "Gardeners' World"},
+		{Channel{Title: "ITV1", Number: "003", Source: "tv:dvbt"}, "This Morning"},
+		{Channel{Title: "Channel 4", Number: "004", Source: "tv:dvbt"}, "Countdown"},
+		{Channel{Title: "Channel 5", Number: "005", Source: "tv:dvbt"}, "Milkshake!"},
+	}
+	for number := 6; number <= 120; number++ {
+		channels = append(channels, simChannel{Channel{Title: fmt.Sprintf("Local TV %d", number), Number: fmt.Sprintf("%03d", number), Source: "tv:dvbt"}, "Local News"})
+	}
+	channels = append(channels,
+		simChannel{Channel{Title: "Sky News", Number: "503", Source: "tv:dvbs"}, "Sky News Today"},
+		simChannel{Channel{Title: "Al Jazeera English", Number: "514", Source: "tv:dvbs"}, "Inside Story"},
+	)
+
+	index := map[string]int{}
+	for i := range channels {
+		channel := &channels[i].Channel
+		channel.Index = index[channel.Source]
+		channel.URI = fmt.Sprintf("%s?trip=9018.4161.%d&srvName=%s", channel.Source, 4000+i, url.QueryEscape(channel.Title))
+		index[channel.Source]++
+	}
+	return channels
+}()
+
+// simulatorCodes is the IRCC code list reported by getRemoteControllerInfo
+var simulatorCodes = []RemoteCode{
+	{"Power", PowerButton}, {"WakeUp", PowerOn}, {"PowerOff", PowerOff},
+	{"VolumeUp", VolumeUp}, {"VolumeDown", VolumeDown}, {"Mute", Mute},
+	{"ChannelUp", ChannelUp}, {"ChannelDown", ChannelDown},
+	{"Up", Up}, {"Down", Down}, {"Left", Left}, {"Right", Right}, {"Confirm", Confirm},
+	{"Home", Home}, {"Options", Menu}, {"Return", Back}, {"Input", Input},
+	{"Hdmi1", HDMI1}, {"Hdmi2", HDMI2}, {"Hdmi3", HDMI3}, {"Hdmi4", HDMI4},
+	{"Num0", Num0}, {"Num1", Num1}, {"Num2", Num2}, {"Num3", Num3}, {"Num4", Num4},
+	{"Num5", Num5}, {"Num6", Num6}, {"Num7", Num7}, {"Num8", Num8}, {"Num9", Num9},
+}
+
+// Simulator is a Bravia TV on a local port serving the IRCC, JSON-RPC and
+// WebSocket notification APIs Lucas uses, with consistent state
+type Simulator struct {
+	listener net.Listener
+	server   *http.Server
+
+	mutex       sync.Mutex
+	psk         string
+	pin         string
+	latency     time.Duration
+	faults      map[BraviaMethod]*Fault
+	calls       map[BraviaMethod]int
+	sent        []string
+	clients     map[string]string
+	power       string
+	volumes     map[string]*simVolume
+	input       string
+	app         string
+	text        string
+	scene       string
+	powerSaving string
+	picture     map[string]string
+	sound       map[string]string
+	pending     []simNotification
+	unsupported map[BraviaEndpoint]bool
+	subscribers map[BraviaEndpoint]map[*websocket.Conn]bool
+	conns       map[*websocket.Conn]bool
+	connects    int
+	closeOnce   sync.Once
+}
+
+// NewSimulator starts a simulated TV that is on and showing HDMI 1
+func NewSimulator(options SimulatorOptions) (*Simulator, error) {
+	address := options.Address
+	if address == "" {
+		address = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s := &Simulator{
+		listener: listener,
+		psk:      options.PSK,
+		pin:      options.PIN,
+		latency:  options.Latency,
+		faults:   make(map[BraviaMethod]*Fault),
+		calls:    make(map[BraviaMethod]int),
+		clients:  make(map[string]string),
+		power:    "active",
+		volumes: map[string]*simVolume{
+			"speaker": {Target: "speaker", Volume: 20, MaxVolume: 100},
+		},
+		input:       InputURI("hdmi", 1),
+		scene:       "auto",
+		powerSaving: "off",
+		picture:     map[string]string{"brightness": "30", "contrast": "90", "pictureMode": "standard"},
+		sound:       map[string]string{"outputTerminal": "speaker", "soundMode": "standard"},
+		unsupported: make(map[BraviaEndpoint]bool),
+		subscribers: make(map[BraviaEndpoint]map[*websocket.Conn]bool),
+		conns:       make(map[*websocket.Conn]bool),
+	}
+	s.server = &http.Server{Handler: http.HandlerFunc(s.serve)}
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// Address returns the host:port of the simulated TV
+func (s *Simulator) Address() string {
+	return s.listener.Addr().String()
+}
+
+// SetLatency sets the delay before every HTTP reply
+func (s *Simulator) SetLatency(latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.latency = latency
+}
+
+// InjectFault makes requests for method fail, IRCCAction covers IRCC commands
+func (s *Simulator) InjectFault(method BraviaMethod, fault Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults[method] = &fault
+}
+
+// ClearFaults removes every injected fault
+func (s *Simulator) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults = make(map[BraviaMethod]*Fault)
+}
+
+// SetUnsupported makes an endpoint refuse WebSocket connections, like older firmware
+func (s *Simulator) SetUnsupported(endpoint BraviaEndpoint) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unsupported[endpoint] = true
+}
+
+// SetPower changes the power status, as the TV's own remote would
+func (s *Simulator) SetPower(status string) {
+	s.mutex.Lock()
+	s.setPowerLocked(status)
+	s.mutex.Unlock()
+	s.flush()
+}
+
+// SetVolume changes the volume and mute state of a target
+func (s *Simulator) SetVolume(target string, volume int, mute bool) {
+	s.mutex.Lock()
+	entry := s.volumeLocked(target)
+	entry.Volume, entry.Mute = volume, mute
+	s.notifyVolumeLocked(entry)
+	s.mutex.Unlock()
+	s.flush()
+}
+
+// SetInput switches to an external input or channel URI
+func (s *Simulator) SetInput(uri string) error {
+	s.mutex.Lock()
+	err := s.setInputLocked(uri)
+	s.mutex.Unlock()
+	s.flush()
+	if err != nil {
+		return fmt.Errorf("%s", err.message)
+	}
+	return nil
+}
+
+// Power returns the power status
+func (s *Simulator) Power() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.power
+}
+
+// Volume returns the volume and mute state of a target
+func (s *Simulator) Volume(target string) (int, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entry, exists := s.volumes[target]
+	if !exists {
+		return 0, false
+	}
+	return entry.Volume, entry.Mute
+}
+
+// Input returns the URI of the external input or channel shown, empty while an app is active
+func (s *Simulator) Input() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.input
+}
+
+// App returns the URI of the active app, empty while an input is shown
+func (s *Simulator) App() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.app
+}
+
+// Text returns the content of the on-screen text field
+func (s *Simulator) Text() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.text
+}
+
+// Sent returns the names of the IRCC codes received, oldest first
+func (s *Simulator) Sent() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+// Calls returns how often a method was called over HTTP
+func (s *Simulator) Calls(method BraviaMethod) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls[method]
+}
+
+// Subscribers returns the number of connections subscribed to notifications on an endpoint
+func (s *Simulator) Subscribers(endpoint BraviaEndpoint) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.subscribers[endpoint])
+}
+
+// Connects returns the number of WebSocket connections accepted so far
+func (s *Simulator) Connects() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.connects
+}
+
+// DropConnections closes every WebSocket connection, as a TV restarting its network stack would
+func (s *Simulator) DropConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the simulated TV and drops open connections
+func (s *Simulator) Close() {
+	s.closeOnce.Do(func() {
+		s.server.Close()
+		s.DropConnections()
+	})
+}
+
+// trust accepts a test mode credential, the first pre-shared key seen becomes the PSK
+func (s *Simulator) trust(credential string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if clientID, cookie, ok := ParseCookieCredential(credential); ok {
+		s.clients[clientID] = cookie
+		return
+	}
+	if s.psk == "" {
+		s.psk = credential
+	}
+}
+
+// serve applies latency and faults, checks credentials and routes the request
+func (s *Simulator) serve(w http.ResponseWriter, r *http.Request) {
+	endpoint := BraviaEndpoint(r.URL.Path)
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		if !s.authorized(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		s.serveWebSocket(w, r, endpoint)
+		return
+	}
+
+	s.mutex.Lock()
+	latency := s.latency
+	s.mutex.Unlock()
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	if endpoint == IRCCEndpoint {
+		s.serveIRCC(w, r)
+		return
+	}
+
+	var request notificationMessage
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	method := BraviaMethod(request.Method)
+	if s.fail(w, method, request.ID) {
+		return
+	}
+
+	if method == ActRegister && endpoint == AccessControlEndpoint {
+		s.serveRegister(w, r, request)
+		return
+	}
+	if !s.authorized(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mutex.Lock()
+	result, rpcErr := s.callLocked(endpoint, method, request.Version, request.Params)
+	s.mutex.Unlock()
+	s.flush()
+
+	response := map[string]interface{}{"id": request.ID}
+	if rpcErr != nil {
+		response["error"] = []interface{}{rpcErr.code, rpcErr.message}
+	} else {
+		response["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// fail counts the call and answers with an injected fault, reporting whether it did
+func (s *Simulator) fail(w http.ResponseWriter, method BraviaMethod, id int) bool {
+	s.mutex.Lock()
+	s.calls[method]++
+	fault, exists := s.faults[method]
+	if exists && fault.Count > 0 {
+		fault.Count--
+		if fault.Count == 0 {
+			delete(s.faults, method)
+		}
+	}
+	s.mutex.Unlock()
+	if !exists {
+		return false
+	}
+
+	switch {
+	case fault.Drop:
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case fault.Status != 0:
+		w.WriteHeader(fault.Status)
+	default:
+		message := fault.Message
+		if message == "" {
+			message = "Simulated Fault"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "error": []interface{}{fault.Code, message}})
+	}
+	return true
+}
+
+// authorized checks the pre-shared key or a registered auth cookie
+func (s *Simulator) authorized(r *http.Request) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if cookie, err := r.Cookie(authCookieName); err == nil {
+		for _, issued := range s.clients {
+			if issued == cookie.Value {
+				return true
+			}
+		}
+	}
+	return s.psk == "" || r.Header.Get("X-Auth-PSK") == s.psk
+}
+
+// serveRegister pairs a client, asking for the PIN unless the client is already registered
+func (s *Simulator) serveRegister(w http.ResponseWriter, r *http.Request, request notificationMessage) {
+	var client struct {
+		ClientID string `json:"clientid"`
+	}
+	if len(request.Params) > 0 {
+		json.Unmarshal(request.Params[0], &client)
+	}
+	if client.ClientID == "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": request.ID, "error": []interface{}{ErrorCodeIllegalArgument, "Illegal Argument"}})
+		return
+	}
+
+	s.mutex.Lock()
+	_, registered := s.clients[client.ClientID]
+	if !registered {
+		_, pin, ok := r.BasicAuth()
+		if !ok || pin == "" || (s.pin != "" && pin != s.pin) {
+			s.mutex.Unlock()
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	cookie := newSimulatorCookie()
+	s.clients[client.ClientID] = cookie
+	s.mutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: authCookieName, Value: cookie, Path: "/sony/"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": request.ID, "result": []interface{}{}})
+}
+
+// serveIRCC applies a SOAP X_SendIRCC command
+func (s *Simulator) serveIRCC(w http.ResponseWriter, r *http.Request) {
+	if s.fail(w, IRCCAction, 0) {
+		return
+	}
+	if !s.authorized(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var envelope struct {
+		Code string `xml:"Body>X_SendIRCC>IRCCCode"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&envelope); err != nil || envelope.Code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name := ""
+	for _, code := range simulatorCodes {
+		if string(code.Value) == strings.TrimSpace(envelope.Code) {
+			name = code.Name
+			break
+		}
+	}
+	if name == "" {
+		// Real TVs answer an unknown code with a UPnP fault
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.mutex.Lock()
+	s.sent = append(s.sent, name)
+	s.pressLocked(name)
+	s.mutex.Unlock()
+	s.flush()
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:X_SendIRCCResponse xmlns:u="urn:schemas-sony-com:service:IRCC:1"/></s:Body></s:Envelope>`))
+}
+
+// pressLocked applies a remote button, only the power buttons work in standby
+func (s *Simulator) pressLocked(name string) {
+	switch name {
+	case "Power":
+		if s.power == "active" {
+			s.setPowerLocked("standby")
+		} else {
+			s.setPowerLocked("active")
+		}
+	case "WakeUp":
+		s.setPowerLocked("active")
+	case "PowerOff":
+		s.setPowerLocked("standby")
+	}
+	if s.power != "active" {
+		return
+	}
+
+	speaker := s.volumeLocked("speaker")
+	switch name {
+	case "VolumeUp":
+		s.setVolumeLocked(speaker, speaker.Volume+1)
+	case "VolumeDown":
+		s.setVolumeLocked(speaker, speaker.Volume-1)
+	case "Mute":
+		speaker.Mute = !speaker.Mute
+		s.notifyVolumeLocked(speaker)
+	case "Hdmi1", "Hdmi2", "Hdmi3", "Hdmi4":
+		port, _ := strconv.Atoi(strings.TrimPrefix(name, "Hdmi"))
+		s.setInputLocked(InputURI("hdmi", port))
+	case "ChannelUp", "ChannelDown":
+		// Channel buttons only step through the list of the tuner being watched
+		current, ok := findSimulatorChannel(s.input)
+		if !ok {
+			return
+		}
+		channels := sourceChannels(current.Source)
+		step := 1
+		if name == "ChannelDown" {
+			step = len(channels) - 1
+		}
+		s.setInputLocked(channels[(current.Index+step)%len(channels)].URI)
+	case "Input":
+		port := 1
+		if kind, current, ok := parseSimulatorInput(s.input); ok && kind == "hdmi" {
+			port = current%MaxInputPort + 1
+		}
+		s.setInputLocked(InputURI("hdmi", port))
+	}
+}
+
+// callLocked runs a JSON-RPC method against the state
+func (s *Simulator) callLocked(endpoint BraviaEndpoint, method BraviaMethod, version string, params []json.RawMessage) ([]interface{}, *rpcError) {
+	versions, exists := simulatorAPIs[endpoint][method]
+	if !exists {
+		return nil, &rpcError{ErrorCodeNoSuchMethod, string(method)}
+	}
+	if version == "" {
+		version = "1.0"
+	}
+	if !containsString(versions, version) {
+		return nil, &rpcError{ErrorCodeUnsupportedVersion, "Unsupported Version"}
+	}
+
+	var param map[string]interface{}
+	if len(params) > 0 {
+		json.Unmarshal(params[0], &param)
+	}
+
+	// Content and picture control need the display on
+	if s.power != "active" && endpoint != SystemEndpoint && endpoint != GuideEndpoint && !strings.HasPrefix(string(method), "get") {
+		return nil, &rpcError{ErrorCodeDisplayOff, "Display Is Turned off"}
+	}
+
+	switch method {
+	case GetPowerStatus:
+		return []interface{}{map[string]string{"status": s.power}}, nil
+	case SetPowerStatus:
+		on, ok := param["status"].(bool)
+		if !ok {
+			return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+		if on {
+			s.setPowerLocked("active")
+		} else {
+			s.setPowerLocked("standby")
+		}
+		return []interface{}{}, nil
+	case GetSystemInformation:
+		return []interface{}{map[string]string{
+			"product": "TV", "name": "BRAVIA", "model": SimulatorModel,
+			"macAddr": SimulatorMAC, "generation": "5.6.0",
+		}}, nil
+	case GetNetworkSettings:
+		host, _, _ := net.SplitHostPort(s.Address())
+		return []interface{}{[]map[string]string{{"netif": "eth0", "hwAddr": SimulatorMAC, "ipAddrV4": host}}}, nil
+	case GetRemoteControllerInfo:
+		return []interface{}{map[string]interface{}{"bundled": true, "type": "IR_REMOTE_BUNDLE_TYPE_AEP_N"}, simulatorCodes}, nil
+	case GetPowerSavingMode:
+		return []interface{}{map[string]string{"mode": s.powerSaving}}, nil
+	case SetPowerSavingMode:
+		mode, _ := param["mode"].(string)
+		if !containsString([]string{"off", "low", "high", "pictureOff"}, mode) {
+			return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+		s.powerSaving = mode
+		return []interface{}{}, nil
+
+	case GetVolumeInformation:
+		return []interface{}{s.volumeListLocked()}, nil
+	case SetAudioVolume:
+		return s.setAudioVolumeLocked(param)
+	case SetAudioMute:
+		mute, ok := param["status"].(bool)
+		if !ok {
+			// setAudioMute takes a boolean, a string status is tolerated
+			text, _ := param["status"].(string)
+			mute, ok = text == "true", text == "true" || text == "false"
+		}
+		if !ok {
+			return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+		speaker := s.volumeLocked("speaker")
+		speaker.Mute = mute
+		s.notifyVolumeLocked(speaker)
+		return []interface{}{}, nil
+
+	case GetSoundSettings:
+		return []interface{}{settingsList(s.sound, param)}, nil
+	case SetSoundSettings:
+		return s.applySettingsLocked(s.sound, param)
+	case GetPictureQualitySettings:
+		return []interface{}{settingsList(s.picture, param)}, nil
+	case SetPictureQualitySettings:
+		return s.applySettingsLocked(s.picture, param)
+	case SetSceneSetting:
+		value, _ := param["value"].(string)
+		if !containsString(sceneSettings, value) {
+			return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+		s.scene = value
+		return []interface{}{}, nil
+
+	case GetPlayingContentInfo:
+		if s.power != "active" || s.input == "" {
+			return nil, &rpcError{ErrorCodeIllegalState, "Illegal State"}
+		}
+		return []interface{}{s.contentLocked()}, nil
+	case GetContentList:
+		uri, _ := param["uri"].(string)
+		if version == "1.0" || !strings.HasPrefix(uri, "tv:") {
+			return []interface{}{s.inputsLocked()}, nil
+		}
+		return channelPage(uri, param)
+	case GetCurrentExternalInputsStatus:
+		return []interface{}{s.inputsLocked()}, nil
+	case GetSourceList:
+		sources := []map[string]string{}
+		if scheme, _ := param["scheme"].(string); scheme == "tv" {
+			sources = append(sources, map[string]string{"source": "tv:dvbt"}, map[string]string{"source": "tv:dvbs"})
+		}
+		return []interface{}{sources}, nil
+	case GetContentCount:
+		uri, _ := param["uri"].(string)
+		if strings.HasPrefix(uri, "tv:") {
+			return []interface{}{map[string]int{"count": len(sourceChannels(uri))}}, nil
+		}
+		return []interface{}{map[string]int{"count": MaxInputPort}}, nil
+	case SetPlayContent:
+		uri, _ := param["uri"].(string)
+		if err := s.setInputLocked(uri); err != nil {
+			return nil, err
+		}
+		return []interface{}{}, nil
+
+	case GetApplicationList:
+		return []interface{}{simulatorApplications}, nil
+	case SetActiveApp:
+		uri, _ := param["uri"].(string)
+		for _, app := range simulatorApplications {
+			if app.URI == uri {
+				s.app, s.input = uri, ""
+				return []interface{}{}, nil
+			}
+		}
+		return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+	case GetTextForm:
+		return []interface{}{map[string]string{"text": s.text}}, nil
+	case SetTextForm:
+		text, ok := param["text"].(string)
+		if !ok {
+			return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+		s.text = text
+		return []interface{}{}, nil
+
+	case GetSupportedApiInfo:
+		return []interface{}{supportedAPIList(param)}, nil
+	}
+	return nil, &rpcError{ErrorCodeNoSuchMethod, string(method)}
+}
+
+// setAudioVolumeLocked applies an absolute or "+N"/"-N" relative volume
+func (s *Simulator) setAudioVolumeLocked(param map[string]interface{}) ([]interface{}, *rpcError) {
+	target, _ := param["target"].(string)
+	if target == "" {
+		target = "speaker"
+	}
+	volume, _ := param["volume"].(string)
+	value, err := strconv.Atoi(strings.TrimPrefix(volume, "+"))
+	if err != nil {
+		return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+	}
+
+	entry := s.volumeLocked(target)
+	if strings.HasPrefix(volume, "+") || strings.HasPrefix(volume, "-") {
+		value += entry.Volume
+	}
+	s.setVolumeLocked(entry, value)
+	return []interface{}{0}, nil
+}
+
+// applySettingsLocked stores the entries of a settings list
+func (s *Simulator) applySettingsLocked(current map[string]string, param map[string]interface{}) ([]interface{}, *rpcError) {
+	entries, _ := param["settings"].([]interface{})
+	if len(entries) == 0 {
+		return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+	}
+	for _, raw := range entries {
+		entry, _ := raw.(map[string]interface{})
+		target, _ := entry["target"].(string)
+		value, _ := entry["value"].(string)
+		if target == "" || value == "" {
+			return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+		current[target] = value
+	}
+	return []interface{}{}, nil
+}
+
+// setPowerLocked changes the power status and queues a notification
+func (s *Simulator) setPowerLocked(status string) {
+	if s.power == status {
+		return
+	}
+	s.power = status
+	s.pending = append(s.pending, simNotification{SystemEndpoint, NotifyPowerStatus, map[string]string{"status": status}})
+}
+
+// setVolumeLocked clamps and applies a volume
+func (s *Simulator) setVolumeLocked(entry *simVolume, volume int) {
+	volume = max(entry.MinVolume, min(entry.MaxVolume, volume))
+	if entry.Volume == volume {
+		return
+	}
+	entry.Volume = volume
+	s.notifyVolumeLocked(entry)
+}
+
+// notifyVolumeLocked queues a volume notification for a target
+func (s *Simulator) notifyVolumeLocked(entry *simVolume) {
+	copied := *entry
+	s.pending = append(s.pending, simNotification{AudioEndpoint, NotifyVolumeInformation, copied})
+}
+
+// setInputLocked switches to an external input or channel, leaving any active app
+func (s *Simulator) setInputLocked(uri string) *rpcError {
+	if _, ok := findSimulatorChannel(uri); !ok {
+		kind, port, ok := parseSimulatorInput(uri)
+		if !ok || kind != "hdmi" {
+			return &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+		if port < 1 || port > MaxInputPort {
+			return &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+		}
+	}
+	if s.input == uri {
+		return nil
+	}
+	s.input, s.app = uri, ""
+	s.pending = append(s.pending, simNotification{AVContentEndpoint, NotifyPlayingContentInfo, s.contentLocked()})
+	return nil
+}
+
+// volumeLocked returns the volume entry of a target, adding it when missing
+func (s *Simulator) volumeLocked(target string) *simVolume {
+	entry, exists := s.volumes[target]
+	if !exists {
+		entry = &simVolume{Target: target, MaxVolume: 100}
+		s.volumes[target] = entry
+	}
+	return entry
+}
+
+// volumeListLocked returns the volume of each target, sorted by target
+func (s *Simulator) volumeListLocked() []simVolume {
+	targets := make([]string, 0, len(s.volumes))
+	for target := range s.volumes {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	list := make([]simVolume, 0, len(targets))
+	for _, target := range targets {
+		list = append(list, *s.volumes[target])
+	}
+	return list
+}
+
+// contentLocked describes the input or channel being shown
+func (s *Simulator) contentLocked() map[string]interface{} {
+	if channel, ok := findSimulatorChannel(s.input); ok {
+		return map[string]interface{}{
+			"uri":           channel.URI,
+			"source":        channel.Source,
+			"title":         channel.Title,
+			"dispNum":       channel.Number,
+			"programTitle":  channel.program,
+			"startDateTime": time.Now().Truncate(time.Hour).Format("2006-01-02T15:04:05-0700"),
+			"durationSec":   3600,
+		}
+	}
+	kind, port, _ := parseSimulatorInput(s.input)
+	return map[string]interface{}{
+		"uri":    s.input,
+		"source": "extInput:" + kind,
+		"title":  fmt.Sprintf("HDMI %d", port),
+	}
+}
+
+// inputsLocked lists the HDMI inputs, with a device connected to HDMI 1
+func (s *Simulator) inputsLocked() []ExternalInput {
+	inputs := make([]ExternalInput, 0, MaxInputPort)
+	for port := 1; port <= MaxInputPort; port++ {
+		uri := InputURI("hdmi", port)
+		status := "false"
+		if uri == s.input {
+			status = "true"
+		}
+		inputs = append(inputs, ExternalInput{
+			URI:        uri,
+			Title:      fmt.Sprintf("HDMI %d", port),
+			Connection: port == 1,
+			Status:     status,
+		})
+	}
+	return inputs
+}
+
+// flush sends the queued notifications to the connections subscribed on each endpoint
+func (s *Simulator) flush() {
+	s.mutex.Lock()
+	pending := s.pending
+	s.pending = nil
+	targets := make([][]*websocket.Conn, len(pending))
+	for i, notification := range pending {
+		for conn := range s.subscribers[notification.endpoint] {
+			targets[i] = append(targets[i], conn)
+		}
+	}
+	s.mutex.Unlock()
+
+	for i, notification := range pending {
+		message := map[string]interface{}{
+			"method":  string(notification.method),
+			"version": "1.0",
+			"params":  []interface{}{notification.params},
+		}
+		for _, conn := range targets[i] {
+			conn.WriteJSON(message)
+		}
+	}
+}
+
+// serveWebSocket answers switchNotifications and keeps the connection for notifications
+func (s *Simulator) serveWebSocket(w http.ResponseWriter, r *http.Request, endpoint BraviaEndpoint) {
+	s.mutex.Lock()
+	unsupported := s.unsupported[endpoint]
+	s.mutex.Unlock()
+	if unsupported {
+		http.NotFound(w, r)
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	s.conns[conn] = true
+	s.connects++
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		delete(s.subscribers[endpoint], conn)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	offered := ""
+	for _, channel := range notificationChannels {
+		if channel.endpoint == endpoint {
+			offered = string(channel.method)
+		}
+	}
+
+	for {
+		var request notificationMessage
+		if err := conn.ReadJSON(&request); err != nil {
+			return
+		}
+		if BraviaMethod(request.Method) != SwitchNotifications {
+			conn.WriteJSON(map[string]interface{}{"id": request.ID, "error": []interface{}{ErrorCodeNoSuchMethod, "No Such Method"}})
+			continue
+		}
+
+		var requested notificationSwitch
+		if len(request.Params) > 0 {
+			json.Unmarshal(request.Params[0], &requested)
+		}
+		result := notificationSwitch{Enabled: []notificationName{}, Disabled: []notificationName{}}
+		enabled := false
+		for _, name := range requested.Enabled {
+			if name.Name == offered {
+				enabled = true
+			}
+		}
+		if offered != "" {
+			entry := notificationName{Name: offered, Version: "1.0"}
+			if enabled {
+				result.Enabled = append(result.Enabled, entry)
+			} else {
+				result.Disabled = append(result.Disabled, entry)
+			}
+		}
+
+		s.mutex.Lock()
+		if enabled {
+			if s.subscribers[endpoint] == nil {
+				s.subscribers[endpoint] = make(map[*websocket.Conn]bool)
+			}
+			s.subscribers[endpoint][conn] = true
+		}
+		s.mutex.Unlock()
+
+		conn.WriteJSON(map[string]interface{}{"id": request.ID, "result": []interface{}{result}})
+	}
+}
+
+// settingsList returns the settings matching the optional target, sorted by target
+func settingsList(current map[string]string, param map[string]interface{}) []map[string]string {
+	target, _ := param["target"].(string)
+	names := make([]string, 0, len(current))
+	for name := range current {
+		if target == "" || name == target {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	list := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		list = append(list, map[string]string{"target": name, "currentValue": current[name]})
+	}
+	return list
+}
+
+// supportedAPIList describes the requested services for getSupportedApiInfo
+func supportedAPIList(param map[string]interface{}) []interface{} {
+	requested := map[string]bool{}
+	if services, ok := param["services"].([]interface{}); ok {
+		for _, service := range services {
+			if name, ok := service.(string); ok {
+				requested[name] = true
+			}
+		}
+	}
+
+	list := []interface{}{}
+	for endpoint, methods := range simulatorAPIs {
+		service := strings.TrimPrefix(string(endpoint), "/sony/")
+		if len(requested) > 0 && !requested[service] {
+			continue
+		}
+		apis := []interface{}{}
+		for method, versions := range methods {
+			entries := make([]map[string]string, 0, len(versions))
+			for _, version := range versions {
+				entries = append(entries, map[string]string{"version": version})
+			}
+			apis = append(apis, map[string]interface{}{"name": string(method), "versions": entries})
+		}
+		list = append(list, map[string]interface{}{"service": service, "apis": apis})
+	}
+	return list
+}
+
+// findSimulatorChannel looks up a channel by URI
+func findSimulatorChannel(uri string) (simChannel, bool) {
+	for _, channel := range simulatorChannels {
+		if channel.URI == uri {
+			return channel, true
+		}
+	}
+	return simChannel{}, false
+}
+
+// sourceChannels returns the channels of one tuner source
+func sourceChannels(source string) []simChannel {
+	var channels []simChannel
+	for _, channel := range simulatorChannels {
+		if channel.Source == source {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// channelPage serves a getContentList page of a tuner source
+func channelPage(source string, param map[string]interface{}) ([]interface{}, *rpcError) {
+	start, _ := param["stIdx"].(float64)
+	count, ok := param["cnt"].(float64)
+	if !ok {
+		count = 50
+	}
+	if start < 0 || count < 1 || count > 200 {
+		return nil, &rpcError{ErrorCodeIllegalArgument, "Illegal Argument"}
+	}
+
+	channels := sourceChannels(source)
+	page := []map[string]interface{}{}
+	for i := int(start); i < len(channels) && len(page) < int(count); i++ {
+		page = append(page, map[string]interface{}{
+			"uri":              channels[i].URI,
+			"title":            channels[i].Title,
+			"index":            channels[i].Index,
+			"dispNum":          channels[i].Number,
+			"programMediaType": "tv",
+		})
+	}
+	return []interface{}{page}, nil
+}
+
+// parseSimulatorInput splits an extInput URI into kind and port
+func parseSimulatorInput(uri string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(uri, "extInput:")
+	if !ok {
+		return "", 0, false
+	}
+	kind, query, _ := strings.Cut(rest, "?port=")
+	port, err := strconv.Atoi(query)
+	if err != nil {
+		return "", 0, false
+	}
+	return kind, port, true
+}
+
+// containsString reports whether list holds value
+func containsString(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newSimulatorCookie returns a random auth cookie value
+func newSimulatorCookie() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// TestSimulators hands out the simulated TVs standing in for test mode clients,
+// one per address so every client of an address sees the same state
+type TestSimulators struct {
+	mutex      sync.Mutex
+	simulators map[string]*Simulator
+}
+
+// NewTestSimulators creates an empty set of simulated TVs
+func NewTestSimulators() *TestSimulators {
+	return &TestSimulators{simulators: make(map[string]*Simulator)}
+}
+
+// Get returns the simulated TV for address, starting it on first use
+func (r *TestSimulators) Get(address, credential string) (*Simulator, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s, exists := r.simulators[address]
+	if !exists {
+		var err error
+		s, err = NewSimulator(SimulatorOptions{})
+		if err != nil {
+			return nil, err
+		}
+		r.simulators[address] = s
+	}
+	if credential != "" {
+		s.trust(credential)
+	}
+	return s, nil
+}
+
+// Close stops every simulated TV and forgets them
+func (r *TestSimulators) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for address, s := range r.simulators {
+		s.Close()
+		delete(r.simulators, address)
+	}
+}
+
+var (
+	testSimulatorsMutex sync.Mutex
+	testSimulators      = NewTestSimulators()
+)
+
+// SetTestSimulators makes test mode clients created from now on use simulators,
+// returning the set they used before
+func SetTestSimulators(simulators *TestSimulators) *TestSimulators {
+	testSimulatorsMutex.Lock()
+	defer testSimulatorsMutex.Unlock()
+	previous := testSimulators
+	testSimulators = simulators
+	return previous
+}
+
+// testSimulator returns the simulated TV standing in for address in test mode
+func testSimulator(address, credential string) (*Simulator, error) {
+	testSimulatorsMutex.Lock()
+	simulators := testSimulators
+	testSimulatorsMutex.Unlock()
+	return simulators.Get(address, credential)
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadlink
+
+import (
+	"errors"
+	"fmt"
+	"lucas/internal"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"lucas/internal/logger"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrLearningTimeout is returned when no IR code is captured in time
+var ErrLearningTimeout = errors.New("no IR code received before the learning timeout")
+
+// DeviceError is an error code reported by the blaster
+type DeviceError struct {
+	Code uint16
+}
+
+func (e *DeviceError) Error() string {
+	switch e.Code {
+	case ErrorAuth:
+		return "broadlink authentication error"
+	case ErrorNoData:
+		return "broadlink has no learned data"
+	default:
+		return fmt.Sprintf("broadlink error 0x%04x", e.Code)
+	}
+}
+
+// BroadlinkClient represents a client for the Broadlink RM local UDP protocol
+type BroadlinkClient struct {
+	address      string
+	mac          [6]byte
+	deviceType   uint16
+	rm4          bool
+	key          []byte
+	deviceID     uint32
+	count        uint16
+	mutex        sync.Mutex
+	timeout      time.Duration
+	pollInterval time.Duration
+	fake         *FakeBlaster
+	debugMode    bool
+	testMode     bool
+	logger       zerolog.Logger
+}
+
+// NewBroadlinkClient creates a new client, address may omit the port
+func NewBroadlinkClient(address string, mac net.HardwareAddr, deviceType uint16, rm4 bool, options internal.FnModeOptions) *BroadlinkClient {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultPort))
+	}
+	if deviceType == 0 {
+		deviceType = DefaultDeviceType
+	}
+
+	client := &BroadlinkClient{
+		address:      address,
+		deviceType:   deviceType,
+		rm4:          rm4,
+		timeout:      5 * time.Second,
+		pollInterval: time.Second,
+		debugMode:    options.Debug,
+		testMode:     options.Test,
+		logger:       logger.New(),
+	}
+	copy(client.mac[:], mac)
+	if options.Debug {
+		logger.SetLevel(logger.LOG_DEBUG)
+	}
+
+	return client
+}
+
+// SetPollInterval sets how often learned data is polled during learning
+func (c *BroadlinkClient) SetPollInterval(interval time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pollInterval = interval
+}
+
+// SendCode transmits a raw Broadlink IR/RF packet
+func (c *BroadlinkClient) SendCode(code []byte) error {
+	_, err := c.command(CommandSendData, code)
+	return err
+}
+
+// Learn enters learning mode and waits for a code to be captured
+func (c *BroadlinkClient) Learn(timeout time.Duration) ([]byte, error) {
+	if _, err := c.command(CommandEnterLearning, nil); err != nil {
+		return nil, fmt.Errorf("failed to enter learning mode: %w", err)
+	}
+
+	c.mutex.Lock()
+	interval := c.pollInterval
+	c.mutex.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		data, err := c.command(CommandCheckData, nil)
+		var deviceErr *DeviceError
+		if errors.As(err, &deviceErr) && deviceErr.Code == ErrorNoData {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read learned code: %w", err)
+		}
+		if len(data) > 0 {
+			return trimCode(data), nil
+		}
+	}
+	return nil, ErrLearningTimeout
+}
+
+// Close releases the test blaster
+func (c *BroadlinkClient) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.fake != nil {
+		c.fake.Close()
+		c.fake = nil
+	}
+}
+
+// command sends an RM command, authenticating first and once more on an auth error
+func (c *BroadlinkClient) command(command uint32, data []byte) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.ensureFake(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if c.key == nil {
+			if err := c.authenticate(); err != nil {
+				return nil, err
+			}
+		}
+
+		response, err := c.exchange(PacketCommand, commandPayload(command, data, c.rm4), c.key)
+		var deviceErr *DeviceError
+		if errors.As(err, &deviceErr) && deviceErr.Code == ErrorAuth && attempt == 0 {
+			// The blaster was reset or re-paired, authenticate again
+			c.key = nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return commandData(response.Payload, c.rm4), nil
+	}
+}
+
+// authenticate obtains the session key and device ID, the mutex must be held
+func (c *BroadlinkClient) authenticate() error {
+	response, err := c.exchange(PacketAuth, authPayload(), initialKey)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate: %w", err)
+	}
+	if len(response.Payload) < 0x14 {
+		return fmt.Errorf("invalid authentication response")
+	}
+
+	c.deviceID = uint32(response.Payload[0]) | uint32(response.Payload[1])<<8 |
+		uint32(response.Payload[2])<<16 | uint32(response.Payload[3])<<24
+	c.key = append([]byte(nil), response.Payload[0x04:0x14]...)
+
+	c.logger.Info().
+		Str("address", c.address).
+		Uint32("device_id", c.deviceID).
+		Msg("Authenticated with Broadlink device")
+	return nil
+}
+
+// exchange sends one packet and waits for its response, the mutex must be held
+func (c *BroadlinkClient) exchange(packetType uint16, payload, key []byte) (*frame, error) {
+	c.count++
+	packet, err := encodeFrame(&frame{
+		DeviceType: c.deviceType,
+		PacketType: packetType,
+		Count:      c.count,
+		MAC:        c.mac,
+		DeviceID:   c.deviceID,
+		Payload:    payload,
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("udp", c.address, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := conn.Write(packet); err != nil {
+		return nil, fmt.Errorf("failed to send packet: %w", err)
+	}
+
+	buffer := make([]byte, 2048)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return nil, fmt.Errorf("no response from device: %w", err)
+		}
+
+		response, err := decodeFrame(buffer[:n], key)
+		if err != nil {
+			c.logger.Debug().Err(err).Msg("Ignoring invalid Broadlink packet")
+			continue
+		}
+		if response.Error != ErrorNone {
+			return nil, &DeviceError{Code: response.Error}
+		}
+		return response, nil
+	}
+}
+
+// ensureFake starts the fake blaster that stands in for the device in test mode
+func (c *BroadlinkClient) ensureFake() error {
+	if !c.testMode || c.fake != nil {
+		return nil
+	}
+
+	fake, err := NewFakeBlaster()
+	if err != nil {
+		return fmt.Errorf("failed to start test blaster: %w", err)
+	}
+	// A remote button is "pressed" as soon as learning starts
+	fake.SetLearnedCode(SampleIRCode)
+	c.fake = fake
+	c.address = fake.Address()
+
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Test mode: Using fake Broadlink blaster")
+	return nil
+}
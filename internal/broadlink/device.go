@@ -0,0 +1,342 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadlink
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/device"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option keys recognised in the device configuration options map
+const (
+	OptionDeviceType = "devtype"
+	OptionRM4        = "rm4"
+	CodeOptionPrefix = "code."
+)
+
+// Control actions for managing learned codes
+const (
+	ControlActionLearn  device.ControlAction = "learn"
+	ControlActionCodes  device.ControlAction = "codes"
+	ControlActionForget device.ControlAction = "forget"
+)
+
+// Learning timeout bounds
+const (
+	DefaultLearnTimeout = 30 * time.Second
+	MaxLearnTimeout     = 120 * time.Second
+)
+
+// codeNamePattern restricts code names to what works as a remote action and a YAML key
+var codeNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// IRDevice implements the Device interface for a Broadlink IR blaster
+type IRDevice struct {
+	client *BroadlinkClient
+	store  device.ConfigStore
+	codes  map[device.RemoteAction][]byte
+	mutex  sync.RWMutex
+	info   device.DeviceInfo
+}
+
+// NewIRDevice creates a new IR device, learned codes are read from code.<name> options
+func NewIRDevice(address, mac string, config map[string]string, store device.ConfigStore, options *internal.FnModeOptions) (*IRDevice, error) {
+	var opts internal.FnModeOptions
+	if options != nil {
+		opts = *options
+	}
+
+	var hw net.HardwareAddr
+	if mac != "" {
+		parsed, err := net.ParseMAC(mac)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mac address: %w", err)
+		}
+		hw = parsed
+	}
+
+	var deviceType uint16
+	if value := config[OptionDeviceType]; value != "" {
+		parsed, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", OptionDeviceType, value)
+		}
+		deviceType = uint16(parsed)
+	}
+
+	rm4 := false
+	if value := config[OptionRM4]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", OptionRM4, value)
+		}
+		rm4 = parsed
+	}
+
+	codes := make(map[device.RemoteAction][]byte)
+	for key, value := range config {
+		name, isCode := strings.CutPrefix(key, CodeOptionPrefix)
+		if !isCode {
+			continue
+		}
+		code, err := DecodeCode(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid code %s: %w", name, err)
+		}
+		codes[device.RemoteAction(name)] = code
+	}
+
+	return &IRDevice{
+		client: NewBroadlinkClient(address, hw, deviceType, rm4, opts),
+		store:  store,
+		codes:  codes,
+		info: device.DeviceInfo{
+			ID:      "", // Will be set from configuration
+			Name:    "", // Will be set from configuration
+			Type:    "ir",
+			Model:   "Broadlink RM",
+			Address: address,
+			Status:  "unknown", // Will be determined by hub
+			Capabilities: []string{
+				"remote_control",
+				"ir_learning",
+			},
+		},
+	}, nil
+}
+
+// GetDeviceInfo returns information about this IR device
+func (d *IRDevice) GetDeviceInfo() device.DeviceInfo {
+	return d.info
+}
+
+// Client returns the underlying Broadlink client
+func (d *IRDevice) Client() *BroadlinkClient {
+	return d.client
+}
+
+// Process handles JSON action requests and routes them to appropriate methods
+func (d *IRDevice) Process(actionJSON []byte) (*device.ActionResponse, error) {
+	request, err := device.ParseActionRequest(actionJSON)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	switch request.Type {
+	case device.ActionTypeRemote:
+		return d.processRemoteAction(request)
+	case device.ActionTypeControl:
+		return d.processControlAction(request)
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported action type: %s", request.Type),
+		}, nil
+	}
+}
+
+// processRemoteAction sends the learned code named by the action
+func (d *IRDevice) processRemoteAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	d.mutex.RLock()
+	code, exists := d.codes[device.RemoteAction(request.Action)]
+	d.mutex.RUnlock()
+
+	if !exists {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("no IR code learned for remote action: %s", request.Action),
+		}, nil
+	}
+
+	if err := d.client.SendCode(code); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("remote request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    fmt.Sprintf("Remote action '%s' executed successfully", request.Action),
+	}, nil
+}
+
+// processControlAction handles learning and code management
+func (d *IRDevice) processControlAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	switch device.ControlAction(request.Action) {
+	case ControlActionLearn:
+		return d.learn(request.Parameters)
+
+	case ControlActionCodes:
+		d.mutex.RLock()
+		names := make([]string, 0, len(d.codes))
+		for name := range d.codes {
+			names = append(names, string(name))
+		}
+		d.mutex.RUnlock()
+		sort.Strings(names)
+
+		return &device.ActionResponse{
+			Success: true,
+			Data:    map[string]interface{}{"codes": names},
+		}, nil
+
+	case ControlActionForget:
+		name, err := codeName(request.Parameters)
+		if err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid parameters: %v", err),
+			}, nil
+		}
+
+		d.mutex.Lock()
+		delete(d.codes, device.RemoteAction(name))
+		d.mutex.Unlock()
+
+		if err := d.persist(name, ""); err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to remove code: %v", err),
+			}, nil
+		}
+
+		return &device.ActionResponse{
+			Success: true,
+			Data:    fmt.Sprintf("Code '%s' removed", name),
+		}, nil
+
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported control action: %s", request.Action),
+		}, nil
+	}
+}
+
+// learn captures a code from the physical remote and stores it under a name
+func (d *IRDevice) learn(params map[string]interface{}) (*device.ActionResponse, error) {
+	name, err := codeName(params)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid parameters: %v", err),
+		}, nil
+	}
+
+	timeout := DefaultLearnTimeout
+	if value, exists := params["timeout"]; exists {
+		var seconds float64
+		switch v := value.(type) {
+		case float64:
+			seconds = v
+		case int:
+			seconds = float64(v)
+		case string:
+			seconds, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				return &device.ActionResponse{
+					Success: false,
+					Error:   fmt.Sprintf("invalid parameters: invalid timeout: %s", v),
+				}, nil
+			}
+		default:
+			return &device.ActionResponse{
+				Success: false,
+				Error:   "invalid parameters: invalid timeout type",
+			}, nil
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+		if timeout <= 0 || timeout > MaxLearnTimeout {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid parameters: timeout must be between 0 and %d seconds", int(MaxLearnTimeout.Seconds())),
+			}, nil
+		}
+	}
+
+	code, err := d.client.Learn(timeout)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("learning failed: %v", err),
+		}, nil
+	}
+
+	d.mutex.Lock()
+	d.codes[device.RemoteAction(name)] = code
+	d.mutex.Unlock()
+
+	encoded := hex.EncodeToString(code)
+	if err := d.persist(name, encoded); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to save code: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"name": name,
+			"code": encoded,
+		},
+	}, nil
+}
+
+// persist saves a code option, an empty value removes it
+func (d *IRDevice) persist(name, value string) error {
+	if d.store == nil {
+		return nil
+	}
+	return d.store.SetOption(CodeOptionPrefix+name, value)
+}
+
+// codeName reads and validates the name parameter
+func codeName(params map[string]interface{}) (string, error) {
+	name, _ := params["name"].(string)
+	if name == "" {
+		return "", fmt.Errorf("name parameter is required")
+	}
+	if !codeNamePattern.MatchString(name) {
+		return "", fmt.Errorf("name must be 1-32 lowercase letters, digits or underscores")
+	}
+	return name, nil
+}
+
+// DecodeCode decodes a code stored as hex or, as exported by other tools, base64
+func DecodeCode(value string) ([]byte, error) {
+	if code, err := hex.DecodeString(value); err == nil && len(code) > 0 {
+		return code, nil
+	}
+	code, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(code) == 0 {
+		return nil, fmt.Errorf("code is neither hex nor base64")
+	}
+	return code, nil
+}
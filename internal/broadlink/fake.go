@@ -0,0 +1,209 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadlink
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// SampleIRCode is a short Broadlink IR packet used by test mode
+var SampleIRCode = []byte{0x26, 0x00, 0x06, 0x00, 0x11, 0x22, 0x11, 0x22, 0x0d, 0x05}
+
+// fakeDeviceID is the device ID handed out by the fake blaster
+const fakeDeviceID uint32 = 0x4c554341
+
+// FakeBlaster speaks the Broadlink RM UDP protocol on a loopback port
+type FakeBlaster struct {
+	conn        *net.UDPConn
+	mutex       sync.Mutex
+	key         []byte
+	rm4         bool
+	learning    bool
+	learnedCode []byte
+	sent        [][]byte
+	closeOnce   sync.Once
+}
+
+// NewFakeBlaster starts a fake RM blaster on 127.0.0.1
+func NewFakeBlaster() (*FakeBlaster, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	fake := &FakeBlaster{conn: conn}
+	go fake.serve()
+	return fake, nil
+}
+
+// Address returns the host:port of the fake blaster
+func (f *FakeBlaster) Address() string {
+	return f.conn.LocalAddr().String()
+}
+
+// SetRM4 switches the fake to the RM4 length prefixed payload format
+func (f *FakeBlaster) SetRM4(rm4 bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rm4 = rm4
+}
+
+// SetLearnedCode sets the code captured the next time learning mode is entered
+func (f *FakeBlaster) SetLearnedCode(code []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.learnedCode = append([]byte(nil), code...)
+}
+
+// Sent returns the codes transmitted so far
+func (f *FakeBlaster) Sent() [][]byte {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([][]byte(nil), f.sent...)
+}
+
+// Reset drops the session key, as a power cycled blaster would
+func (f *FakeBlaster) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.key = nil
+	f.learning = false
+}
+
+// Close stops the fake blaster
+func (f *FakeBlaster) Close() {
+	f.closeOnce.Do(func() {
+		f.conn.Close()
+	})
+}
+
+// serve answers packets until the socket is closed
+func (f *FakeBlaster) serve() {
+	buffer := make([]byte, 2048)
+	for {
+		n, addr, err := f.conn.ReadFromUDP(buffer)
+		if err != nil {
+			return
+		}
+
+		response, key := f.handle(buffer[:n])
+		if response == nil {
+			continue
+		}
+		packet, err := encodeFrame(response, key)
+		if err != nil {
+			continue
+		}
+		f.conn.WriteToUDP(packet, addr)
+	}
+}
+
+// handle builds the response to a single packet and the key to encrypt it with
+func (f *FakeBlaster) handle(packet []byte) (*frame, []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(packet) < headerSize {
+		return nil, nil
+	}
+
+	switch binary.LittleEndian.Uint16(packet[0x26:]) {
+	case PacketAuth:
+		request, err := decodeFrame(packet, initialKey)
+		if err != nil {
+			return nil, nil
+		}
+		f.key = make([]byte, 16)
+		rand.Read(f.key)
+
+		payload := make([]byte, 0x20)
+		binary.LittleEndian.PutUint32(payload, fakeDeviceID)
+		copy(payload[0x04:], f.key)
+		return f.reply(request, PacketAuthResp, ErrorNone, payload), initialKey
+
+	case PacketCommand:
+		if f.key == nil {
+			request, err := decodeFrame(packet, initialKey)
+			if err != nil {
+				return nil, nil
+			}
+			return f.reply(request, PacketCmdResp, ErrorAuth, nil), initialKey
+		}
+		request, err := decodeFrame(packet, f.key)
+		if err != nil {
+			return nil, nil
+		}
+		if request.DeviceID != fakeDeviceID {
+			return f.reply(request, PacketCmdResp, ErrorAuth, nil), f.key
+		}
+		return f.handleCommand(request), f.key
+	}
+
+	return nil, nil
+}
+
+// handleCommand executes an RM command, the mutex must be held
+func (f *FakeBlaster) handleCommand(request *frame) *frame {
+	payload := request.Payload
+	if f.rm4 {
+		if len(payload) < 2 {
+			return f.reply(request, PacketCmdResp, ErrorBadPacket, nil)
+		}
+		length := int(binary.LittleEndian.Uint16(payload)) + 2
+		if length > len(payload) {
+			return f.reply(request, PacketCmdResp, ErrorBadPacket, nil)
+		}
+		payload = payload[2:length]
+	}
+	if len(payload) < 4 {
+		return f.reply(request, PacketCmdResp, ErrorBadPacket, nil)
+	}
+	command := binary.LittleEndian.Uint32(payload)
+	data := payload[4:]
+
+	switch command {
+	case CommandSendData:
+		f.sent = append(f.sent, trimCode(data))
+		return f.reply(request, PacketCmdResp, ErrorNone, commandPayload(command, nil, f.rm4))
+	case CommandEnterLearning:
+		f.learning = true
+		return f.reply(request, PacketCmdResp, ErrorNone, commandPayload(command, nil, f.rm4))
+	case CommandCheckData:
+		if !f.learning || f.learnedCode == nil {
+			return f.reply(request, PacketCmdResp, ErrorNoData, nil)
+		}
+		f.learning = false
+		return f.reply(request, PacketCmdResp, ErrorNone, commandPayload(command, f.learnedCode, f.rm4))
+	default:
+		return f.reply(request, PacketCmdResp, ErrorBadPacket, nil)
+	}
+}
+
+// reply builds a response frame for request
+func (f *FakeBlaster) reply(request *frame, packetType, code uint16, payload []byte) *frame {
+	return &frame{
+		DeviceType: request.DeviceType,
+		PacketType: packetType,
+		Count:      request.Count,
+		MAC:        request.MAC,
+		DeviceID:   fakeDeviceID,
+		Error:      code,
+		Payload:    payload,
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadlink
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+)
+
+// Packet types in the frame header
+const (
+	PacketAuth     uint16 = 0x65
+	PacketCommand  uint16 = 0x6a
+	PacketAuthResp uint16 = 0x3e9
+	PacketCmdResp  uint16 = 0x3ee
+)
+
+// RM commands carried in a PacketCommand payload
+const (
+	CommandSendData      uint32 = 0x02
+	CommandEnterLearning uint32 = 0x03
+	CommandCheckData     uint32 = 0x04
+)
+
+// Error codes reported in the frame header
+const (
+	ErrorNone      uint16 = 0x0000
+	ErrorAuth      uint16 = 0xfff9
+	ErrorNoData    uint16 = 0xfff6
+	ErrorBadPacket uint16 = 0xfffb
+)
+
+// Frame layout
+const (
+	headerSize      = 0x38
+	checksumSeed    = 0xbeaf
+	authPayloadSize = 0x50
+)
+
+// DefaultPort is the UDP port of Broadlink devices
+const DefaultPort = 80
+
+// DefaultDeviceType is the RM mini 3 device type used when none is configured
+const DefaultDeviceType uint16 = 0x2737
+
+// Initial AES key and IV shared by all Broadlink devices before authentication
+var (
+	initialKey = []byte{0x09, 0x76, 0x28, 0x34, 0x3f, 0xe9, 0x9e, 0x23, 0x76, 0x5c, 0x15, 0x13, 0xac, 0xcf, 0x8b, 0x02}
+	initialIV  = []byte{0x56, 0x2e, 0x17, 0x99, 0x6d, 0x09, 0x3d, 0x28, 0xdd, 0xb3, 0xba, 0x69, 0x5a, 0x2e, 0x6f, 0x58}
+	magic      = []byte{0x5a, 0xa5, 0xaa, 0x55, 0x5a, 0xa5, 0xaa, 0x55}
+)
+
+// frame is a decoded Broadlink UDP packet
+type frame struct {
+	DeviceType uint16
+	PacketType uint16
+	Count      uint16
+	MAC        [6]byte
+	DeviceID   uint32
+	Error      uint16
+	Payload    []byte
+}
+
+// encodeFrame encrypts the payload with key and builds the UDP packet
+func encodeFrame(f *frame, key []byte) ([]byte, error) {
+	packet := make([]byte, headerSize)
+	copy(packet, magic)
+	binary.LittleEndian.PutUint16(packet[0x22:], f.Error)
+	binary.LittleEndian.PutUint16(packet[0x24:], f.DeviceType)
+	binary.LittleEndian.PutUint16(packet[0x26:], f.PacketType)
+	binary.LittleEndian.PutUint16(packet[0x28:], f.Count)
+	for i := 0; i < 6; i++ {
+		// The MAC is sent in reverse byte order
+		packet[0x2a+i] = f.MAC[5-i]
+	}
+	binary.LittleEndian.PutUint32(packet[0x30:], f.DeviceID)
+	binary.LittleEndian.PutUint16(packet[0x34:], checksum(f.Payload))
+
+	encrypted, err := encrypt(key, f.Payload)
+	if err != nil {
+		return nil, err
+	}
+	packet = append(packet, encrypted...)
+	binary.LittleEndian.PutUint16(packet[0x20:], checksum(packet))
+
+	return packet, nil
+}
+
+// decodeFrame validates a UDP packet and decrypts its payload with key
+func decodeFrame(packet []byte, key []byte) (*frame, error) {
+	if len(packet) < headerSize {
+		return nil, fmt.Errorf("packet too short: %d bytes", len(packet))
+	}
+	for i, b := range magic {
+		if packet[i] != b {
+			return nil, fmt.Errorf("invalid packet magic")
+		}
+	}
+
+	expected := binary.LittleEndian.Uint16(packet[0x20:])
+	unsummed := append([]byte(nil), packet...)
+	binary.LittleEndian.PutUint16(unsummed[0x20:], 0)
+	if sum := checksum(unsummed); sum != expected {
+		return nil, fmt.Errorf("packet checksum mismatch")
+	}
+
+	f := &frame{
+		Error:      binary.LittleEndian.Uint16(packet[0x22:]),
+		DeviceType: binary.LittleEndian.Uint16(packet[0x24:]),
+		PacketType: binary.LittleEndian.Uint16(packet[0x26:]),
+		Count:      binary.LittleEndian.Uint16(packet[0x28:]),
+		DeviceID:   binary.LittleEndian.Uint32(packet[0x30:]),
+	}
+	for i := 0; i < 6; i++ {
+		f.MAC[i] = packet[0x2f-i]
+	}
+
+	if len(packet) > headerSize {
+		payload, err := decrypt(key, packet[headerSize:])
+		if err != nil {
+			return nil, err
+		}
+		f.Payload = payload
+	}
+	return f, nil
+}
+
+// authPayload builds the payload of the authentication request
+func authPayload() []byte {
+	payload := make([]byte, authPayloadSize)
+	for i := 0x04; i < 0x13; i++ {
+		payload[i] = 0x31
+	}
+	payload[0x1e] = 0x01
+	payload[0x2d] = 0x01
+	copy(payload[0x30:], "Lucas 1")
+	return payload
+}
+
+// commandPayload builds an RM command payload, RM4 models prefix the length
+func commandPayload(command uint32, data []byte, rm4 bool) []byte {
+	var payload []byte
+	if rm4 {
+		payload = binary.LittleEndian.AppendUint16(payload, uint16(len(data)+4))
+	}
+	payload = binary.LittleEndian.AppendUint32(payload, command)
+	return append(payload, data...)
+}
+
+// commandData extracts the data of an RM command response
+func commandData(payload []byte, rm4 bool) []byte {
+	if rm4 {
+		if len(payload) < 6 {
+			return nil
+		}
+		length := int(binary.LittleEndian.Uint16(payload)) + 2
+		if length > len(payload) || length < 6 {
+			length = len(payload)
+		}
+		return payload[6:length]
+	}
+	if len(payload) < 4 {
+		return nil
+	}
+	return payload[4:]
+}
+
+// trimCode strips the zero padding added by encryption from a Broadlink packet
+func trimCode(data []byte) []byte {
+	// Broadlink IR/RF packets carry their length at bytes 2-3 after the type and repeat bytes
+	if len(data) >= 4 {
+		length := int(binary.LittleEndian.Uint16(data[2:])) + 4
+		if length <= len(data) {
+			return append([]byte(nil), data[:length]...)
+		}
+	}
+	return append([]byte(nil), data...)
+}
+
+// checksum is the Broadlink 16 bit additive checksum
+func checksum(data []byte) uint16 {
+	sum := uint32(checksumSeed)
+	for _, b := range data {
+		sum += uint32(b)
+	}
+	return uint16(sum)
+}
+
+// encrypt zero pads and encrypts data with AES-128-CBC
+func encrypt(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	padded := make([]byte, (len(data)+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	copy(padded, data)
+	cipher.NewCBCEncrypter(block, initialIV).CryptBlocks(padded, padded)
+	return padded, nil
+}
+
+// decrypt decrypts AES-128-CBC data
+func decrypt(key, data []byte) ([]byte, error) {
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted payload is not block aligned")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, initialIV).CryptBlocks(plain, data)
+	return plain, nil
+}
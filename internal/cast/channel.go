@@ -0,0 +1,224 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cast
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// channel is a Cast v2 connection that correlates replies by requestId
+type channel struct {
+	conn       net.Conn
+	writeMutex sync.Mutex
+	mutex      sync.Mutex
+	pending    map[int]chan map[string]interface{}
+	connected  map[string]bool
+	nextID     int
+	closed     chan struct{}
+	closeOnce  sync.Once
+	logger     zerolog.Logger
+}
+
+// dialChannel opens a TLS connection to a Cast device
+func dialChannel(address string, timeout time.Duration, logger zerolog.Logger) (*channel, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	// Cast devices present certificates signed by Google's device CA
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to cast device: %w", err)
+	}
+
+	ch := &channel{
+		conn:      conn,
+		pending:   make(map[int]chan map[string]interface{}),
+		connected: make(map[string]bool),
+		closed:    make(chan struct{}),
+		logger:    logger,
+	}
+
+	go ch.readLoop()
+	go ch.heartbeat()
+
+	return ch, nil
+}
+
+// connect opens a virtual connection to a destination once
+func (ch *channel) connect(destination string) error {
+	ch.mutex.Lock()
+	if ch.connected[destination] {
+		ch.mutex.Unlock()
+		return nil
+	}
+	ch.connected[destination] = true
+	ch.mutex.Unlock()
+
+	return ch.send(NamespaceConnection, destination, map[string]interface{}{"type": TypeConnect})
+}
+
+// request sends a message and waits for the reply carrying the same requestId
+func (ch *channel) request(namespace, destination string, payload map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	if err := ch.connect(destination); err != nil {
+		return nil, err
+	}
+
+	ch.mutex.Lock()
+	ch.nextID++
+	requestID := ch.nextID
+	reply := make(chan map[string]interface{}, 1)
+	ch.pending[requestID] = reply
+	ch.mutex.Unlock()
+
+	defer func() {
+		ch.mutex.Lock()
+		delete(ch.pending, requestID)
+		ch.mutex.Unlock()
+	}()
+
+	payload["requestId"] = requestID
+	if err := ch.send(namespace, destination, payload); err != nil {
+		return nil, err
+	}
+
+	select {
+	case response := <-reply:
+		return response, nil
+	case <-ch.closed:
+		return nil, fmt.Errorf("cast connection closed")
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out waiting for %v reply", payload["type"])
+	}
+}
+
+// send writes a JSON payload on a namespace
+func (ch *channel) send(namespace, destination string, payload map[string]interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cast payload: %w", err)
+	}
+
+	message := &CastMessage{
+		SourceID:      DefaultSenderID,
+		DestinationID: destination,
+		Namespace:     namespace,
+		PayloadType:   PayloadString,
+		PayloadUTF8:   string(data),
+	}
+
+	ch.writeMutex.Lock()
+	defer ch.writeMutex.Unlock()
+
+	ch.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := WriteMessage(ch.conn, message); err != nil {
+		ch.close()
+		return fmt.Errorf("failed to send cast message: %w", err)
+	}
+
+	ch.logger.Debug().
+		Str("namespace", namespace).
+		Str("destination", destination).
+		RawJSON("payload", data).
+		Msg("Cast message sent")
+	return nil
+}
+
+// readLoop dispatches replies and answers heartbeats
+func (ch *channel) readLoop() {
+	defer ch.close()
+
+	for {
+		message, err := ReadMessage(ch.conn)
+		if err != nil {
+			ch.logger.Debug().Err(err).Msg("Cast connection closed")
+			return
+		}
+		if message.PayloadType != PayloadString {
+			continue
+		}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(message.PayloadUTF8), &payload); err != nil {
+			continue
+		}
+
+		if message.Namespace == NamespaceHeartbeat && payload["type"] == TypePing {
+			ch.send(NamespaceHeartbeat, message.SourceID, map[string]interface{}{"type": TypePong})
+			continue
+		}
+		if message.Namespace == NamespaceConnection && payload["type"] == TypeClose {
+			ch.mutex.Lock()
+			delete(ch.connected, message.SourceID)
+			ch.mutex.Unlock()
+			continue
+		}
+
+		requestID, ok := payload["requestId"].(float64)
+		if !ok || requestID == 0 {
+			// Unsolicited status broadcast
+			continue
+		}
+
+		ch.mutex.Lock()
+		reply, exists := ch.pending[int(requestID)]
+		ch.mutex.Unlock()
+		if exists {
+			select {
+			case reply <- payload:
+			default:
+			}
+		}
+	}
+}
+
+// heartbeat keeps the connection alive while it is open
+func (ch *channel) heartbeat() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ch.send(NamespaceHeartbeat, DefaultReceiverID, map[string]interface{}{"type": TypePing}); err != nil {
+				return
+			}
+		case <-ch.closed:
+			return
+		}
+	}
+}
+
+// isClosed reports whether the connection has gone away
+func (ch *channel) isClosed() bool {
+	select {
+	case <-ch.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// close shuts down the connection
+func (ch *channel) close() {
+	ch.closeOnce.Do(func() {
+		close(ch.closed)
+		ch.conn.Close()
+	})
+}
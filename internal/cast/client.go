@@ -0,0 +1,499 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cast
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"lucas/internal"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lucas/internal/logger"
+
+	"github.com/rs/zerolog"
+)
+
+// DIALAppStatus is the DIAL application status document
+type DIALAppStatus struct {
+	XMLName xml.Name `xml:"service" json:"-"`
+	Name    string   `xml:"name" json:"name"`
+	State   string   `xml:"state" json:"state"`
+}
+
+// DIALDeviceDescription holds the fields Lucas reads from the UPnP description
+type DIALDeviceDescription struct {
+	FriendlyName string `xml:"device>friendlyName" json:"friendly_name"`
+	Manufacturer string `xml:"device>manufacturer" json:"manufacturer"`
+	ModelName    string `xml:"device>modelName" json:"model_name"`
+	UDN          string `xml:"device>UDN" json:"udn"`
+}
+
+// CastClient represents a client for DIAL and Cast v2 devices
+type CastClient struct {
+	httpClient *http.Client
+	host       string
+	castPort   int
+	dialPort   int
+	appsURL    string
+	channel    *channel
+	receiver   *FakeReceiver
+	mutex      sync.Mutex
+	timeout    time.Duration
+	debugMode  bool
+	testMode   bool
+	logger     zerolog.Logger
+}
+
+// NewCastClient creates a new Cast client, zero ports select the defaults
+func NewCastClient(host string, castPort, dialPort int, options internal.FnModeOptions) *CastClient {
+	if castPort == 0 {
+		castPort = DefaultCastPort
+	}
+	if dialPort == 0 {
+		dialPort = DefaultDIALPort
+	}
+
+	client := &CastClient{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		host:      host,
+		castPort:  castPort,
+		dialPort:  dialPort,
+		timeout:   10 * time.Second,
+		debugMode: options.Debug,
+		testMode:  options.Test,
+		logger:    logger.New(),
+	}
+	if options.Debug {
+		logger.SetLevel(logger.LOG_DEBUG)
+	}
+
+	return client
+}
+
+// ReceiverStatus returns the receiver status including volume and running applications
+func (c *CastClient) ReceiverStatus() (map[string]interface{}, error) {
+	return c.receiverRequest(map[string]interface{}{"type": TypeGetStatus})
+}
+
+// Launch starts a Cast application by ID
+func (c *CastClient) Launch(appID string) (map[string]interface{}, error) {
+	return c.receiverRequest(map[string]interface{}{"type": TypeLaunch, "appId": appID})
+}
+
+// StopApp stops the running Cast application
+func (c *CastClient) StopApp() (map[string]interface{}, error) {
+	app, err := c.runningApp()
+	if err != nil {
+		return nil, err
+	}
+	return c.receiverRequest(map[string]interface{}{"type": TypeStop, "sessionId": app["sessionId"]})
+}
+
+// SetVolume sets the receiver volume level between 0 and 1
+func (c *CastClient) SetVolume(level float64) (map[string]interface{}, error) {
+	return c.receiverRequest(map[string]interface{}{
+		"type":   TypeSetVolume,
+		"volume": map[string]interface{}{"level": level},
+	})
+}
+
+// SetMuted mutes or unmutes the receiver
+func (c *CastClient) SetMuted(muted bool) (map[string]interface{}, error) {
+	return c.receiverRequest(map[string]interface{}{
+		"type":   TypeSetVolume,
+		"volume": map[string]interface{}{"muted": muted},
+	})
+}
+
+// Load plays a media URL, launching the default media receiver when no media app runs
+func (c *CastClient) Load(contentID, contentType string, autoplay bool) (map[string]interface{}, error) {
+	app, err := c.runningApp()
+	if err != nil || !supportsMedia(app) {
+		if _, err := c.Launch(DefaultMediaReceiverAppID); err != nil {
+			return nil, fmt.Errorf("failed to launch media receiver: %w", err)
+		}
+		if app, err = c.runningApp(); err != nil {
+			return nil, err
+		}
+	}
+
+	transportID, _ := app["transportId"].(string)
+	return c.mediaRequest(transportID, map[string]interface{}{
+		"type":     TypeLoad,
+		"autoplay": autoplay,
+		"media": map[string]interface{}{
+			"contentId":   contentID,
+			"contentType": contentType,
+			"streamType":  "BUFFERED",
+		},
+	})
+}
+
+// MediaStatus returns the status of the current media session
+func (c *CastClient) MediaStatus() (map[string]interface{}, error) {
+	transportID, err := c.mediaTransport()
+	if err != nil {
+		return nil, err
+	}
+	return c.mediaRequest(transportID, map[string]interface{}{"type": TypeGetStatus})
+}
+
+// MediaCommand sends PLAY, PAUSE, STOP or SEEK to the current media session
+func (c *CastClient) MediaCommand(commandType string, extra map[string]interface{}) (map[string]interface{}, error) {
+	transportID, err := c.mediaTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := c.mediaRequest(transportID, map[string]interface{}{"type": TypeGetStatus})
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := mediaSessionID(status)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{
+		"type":           commandType,
+		"mediaSessionId": sessionID,
+	}
+	for key, value := range extra {
+		payload[key] = value
+	}
+	return c.mediaRequest(transportID, payload)
+}
+
+// DIALLaunch launches a DIAL application with an optional payload
+func (c *CastClient) DIALLaunch(app, payload string) (string, error) {
+	appsURL, err := c.dialAppsURL()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", appsURL+app, bytes.NewBufferString(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create DIAL launch request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send DIAL launch request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		return resp.Header.Get("Location"), nil
+	case http.StatusNotFound:
+		return "", fmt.Errorf("DIAL application not found: %s", app)
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("DIAL launch failed with status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// DIALStatus returns the state of a DIAL application
+func (c *CastClient) DIALStatus(app string) (*DIALAppStatus, error) {
+	appsURL, err := c.dialAppsURL()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Get(appsURL + app)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DIAL application: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("DIAL application not found: %s", app)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DIAL status failed with status %d", resp.StatusCode)
+	}
+
+	var status DIALAppStatus
+	if err := xml.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to parse DIAL status: %w", err)
+	}
+	return &status, nil
+}
+
+// DIALStop stops a running DIAL application
+func (c *CastClient) DIALStop(app string) error {
+	appsURL, err := c.dialAppsURL()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("DELETE", appsURL+app+"/run", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create DIAL stop request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send DIAL stop request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DIAL stop failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// DeviceDescription fetches the UPnP description served with DIAL
+func (c *CastClient) DeviceDescription() (*DIALDeviceDescription, error) {
+	if err := c.ensureReceiver(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Get(fmt.Sprintf("http://%s%s", c.dialAddress(), DIALDescriptionPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch device description: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device description failed with status %d", resp.StatusCode)
+	}
+
+	// Cache the Application-URL while we have it
+	if appsURL := resp.Header.Get("Application-URL"); appsURL != "" {
+		c.mutex.Lock()
+		c.appsURL = strings.TrimSuffix(appsURL, "/") + "/"
+		c.mutex.Unlock()
+	}
+
+	var description DIALDeviceDescription
+	if err := xml.NewDecoder(resp.Body).Decode(&description); err != nil {
+		return nil, fmt.Errorf("failed to parse device description: %w", err)
+	}
+	return &description, nil
+}
+
+// Close closes the Cast connection and any test receiver
+func (c *CastClient) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.channel != nil {
+		c.channel.close()
+		c.channel = nil
+	}
+	if c.receiver != nil {
+		c.receiver.Close()
+		c.receiver = nil
+	}
+}
+
+// receiverRequest sends a request to the platform receiver
+func (c *CastClient) receiverRequest(payload map[string]interface{}) (map[string]interface{}, error) {
+	ch, err := c.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := ch.request(NamespaceReceiver, DefaultReceiverID, payload, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := replyError(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// mediaRequest sends a request to the media namespace of a running application
+func (c *CastClient) mediaRequest(transportID string, payload map[string]interface{}) (map[string]interface{}, error) {
+	if transportID == "" {
+		return nil, fmt.Errorf("no media application is running")
+	}
+
+	ch, err := c.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := ch.request(NamespaceMedia, transportID, payload, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := replyError(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// runningApp returns the first running application from the receiver status
+func (c *CastClient) runningApp() (map[string]interface{}, error) {
+	status, err := c.ReceiverStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	receiverStatus, _ := status["status"].(map[string]interface{})
+	applications, _ := receiverStatus["applications"].([]interface{})
+	for _, entry := range applications {
+		if app, ok := entry.(map[string]interface{}); ok {
+			return app, nil
+		}
+	}
+	return nil, fmt.Errorf("no application is running")
+}
+
+// mediaTransport returns the transport ID of the running media application
+func (c *CastClient) mediaTransport() (string, error) {
+	app, err := c.runningApp()
+	if err != nil {
+		return "", err
+	}
+	if !supportsMedia(app) {
+		return "", fmt.Errorf("running application does not support media control")
+	}
+	transportID, _ := app["transportId"].(string)
+	return transportID, nil
+}
+
+// connection returns the open Cast channel, dialing it if required
+func (c *CastClient) connection() (*channel, error) {
+	if err := c.ensureReceiver(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.channel != nil && !c.channel.isClosed() {
+		return c.channel, nil
+	}
+
+	address := net.JoinHostPort(c.host, strconv.Itoa(c.castPort))
+	ch, err := dialChannel(address, c.timeout, c.logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.connect(DefaultReceiverID); err != nil {
+		ch.close()
+		return nil, err
+	}
+
+	c.channel = ch
+	c.logger.Info().
+		Str("address", address).
+		Msg("Connected to Cast device")
+	return ch, nil
+}
+
+// dialAppsURL returns the DIAL application URL, discovering it on first use
+func (c *CastClient) dialAppsURL() (string, error) {
+	c.mutex.Lock()
+	appsURL := c.appsURL
+	c.mutex.Unlock()
+	if appsURL != "" {
+		return appsURL, nil
+	}
+
+	if _, err := c.DeviceDescription(); err != nil {
+		return "", err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.appsURL == "" {
+		c.appsURL = fmt.Sprintf("http://%s%s", c.dialAddress(), DIALAppsPath)
+	}
+	return c.appsURL, nil
+}
+
+// dialAddress returns the host and port of the DIAL server
+func (c *CastClient) dialAddress() string {
+	return net.JoinHostPort(c.host, strconv.Itoa(c.dialPort))
+}
+
+// ensureReceiver starts the fake receiver that stands in for the device in test mode
+func (c *CastClient) ensureReceiver() error {
+	if !c.testMode {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.receiver != nil {
+		return nil
+	}
+
+	receiver, err := NewFakeReceiver()
+	if err != nil {
+		return fmt.Errorf("failed to start test receiver: %w", err)
+	}
+	c.receiver = receiver
+	c.host = "127.0.0.1"
+	c.castPort = receiver.CastPort()
+	c.dialPort = receiver.DIALPort()
+
+	c.logger.Info().
+		Int("cast_port", c.castPort).
+		Int("dial_port", c.dialPort).
+		Msg("Test mode: Using fake Cast receiver")
+	return nil
+}
+
+// supportsMedia reports whether an application exposes the media namespace
+func supportsMedia(app map[string]interface{}) bool {
+	namespaces, _ := app["namespaces"].([]interface{})
+	for _, entry := range namespaces {
+		if ns, ok := entry.(map[string]interface{}); ok && ns["name"] == NamespaceMedia {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaSessionID extracts the media session from a MEDIA_STATUS reply
+func mediaSessionID(status map[string]interface{}) (float64, error) {
+	entries, _ := status["status"].([]interface{})
+	for _, entry := range entries {
+		if media, ok := entry.(map[string]interface{}); ok {
+			if id, ok := media["mediaSessionId"].(float64); ok {
+				return id, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no active media session")
+}
+
+// replyError converts error replies into Go errors
+func replyError(response map[string]interface{}) error {
+	switch response["type"] {
+	case TypeLaunchError, TypeInvalidRequest, TypeLoadFailed:
+		reason, _ := response["reason"].(string)
+		if reason == "" {
+			reason = "unknown"
+		}
+		return fmt.Errorf("%v: %s", response["type"], reason)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cast
+
+// Cast v2 namespaces
+const (
+	NamespaceConnection = "urn:x-cast:com.google.cast.tp.connection"
+	NamespaceHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	NamespaceReceiver   = "urn:x-cast:com.google.cast.receiver"
+	NamespaceMedia      = "urn:x-cast:com.google.cast.media"
+)
+
+// Cast v2 message types
+const (
+	TypeConnect        = "CONNECT"
+	TypeClose          = "CLOSE"
+	TypePing           = "PING"
+	TypePong           = "PONG"
+	TypeGetStatus      = "GET_STATUS"
+	TypeReceiverStatus = "RECEIVER_STATUS"
+	TypeLaunch         = "LAUNCH"
+	TypeStop           = "STOP"
+	TypeSetVolume      = "SET_VOLUME"
+	TypeMediaStatus    = "MEDIA_STATUS"
+	TypeLoad           = "LOAD"
+	TypePlay           = "PLAY"
+	TypePause          = "PAUSE"
+	TypeSeek           = "SEEK"
+	TypeLaunchError    = "LAUNCH_ERROR"
+	TypeInvalidRequest = "INVALID_REQUEST"
+	TypeLoadFailed     = "LOAD_FAILED"
+)
+
+// Well known endpoint IDs
+const (
+	DefaultSenderID   = "sender-0"
+	DefaultReceiverID = "receiver-0"
+)
+
+// DefaultMediaReceiverAppID is the built in receiver used to load media URLs
+const DefaultMediaReceiverAppID = "CC1AD845"
+
+// Default ports of Cast devices
+const (
+	DefaultCastPort = 8009
+	DefaultDIALPort = 8008
+)
+
+// DIAL endpoints
+const (
+	DIALDescriptionPath = "/ssdp/device-desc.xml"
+	DIALAppsPath        = "/apps/"
+)
+
+// Option keys recognised in the device configuration options map
+const (
+	OptionCastPort = "cast_port"
+	OptionDIALPort = "dial_port"
+)
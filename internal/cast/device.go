@@ -0,0 +1,277 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cast
+
+import (
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/device"
+	"strconv"
+)
+
+// CastDevice implements the Device interface for Google Cast and DIAL receivers
+type CastDevice struct {
+	client *CastClient
+	info   device.DeviceInfo
+}
+
+// NewCastDevice creates a new CastDevice, zero ports select the defaults
+func NewCastDevice(address string, castPort, dialPort int, options *internal.FnModeOptions) *CastDevice {
+	var opts internal.FnModeOptions
+	if options != nil {
+		opts = *options
+	}
+
+	return &CastDevice{
+		client: NewCastClient(address, castPort, dialPort, opts),
+		info: device.DeviceInfo{
+			ID:      "", // Will be set from configuration
+			Name:    "", // Will be set from configuration
+			Type:    "cast",
+			Model:   "Google Cast",
+			Address: address,
+			Status:  "unknown", // Will be determined by hub
+			Capabilities: []string{
+				"media_control",
+				"app_control",
+				"audio_control",
+			},
+		},
+	}
+}
+
+// GetDeviceInfo returns information about this Cast device
+func (cd *CastDevice) GetDeviceInfo() device.DeviceInfo {
+	return cd.info
+}
+
+// Client returns the underlying Cast client
+func (cd *CastDevice) Client() *CastClient {
+	return cd.client
+}
+
+// Process handles JSON action requests and routes them to appropriate methods
+func (cd *CastDevice) Process(actionJSON []byte) (*device.ActionResponse, error) {
+	request, err := device.ParseActionRequest(actionJSON)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	switch request.Type {
+	case device.ActionTypeMedia:
+		return cd.processMediaAction(request)
+	case device.ActionTypeControl:
+		return cd.processControlAction(request)
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported action type: %s", request.Type),
+		}, nil
+	}
+}
+
+// processMediaAction handles media and app actions
+func (cd *CastDevice) processMediaAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	params := request.Parameters
+
+	var result interface{}
+	var err error
+
+	switch device.MediaAction(request.Action) {
+	case device.MediaActionPlay:
+		result, err = cd.client.MediaCommand(TypePlay, nil)
+	case device.MediaActionPause:
+		result, err = cd.client.MediaCommand(TypePause, nil)
+	case device.MediaActionStop:
+		result, err = cd.client.MediaCommand(TypeStop, nil)
+	case device.MediaActionStatus:
+		result, err = cd.client.MediaStatus()
+
+	case device.MediaActionSeek:
+		position, perr := floatParam(params, "position")
+		if perr != nil {
+			return invalidParameters(perr), nil
+		}
+		if position < 0 {
+			return invalidParameters(fmt.Errorf("position must not be negative")), nil
+		}
+		result, err = cd.client.MediaCommand(TypeSeek, map[string]interface{}{"currentTime": position})
+
+	case device.MediaActionSetVolume:
+		volume, perr := floatParam(params, "volume")
+		if perr != nil {
+			return invalidParameters(perr), nil
+		}
+		if volume < 0 || volume > 100 {
+			return invalidParameters(fmt.Errorf("volume must be between 0 and 100")), nil
+		}
+		result, err = cd.client.SetVolume(volume / 100)
+
+	case device.MediaActionSetMute:
+		muted, perr := boolParam(params, "status")
+		if perr != nil {
+			return invalidParameters(perr), nil
+		}
+		result, err = cd.client.SetMuted(muted)
+
+	case device.MediaActionLoad:
+		url, _ := params["url"].(string)
+		if url == "" {
+			return invalidParameters(fmt.Errorf("url parameter is required for load action")), nil
+		}
+		contentType, _ := params["content_type"].(string)
+		if contentType == "" {
+			contentType = "video/mp4"
+		}
+		autoplay := true
+		if _, exists := params["autoplay"]; exists {
+			if autoplay, err = boolParam(params, "autoplay"); err != nil {
+				return invalidParameters(err), nil
+			}
+		}
+		result, err = cd.client.Load(url, contentType, autoplay)
+
+	case device.MediaActionLaunchApp:
+		if appID, _ := params["app_id"].(string); appID != "" {
+			result, err = cd.client.Launch(appID)
+			break
+		}
+		app, _ := params["app"].(string)
+		if app == "" {
+			return invalidParameters(fmt.Errorf("app_id or app parameter is required for launch_app action")), nil
+		}
+		payload, _ := params["payload"].(string)
+		var location string
+		location, err = cd.client.DIALLaunch(app, payload)
+		result = map[string]interface{}{"app": app, "location": location}
+
+	case device.MediaActionStopApp:
+		if app, _ := params["app"].(string); app != "" {
+			err = cd.client.DIALStop(app)
+			result = map[string]interface{}{"app": app, "state": "stopped"}
+			break
+		}
+		result, err = cd.client.StopApp()
+
+	case device.MediaActionAppStatus:
+		app, _ := params["app"].(string)
+		if app == "" {
+			result, err = cd.client.ReceiverStatus()
+			break
+		}
+		result, err = cd.client.DIALStatus(app)
+
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported media action: %s", request.Action),
+		}, nil
+	}
+
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("media request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    result,
+	}, nil
+}
+
+// processControlAction handles API control actions
+func (cd *CastDevice) processControlAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	var result interface{}
+	var err error
+
+	switch device.ControlAction(request.Action) {
+	case device.ControlActionSystemInfo:
+		result, err = cd.client.DeviceDescription()
+	case device.ControlActionVolumeInfo:
+		result, err = cd.client.ReceiverStatus()
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported control action: %s", request.Action),
+		}, nil
+	}
+
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("control request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    result,
+	}, nil
+}
+
+// invalidParameters builds the response for a parameter validation error
+func invalidParameters(err error) *device.ActionResponse {
+	return &device.ActionResponse{
+		Success: false,
+		Error:   fmt.Sprintf("invalid parameters: %v", err),
+	}
+}
+
+// floatParam reads a required numeric parameter
+func floatParam(params map[string]interface{}, name string) (float64, error) {
+	value, exists := params[name]
+	if !exists {
+		return 0, fmt.Errorf("%s parameter is required", name)
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s parameter: %s", name, v)
+		}
+		return parsed, nil
+	default:
+		return 0, fmt.Errorf("invalid %s parameter type", name)
+	}
+}
+
+// boolParam reads a required boolean parameter
+func boolParam(params map[string]interface{}, name string) (bool, error) {
+	value, exists := params[name]
+	if !exists {
+		return false, fmt.Errorf("%s parameter is required", name)
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s parameter: %s", name, v)
+		}
+		return parsed, nil
+	default:
+		return false, fmt.Errorf("invalid %s parameter type", name)
+	}
+}
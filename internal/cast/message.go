@@ -0,0 +1,148 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cast
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Payload types of a CastMessage
+const (
+	PayloadString = 0
+	PayloadBinary = 1
+)
+
+// maxMessageSize is the largest frame accepted on the Cast channel
+const maxMessageSize = 64 << 10
+
+// CastMessage is the protobuf envelope exchanged on the Cast v2 channel
+type CastMessage struct {
+	ProtocolVersion int
+	SourceID        string
+	DestinationID   string
+	Namespace       string
+	PayloadType     int
+	PayloadUTF8     string
+	PayloadBinary   []byte
+}
+
+// Marshal encodes the message in protobuf wire format
+func (m *CastMessage) Marshal() []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(m.ProtocolVersion))
+	b = appendBytesField(b, 2, []byte(m.SourceID))
+	b = appendBytesField(b, 3, []byte(m.DestinationID))
+	b = appendBytesField(b, 4, []byte(m.Namespace))
+	b = appendVarintField(b, 5, uint64(m.PayloadType))
+	if m.PayloadType == PayloadBinary {
+		b = appendBytesField(b, 7, m.PayloadBinary)
+	} else {
+		b = appendBytesField(b, 6, []byte(m.PayloadUTF8))
+	}
+	return b
+}
+
+// UnmarshalCastMessage decodes a protobuf encoded CastMessage
+func UnmarshalCastMessage(data []byte) (*CastMessage, error) {
+	m := &CastMessage{}
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+		field, wireType := key>>3, key&0x7
+
+		switch wireType {
+		case 0:
+			value, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid varint in field %d", field)
+			}
+			data = data[n:]
+			switch field {
+			case 1:
+				m.ProtocolVersion = int(value)
+			case 5:
+				m.PayloadType = int(value)
+			}
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, fmt.Errorf("invalid length in field %d", field)
+			}
+			value := data[n : n+int(length)]
+			data = data[n+int(length):]
+			switch field {
+			case 2:
+				m.SourceID = string(value)
+			case 3:
+				m.DestinationID = string(value)
+			case 4:
+				m.Namespace = string(value)
+			case 6:
+				m.PayloadUTF8 = string(value)
+			case 7:
+				m.PayloadBinary = append([]byte(nil), value...)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", wireType, field)
+		}
+	}
+	return m, nil
+}
+
+// WriteMessage writes a length prefixed CastMessage
+func WriteMessage(w io.Writer, m *CastMessage) error {
+	body := m.Marshal()
+	frame := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	frame = append(frame, body...)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadMessage reads a length prefixed CastMessage
+func ReadMessage(r io.Reader) (*CastMessage, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("cast message too large: %d bytes", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return UnmarshalCastMessage(body)
+}
+
+// appendVarintField appends a varint field
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, value)
+}
+
+// appendBytesField appends a length delimited field
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
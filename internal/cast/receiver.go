@@ -0,0 +1,381 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cast
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeReceiver is a local Cast and DIAL endpoint used by test mode and tests
+type FakeReceiver struct {
+	castListener net.Listener
+	dialListener net.Listener
+	dialServer   *http.Server
+
+	mutex       sync.Mutex
+	volume      float64
+	muted       bool
+	appID       string
+	sessionID   string
+	sessionSeq  int
+	playerState string
+	contentID   string
+	currentTime float64
+	dialApps    map[string]string
+	conns       map[net.Conn]struct{}
+	closeOnce   sync.Once
+}
+
+// NewFakeReceiver starts a fake receiver on loopback ports
+func NewFakeReceiver() (*FakeReceiver, error) {
+	certificate, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	castListener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for cast connections: %w", err)
+	}
+	dialListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		castListener.Close()
+		return nil, fmt.Errorf("failed to listen for DIAL requests: %w", err)
+	}
+
+	r := &FakeReceiver{
+		castListener: castListener,
+		dialListener: dialListener,
+		volume:       0.5,
+		dialApps: map[string]string{
+			"YouTube": "stopped",
+			"Netflix": "stopped",
+		},
+		conns: make(map[net.Conn]struct{}),
+	}
+	r.dialServer = &http.Server{Handler: http.HandlerFunc(r.serveDIAL)}
+
+	go r.acceptLoop()
+	go r.dialServer.Serve(dialListener)
+
+	return r, nil
+}
+
+// CastPort returns the port of the Cast v2 listener
+func (r *FakeReceiver) CastPort() int {
+	return r.castListener.Addr().(*net.TCPAddr).Port
+}
+
+// DIALPort returns the port of the DIAL HTTP server
+func (r *FakeReceiver) DIALPort() int {
+	return r.dialListener.Addr().(*net.TCPAddr).Port
+}
+
+// Volume returns the current volume level and mute state
+func (r *FakeReceiver) Volume() (float64, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.volume, r.muted
+}
+
+// PlayerState returns the state of the media session, empty when idle
+func (r *FakeReceiver) PlayerState() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.playerState
+}
+
+// Close stops the receiver and drops open connections
+func (r *FakeReceiver) Close() {
+	r.closeOnce.Do(func() {
+		r.castListener.Close()
+		r.dialServer.Close()
+
+		r.mutex.Lock()
+		for conn := range r.conns {
+			conn.Close()
+		}
+		r.mutex.Unlock()
+	})
+}
+
+// acceptLoop accepts Cast connections
+func (r *FakeReceiver) acceptLoop() {
+	for {
+		conn, err := r.castListener.Accept()
+		if err != nil {
+			return
+		}
+		r.mutex.Lock()
+		r.conns[conn] = struct{}{}
+		r.mutex.Unlock()
+		go r.serveCast(conn)
+	}
+}
+
+// serveCast answers messages on a single Cast connection
+func (r *FakeReceiver) serveCast(conn net.Conn) {
+	defer func() {
+		r.mutex.Lock()
+		delete(r.conns, conn)
+		r.mutex.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		message, err := ReadMessage(conn)
+		if err != nil {
+			return
+		}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(message.PayloadUTF8), &payload); err != nil {
+			continue
+		}
+
+		var reply map[string]interface{}
+		switch message.Namespace {
+		case NamespaceHeartbeat:
+			if payload["type"] == TypePing {
+				reply = map[string]interface{}{"type": TypePong}
+			}
+		case NamespaceReceiver:
+			reply = r.handleReceiver(payload)
+		case NamespaceMedia:
+			reply = r.handleMedia(payload)
+		}
+		if reply == nil {
+			continue
+		}
+		if requestID, ok := payload["requestId"]; ok {
+			reply["requestId"] = requestID
+		}
+
+		data, _ := json.Marshal(reply)
+		response := &CastMessage{
+			SourceID:      message.DestinationID,
+			DestinationID: message.SourceID,
+			Namespace:     message.Namespace,
+			PayloadType:   PayloadString,
+			PayloadUTF8:   string(data),
+		}
+		if err := WriteMessage(conn, response); err != nil {
+			return
+		}
+	}
+}
+
+// handleReceiver answers platform receiver requests
+func (r *FakeReceiver) handleReceiver(payload map[string]interface{}) map[string]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch payload["type"] {
+	case TypeGetStatus:
+	case TypeLaunch:
+		appID, _ := payload["appId"].(string)
+		if appID == "" {
+			return map[string]interface{}{"type": TypeLaunchError, "reason": "NOT_FOUND"}
+		}
+		r.sessionSeq++
+		r.appID = appID
+		r.sessionID = fmt.Sprintf("session-%d", r.sessionSeq)
+		r.playerState = ""
+		r.contentID = ""
+		r.currentTime = 0
+	case TypeStop:
+		r.appID = ""
+		r.sessionID = ""
+		r.playerState = ""
+	case TypeSetVolume:
+		volume, _ := payload["volume"].(map[string]interface{})
+		if level, ok := volume["level"].(float64); ok {
+			r.volume = level
+		}
+		if muted, ok := volume["muted"].(bool); ok {
+			r.muted = muted
+		}
+	default:
+		return map[string]interface{}{"type": TypeInvalidRequest, "reason": "INVALID_COMMAND"}
+	}
+
+	return r.receiverStatusLocked()
+}
+
+// handleMedia answers media namespace requests for the running app
+func (r *FakeReceiver) handleMedia(payload map[string]interface{}) map[string]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.appID != DefaultMediaReceiverAppID {
+		return map[string]interface{}{"type": TypeInvalidRequest, "reason": "INVALID_PLAYER_STATE"}
+	}
+
+	switch payload["type"] {
+	case TypeGetStatus:
+	case TypeLoad:
+		media, _ := payload["media"].(map[string]interface{})
+		contentID, _ := media["contentId"].(string)
+		if contentID == "" {
+			return map[string]interface{}{"type": TypeLoadFailed, "reason": "missing contentId"}
+		}
+		r.contentID = contentID
+		r.currentTime = 0
+		r.playerState = "PAUSED"
+		if autoplay, _ := payload["autoplay"].(bool); autoplay {
+			r.playerState = "PLAYING"
+		}
+	case TypePlay, TypePause, TypeStop, TypeSeek:
+		if r.playerState == "" {
+			return map[string]interface{}{"type": TypeInvalidRequest, "reason": "INVALID_MEDIA_SESSION_ID"}
+		}
+		switch payload["type"] {
+		case TypePlay:
+			r.playerState = "PLAYING"
+		case TypePause:
+			r.playerState = "PAUSED"
+		case TypeStop:
+			r.playerState = ""
+			r.contentID = ""
+			r.currentTime = 0
+		case TypeSeek:
+			if position, ok := payload["currentTime"].(float64); ok {
+				r.currentTime = position
+			}
+		}
+	default:
+		return map[string]interface{}{"type": TypeInvalidRequest, "reason": "INVALID_COMMAND"}
+	}
+
+	status := []interface{}{}
+	if r.playerState != "" {
+		status = append(status, map[string]interface{}{
+			"mediaSessionId": 1,
+			"playerState":    r.playerState,
+			"currentTime":    r.currentTime,
+			"media":          map[string]interface{}{"contentId": r.contentID},
+		})
+	}
+	return map[string]interface{}{"type": TypeMediaStatus, "status": status}
+}
+
+// receiverStatusLocked builds a RECEIVER_STATUS reply, the mutex must be held
+func (r *FakeReceiver) receiverStatusLocked() map[string]interface{} {
+	status := map[string]interface{}{
+		"volume": map[string]interface{}{"level": r.volume, "muted": r.muted},
+	}
+	if r.appID != "" {
+		namespaces := []interface{}{}
+		if r.appID == DefaultMediaReceiverAppID {
+			namespaces = append(namespaces, map[string]interface{}{"name": NamespaceMedia})
+		}
+		status["applications"] = []interface{}{
+			map[string]interface{}{
+				"appId":       r.appID,
+				"sessionId":   r.sessionID,
+				"transportId": r.sessionID,
+				"namespaces":  namespaces,
+			},
+		}
+	}
+	return map[string]interface{}{"type": TypeReceiverStatus, "status": status}
+}
+
+// serveDIAL implements the DIAL REST service
+func (r *FakeReceiver) serveDIAL(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == DIALDescriptionPath {
+		w.Header().Set("Application-URL", fmt.Sprintf("http://%s%s", req.Host, DIALAppsPath))
+		w.Header().Set("Content-Type", "application/xml")
+		io.WriteString(w, `<?xml version="1.0"?><root xmlns="urn:schemas-upnp-org:device-1-0"><device>`+
+			`<friendlyName>Lucas Test Receiver</friendlyName><manufacturer>Lucas</manufacturer>`+
+			`<modelName>Fake Cast</modelName><UDN>uuid:lucas-fake-receiver</UDN></device></root>`)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, DIALAppsPath)
+	if path == req.URL.Path {
+		http.NotFound(w, req)
+		return
+	}
+	app, run, _ := strings.Cut(path, "/")
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	state, exists := r.dialApps[app]
+	if !exists {
+		http.NotFound(w, req)
+		return
+	}
+
+	switch {
+	case req.Method == http.MethodGet && run == "":
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		data, _ := xml.Marshal(DIALAppStatus{Name: app, State: state})
+		w.Write(data)
+	case req.Method == http.MethodPost && run == "":
+		r.dialApps[app] = "running"
+		w.Header().Set("Location", fmt.Sprintf("http://%s%s%s/run", req.Host, DIALAppsPath, app))
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodDelete && run == "run":
+		if state != "running" {
+			http.NotFound(w, req)
+			return
+		}
+		r.dialApps[app] = "stopped"
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// selfSignedCertificate creates a throwaway certificate for the Cast listener
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "lucas-fake-receiver"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
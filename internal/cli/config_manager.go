@@ -271,7 +271,13 @@ func (cm *ConfigManager) GetDevicesByType(deviceType string) ([]hub.DeviceConfig
 // GetSupportedDeviceTypes returns a list of supported device types
 func (cm *ConfigManager) GetSupportedDeviceTypes() []string {
 	return []string{
-		"bravia", // Sony Bravia TV
+		"bravia",        // Sony Bravia TV
+		"samsung",       // Samsung Tizen TV
+		"webos",         // LG webOS TV
+		"wol",           // Wake-on-LAN host
+		"cast",          // Google Cast / DIAL receiver
+		"homeassistant", // Home Assistant entity
+		"ir",            // Broadlink IR blaster
 		// Add more device types as they are implemented
 	}
 }
@@ -293,6 +299,90 @@ func (cm *ConfigManager) CreateDeviceTemplate(deviceType string) hub.DeviceConfi
 				"content_control",
 			},
 		}
+	case "samsung":
+		return hub.DeviceConfig{
+			ID:         "",
+			Type:       "samsung",
+			Model:      "Samsung Tizen",
+			Address:    "192.168.1.101",
+			Credential: "", // Filled in after pairing on the TV
+			Capabilities: []string{
+				"remote_control",
+				"system_control",
+				"app_control",
+			},
+		}
+	case "webos":
+		return hub.DeviceConfig{
+			ID:         "",
+			Type:       "webos",
+			Model:      "LG webOS",
+			Address:    "192.168.1.102",
+			Credential: "", // Filled in after pairing on the TV
+			Capabilities: []string{
+				"remote_control",
+				"system_control",
+				"audio_control",
+				"content_control",
+			},
+		}
+	case "wol":
+		return hub.DeviceConfig{
+			ID:      "",
+			Type:    "wol",
+			Model:   "Wake-on-LAN",
+			Address: "192.168.1.50",
+			MAC:     "00:11:22:33:44:55",
+			Options: map[string]string{
+				"broadcast": "255.255.255.255:9",
+			},
+			Capabilities: []string{
+				"power_control",
+			},
+		}
+	case "cast":
+		return hub.DeviceConfig{
+			ID:      "",
+			Type:    "cast",
+			Model:   "Google Cast",
+			Address: "192.168.1.103",
+			Options: map[string]string{
+				"cast_port": "8009",
+				"dial_port": "8008",
+			},
+			Capabilities: []string{
+				"media_control",
+				"app_control",
+				"audio_control",
+			},
+		}
+	case "homeassistant":
+		return hub.DeviceConfig{
+			ID:         "",
+			Type:       "homeassistant",
+			Model:      "Home Assistant",
+			Address:    "http://homeassistant.local:8123",
+			Credential: "", // Long-lived access token
+			Options: map[string]string{
+				"entity_id": "light.living_room",
+			},
+			Capabilities: []string{
+				"power_control",
+			},
+		}
+	case "ir":
+		return hub.DeviceConfig{
+			ID:      "",
+			Type:    "ir",
+			Model:   "Broadlink RM",
+			Address: "192.168.1.104",
+			MAC:     "34:ea:34:00:00:00",
+			Options: map[string]string{}, // Learned codes are saved as code.<name>
+			Capabilities: []string{
+				"remote_control",
+				"ir_learning",
+			},
+		}
 	default:
 		return hub.DeviceConfig{
 			ID:           "",
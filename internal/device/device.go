@@ -14,6 +14,13 @@
 
 package device
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // Device represents a generic device that can process commands
 type Device interface {
 	// Process handles a JSON-encoded action and executes the corresponding operation
@@ -23,6 +30,38 @@ type Device interface {
 	GetDeviceInfo() DeviceInfo
 }
 
+// ConfigStore persists settings a device learns at runtime, such as pairing tokens
+type ConfigStore interface {
+	// SetCredential replaces the device credential in the hub configuration
+	SetCredential(credential string) error
+
+	// SetOption stores a device specific option in the hub configuration
+	SetOption(key, value string) error
+}
+
+// EventSource is implemented by devices that push state changes instead of being polled
+type EventSource interface {
+	// StartEvents begins delivering state changes to handler until StopEvents
+	StartEvents(handler func(StateEvent))
+
+	// StopEvents closes the connections held open for events
+	StopEvents()
+}
+
+// ContextProcessor is implemented by devices whose actions can take long enough to outlast
+// the request that asked for them, such as waiting for a TV to confirm it has powered on
+type ContextProcessor interface {
+	// ProcessContext is Process giving up once ctx ends
+	ProcessContext(ctx context.Context, actionJSON []byte) (*ActionResponse, error)
+}
+
+// StateEvent is a change of one piece of device state
+type StateEvent struct {
+	Property string      `json:"property"`
+	Value    interface{} `json:"value"`
+	Time     time.Time   `json:"time"`
+}
+
 // DeviceInfo contains basic information about a device
 type DeviceInfo struct {
 	ID           string   `json:"id"`
@@ -40,11 +79,12 @@ type ActionType string
 const (
 	ActionTypeRemote  ActionType = "remote"
 	ActionTypeControl ActionType = "control"
+	ActionTypeMedia   ActionType = "media"
 )
 
 // ActionRequest represents a JSON action request
 type ActionRequest struct {
-	Type       ActionType             `json:"type"`       // "remote" or "control"
+	Type       ActionType             `json:"type"`       // "remote", "control" or "media"
 	Action     string                 `json:"action"`     // specific action name
 	Parameters map[string]interface{} `json:"parameters"` // optional parameters
 }
@@ -81,6 +121,16 @@ const (
 	RemoteActionHDMI2       RemoteAction = "hdmi2"
 	RemoteActionHDMI3       RemoteAction = "hdmi3"
 	RemoteActionHDMI4       RemoteAction = "hdmi4"
+	RemoteActionNum0        RemoteAction = "num0"
+	RemoteActionNum1        RemoteAction = "num1"
+	RemoteActionNum2        RemoteAction = "num2"
+	RemoteActionNum3        RemoteAction = "num3"
+	RemoteActionNum4        RemoteAction = "num4"
+	RemoteActionNum5        RemoteAction = "num5"
+	RemoteActionNum6        RemoteAction = "num6"
+	RemoteActionNum7        RemoteAction = "num7"
+	RemoteActionNum8        RemoteAction = "num8"
+	RemoteActionNum9        RemoteAction = "num9"
 )
 
 // ControlAction represents available control API actions
@@ -96,3 +146,54 @@ const (
 	ControlActionSetVolume      ControlAction = "set_volume"
 	ControlActionSetMute        ControlAction = "set_mute"
 )
+
+// MediaAction represents available media and app actions
+type MediaAction string
+
+const (
+	MediaActionPlay      MediaAction = "play"
+	MediaActionPause     MediaAction = "pause"
+	MediaActionStop      MediaAction = "stop"
+	MediaActionSeek      MediaAction = "seek"
+	MediaActionSetVolume MediaAction = "set_volume"
+	MediaActionSetMute   MediaAction = "set_mute"
+	MediaActionLoad      MediaAction = "load"
+	MediaActionStatus    MediaAction = "status"
+	MediaActionLaunchApp MediaAction = "launch_app"
+	MediaActionStopApp   MediaAction = "stop_app"
+	MediaActionAppStatus MediaAction = "app_status"
+)
+
+// Power status values reported by power_status, matching Bravia getPowerStatus
+const (
+	PowerStatusActive  = "active"
+	PowerStatusStandby = "standby"
+)
+
+// PowerStatusData builds a power_status result in the Bravia getPowerStatus shape
+func PowerStatusData(status string) map[string]interface{} {
+	return map[string]interface{}{
+		"id": 1,
+		"result": []interface{}{
+			map[string]interface{}{"status": status},
+		},
+	}
+}
+
+// ParseActionRequest parses JSON input into ActionRequest
+func ParseActionRequest(actionJSON []byte) (*ActionRequest, error) {
+	var request ActionRequest
+	if err := json.Unmarshal(actionJSON, &request); err != nil {
+		return nil, fmt.Errorf("failed to parse action request: %w", err)
+	}
+
+	if request.Type == "" {
+		return nil, fmt.Errorf("action type is required")
+	}
+
+	if request.Action == "" {
+		return nil, fmt.Errorf("action is required")
+	}
+
+	return &request, nil
+}
@@ -16,6 +16,7 @@ package gateway
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
+	"lucas/internal/hermes"
 	"lucas/internal/logger"
 )
 
@@ -36,6 +38,7 @@ type APIServer struct {
 	jwtService      *JWTService
 	passwordService *PasswordService
 	authMiddleware  *AuthMiddleware
+	channels        *ChannelCache
 }
 
 // NewAPIServer creates a new API server
@@ -52,6 +55,7 @@ func NewAPIServer(database *Database, brokerService *BrokerService, keys *Gatewa
 		jwtService:      jwtService,
 		passwordService: passwordService,
 		authMiddleware:  authMiddleware,
+		channels:        NewChannelCache(DefaultChannelCacheTTL),
 	}
 }
 
@@ -84,8 +88,14 @@ func (api *APIServer) Start(address string) error {
 	apiRouter.Handle("/user/hubs/{hub_id}/devices/configure", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleHubDeviceConfigure))).Methods("POST")
 	apiRouter.Handle("/user/hubs/{hub_id}/devices", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleGetHubDevices))).Methods("GET")
 	apiRouter.Handle("/user/hubs/{hub_id}/devices/reload", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleHubDeviceReload))).Methods("POST")
+	apiRouter.Handle("/user/hubs/{hub_id}/discovered", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleHubDiscover))).Methods("GET")
+	apiRouter.Handle("/user/hubs/{hub_id}/discovered/{discovered_id}/adopt", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleHubAdopt))).Methods("POST")
 	apiRouter.Handle("/user/devices", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleGetUserDevices))).Methods("GET")
 	apiRouter.Handle("/user/devices/{device_id}/action", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleDeviceAction))).Methods("POST")
+	apiRouter.Handle("/user/devices/{device_id}/channels", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleDeviceChannels))).Methods("GET")
+	apiRouter.Handle("/user/devices/{device_id}/requests", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleCreateDurableRequest))).Methods("POST")
+	apiRouter.Handle("/user/requests/{request_id}", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleGetDurableRequest))).Methods("GET")
+	apiRouter.Handle("/user/requests/{request_id}", api.authMiddleware.RequireAuth(http.HandlerFunc(api.handleCancelDurableRequest))).Methods("DELETE")
 	
 	// Debug logging for route registration
 	api.logger.Info().Msg("User hub claim endpoint registered at /api/v1/user/hubs/claim")
@@ -172,6 +182,19 @@ func (api *APIServer) sendError(w http.ResponseWriter, status int, message strin
 	})
 }
 
+// hubErrorStatus maps an error from a hub request to an HTTP status: 501 when the hub
+// lacks the feature, 503 when the broker turned the request away, 502 otherwise
+func hubErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrHubUnsupported):
+		return http.StatusNotImplemented
+	case hermes.IsRetryable(err):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
 // Gateway endpoints
 func (api *APIServer) handleGatewayStatus(w http.ResponseWriter, r *http.Request) {
 	stats := api.brokerService.GetServiceStats()
@@ -504,16 +527,16 @@ func (api *APIServer) handleDeviceAction(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Parse action request
-	var actionReq struct {
-		Type       string                 `json:"type"`
-		Action     string                 `json:"action"`
-		Parameters map[string]interface{} `json:"parameters"`
-	}
-
+	var actionReq deviceAction
 	if err := json.NewDecoder(r.Body).Decode(&actionReq); err != nil {
 		api.sendError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
+	deviceAction, err := actionReq.marshal()
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Find device and its hub
 	device, deviceHub, err := api.database.FindDeviceByID(deviceID)
@@ -528,12 +551,8 @@ func (api *APIServer) handleDeviceAction(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Create device action using BrokerService
-	deviceAction := json.RawMessage(fmt.Sprintf(`{"type":"%s","action":"%s","parameters":%s}`, 
-		actionReq.Type, actionReq.Action, mustMarshal(actionReq.Parameters)))
-
 	// Send device command via Hermes BrokerService
-	response, err := api.brokerService.SendDeviceCommand(deviceHub.HubID, deviceID, deviceAction)
+	response, err := api.brokerService.SendDeviceCommand(r.Context(), deviceHub.HubID, deviceID, deviceAction)
 	if err != nil {
 		api.logger.Error().
 			Str("hub_id", deviceHub.HubID).
@@ -555,6 +574,196 @@ func (api *APIServer) handleDeviceAction(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// handleCreateDurableRequest stores a device command for delivery whenever the device's
+// hub is connected, so it survives hub outages and gateway restarts
+func (api *APIServer) handleCreateDurableRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	deviceID := vars["device_id"]
+
+	authUser, ok := GetUserFromContext(r)
+	if !ok {
+		api.sendError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	var actionReq struct {
+		deviceAction
+		TTLSeconds int `json:"ttl_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&actionReq); err != nil {
+		api.sendError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if actionReq.Action == "" {
+		api.sendError(w, http.StatusBadRequest, "action is required")
+		return
+	}
+	deviceAction, err := actionReq.marshal()
+	if err != nil {
+		api.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ttl := time.Duration(actionReq.TTLSeconds) * time.Second
+	if ttl < 0 || ttl > MaxDurableTTL {
+		api.sendError(w, http.StatusBadRequest, fmt.Sprintf("ttl_seconds must be between 0 and %d", int(MaxDurableTTL.Seconds())))
+		return
+	}
+
+	_, deviceHub, err := api.database.FindDeviceByID(deviceID)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	if !deviceHub.UserID.Valid || int(deviceHub.UserID.Int32) != authUser.ID {
+		api.sendError(w, http.StatusForbidden, "Device not accessible by user")
+		return
+	}
+
+	request, err := api.brokerService.SubmitDurableDeviceCommand(deviceHub.HubID, deviceID, deviceAction, ttl)
+	if err != nil {
+		api.logger.Error().
+			Str("hub_id", deviceHub.HubID).
+			Str("device_id", deviceID).
+			Err(err).
+			Msg("Failed to store durable request")
+		api.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to store request: %v", err))
+		return
+	}
+
+	api.sendJSON(w, http.StatusAccepted, map[string]interface{}{
+		"request_id": request.RequestID,
+		"status":     request.Status,
+		"expires_at": request.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// handleGetDurableRequest returns the status and, once delivered, the outcome of a durable request
+func (api *APIServer) handleGetDurableRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := api.userDurableRequest(w, r)
+	if !ok {
+		return
+	}
+	api.sendJSON(w, http.StatusOK, request)
+}
+
+// handleCancelDurableRequest cancels a durable request that has not been delivered yet
+func (api *APIServer) handleCancelDurableRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := api.userDurableRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cancelled, err := api.database.CancelDurableRequest(request.RequestID)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Failed to cancel request")
+		return
+	}
+	if !cancelled {
+		api.sendError(w, http.StatusConflict, "Request is no longer pending")
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"request_id": request.RequestID,
+		"status":     DurableStatusCancelled,
+	})
+}
+
+// userDurableRequest loads the durable request named in the path, writing an error
+// response unless it belongs to a hub of the authenticated user
+func (api *APIServer) userDurableRequest(w http.ResponseWriter, r *http.Request) (*DurableRequest, bool) {
+	authUser, ok := GetUserFromContext(r)
+	if !ok {
+		api.sendError(w, http.StatusUnauthorized, "User not found in context")
+		return nil, false
+	}
+
+	request, err := api.database.GetDurableRequest(mux.Vars(r)["request_id"])
+	if errors.Is(err, ErrDurableRequestNotFound) {
+		api.sendError(w, http.StatusNotFound, "Request not found")
+		return nil, false
+	}
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Failed to load request")
+		return nil, false
+	}
+
+	hub, err := api.database.GetHubByHubID(request.HubID)
+	if err != nil || !hub.UserID.Valid || int(hub.UserID.Int32) != authUser.ID {
+		// Requests of other users' hubs are reported as missing
+		api.sendError(w, http.StatusNotFound, "Request not found")
+		return nil, false
+	}
+
+	return request, true
+}
+
+// handleDeviceChannels returns a TV's channel list, filtered by the q query parameter.
+// Lists are cached, refresh=true asks the hub for a fresh one
+func (api *APIServer) handleDeviceChannels(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	deviceID := vars["device_id"]
+
+	authUser, ok := GetUserFromContext(r)
+	if !ok {
+		api.sendError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	_, deviceHub, err := api.database.FindDeviceByID(deviceID)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	if !deviceHub.UserID.Valid || int(deviceHub.UserID.Int32) != authUser.ID {
+		api.sendError(w, http.StatusForbidden, "Device not accessible by user")
+		return
+	}
+
+	refresh := r.URL.Query().Get("refresh") == "true"
+	channels, cached := api.channels.Get(deviceID)
+	if refresh || !cached {
+		action := json.RawMessage(fmt.Sprintf(`{"type":"control","action":"get_channels","parameters":{"refresh":%t}}`, refresh))
+		response, err := api.brokerService.QueryDeviceCommand(r.Context(), deviceHub.HubID, deviceID, action, 30*time.Second)
+		if err != nil {
+			api.logger.Error().
+				Str("hub_id", deviceHub.HubID).
+				Str("device_id", deviceID).
+				Err(err).
+				Msg("Failed to get channel list via broker service")
+			api.sendError(w, http.StatusBadGateway, fmt.Sprintf("Failed to get channels from device: %v", err))
+			return
+		}
+		if !response.Success {
+			api.sendError(w, http.StatusBadGateway, fmt.Sprintf("Device could not list channels: %s", response.Error))
+			return
+		}
+
+		var data struct {
+			Channels []Channel `json:"channels"`
+		}
+		raw, _ := json.Marshal(response.Data)
+		if err := json.Unmarshal(raw, &data); err != nil {
+			api.sendError(w, http.StatusBadGateway, "Device returned an invalid channel list")
+			return
+		}
+		channels = data.Channels
+		api.channels.Put(deviceID, channels)
+		cached = false
+	}
+
+	channels = SearchChannels(channels, r.URL.Query().Get("q"))
+	api.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"channels": channels,
+		"count":    len(channels),
+		"cached":   cached,
+		"hub":      deviceHub.HubID,
+	})
+}
+
 // Admin endpoints
 func (api *APIServer) handleListUsers(w http.ResponseWriter, r *http.Request) {
 	// This would normally require admin authentication
@@ -596,15 +805,24 @@ func (api *APIServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 // Helper functions
-func mustMarshal(v interface{}) string {
-	if v == nil {
-		return "{}"
+
+// deviceAction is a device command as sent to the device's hub
+type deviceAction struct {
+	Type       string          `json:"type"`
+	Action     string          `json:"action"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// marshal checks that the parameters are a JSON object and encodes the action
+func (a deviceAction) marshal() (json.RawMessage, error) {
+	if len(a.Parameters) == 0 || string(a.Parameters) == "null" {
+		a.Parameters = json.RawMessage(`{}`)
 	}
-	data, err := json.Marshal(v)
-	if err != nil {
-		return "{}"
+	var parameters map[string]interface{}
+	if err := json.Unmarshal(a.Parameters, &parameters); err != nil {
+		return nil, fmt.Errorf("parameters must be a JSON object")
 	}
-	return string(data)
+	return json.Marshal(a)
 }
 
 // getActiveHubCount extracts the number of active hubs from service stats
@@ -926,4 +1144,185 @@ func (api *APIServer) handleHubDeviceReload(w http.ResponseWriter, r *http.Reque
 	// This functionality should be implemented via ZMQ messaging through broker service
 	api.sendError(w, http.StatusNotImplemented, "Hub device reload via direct HTTP not implemented - use ZMQ messaging")
 	return
-}
\ No newline at end of file
+}
+
+// handleHubDiscover asks a hub to search its LAN and returns the devices it has not
+// configured yet, or every device found when all=true. With stream=true the response is
+// newline-delimited JSON: a "found" line for each device as the hub reports it, then the
+// "result" line carrying what a plain request returns, or an "error" line
+func (api *APIServer) handleHubDiscover(w http.ResponseWriter, r *http.Request) {
+	user, ok := GetUserFromContext(r)
+	if !ok {
+		api.sendError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	hubID := vars["hub_id"]
+
+	// Verify user owns the hub
+	hub, err := api.database.GetHubByHubID(hubID)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Hub not found")
+		return
+	}
+
+	if !hub.UserID.Valid || int(hub.UserID.Int32) != user.ID {
+		api.sendError(w, http.StatusForbidden, "You don't have permission to access this hub")
+		return
+	}
+
+	all := r.URL.Query().Get("all") == "true"
+	if r.URL.Query().Get("stream") == "true" {
+		api.streamHubDiscover(w, r, hubID, all)
+		return
+	}
+
+	data, err := api.brokerService.QueryHub(r.Context(), hubID, "discover", map[string]string{}, 15*time.Second)
+	if err != nil {
+		api.logger.Error().
+			Str("hub_id", hubID).
+			Err(err).
+			Msg("Failed to discover devices via broker service")
+		api.sendError(w, hubErrorStatus(err), fmt.Sprintf("Failed to discover devices: %v", err))
+		return
+	}
+
+	result, err := discoveryResult(hubID, data, all)
+	if err != nil {
+		api.sendError(w, http.StatusBadGateway, "Hub returned an invalid discovery result")
+		return
+	}
+	api.sendJSON(w, http.StatusOK, result)
+}
+
+// streamHubDiscover relays a hub's discovery progress as it arrives. Until the first line
+// is written a failure is still answered with an error status
+func (api *APIServer) streamHubDiscover(w http.ResponseWriter, r *http.Request, hubID string, all bool) {
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	started := false
+	send := func(line map[string]interface{}) {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		encoder.Encode(line)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	data, err := api.brokerService.StreamHub(r.Context(), hubID, "discover", map[string]string{}, 15*time.Second, func(progress json.RawMessage) {
+		var partial struct {
+			Found map[string]interface{} `json:"found"`
+		}
+		if json.Unmarshal(progress, &partial) != nil || partial.Found == nil {
+			return
+		}
+		if configured, _ := partial.Found["configured"].(bool); configured && !all {
+			return
+		}
+		send(map[string]interface{}{"type": "found", "device": partial.Found})
+	})
+
+	var result map[string]interface{}
+	if err == nil {
+		result, err = discoveryResult(hubID, data, all)
+	}
+	if err != nil {
+		api.logger.Error().
+			Str("hub_id", hubID).
+			Err(err).
+			Msg("Failed to discover devices via broker service")
+		if !started {
+			api.sendError(w, hubErrorStatus(err), fmt.Sprintf("Failed to discover devices: %v", err))
+			return
+		}
+		send(map[string]interface{}{"type": "error", "message": fmt.Sprintf("Failed to discover devices: %v", err)})
+		return
+	}
+
+	result["type"] = "result"
+	send(result)
+}
+
+// discoveryResult turns the data of a hub's discover reply into the response body,
+// leaving out configured devices unless all is set
+func discoveryResult(hubID string, data json.RawMessage, all bool) (map[string]interface{}, error) {
+	var result struct {
+		Discovered []map[string]interface{} `json:"discovered"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid discovery result: %w", err)
+	}
+
+	discovered := make([]map[string]interface{}, 0, len(result.Discovered))
+	for _, found := range result.Discovered {
+		if configured, _ := found["configured"].(bool); configured && !all {
+			continue
+		}
+		discovered = append(discovered, found)
+	}
+
+	return map[string]interface{}{
+		"discovered": discovered,
+		"count":      len(discovered),
+		"hub":        hubID,
+	}, nil
+}
+
+// handleHubAdopt configures a device found by the hub's last discovery
+func (api *APIServer) handleHubAdopt(w http.ResponseWriter, r *http.Request) {
+	user, ok := GetUserFromContext(r)
+	if !ok {
+		api.sendError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	hubID := vars["hub_id"]
+	discoveredID := vars["discovered_id"]
+
+	// Verify user owns the hub
+	hub, err := api.database.GetHubByHubID(hubID)
+	if err != nil {
+		api.sendError(w, http.StatusNotFound, "Hub not found")
+		return
+	}
+
+	if !hub.UserID.Valid || int(hub.UserID.Int32) != user.ID {
+		api.sendError(w, http.StatusForbidden, "You don't have permission to configure this hub")
+		return
+	}
+
+	// Both fields are optional, an empty body adopts with a generated ID
+	var adoptReq struct {
+		DeviceID   string `json:"device_id"`
+		Credential string `json:"credential"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&adoptReq); err != nil {
+			api.sendError(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+	}
+
+	device, err := api.brokerService.AdoptDevice(r.Context(), hubID, discoveredID, adoptReq.DeviceID, adoptReq.Credential)
+	if err != nil {
+		api.logger.Error().
+			Str("hub_id", hubID).
+			Str("discovered_id", discoveredID).
+			Err(err).
+			Msg("Failed to adopt device via broker service")
+		api.sendError(w, hubErrorStatus(err), fmt.Sprintf("Failed to adopt device: %v", err))
+		return
+	}
+
+	api.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"device":  device,
+		"hub":     hubID,
+	})
+}
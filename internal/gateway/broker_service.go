@@ -16,13 +16,16 @@ package gateway
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
+	"lucas/internal/device"
 	"lucas/internal/hermes"
 	"lucas/internal/logger"
 )
@@ -37,6 +40,7 @@ type BrokerService struct {
 	ctx         context.Context
 	cancel      context.CancelFunc
 	hubHandlers map[string]*HubServiceHandler
+	delivering  map[string]bool // hubs whose durable requests are being delivered
 	mutex       sync.RWMutex
 	// Single persistent client for all gateway-hub communication
 	client      *hermes.HermesClient
@@ -111,6 +115,7 @@ func NewBrokerService(address string, keys *GatewayKeys, database *Database) *Br
 		ctx:         ctx,
 		cancel:      cancel,
 		hubHandlers: make(map[string]*HubServiceHandler),
+		delivering:  make(map[string]bool),
 	}
 
 	// Initialize persistent client for all gateway-hub communication
@@ -121,6 +126,38 @@ func NewBrokerService(address string, keys *GatewayKeys, database *Database) *Br
 	return bs
 }
 
+// SetBrokerLimits bounds the requests the broker queues and routes to each hub
+func (bs *BrokerService) SetBrokerLimits(limits hermes.BrokerLimits) {
+	bs.broker.SetLimits(limits)
+}
+
+// SetPayloadLimits bounds the size of the messages the broker and the gateway's own client
+// accept, and sets from which size they compress bodies for hubs that accept it
+func (bs *BrokerService) SetPayloadLimits(limits hermes.PayloadLimits) {
+	bs.broker.SetPayloadLimits(limits)
+	bs.client.SetPayloadLimits(limits)
+}
+
+// authorizeManagement lets a client use the mmi.* services when its token is the API key
+// of a gateway user, or the product key a hub registered with when the client is that hub
+// or one of its tools (<hub_id>/...). The client ID alone proves nothing, any peer can
+// claim one, so a request without a matching token is refused
+func (bs *BrokerService) authorizeManagement(clientID, token string) bool {
+	if token == "" || bs.database == nil {
+		return false
+	}
+	if _, err := bs.database.GetUserByAPIKey(token); err == nil {
+		return true
+	}
+
+	hubID, _, _ := strings.Cut(clientID, "/")
+	hub, err := bs.database.GetHubByHubID(hubID)
+	if err != nil || hub.ProductKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hub.ProductKey), []byte(token)) == 1
+}
+
 // NewServiceRegistry creates a new service registry
 func NewServiceRegistry() *ServiceRegistry {
 	return &ServiceRegistry{
@@ -134,6 +171,7 @@ func (bs *BrokerService) Start() error {
 
 	// Set broker service reference for immediate device list processing
 	bs.broker.SetBrokerService(bs)
+	bs.broker.SetManagementAuthorizer(bs.authorizeManagement)
 
 	// Start Hermes broker FIRST (must be listening before client connects)
 	if err := bs.broker.Start(); err != nil {
@@ -155,6 +193,10 @@ func (bs *BrokerService) Start() error {
 
 	// Hub services will announce themselves when they connect
 
+	// Durable requests in flight when a gateway last stopped get delivered again. Another
+	// gateway sharing the database may still be delivering its own, so only stale ones
+	bs.releaseStaleDurableRequests()
+
 	// Start service monitoring (simplified)
 	go bs.monitorServices()
 
@@ -307,8 +349,12 @@ func (bs *BrokerService) RegisterDeviceService(hubID, deviceType string, devices
 	return nil
 }
 
+// deviceCommandTimeout is how long a device command stays worth executing, a hub that gets
+// to it later abandons it rather than acting on a stale command
+const deviceCommandTimeout = 30 * time.Second
+
 // SendDeviceCommand sends a command to a device via the appropriate service
-func (bs *BrokerService) SendDeviceCommand(hubID, deviceID string, action json.RawMessage) ([]byte, error) {
+func (bs *BrokerService) SendDeviceCommand(ctx context.Context, hubID, deviceID string, action json.RawMessage) ([]byte, error) {
 	bs.logger.Debug().
 		Str("hub_id", hubID).
 		Str("device_id", deviceID).
@@ -351,6 +397,7 @@ func (bs *BrokerService) SendDeviceCommand(hubID, deviceID string, action json.R
 		Action:    "execute",
 		Payload:   json.RawMessage(deviceCommandBytes),
 		Nonce:     nonce,
+		HubID:     hubID,
 	}
 
 	bs.logger.Debug().
@@ -376,7 +423,9 @@ func (bs *BrokerService) SendDeviceCommand(hubID, deviceID string, action json.R
 	}
 
 	// Send as fire-and-forget request using nonce correlation
-	err = bs.client.RequestFireAndForget(serviceName, requestBytes, nonce)
+	ctx, cancel := context.WithTimeout(ctx, deviceCommandTimeout)
+	defer cancel()
+	err = bs.client.RequestFireAndForget(ctx, serviceName, requestBytes, nonce)
 	if err != nil {
 		bs.logger.Error().
 			Str("hub_id", hubID).
@@ -411,6 +460,245 @@ func (bs *BrokerService) SendDeviceCommand(hubID, deviceID string, action json.R
 	return dataBytes, nil
 }
 
+// QueryDeviceCommand sends a command to a device and waits for the hub's reply,
+// for actions whose result the caller needs such as listing channels
+func (bs *BrokerService) QueryDeviceCommand(ctx context.Context, hubID, deviceID string, action json.RawMessage, timeout time.Duration) (*device.ActionResponse, error) {
+	data, err := bs.QueryHub(ctx, hubID, "execute", map[string]interface{}{
+		"device_id": deviceID,
+		"action":    action,
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *device.ActionResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse device response: %w", err)
+	}
+	if response == nil {
+		return nil, fmt.Errorf("hub returned no device response")
+	}
+	return response, nil
+}
+
+// ErrHubUnsupported is returned for a hub.control action the connected hub did not
+// announce support for
+var ErrHubUnsupported = errors.New("hub does not support this action")
+
+// hubActionCapabilities maps the hub.control actions hubs did not always have to the
+// capability a hub announces for them on READY
+var hubActionCapabilities = map[string]string{
+	"discover": hermes.CapabilityDiscover,
+	"adopt":    hermes.CapabilityAdopt,
+}
+
+// HubInfo returns the hub.control worker of a connected hub, which carries the protocol
+// version and capabilities the hub announced, false when the hub is not connected
+func (bs *BrokerService) HubInfo(hubID string) (*hermes.WorkerInfo, bool) {
+	for identity, worker := range bs.broker.GetWorkers() {
+		if worker.Service == "hub.control" && worker.Status == "ready" &&
+			bs.extractHubIDFromWorkerIdentity(identity) == bs.extractHubIDFromWorkerIdentity(hubID) {
+			return worker, true
+		}
+	}
+	return nil, false
+}
+
+// checkHubSupports refuses an action the connected hub lacks, rather than letting an
+// older hub fail it with an unknown action error or not answer at all. Hubs that are not
+// connected are left for the broker to answer
+func (bs *BrokerService) checkHubSupports(hubID, action string) error {
+	capability, gated := hubActionCapabilities[action]
+	if !gated {
+		return nil
+	}
+	info, connected := bs.HubInfo(hubID)
+	if !connected || hasCapability(info, capability) {
+		return nil
+	}
+	return fmt.Errorf("%w: hub %s (protocol version %d) does not support %s", ErrHubUnsupported, hubID, info.Version, action)
+}
+
+// hasCapability reports whether a worker announced capability
+func hasCapability(info *hermes.WorkerInfo, capability string) bool {
+	for _, c := range info.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryHub sends a hub.control action and waits for the hub's reply, returning its data.
+// The request is withdrawn when ctx is cancelled, and waits at most timeout
+func (bs *BrokerService) QueryHub(ctx context.Context, hubID, action string, payload interface{}, timeout time.Duration) (json.RawMessage, error) {
+	return bs.requestHub(ctx, hubID, action, payload, hermes.GenerateNonce(), timeout)
+}
+
+// requestHub sends a hub.control action to one hub and waits for the data of its reply.
+// The hub echoes the nonce, which correlates the reply with this request
+func (bs *BrokerService) requestHub(ctx context.Context, hubID, action string, payload interface{}, nonce string, timeout time.Duration) (json.RawMessage, error) {
+	if err := bs.checkHubSupports(hubID, action); err != nil {
+		return nil, err
+	}
+
+	requestBytes, err := newHubRequest(hubID, action, payload, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := bs.persistentClient()
+	if err != nil {
+		return nil, err
+	}
+
+	bs.logger.Debug().
+		Str("hub_id", hubID).
+		Str("action", action).
+		Str("nonce", nonce).
+		Msg("Querying hub via broker service")
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	responseBytes, err := client.RequestWithNonce(ctx, "hub.control", requestBytes, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hub: %w", err)
+	}
+	return replyData(responseBytes)
+}
+
+// StreamHub sends a hub.control action like QueryHub, handing the data of each partial
+// reply to progress as the hub reports it. Hubs that do not stream are queried as usual
+// and only send the final reply
+func (bs *BrokerService) StreamHub(ctx context.Context, hubID, action string, payload interface{}, timeout time.Duration, progress func(json.RawMessage)) (json.RawMessage, error) {
+	info, connected := bs.HubInfo(hubID)
+	if !connected || !hasCapability(info, hermes.CapabilityStreaming) {
+		return bs.QueryHub(ctx, hubID, action, payload, timeout)
+	}
+	if err := bs.checkHubSupports(hubID, action); err != nil {
+		return nil, err
+	}
+
+	requestBytes, err := newHubRequest(hubID, action, payload, hermes.GenerateNonce())
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := bs.persistentClient()
+	if err != nil {
+		return nil, err
+	}
+
+	bs.logger.Debug().
+		Str("hub_id", hubID).
+		Str("action", action).
+		Msg("Streaming hub request via broker service")
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	replies, err := client.RequestStream(ctx, "hub.control", requestBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hub: %w", err)
+	}
+
+	for reply := range replies {
+		if reply.Err != nil {
+			return nil, fmt.Errorf("failed to query hub: %w", reply.Err)
+		}
+		data, err := replyData(reply.Body)
+		if err != nil {
+			return nil, err
+		}
+		if !reply.Partial {
+			return data, nil
+		}
+		progress(data)
+	}
+	return nil, fmt.Errorf("failed to query hub: stream ended without a reply")
+}
+
+// newHubRequest builds the hub.control request for an action addressed to one hub
+func newHubRequest(hubID, action string, payload interface{}, nonce string) ([]byte, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", action, err)
+	}
+
+	requestBytes, err := json.Marshal(hermes.ServiceRequest{
+		MessageID: hermes.GenerateMessageID(),
+		Service:   "hub.control",
+		Action:    action,
+		Payload:   json.RawMessage(payloadBytes),
+		Nonce:     nonce,
+		HubID:     hubID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s request: %w", action, err)
+	}
+	return requestBytes, nil
+}
+
+// persistentClient returns the client hub requests are sent with
+func (bs *BrokerService) persistentClient() (*hermes.HermesClient, error) {
+	bs.clientMutex.Lock()
+	client := bs.client
+	bs.clientMutex.Unlock()
+	if client == nil {
+		return nil, fmt.Errorf("persistent client not initialized")
+	}
+	return client, nil
+}
+
+// replyData returns the data of a hub's reply
+func replyData(responseBytes []byte) (json.RawMessage, error) {
+	var reply struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(responseBytes, &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse hub response: %w", err)
+	}
+	return reply.Data, nil
+}
+
+// AdoptDevice asks a hub to configure a device it discovered and records the device
+func (bs *BrokerService) AdoptDevice(ctx context.Context, hubID, discoveredID, deviceID, credential string) (*Device, error) {
+	data, err := bs.QueryHub(ctx, hubID, "adopt", map[string]string{
+		"id":         discoveredID,
+		"device_id":  deviceID,
+		"credential": credential,
+	}, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var adopted struct {
+		Device struct {
+			ID           string   `json:"id"`
+			Name         string   `json:"name"`
+			Type         string   `json:"type"`
+			Model        string   `json:"model"`
+			Address      string   `json:"address"`
+			Capabilities []string `json:"capabilities"`
+		} `json:"device"`
+	}
+	if err := json.Unmarshal(data, &adopted); err != nil {
+		return nil, fmt.Errorf("failed to parse adopted device: %w", err)
+	}
+
+	hub, err := bs.database.GetHubByHubID(hubID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hub: %w", err)
+	}
+	dev := adopted.Device
+	record, err := bs.database.CreateDevice(hub.ID, dev.ID, dev.Type, dev.Name, dev.Model, dev.Address, dev.Capabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record adopted device: %w", err)
+	}
+	return record, nil
+}
+
 // GetServiceStats returns statistics about registered services
 func (bs *BrokerService) GetServiceStats() map[string]interface{} {
 	brokerStats := bs.broker.GetStats()
@@ -445,6 +733,7 @@ func (bs *BrokerService) monitorServices() {
 		case <-ticker.C:
 			bs.checkServiceHealth()
 			bs.cleanupStaleServices()
+			bs.sweepDurableRequests()
 		case <-bs.ctx.Done():
 			bs.logger.Info().Msg("Service monitoring stopping")
 			return
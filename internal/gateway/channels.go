@@ -0,0 +1,119 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultChannelCacheTTL is how long a device's channel list is served without asking its hub
+const DefaultChannelCacheTTL = 5 * time.Minute
+
+// Channel is a broadcast channel reported by a TV's get_channels action
+type Channel struct {
+	URI    string `json:"uri"`
+	Title  string `json:"title"`
+	Number string `json:"number,omitempty"`
+	Source string `json:"source"`
+	Index  int    `json:"index"`
+}
+
+// channelList is a device's channel list and when it was fetched
+type channelList struct {
+	channels  []Channel
+	fetchedAt time.Time
+}
+
+// ChannelCache holds the channel lists of TVs so they can be searched without a hub round trip
+type ChannelCache struct {
+	mutex sync.RWMutex
+	ttl   time.Duration
+	lists map[string]channelList
+}
+
+// NewChannelCache creates a channel cache whose lists expire after ttl
+func NewChannelCache(ttl time.Duration) *ChannelCache {
+	return &ChannelCache{
+		ttl:   ttl,
+		lists: make(map[string]channelList),
+	}
+}
+
+// Get returns the channel list of a device unless it is missing or expired
+func (cc *ChannelCache) Get(deviceID string) ([]Channel, bool) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	list, exists := cc.lists[deviceID]
+	if !exists || time.Since(list.fetchedAt) > cc.ttl {
+		return nil, false
+	}
+	return list.channels, true
+}
+
+// Put stores the channel list of a device
+func (cc *ChannelCache) Put(deviceID string, channels []Channel) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	cc.lists[deviceID] = channelList{channels: channels, fetchedAt: time.Now()}
+}
+
+// Invalidate drops the channel list of a device
+func (cc *ChannelCache) Invalidate(deviceID string) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	delete(cc.lists, deviceID)
+}
+
+// SearchChannels returns the channels whose number or name matches query, ranked by
+// exact number, exact name, name prefix and then name substring
+func SearchChannels(channels []Channel, query string) []Channel {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return channels
+	}
+
+	type ranked struct {
+		channel Channel
+		rank    int
+	}
+	number := strings.TrimLeft(query, "0")
+	var matches []ranked
+	for _, channel := range channels {
+		title := strings.ToLower(channel.Title)
+		switch {
+		case channel.Number != "" && strings.TrimLeft(channel.Number, "0") == number:
+			matches = append(matches, ranked{channel, 0})
+		case title == query:
+			matches = append(matches, ranked{channel, 1})
+		case strings.HasPrefix(title, query):
+			matches = append(matches, ranked{channel, 2})
+		case strings.Contains(title, query):
+			matches = append(matches, ranked{channel, 3})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].rank < matches[j].rank
+	})
+
+	result := make([]Channel, len(matches))
+	for i, match := range matches {
+		result[i] = match.channel
+	}
+	return result
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v3"
+	"lucas/internal/hermes"
 )
 
 // GatewayConfig represents the complete gateway configuration
@@ -53,8 +54,28 @@ type TLSConfig struct {
 
 // ZMQConfig contains ZeroMQ server settings
 type ZMQConfig struct {
-	Address string `yaml:"address"`
-	Timeout string `yaml:"timeout"`
+	Address string        `yaml:"address"`
+	Timeout string        `yaml:"timeout"`
+	Limits  BrokerLimits  `yaml:"limits"`
+	Payload PayloadLimits `yaml:"payload"`
+}
+
+// BrokerLimits bounds the requests the broker holds for busy or stuck hubs. A limit left
+// out or 0 takes the broker's default, -1 disables it
+type BrokerLimits struct {
+	QueueDepth        int            `yaml:"queue_depth"`                   // Requests queued per service
+	ServiceQueueDepth map[string]int `yaml:"service_queue_depth,omitempty"` // Queue depths of services that differ from queue_depth
+	ClientInflight    int            `yaml:"client_inflight"`               // Unanswered requests per client
+	HubInflight       int            `yaml:"hub_inflight"`                  // Unanswered requests per hub
+	RequestTTL        string         `yaml:"request_ttl"`                   // How long a request may stay queued, "0s" disables
+}
+
+// PayloadLimits bounds the size of Hermes messages in bytes. A limit left out or 0 takes
+// Hermes' default, -1 disables it
+type PayloadLimits struct {
+	MaxFrameSize      int `yaml:"max_frame_size"`     // Largest frame accepted as received
+	MaxBodySize       int `yaml:"max_body_size"`      // Largest body once decompressed
+	CompressThreshold int `yaml:"compress_threshold"` // Bodies this large are compressed for peers that accept it
 }
 
 // DatabaseConfig contains database settings
@@ -169,6 +190,8 @@ func NewDefaultGatewayConfig() *GatewayConfig {
 			ZMQ: ZMQConfig{
 				Address: "tcp://*:5555",
 				Timeout: "30s",
+				Limits:  defaultBrokerLimits(),
+				Payload: defaultPayloadLimits(),
 			},
 		},
 		Database: DatabaseConfig{
@@ -222,6 +245,29 @@ func (c *GatewayConfig) setDefaults() error {
 	if c.Server.ZMQ.Timeout == "" {
 		c.Server.ZMQ.Timeout = "30s"
 	}
+	limits, defaultLimits := &c.Server.ZMQ.Limits, defaultBrokerLimits()
+	if limits.QueueDepth == 0 {
+		limits.QueueDepth = defaultLimits.QueueDepth
+	}
+	if limits.ClientInflight == 0 {
+		limits.ClientInflight = defaultLimits.ClientInflight
+	}
+	if limits.HubInflight == 0 {
+		limits.HubInflight = defaultLimits.HubInflight
+	}
+	if limits.RequestTTL == "" {
+		limits.RequestTTL = defaultLimits.RequestTTL
+	}
+	payload, defaultPayload := &c.Server.ZMQ.Payload, defaultPayloadLimits()
+	if payload.MaxFrameSize == 0 {
+		payload.MaxFrameSize = defaultPayload.MaxFrameSize
+	}
+	if payload.MaxBodySize == 0 {
+		payload.MaxBodySize = defaultPayload.MaxBodySize
+	}
+	if payload.CompressThreshold == 0 {
+		payload.CompressThreshold = defaultPayload.CompressThreshold
+	}
 
 	if c.Database.Path == "" {
 		c.Database.Path = "gateway.db"
@@ -288,6 +334,22 @@ func (c *GatewayConfig) validate() error {
 	if _, err := time.ParseDuration(c.Server.ZMQ.Timeout); err != nil {
 		return fmt.Errorf("invalid ZMQ timeout format: %w", err)
 	}
+	limits := c.Server.ZMQ.Limits
+	if ttl, err := time.ParseDuration(limits.RequestTTL); err != nil || ttl < 0 {
+		return fmt.Errorf("invalid ZMQ limits request_ttl: %q", limits.RequestTTL)
+	}
+	if limits.QueueDepth < -1 || limits.ClientInflight < -1 || limits.HubInflight < -1 {
+		return fmt.Errorf("ZMQ limits must be positive, or -1 to disable them")
+	}
+	for service, depth := range limits.ServiceQueueDepth {
+		if depth < -1 {
+			return fmt.Errorf("ZMQ queue depth of %s must be positive, or -1 to disable it", service)
+		}
+	}
+	payload := c.Server.ZMQ.Payload
+	if payload.MaxFrameSize < -1 || payload.MaxBodySize < -1 || payload.CompressThreshold < -1 {
+		return fmt.Errorf("ZMQ payload limits must be positive, or -1 to disable them")
+	}
 	if _, err := time.ParseDuration(c.Database.Timeout); err != nil {
 		return fmt.Errorf("invalid database timeout format: %w", err)
 	}
@@ -392,6 +454,63 @@ func (c *GatewayConfig) GetAPITimeout() time.Duration {
 	return duration
 }
 
+// GetBrokerLimits returns the configured broker limits
+func (c *GatewayConfig) GetBrokerLimits() hermes.BrokerLimits {
+	ttl, _ := time.ParseDuration(c.Server.ZMQ.Limits.RequestTTL)
+	depths := make(map[string]int, len(c.Server.ZMQ.Limits.ServiceQueueDepth))
+	for service, depth := range c.Server.ZMQ.Limits.ServiceQueueDepth {
+		// A service left at 0 takes queue_depth like any other
+		if depth != 0 {
+			depths[service] = hermesLimit(depth)
+		}
+	}
+	return hermes.BrokerLimits{
+		MaxQueueDepth:     hermesLimit(c.Server.ZMQ.Limits.QueueDepth),
+		ServiceQueueDepth: depths,
+		MaxClientInflight: hermesLimit(c.Server.ZMQ.Limits.ClientInflight),
+		MaxWorkerInflight: hermesLimit(c.Server.ZMQ.Limits.HubInflight),
+		RequestTTL:        ttl,
+	}
+}
+
+// defaultBrokerLimits returns the broker's own default limits in config form
+func defaultBrokerLimits() BrokerLimits {
+	limits := hermes.DefaultBrokerLimits()
+	return BrokerLimits{
+		QueueDepth:     limits.MaxQueueDepth,
+		ClientInflight: limits.MaxClientInflight,
+		HubInflight:    limits.MaxWorkerInflight,
+		RequestTTL:     limits.RequestTTL.String(),
+	}
+}
+
+// GetPayloadLimits returns the configured limits on Hermes message sizes
+func (c *GatewayConfig) GetPayloadLimits() hermes.PayloadLimits {
+	return hermes.PayloadLimits{
+		MaxFrameSize:      hermesLimit(c.Server.ZMQ.Payload.MaxFrameSize),
+		MaxBodySize:       hermesLimit(c.Server.ZMQ.Payload.MaxBodySize),
+		CompressThreshold: hermesLimit(c.Server.ZMQ.Payload.CompressThreshold),
+	}
+}
+
+// hermesLimit turns a configured limit into Hermes' form, where 0 rather than -1 disables it
+func hermesLimit(limit int) int {
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// defaultPayloadLimits returns Hermes' own default payload limits in config form
+func defaultPayloadLimits() PayloadLimits {
+	limits := hermes.DefaultPayloadLimits()
+	return PayloadLimits{
+		MaxFrameSize:      limits.MaxFrameSize,
+		MaxBodySize:       limits.MaxBodySize,
+		CompressThreshold: limits.CompressThreshold,
+	}
+}
+
 // GetZMQTimeout returns the ZMQ timeout as a time.Duration
 func (c *GatewayConfig) GetZMQTimeout() time.Duration {
 	duration, _ := time.ParseDuration(c.Server.ZMQ.Timeout)
@@ -69,7 +69,7 @@ type Database struct {
 
 // NewDatabase creates a new database connection
 func NewDatabase(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", sharedDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -85,6 +85,16 @@ func NewDatabase(dbPath string) (*Database, error) {
 	return database, nil
 }
 
+// sharedDSN adds the pragmas that let several gateways use the same database file:
+// WAL so readers do not block the writer, and a busy timeout so writers wait for each other
+func sharedDSN(dbPath string) string {
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return dbPath + separator + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
 // Close closes the database connection
 func (d *Database) Close() error {
 	return d.db.Close()
@@ -127,11 +137,27 @@ func (d *Database) initSchema() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			UNIQUE(hub_id, device_id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS durable_requests (
+			request_id TEXT PRIMARY KEY,
+			hub_id TEXT NOT NULL,
+			device_id TEXT,
+			action TEXT NOT NULL,
+			payload TEXT NOT NULL, -- JSON as TEXT
+			status TEXT NOT NULL DEFAULT 'pending',
+			reply TEXT, -- JSON as TEXT
+			error_message TEXT,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			delivered_at DATETIME,
+			completed_at DATETIME
+		)`,
 		`CREATE INDEX IF NOT EXISTS idx_hubs_user_id ON hubs(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_devices_hub_id ON devices(hub_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_api_key ON users(api_key)`,
 		`CREATE INDEX IF NOT EXISTS idx_hubs_hub_id ON hubs(hub_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_hubs_product_key ON hubs(product_key)`,
+		`CREATE INDEX IF NOT EXISTS idx_durable_requests_hub_status ON durable_requests(hub_id, status)`,
 	}
 
 	for _, query := range queries {
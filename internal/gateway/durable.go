@@ -0,0 +1,432 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"lucas/internal/hermes"
+)
+
+// Durable request lifecycle, in the style of the Titanic pattern (RFC 9): a request is
+// stored as pending, delivered to its hub once the hub's worker is ready and completed
+// or failed with the hub's reply. Pending requests that outlive their TTL expire
+const (
+	DurableStatusPending   = "pending"
+	DurableStatusDelivered = "delivered"
+	DurableStatusCompleted = "completed"
+	DurableStatusFailed    = "failed"
+	DurableStatusExpired   = "expired"
+	DurableStatusCancelled = "cancelled"
+)
+
+const (
+	// DefaultDurableTTL is how long a durable request waits for its hub when no TTL is given
+	DefaultDurableTTL = 24 * time.Hour
+	// MaxDurableTTL bounds the TTL a caller may ask for
+	MaxDurableTTL = 7 * 24 * time.Hour
+	// durableDeliveryTimeout is how long a delivered request waits for the hub's reply
+	durableDeliveryTimeout = 30 * time.Second
+)
+
+// ErrDurableRequestNotFound is returned for unknown durable request IDs
+var ErrDurableRequestNotFound = errors.New("durable request not found")
+
+// DurableRequest is a hub.control request persisted until its hub can handle it
+type DurableRequest struct {
+	RequestID   string          `json:"request_id"`
+	HubID       string          `json:"hub_id"`
+	DeviceID    string          `json:"device_id,omitempty"`
+	Action      string          `json:"action"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Reply       json.RawMessage `json:"reply,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	DeliveredAt *time.Time      `json:"delivered_at,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// Durable request operations
+
+// CreateDurableRequest stores a new pending request for a hub
+func (d *Database) CreateDurableRequest(hubID, deviceID, action string, payload json.RawMessage, ttl time.Duration) (*DurableRequest, error) {
+	now := time.Now().UTC()
+	request := &DurableRequest{
+		RequestID: uuid.New().String(),
+		HubID:     hubID,
+		DeviceID:  deviceID,
+		Action:    action,
+		Payload:   payload,
+		Status:    DurableStatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	query := `INSERT INTO durable_requests (request_id, hub_id, device_id, action, payload, status, created_at, expires_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := d.db.Exec(query, request.RequestID, request.HubID, request.DeviceID, request.Action,
+		string(request.Payload), request.Status, request.CreatedAt, request.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create durable request: %w", err)
+	}
+
+	return request, nil
+}
+
+// GetDurableRequest returns a durable request by its ID
+func (d *Database) GetDurableRequest(requestID string) (*DurableRequest, error) {
+	query := `SELECT request_id, hub_id, device_id, action, payload, status, reply, error_message, attempts,
+					 created_at, expires_at, delivered_at, completed_at
+			  FROM durable_requests WHERE request_id = ?`
+
+	request, err := scanDurableRequest(d.db.QueryRow(query, requestID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDurableRequestNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get durable request: %w", err)
+	}
+	return request, nil
+}
+
+// GetPendingDurableRequests returns the unexpired pending requests of a hub, oldest first
+func (d *Database) GetPendingDurableRequests(hubID string) ([]*DurableRequest, error) {
+	query := `SELECT request_id, hub_id, device_id, action, payload, status, reply, error_message, attempts,
+					 created_at, expires_at, delivered_at, completed_at
+			  FROM durable_requests WHERE hub_id = ? AND status = ? AND expires_at > ?
+			  ORDER BY created_at`
+
+	rows, err := d.db.Query(query, hubID, DurableStatusPending, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending durable requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*DurableRequest
+	for rows.Next() {
+		request, err := scanDurableRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan durable request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
+
+// GetDurableRequestHubs returns the hubs with unexpired pending requests
+func (d *Database) GetDurableRequestHubs() ([]string, error) {
+	query := `SELECT DISTINCT hub_id FROM durable_requests WHERE status = ? AND expires_at > ?`
+
+	rows, err := d.db.Query(query, DurableStatusPending, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query durable request hubs: %w", err)
+	}
+	defer rows.Close()
+
+	var hubIDs []string
+	for rows.Next() {
+		var hubID string
+		if err := rows.Scan(&hubID); err != nil {
+			return nil, fmt.Errorf("failed to scan hub ID: %w", err)
+		}
+		hubIDs = append(hubIDs, hubID)
+	}
+	return hubIDs, rows.Err()
+}
+
+// ClaimDurableRequest marks a pending request as delivered, reporting false when it is no
+// longer pending so that it is never delivered twice at once
+func (d *Database) ClaimDurableRequest(requestID string) (bool, error) {
+	query := `UPDATE durable_requests SET status = ?, attempts = attempts + 1, delivered_at = ?
+			  WHERE request_id = ? AND status = ? AND expires_at > ?`
+	now := time.Now().UTC()
+	return d.updateDurableRequest(query, DurableStatusDelivered, now, requestID, DurableStatusPending, now)
+}
+
+// ReleaseDurableRequest returns a delivered request to pending, for another attempt once
+// the hub is reachable again
+func (d *Database) ReleaseDurableRequest(requestID string) error {
+	query := `UPDATE durable_requests SET status = ? WHERE request_id = ? AND status = ?`
+	_, err := d.updateDurableRequest(query, DurableStatusPending, requestID, DurableStatusDelivered)
+	return err
+}
+
+// FinishDurableRequest records the outcome of a delivered request
+func (d *Database) FinishDurableRequest(requestID, status string, reply json.RawMessage, errMsg string) error {
+	query := `UPDATE durable_requests SET status = ?, reply = ?, error_message = ?, completed_at = ?
+			  WHERE request_id = ? AND status = ?`
+	_, err := d.updateDurableRequest(query, status, nullableJSON(reply), errMsg, time.Now().UTC(),
+		requestID, DurableStatusDelivered)
+	return err
+}
+
+// CancelDurableRequest cancels a pending request, reporting false when it is no longer pending
+func (d *Database) CancelDurableRequest(requestID string) (bool, error) {
+	query := `UPDATE durable_requests SET status = ?, completed_at = ? WHERE request_id = ? AND status = ?`
+	return d.updateDurableRequest(query, DurableStatusCancelled, time.Now().UTC(), requestID, DurableStatusPending)
+}
+
+// ExpireDurableRequests expires the pending requests whose TTL has passed
+func (d *Database) ExpireDurableRequests() (int64, error) {
+	now := time.Now().UTC()
+	query := `UPDATE durable_requests SET status = ?, completed_at = ? WHERE status = ? AND expires_at <= ?`
+	result, err := d.db.Exec(query, DurableStatusExpired, now, DurableStatusPending, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire durable requests: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ReleaseStaleDurableRequests returns requests delivered longer than olderThan ago to pending.
+// These were claimed by a gateway that stopped, or lost its hub, before recording the outcome.
+// Their hubs deduplicate by nonce, so delivering them again is safe
+func (d *Database) ReleaseStaleDurableRequests(olderThan time.Duration) (int64, error) {
+	query := `UPDATE durable_requests SET status = ? WHERE status = ? AND delivered_at <= ?`
+	result, err := d.db.Exec(query, DurableStatusPending, DurableStatusDelivered, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to release stale durable requests: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// updateDurableRequest runs a durable request update, reporting whether it changed a row
+func (d *Database) updateDurableRequest(query string, args ...interface{}) (bool, error) {
+	result, err := d.db.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update durable request: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// scanDurableRequest reads a durable request from a query row
+func scanDurableRequest(row interface{ Scan(...interface{}) error }) (*DurableRequest, error) {
+	var request DurableRequest
+	var payload string
+	var reply, errMsg sql.NullString
+	var deliveredAt, completedAt sql.NullTime
+
+	err := row.Scan(
+		&request.RequestID, &request.HubID, &request.DeviceID, &request.Action, &payload,
+		&request.Status, &reply, &errMsg, &request.Attempts,
+		&request.CreatedAt, &request.ExpiresAt, &deliveredAt, &completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Payload = json.RawMessage(payload)
+	if reply.Valid && reply.String != "" {
+		request.Reply = json.RawMessage(reply.String)
+	}
+	request.Error = errMsg.String
+	if deliveredAt.Valid {
+		request.DeliveredAt = &deliveredAt.Time
+	}
+	if completedAt.Valid {
+		request.CompletedAt = &completedAt.Time
+	}
+	return &request, nil
+}
+
+// nullableJSON stores an empty reply as NULL
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// SubmitDurableRequest stores a hub.control request and delivers it now if the hub is
+// connected, otherwise when its worker next sends READY
+func (bs *BrokerService) SubmitDurableRequest(hubID, deviceID, action string, payload json.RawMessage, ttl time.Duration) (*DurableRequest, error) {
+	if ttl <= 0 {
+		ttl = DefaultDurableTTL
+	}
+	if ttl > MaxDurableTTL {
+		return nil, fmt.Errorf("ttl exceeds the maximum of %s", MaxDurableTTL)
+	}
+
+	request, err := bs.database.CreateDurableRequest(hubID, deviceID, action, payload, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	bs.logger.Info().
+		Str("request_id", request.RequestID).
+		Str("hub_id", hubID).
+		Str("device_id", deviceID).
+		Time("expires_at", request.ExpiresAt).
+		Msg("Stored durable request")
+
+	if bs.hubWorkerConnected(hubID) {
+		go bs.deliverDurableRequests(hubID)
+	}
+	return request, nil
+}
+
+// HubWorkerReady is called by the broker when a hub worker sends READY. Hubs are
+// recorded with or without the "hub_" prefix, so requests under either form are delivered
+func (bs *BrokerService) HubWorkerReady(workerID string) {
+	bs.deliverDurableRequests(workerID)
+	if hubID := bs.extractHubIDFromWorkerIdentity(workerID); hubID != workerID {
+		bs.deliverDurableRequests(hubID)
+	}
+}
+
+// hubWorkerConnected reports whether a hub's hub.control worker is registered and ready
+func (bs *BrokerService) hubWorkerConnected(hubID string) bool {
+	_, connected := bs.HubInfo(hubID)
+	return connected
+}
+
+// deliverDurableRequests delivers the pending requests of a hub in order, stopping at the
+// first one the hub cannot be reached for. Only one delivery runs per hub at a time
+func (bs *BrokerService) deliverDurableRequests(hubID string) {
+	bs.mutex.Lock()
+	if bs.delivering[hubID] {
+		bs.mutex.Unlock()
+		return
+	}
+	bs.delivering[hubID] = true
+	bs.mutex.Unlock()
+
+	defer func() {
+		bs.mutex.Lock()
+		delete(bs.delivering, hubID)
+		bs.mutex.Unlock()
+	}()
+
+	requests, err := bs.database.GetPendingDurableRequests(hubID)
+	if err != nil {
+		bs.logger.Error().Str("hub_id", hubID).Err(err).Msg("Failed to load pending durable requests")
+		return
+	}
+
+	for _, request := range requests {
+		if bs.ctx.Err() != nil {
+			return
+		}
+		if !bs.deliverDurableRequest(request) {
+			return
+		}
+	}
+}
+
+// deliverDurableRequest sends one durable request to its hub and records the outcome,
+// reporting false when the hub could not be reached and the request stays pending
+func (bs *BrokerService) deliverDurableRequest(request *DurableRequest) bool {
+	claimed, err := bs.database.ClaimDurableRequest(request.RequestID)
+	if err != nil {
+		bs.logger.Error().Str("request_id", request.RequestID).Err(err).Msg("Failed to claim durable request")
+		return false
+	}
+	if !claimed {
+		// Cancelled, expired or taken by another delivery meanwhile
+		return true
+	}
+
+	// The request ID doubles as the nonce, so a hub that already handled an earlier
+	// attempt answers from its nonce cache instead of acting twice
+	reply, err := bs.requestHub(bs.ctx, request.HubID, request.Action, request.Payload, request.RequestID, durableDeliveryTimeout)
+
+	// A hub that answered settles the request, anything else, including the broker turning
+	// it away while the hub is busy, leaves it for another attempt
+	status, errMsg := DurableStatusCompleted, ""
+	var serviceErr *hermes.ServiceError
+	if errors.As(err, &serviceErr) && !hermes.IsRetryable(err) {
+		status, errMsg = DurableStatusFailed, serviceErr.Message
+	} else if err != nil {
+		bs.logger.Warn().
+			Str("request_id", request.RequestID).
+			Str("hub_id", request.HubID).
+			Err(err).
+			Msg("Durable request not delivered, keeping it pending")
+		if err := bs.database.ReleaseDurableRequest(request.RequestID); err != nil {
+			bs.logger.Error().Str("request_id", request.RequestID).Err(err).Msg("Failed to release durable request")
+		}
+		return false
+	}
+
+	if err := bs.database.FinishDurableRequest(request.RequestID, status, reply, errMsg); err != nil {
+		bs.logger.Error().Str("request_id", request.RequestID).Err(err).Msg("Failed to record durable request outcome")
+	}
+
+	bs.logger.Info().
+		Str("request_id", request.RequestID).
+		Str("hub_id", request.HubID).
+		Str("status", status).
+		Msg("Durable request delivered")
+	return true
+}
+
+// sweepDurableRequests expires overdue requests and retries those of connected hubs
+func (bs *BrokerService) sweepDurableRequests() {
+	bs.releaseStaleDurableRequests()
+
+	if expired, err := bs.database.ExpireDurableRequests(); err != nil {
+		bs.logger.Error().Err(err).Msg("Failed to expire durable requests")
+	} else if expired > 0 {
+		bs.logger.Info().Int64("expired", expired).Msg("Expired durable requests")
+	}
+
+	hubIDs, err := bs.database.GetDurableRequestHubs()
+	if err != nil {
+		bs.logger.Error().Err(err).Msg("Failed to list hubs with durable requests")
+		return
+	}
+	for _, hubID := range hubIDs {
+		if bs.hubWorkerConnected(hubID) {
+			go bs.deliverDurableRequests(hubID)
+		}
+	}
+}
+
+// releaseStaleDurableRequests returns requests whose delivery outlived its timeout to pending.
+// With several gateways on one database the delivering gateway may be another one, so this
+// goes by age rather than by which gateway claimed the request
+func (bs *BrokerService) releaseStaleDurableRequests() {
+	// Allow the delivering gateway a grace period on top of its timeout to record the outcome
+	released, err := bs.database.ReleaseStaleDurableRequests(2 * durableDeliveryTimeout)
+	if err != nil {
+		bs.logger.Error().Err(err).Msg("Failed to release stale durable requests")
+	} else if released > 0 {
+		bs.logger.Info().Int64("requests", released).Msg("Stale durable requests returned to pending")
+	}
+}
+
+// SubmitDurableDeviceCommand stores a device command for the device's hub, delivered
+// as a hub.control execute request
+func (bs *BrokerService) SubmitDurableDeviceCommand(hubID, deviceID string, action json.RawMessage, ttl time.Duration) (*DurableRequest, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"device_id": deviceID,
+		"action":    action,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal device command: %w", err)
+	}
+	return bs.SubmitDurableRequest(hubID, deviceID, "execute", payload, ttl)
+}
@@ -17,6 +17,7 @@ package hermes
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -47,6 +48,7 @@ type BrokerWorker struct {
 	Status   string
 	Liveness int
 	Requests int
+	Info     *PeerInfo // Version and capabilities announced on READY
 	mutex    sync.RWMutex
 }
 
@@ -57,12 +59,71 @@ type BrokerPendingRequest struct {
 	Service   string
 	Body      []byte
 	Timestamp time.Time
+	Expiry    time.Time // Zero when the request may wait indefinitely
 }
 
+// brokerInflight is a request handed to a worker and not answered yet
+type brokerInflight struct {
+	ClientID  string
+	WorkerID  string
+	Timestamp time.Time
+}
+
+// inflightRetention is how long the broker remembers an unanswered request for cancellation,
+// replies without a message ID never clear their entry
+const inflightRetention = 10 * time.Minute
+
+// clientRetention is how long the broker remembers a client it has not heard from, clients
+// repeat their hello well within it so only gone clients are forgotten
+const clientRetention = 30 * time.Minute
+
+// queueSweepInterval is how often queued requests are checked for expiry
+const queueSweepInterval = time.Second
+
+// BrokerLimits bounds the requests a broker holds, a zero value disables that limit.
+// Only requests with a message ID count towards the in-flight limits
+type BrokerLimits struct {
+	MaxQueueDepth     int            // Requests queued per service while its workers are busy
+	ServiceQueueDepth map[string]int // Queue depths of the services that differ from MaxQueueDepth
+	MaxClientInflight int            // Requests a client may have queued or with workers
+	MaxWorkerInflight int            // Unanswered requests routed to one hub worker
+	RequestTTL        time.Duration  // How long a request may wait in a queue
+}
+
+// queueDepth returns how many requests a service may have queued, 0 when unbounded
+func (l BrokerLimits) queueDepth(service string) int {
+	if depth, exists := l.ServiceQueueDepth[service]; exists {
+		return depth
+	}
+	return l.MaxQueueDepth
+}
+
+// DefaultBrokerLimits returns the limits a new broker starts with
+func DefaultBrokerLimits() BrokerLimits {
+	return BrokerLimits{
+		MaxQueueDepth:     1000,
+		MaxClientInflight: 1000,
+		MaxWorkerInflight: 50,
+		RequestTTL:        30 * time.Second,
+	}
+}
+
+// Errors the broker answers requests it turns away with, clients see them as the
+// message of a ServiceError
+var (
+	ErrServiceQueueFull = errors.New("service queue full")
+	ErrTooManyInflight  = errors.New("too many requests in flight")
+	ErrWorkerBusy       = errors.New("hub worker busy")
+	ErrRequestExpired   = errors.New("request expired in queue")
+	ErrBrokerBusy       = errors.New("broker overloaded")
+)
+
 // Broker implements the Hermes Majordomo Protocol broker with channel-based architecture
 type Broker struct {
 	address       string
-	socket        zmq4.Socket
+	socket        Socket
+	transport     Transport
+	clock         Clock
 	services      map[string]*BrokerService
 	workers       map[string]*BrokerWorker
 	clients       map[string]time.Time
@@ -73,6 +134,13 @@ type Broker struct {
 	stats         *BrokerStats
 	mutex         sync.RWMutex
 	brokerService interface{} // Reference to gateway broker service for immediate device requests
+	framings      map[string]Framing // Framing each peer last spoke, replies are sent in kind
+	clientServices map[string]string // Service each client last requested, named in MDP replies
+	authorizeManagement ManagementAuthorizer // Clients allowed to use the mmi.* services, all when nil
+	inflight      map[string]*brokerInflight // Requests handed to workers, keyed by client and message ID
+	limits        BrokerLimits
+	payload       PayloadLimits
+	clientInfo    map[string]*PeerInfo // What each client announced in its hello
 	
 	// Channel-based architecture
 	messagesCh      chan zmq4.Msg           // Incoming messages from clients/workers
@@ -90,7 +158,7 @@ type WorkerEvent struct {
 	Service  string
 	ClientID string
 	Body     []byte
-	ExtraParts [][]byte
+	Info     *PeerInfo
 }
 
 // ClientEvent represents a client request event
@@ -108,9 +176,17 @@ func NewBroker(address string) *Broker {
 
 	return &Broker{
 		address:   address,
+		transport: ZMQTransport{},
+		clock:     systemClock{},
 		services:  make(map[string]*BrokerService),
 		workers:   make(map[string]*BrokerWorker),
 		clients:   make(map[string]time.Time),
+		framings:  make(map[string]Framing),
+		clientServices: make(map[string]string),
+		inflight:  make(map[string]*brokerInflight),
+		limits:    DefaultBrokerLimits(),
+		payload:   DefaultPayloadLimits(),
+		clientInfo: make(map[string]*PeerInfo),
 		heartbeat: GetMDPHeartbeatExpiry(), // Use RFC 7/MDP standard expiry (7.5s)
 		ctx:       ctx,
 		cancel:    cancel,
@@ -135,22 +211,31 @@ func (b *Broker) SetBrokerService(brokerService interface{}) {
 	b.brokerService = brokerService
 }
 
+// SetTransport sets the transport the broker listens with, ZeroMQ by default. Call it before Start
+func (b *Broker) SetTransport(transport Transport) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.transport = transport
+}
+
+// SetClock sets the clock that paces heartbeats, worker expiry and queue expiry, the
+// system clock by default. Call it before Start
+func (b *Broker) SetClock(clock Clock) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.clock = clock
+	b.stats.StartTime = clock.Now()
+}
+
 // Start starts the broker with channel-based architecture
 func (b *Broker) Start() error {
 	b.logger.Info().
 		Str("address", b.address).
 		Msg("Starting Hermes broker with channel-based architecture")
 
-	// Create ROUTER socket
-	socket := zmq4.NewRouter(b.ctx)
-
-	// Set high watermark option if available
-	if err := socket.SetOption(zmq4.OptionHWM, 1000); err != nil {
-		b.logger.Warn().Err(err).Msg("Failed to set high watermark - continuing without it")
-	}
-
-	// Bind to address
-	if err := socket.Listen(b.address); err != nil {
+	// Bind a ROUTER socket to address
+	socket, err := b.transport.Listen(b.ctx, b.address)
+	if err != nil {
 		return fmt.Errorf("failed to bind to address: %w", err)
 	}
 
@@ -165,6 +250,7 @@ func (b *Broker) Start() error {
 	go b.clientEventHandler()  // Handle client events
 	go b.heartbeatManager()    // Manage heartbeats using heartbeatCh
 	go b.errorHandler()        // Handle errors from errorsCh
+	go b.queueExpirer()        // Expire requests queued past their TTL
 
 	return nil
 }
@@ -181,11 +267,11 @@ func (b *Broker) Stop() error {
 
 	b.cancel()
 
+	// The socket stays set, goroutines still winding down read it and fail on the closed socket
 	if b.socket != nil {
 		if err := b.socket.Close(); err != nil {
 			b.logger.Error().Err(err).Msg("Error closing broker socket")
 		}
-		b.socket = nil
 	}
 
 	b.logger.Info().Msg("Hermes broker stopped")
@@ -200,22 +286,22 @@ func (b *Broker) routeMessage(sender string, msgParts [][]byte) error {
 	}
 
 	// Try to parse as worker message first
-	var workerMsg WorkerMessage
-	if err := json.Unmarshal(msgParts[0], &workerMsg); err == nil && workerMsg.Protocol == HERMES_WORKER {
-		return b.handleWorkerMessage(sender, &workerMsg, msgParts[1:])
+	if workerMsg, framing, err := DecodeWorkerMessage(msgParts); err == nil {
+		b.setPeerFraming(sender, framing)
+		return b.handleWorkerMessage(sender, workerMsg)
 	}
 
 	// Try to parse as client message
-	var clientMsg ClientMessage
-	if err := json.Unmarshal(msgParts[0], &clientMsg); err == nil && clientMsg.Protocol == HERMES_CLIENT {
-		return b.handleClientMessage(sender, &clientMsg)
+	if clientMsg, framing, err := DecodeClientMessage(msgParts); err == nil {
+		b.setPeerFraming(sender, framing)
+		return b.handleClientMessage(sender, clientMsg)
 	}
 
 	return fmt.Errorf("unknown message format")
 }
 
 // handleWorkerMessage handles messages from workers
-func (b *Broker) handleWorkerMessage(workerID string, msg *WorkerMessage, extraParts [][]byte) error {
+func (b *Broker) handleWorkerMessage(workerID string, msg *WorkerMessage) error {
 	b.logger.Debug().
 		Str("worker_id", workerID).
 		Str("command", msg.Command).
@@ -224,12 +310,11 @@ func (b *Broker) handleWorkerMessage(workerID string, msg *WorkerMessage, extraP
 
 	switch msg.Command {
 	case HERMES_READY:
-		return b.handleWorkerReady(workerID, msg.Service)
+		return b.handleWorkerReady(workerID, msg.Service, msg.Info)
 	case HERMES_REPLY:
-		if len(extraParts) > 0 {
-			return b.handleWorkerReply(workerID, msg.ClientID, extraParts[0])
-		}
 		return b.handleWorkerReply(workerID, msg.ClientID, msg.Body)
+	case HERMES_PARTIAL:
+		return b.handleWorkerPartial(workerID, msg.ClientID, msg.Body)
 	case HERMES_HEARTBEAT:
 		return b.handleWorkerHeartbeat(workerID)
 	case HERMES_DISCONNECT:
@@ -256,8 +341,19 @@ func (b *Broker) handleClientMessage(clientID string, msg *ClientMessage) error
 	}
 }
 
-// handleWorkerReady handles worker registration
-func (b *Broker) handleWorkerReady(workerID, serviceName string) error {
+// handleWorkerReady handles worker registration, info is nil for workers that announce
+// no version and are recorded as legacy
+func (b *Broker) handleWorkerReady(workerID, serviceName string, info *PeerInfo) error {
+	if info == nil {
+		info = legacyPeerInfo()
+	}
+	if info.HasCapability(CapabilityCompression) {
+		// Workers learn the broker takes compressed replies from its answer, others hear nothing new
+		if err := b.sendBrokerInfo(workerID); err != nil {
+			b.logger.Debug().Str("worker_id", workerID).Err(err).Msg("Failed to send broker info to worker")
+		}
+	}
+
 	b.mutex.Lock()
 
 	// Create or get service
@@ -281,22 +377,25 @@ func (b *Broker) handleWorkerReady(workerID, serviceName string) error {
 			Service:  serviceName,
 			Status:   "ready",
 			Liveness: 10, // Default liveness for internet tolerance
+			Info:     info,
 		}
 		b.workers[workerID] = worker
 	} else {
-		// Update existing worker
+		// Update existing worker, a worker that reconnects may have been upgraded
 		worker.Service = serviceName
 		worker.Status = "ready"
 		worker.Liveness = 10
+		worker.Info = info
 	}
 
-	worker.Expiry = time.Now().Add(b.heartbeat * 10) // 10 heartbeat intervals for internet tolerance
-	worker.LastPing = time.Now()
+	worker.Expiry = b.clock.Now().Add(b.heartbeat * 10) // 10 heartbeat intervals for internet tolerance
+	worker.LastPing = b.clock.Now()
 
 	// Add worker to service
+	// A worker that reconnects under the same identity is already listed
 	service.mutex.Lock()
-	service.Workers = append(service.Workers, worker)
-	service.Waiting = append(service.Waiting, worker)
+	service.Workers = appendWorker(service.Workers, worker)
+	service.Waiting = appendWorker(service.Waiting, worker)
 	service.mutex.Unlock()
 
 	b.logger.Info().
@@ -312,13 +411,52 @@ func (b *Broker) handleWorkerReady(workerID, serviceName string) error {
 	// For hub.control service, immediately request device list as part of handshake
 	if serviceName == "hub.control" {
 		b.sendImmediateDeviceListRequest(workerID)
+		b.notifyHubWorkerReady(workerID)
 	}
 
 	return nil
 }
 
+// notifyHubWorkerReady tells the broker service a hub worker is ready, so it can deliver
+// whatever was held back while the hub was away
+func (b *Broker) notifyHubWorkerReady(workerID string) {
+	b.mutex.RLock()
+	brokerService := b.brokerService
+	b.mutex.RUnlock()
+
+	if bs, ok := brokerService.(interface {
+		HubWorkerReady(workerID string)
+	}); ok {
+		go bs.HubWorkerReady(workerID)
+	}
+}
+
+// hubControlRequest holds the fields of a hub.control request the broker routes by
+type hubControlRequest struct {
+	HubID string `json:"hub_id"`
+	Nonce string `json:"nonce"`
+}
+
+// parseHubControlRequest returns the routing fields of a hub.control request, left empty
+// when the body is not a service request
+func parseHubControlRequest(body []byte) hubControlRequest {
+	var request hubControlRequest
+	if json.Unmarshal(body, &request) != nil {
+		return hubControlRequest{}
+	}
+	return request
+}
+
+// isHubWorker reports whether a worker identity belongs to a hub, hub workers may
+// identify with or without the "hub_" prefix
+func isHubWorker(identity, hubID string) bool {
+	return identity == hubID || strings.TrimPrefix(identity, "hub_") == strings.TrimPrefix(hubID, "hub_")
+}
+
 // handleWorkerReply handles replies from workers
 func (b *Broker) handleWorkerReply(workerID, clientID string, reply []byte) error {
+	b.completeInflight(clientID, replyMessageID(reply))
+
 	b.mutex.RLock()
 	worker, exists := b.workers[workerID]
 	b.mutex.RUnlock()
@@ -332,7 +470,7 @@ func (b *Broker) handleWorkerReply(workerID, clientID string, reply []byte) erro
 			Msg("Received reply from unknown worker - forwarding to client and requesting re-registration")
 
 		// Send reply to client anyway (client is waiting for this)
-		if err := b.sendToClient(clientID, reply); err != nil {
+		if err := b.sendToClient(clientID, b.clientService(clientID), reply); err != nil {
 			return fmt.Errorf("failed to send reply from unknown worker to client: %w", err)
 		}
 
@@ -343,10 +481,13 @@ func (b *Broker) handleWorkerReply(workerID, clientID string, reply []byte) erro
 	// Update worker stats
 	worker.mutex.Lock()
 	worker.Requests++
-	worker.LastPing = time.Now()
-	worker.Expiry = time.Now().Add(b.heartbeat * 10) // Increased from 3 to 10 for consistency
+	worker.LastPing = b.clock.Now()
+	worker.Expiry = b.clock.Now().Add(b.heartbeat * 10) // Increased from 3 to 10 for consistency
 	worker.mutex.Unlock()
 
+	// A worker that replied is free for the next request of its service
+	b.requeueWorker(worker)
+
 	// Check if this is an immediate device list response from hub.control service
 	// Use standardized client ID from jargon specification
 	if clientID == "gateway_main" && worker.Service == "hub.control" {
@@ -428,7 +569,62 @@ func (b *Broker) handleWorkerReply(workerID, clientID string, reply []byte) erro
 	}
 
 	// Send reply to client for regular requests
-	return b.sendToClient(clientID, reply)
+	return b.sendToClient(clientID, worker.Service, reply)
+}
+
+// handleWorkerPartial forwards a partial reply to its client. The worker stays busy with
+// the request and its entry stays in flight until the final reply
+func (b *Broker) handleWorkerPartial(workerID, clientID string, partial []byte) error {
+	b.touchInflight(clientID, replyMessageID(partial))
+
+	b.mutex.Lock()
+	b.stats.Partials++
+	worker, exists := b.workers[workerID]
+	b.mutex.Unlock()
+
+	if !exists {
+		b.logger.Warn().
+			Str("worker_id", workerID).
+			Str("client_id", clientID).
+			Msg("Received partial reply from unknown worker - forwarding to client")
+		return b.sendToClient(clientID, b.clientService(clientID), partial)
+	}
+
+	// A worker reporting progress is alive, however long the request takes
+	worker.mutex.Lock()
+	worker.LastPing = b.clock.Now()
+	worker.Expiry = b.clock.Now().Add(b.heartbeat * 10)
+	worker.mutex.Unlock()
+
+	return b.sendToClient(clientID, worker.Service, partial)
+}
+
+// requeueWorker puts a worker back among the waiting workers of its service and hands
+// it any request queued meanwhile
+func (b *Broker) requeueWorker(worker *BrokerWorker) {
+	b.mutex.RLock()
+	service, exists := b.services[worker.Service]
+	b.mutex.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	service.mutex.Lock()
+	service.Waiting = appendWorker(service.Waiting, worker)
+	service.mutex.Unlock()
+
+	b.processPendingRequests(worker.Service)
+}
+
+// appendWorker appends a worker to a list unless it is already in it
+func appendWorker(workers []*BrokerWorker, worker *BrokerWorker) []*BrokerWorker {
+	for _, w := range workers {
+		if w == worker {
+			return workers
+		}
+	}
+	return append(workers, worker)
 }
 
 // handleWorkerHeartbeat handles heartbeat from workers
@@ -450,15 +646,15 @@ func (b *Broker) handleWorkerHeartbeat(workerID string) error {
 	}
 
 	worker.mutex.Lock()
-	worker.LastPing = time.Now()
-	worker.Expiry = time.Now().Add(b.heartbeat * 10) // 10 heartbeat intervals for internet tolerance
+	worker.LastPing = b.clock.Now()
+	worker.Expiry = b.clock.Now().Add(b.heartbeat * 10) // 10 heartbeat intervals for internet tolerance
 	worker.Liveness = 10
 	worker.mutex.Unlock()
 
 	// Update heartbeat statistics
 	b.mutex.Lock()
 	b.stats.HeartbeatsReceived++
-	b.stats.LastHeartbeat = time.Now()
+	b.stats.LastHeartbeat = b.clock.Now()
 	b.mutex.Unlock()
 
 	b.logger.Debug().
@@ -472,23 +668,17 @@ func (b *Broker) handleWorkerHeartbeat(workerID string) error {
 
 // sendHeartbeatResponse sends a heartbeat response to a worker
 func (b *Broker) sendHeartbeatResponse(workerID string) error {
-	if b.socket == nil {
-		// In test scenarios, socket may be nil - just skip sending
-		b.logger.Debug().Msg("Socket not available - skipping heartbeat response")
-		return nil
-	}
-
 	heartbeatMsg := &WorkerMessage{
 		Protocol: HERMES_WORKER,
 		Command:  HERMES_HEARTBEAT,
 	}
 
-	msgBytes, err := SerializeMessage(heartbeatMsg)
+	frames, err := EncodeWorkerMessage(heartbeatMsg, b.peerFraming(workerID))
 	if err != nil {
 		return fmt.Errorf("failed to serialize heartbeat response: %w", err)
 	}
 
-	err = b.socket.Send(zmq4.NewMsgFrom([]byte(workerID), []byte(""), msgBytes))
+	err = b.sendFrames(workerID, frames)
 	if err != nil {
 		return fmt.Errorf("failed to send heartbeat response to worker: %w", err)
 	}
@@ -506,14 +696,21 @@ func (b *Broker) sendHeartbeatResponse(workerID string) error {
 	return nil
 }
 
-// sendReregistrationRequest sends a re-registration request to a worker
-func (b *Broker) sendReregistrationRequest(workerID string) error {
-	if b.socket == nil {
-		// In test scenarios, socket may be nil - just skip sending
-		b.logger.Debug().Msg("Socket not available - skipping re-registration request")
-		return nil
+// sendBrokerInfo answers a worker's READY with a HEARTBEAT carrying the broker's PeerInfo
+func (b *Broker) sendBrokerInfo(workerID string) error {
+	frames, err := EncodeWorkerMessage(&WorkerMessage{
+		Protocol: HERMES_WORKER,
+		Command:  HERMES_HEARTBEAT,
+		Info:     b.BrokerInfo(),
+	}, b.peerFraming(workerID))
+	if err != nil {
+		return fmt.Errorf("failed to serialize broker info: %w", err)
 	}
+	return b.sendFrames(workerID, frames)
+}
 
+// sendReregistrationRequest sends a re-registration request to a worker
+func (b *Broker) sendReregistrationRequest(workerID string) error {
 	// Send a special disconnect message to trigger worker re-registration
 	// The worker will interpret this as a signal to re-send its READY message
 	reregMsg := &WorkerMessage{
@@ -521,12 +718,12 @@ func (b *Broker) sendReregistrationRequest(workerID string) error {
 		Command:  HERMES_DISCONNECT,
 	}
 
-	msgBytes, err := SerializeMessage(reregMsg)
+	frames, err := EncodeWorkerMessage(reregMsg, b.peerFraming(workerID))
 	if err != nil {
 		return fmt.Errorf("failed to serialize re-registration request: %w", err)
 	}
 
-	err = b.socket.Send(zmq4.NewMsgFrom([]byte(workerID), []byte(""), msgBytes))
+	err = b.sendFrames(workerID, frames)
 	if err != nil {
 		return fmt.Errorf("failed to send re-registration request to worker: %w", err)
 	}
@@ -545,12 +742,27 @@ func (b *Broker) handleWorkerDisconnect(workerID string) error {
 
 // handleClientRequest handles requests from clients
 func (b *Broker) handleClientRequest(clientID string, msg *ClientMessage) error {
+	// Cancellations are not requests of their own, the reply to the request they cancel
+	// must still name its service
+	if msg.Service == HERMES_CANCEL_SERVICE {
+		return b.handleCancelRequest(clientID, string(msg.Body))
+	}
+
 	b.mutex.Lock()
-	b.clients[clientID] = time.Now()
+	b.clients[clientID] = b.clock.Now()
+	b.clientServices[clientID] = msg.Service
 	b.stats.Requests++
-	b.stats.LastRequest = time.Now()
+	b.stats.LastRequest = b.clock.Now()
 	b.mutex.Unlock()
 
+	// The broker answers the management interface and hellos itself
+	if IsManagementService(msg.Service) {
+		return b.handleManagementRequest(clientID, msg)
+	}
+	if msg.Service == HERMES_HELLO_SERVICE {
+		return b.handleHello(clientID, msg.Body)
+	}
+
 	// Get service
 	b.mutex.RLock()
 	service, exists := b.services[msg.Service]
@@ -561,22 +773,37 @@ func (b *Broker) handleClientRequest(clientID string, msg *ClientMessage) error
 		errorResp := CreateServiceResponse(msg.MessageID, msg.Service, false, nil,
 			fmt.Errorf("service not available: %s", msg.Service))
 		respBytes, _ := SerializeServiceResponse(errorResp)
-		return b.sendToClient(clientID, respBytes)
+		return b.sendToClient(clientID, msg.Service, respBytes)
+	}
+
+	limits := b.getLimits()
+	if msg.MessageID != "" && limits.MaxClientInflight > 0 &&
+		b.clientInflight(clientID) >= limits.MaxClientInflight {
+		return b.rejectRequest(clientID, msg.Service, msg.MessageID, msg.Body, ErrTooManyInflight)
 	}
 
 	// For hub.control service, use direct worker lookup (1:1 mapping)
 	if msg.Service == "hub.control" {
-		// Find the hub worker directly by iterating through workers for this service
+		// Find the hub worker directly by iterating through workers for this service.
+		// Requests naming a hub only go to that hub's worker
+		target := parseHubControlRequest(msg.Body)
 		service.mutex.Lock()
 		var hubWorker *BrokerWorker
 		for _, worker := range service.Workers {
-			if worker.Service == "hub.control" && worker.Status == "ready" {
+			if worker.Service == "hub.control" && worker.Status == "ready" &&
+				(target.HubID == "" || isHubWorker(worker.Identity, target.HubID)) {
 				hubWorker = worker
 				break
 			}
 		}
 		service.mutex.Unlock()
 		
+		// A hub that stopped answering must not collect requests without bound
+		if hubWorker != nil && limits.MaxWorkerInflight > 0 &&
+			b.workerInflight(hubWorker.Identity) >= limits.MaxWorkerInflight {
+			return b.rejectRequest(clientID, msg.Service, msg.MessageID, msg.Body, ErrWorkerBusy)
+		}
+
 		if hubWorker != nil {
 			b.logger.Debug().
 				Str("client_id", clientID).
@@ -584,35 +811,44 @@ func (b *Broker) handleClientRequest(clientID string, msg *ClientMessage) error
 				Str("hub_worker_id", hubWorker.Identity).
 				Str("message_id", msg.MessageID).
 				Msg("Routing request directly to hub worker")
-			return b.sendToWorker(hubWorker.Identity, clientID, msg.Body)
+			return b.dispatchToWorker(hubWorker.Identity, clientID, msg.MessageID, msg.Body)
 		} else {
 			// Hub worker not available
 			b.logger.Warn().
 				Str("client_id", clientID).
 				Str("service", msg.Service).
+				Str("hub_id", target.HubID).
 				Str("message_id", msg.MessageID).
 				Msg("Hub worker not available")
-			errorResp := CreateServiceResponse(msg.MessageID, msg.Service, false, nil, 
+			// Echo the nonce so callers correlating by nonce see the error rather than a timeout
+			errorResp := CreateServiceResponseWithNonce(msg.MessageID, msg.Service, target.Nonce, false, nil,
 				fmt.Errorf("hub worker not available"))
 			respBytes, _ := SerializeServiceResponse(errorResp)
-			return b.sendToClient(clientID, respBytes)
+			return b.sendToClient(clientID, msg.Service, respBytes)
 		}
 	}
 
-	// For other services, use the queue system
+	// For other services, use the queue system. The service lock is released before
+	// replying or dispatching, both of which take the broker lock
 	service.mutex.Lock()
-	defer service.mutex.Unlock()
-
 	if len(service.Waiting) == 0 {
+		if depth := limits.queueDepth(msg.Service); depth > 0 && len(service.Requests) >= depth {
+			service.mutex.Unlock()
+			return b.rejectRequest(clientID, msg.Service, msg.MessageID, msg.Body, ErrServiceQueueFull)
+		}
+
 		// No workers available, queue the request
+		now := b.clock.Now()
 		request := &BrokerPendingRequest{
 			ClientID:  clientID,
 			MessageID: msg.MessageID,
 			Service:   msg.Service,
 			Body:      msg.Body,
-			Timestamp: time.Now(),
+			Timestamp: now,
+			Expiry:    queuedRequestExpiry(msg.Body, now, limits.RequestTTL),
 		}
 		service.Requests = append(service.Requests, request)
+		service.mutex.Unlock()
 
 		b.logger.Debug().
 			Str("client_id", clientID).
@@ -625,9 +861,10 @@ func (b *Broker) handleClientRequest(clientID string, msg *ClientMessage) error
 	// Get available worker
 	worker := service.Waiting[0]
 	service.Waiting = service.Waiting[1:]
+	service.mutex.Unlock()
 
 	// Send request to worker
-	return b.sendToWorker(worker.Identity, clientID, msg.Body)
+	return b.dispatchToWorker(worker.Identity, clientID, msg.MessageID, msg.Body)
 }
 
 // processPendingRequests processes queued requests for a service
@@ -640,33 +877,378 @@ func (b *Broker) processPendingRequests(serviceName string) {
 		return
 	}
 
-	service.mutex.Lock()
-	defer service.mutex.Unlock()
+	type assignment struct {
+		request *BrokerPendingRequest
+		worker  *BrokerWorker
+	}
+	var assignments []assignment
+	var expired []*BrokerPendingRequest
 
+	now := b.clock.Now()
+	service.mutex.Lock()
 	for len(service.Requests) > 0 && len(service.Waiting) > 0 {
 		request := service.Requests[0]
 		service.Requests = service.Requests[1:]
 
+		// A request nobody waits for any more is answered instead of handed on
+		if request.expired(now) {
+			expired = append(expired, request)
+			continue
+		}
+
 		worker := service.Waiting[0]
 		service.Waiting = service.Waiting[1:]
+		assignments = append(assignments, assignment{request, worker})
+	}
+	service.mutex.Unlock()
+
+	for _, request := range expired {
+		b.expireRequest(request)
+	}
 
+	for _, a := range assignments {
 		// Send request to worker
-		if err := b.sendToWorker(worker.Identity, request.ClientID, request.Body); err != nil {
+		if err := b.dispatchToWorker(a.worker.Identity, a.request.ClientID, a.request.MessageID, a.request.Body); err != nil {
 			b.logger.Error().
-				Str("worker_id", worker.Identity).
-				Str("client_id", request.ClientID).
+				Str("worker_id", a.worker.Identity).
+				Str("client_id", a.request.ClientID).
 				Err(err).
 				Msg("Failed to send pending request to worker")
 		}
 	}
 }
 
+// dispatchToWorker sends a client request to a worker, remembering which worker has it
+// so the client can still cancel it
+func (b *Broker) dispatchToWorker(workerID, clientID, messageID string, body []byte) error {
+	if messageID != "" {
+		b.mutex.Lock()
+		b.inflight[inflightKey(clientID, messageID)] = &brokerInflight{
+			ClientID:  clientID,
+			WorkerID:  workerID,
+			Timestamp: b.clock.Now(),
+		}
+		b.mutex.Unlock()
+	}
+	return b.sendToWorker(workerID, clientID, body)
+}
+
+// completeInflight forgets a request once its worker answered
+func (b *Broker) completeInflight(clientID, messageID string) {
+	if messageID == "" {
+		return
+	}
+	b.mutex.Lock()
+	delete(b.inflight, inflightKey(clientID, messageID))
+	b.mutex.Unlock()
+}
+
+// touchInflight keeps a request that is still making progress from being forgotten
+func (b *Broker) touchInflight(clientID, messageID string) {
+	if messageID == "" {
+		return
+	}
+	b.mutex.Lock()
+	if inflight, exists := b.inflight[inflightKey(clientID, messageID)]; exists {
+		inflight.Timestamp = b.clock.Now()
+	}
+	b.mutex.Unlock()
+}
+
+// SetLimits replaces the limits on the requests the broker holds, requests already
+// queued keep their expiry
+func (b *Broker) SetLimits(limits BrokerLimits) {
+	// Copy the overrides so the caller's map can't change under the broker
+	depths := make(map[string]int, len(limits.ServiceQueueDepth))
+	for service, depth := range limits.ServiceQueueDepth {
+		depths[service] = depth
+	}
+	limits.ServiceQueueDepth = depths
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.limits = limits
+}
+
+// getLimits returns the broker's current limits
+func (b *Broker) getLimits() BrokerLimits {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.limits
+}
+
+// SetPayloadLimits replaces the size limits on the messages the broker accepts and the
+// threshold from which it compresses bodies for peers announcing compression
+func (b *Broker) SetPayloadLimits(limits PayloadLimits) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.payload = limits
+}
+
+// getPayloadLimits returns the broker's current payload limits
+func (b *Broker) getPayloadLimits() PayloadLimits {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.payload
+}
+
+// clientInflight counts a client's requests that are queued or with a worker
+func (b *Broker) clientInflight(clientID string) int {
+	count := 0
+	b.mutex.RLock()
+	for _, inflight := range b.inflight {
+		if inflight.ClientID == clientID {
+			count++
+		}
+	}
+	services := make([]*BrokerService, 0, len(b.services))
+	for _, service := range b.services {
+		services = append(services, service)
+	}
+	b.mutex.RUnlock()
+
+	for _, service := range services {
+		service.mutex.RLock()
+		for _, request := range service.Requests {
+			if request.ClientID == clientID && request.MessageID != "" {
+				count++
+			}
+		}
+		service.mutex.RUnlock()
+	}
+	return count
+}
+
+// workerInflight counts the unanswered requests handed to a worker
+func (b *Broker) workerInflight(workerID string) int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	count := 0
+	for _, inflight := range b.inflight {
+		if inflight.WorkerID == workerID {
+			count++
+		}
+	}
+	return count
+}
+
+// rejectRequest answers a request the broker will not take on
+func (b *Broker) rejectRequest(clientID, service, messageID string, body []byte, reason error) error {
+	b.mutex.Lock()
+	b.stats.Rejected++
+	b.mutex.Unlock()
+
+	b.logger.Warn().
+		Str("client_id", clientID).
+		Str("service", service).
+		Str("message_id", messageID).
+		Str("reason", reason.Error()).
+		Msg("Request rejected")
+	return b.sendErrorReply(clientID, service, messageID, body, reason)
+}
+
+// expireRequest answers a request that waited in its queue too long
+func (b *Broker) expireRequest(request *BrokerPendingRequest) {
+	b.mutex.Lock()
+	b.stats.Expired++
+	b.mutex.Unlock()
+
+	b.logger.Warn().
+		Str("client_id", request.ClientID).
+		Str("service", request.Service).
+		Str("message_id", request.MessageID).
+		Dur("queued_for", b.clock.Now().Sub(request.Timestamp)).
+		Msg("Queued request expired")
+	if err := b.sendErrorReply(request.ClientID, request.Service, request.MessageID, request.Body, ErrRequestExpired); err != nil {
+		b.logger.Debug().Str("client_id", request.ClientID).Err(err).Msg("Failed to send expiry reply")
+	}
+}
+
+// sendErrorReply answers a request with an error, echoing its nonce so callers
+// correlating by nonce see the error rather than a timeout
+func (b *Broker) sendErrorReply(clientID, service, messageID string, body []byte, reason error) error {
+	nonce := parseHubControlRequest(body).Nonce
+	errorResp := CreateServiceResponseWithNonce(messageID, service, nonce, false, nil, reason)
+	respBytes, _ := SerializeServiceResponse(errorResp)
+	return b.sendToClient(clientID, service, respBytes)
+}
+
+// queuedRequestExpiry returns when a request queued at now stops being worth delivering:
+// after the broker's TTL or the request's own timeout, whichever comes first
+func queuedRequestExpiry(body []byte, now time.Time, ttl time.Duration) time.Time {
+	var request struct {
+		Timeout int `json:"timeout"`
+	}
+	if json.Unmarshal(body, &request) == nil && request.Timeout > 0 {
+		if timeout := time.Duration(request.Timeout) * time.Second; ttl <= 0 || timeout < ttl {
+			ttl = timeout
+		}
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// expired reports whether a queued request has outlived its expiry
+func (r *BrokerPendingRequest) expired(now time.Time) bool {
+	return !r.Expiry.IsZero() && now.After(r.Expiry)
+}
+
+// expireQueuedRequests answers and drops every queued request past its expiry
+func (b *Broker) expireQueuedRequests() {
+	b.mutex.RLock()
+	services := make([]*BrokerService, 0, len(b.services))
+	for _, service := range b.services {
+		services = append(services, service)
+	}
+	b.mutex.RUnlock()
+
+	now := b.clock.Now()
+	for _, service := range services {
+		var expired []*BrokerPendingRequest
+		service.mutex.Lock()
+		kept := service.Requests[:0]
+		for _, request := range service.Requests {
+			if request.expired(now) {
+				expired = append(expired, request)
+			} else {
+				kept = append(kept, request)
+			}
+		}
+		service.Requests = kept
+		service.mutex.Unlock()
+
+		for _, request := range expired {
+			b.expireRequest(request)
+		}
+	}
+}
+
+// handleCancelRequest withdraws a client's request: a queued request is dropped, one a
+// worker is handling is cancelled at the worker. Cancellations are not answered
+func (b *Broker) handleCancelRequest(clientID, messageID string) error {
+	if messageID == "" {
+		return fmt.Errorf("cancel without message ID from %s", clientID)
+	}
+
+	b.mutex.RLock()
+	services := make([]*BrokerService, 0, len(b.services))
+	for _, service := range b.services {
+		services = append(services, service)
+	}
+	b.mutex.RUnlock()
+
+	for _, service := range services {
+		service.mutex.Lock()
+		for i, request := range service.Requests {
+			if request.ClientID == clientID && request.MessageID == messageID {
+				service.Requests = append(service.Requests[:i], service.Requests[i+1:]...)
+				service.mutex.Unlock()
+
+				b.mutex.Lock()
+				b.stats.Cancelled++
+				b.mutex.Unlock()
+
+				b.logger.Debug().
+					Str("client_id", clientID).
+					Str("message_id", messageID).
+					Str("service", service.Name).
+					Msg("Queued request cancelled")
+				return nil
+			}
+		}
+		service.mutex.Unlock()
+	}
+
+	key := inflightKey(clientID, messageID)
+	b.mutex.Lock()
+	inflight, exists := b.inflight[key]
+	if exists {
+		delete(b.inflight, key)
+		b.stats.Cancelled++
+	}
+	b.mutex.Unlock()
+
+	if !exists {
+		b.logger.Debug().
+			Str("client_id", clientID).
+			Str("message_id", messageID).
+			Msg("Cancel for a request that is no longer pending")
+		return nil
+	}
+
+	// Workers that predate CANCEL would take it for a malformed message
+	if !b.workerHasCapability(inflight.WorkerID, CapabilityCancel) {
+		b.logger.Debug().
+			Str("client_id", clientID).
+			Str("message_id", messageID).
+			Str("worker_id", inflight.WorkerID).
+			Msg("Worker does not support cancel, letting the request run")
+		return nil
+	}
+
+	b.logger.Debug().
+		Str("client_id", clientID).
+		Str("message_id", messageID).
+		Str("worker_id", inflight.WorkerID).
+		Msg("Forwarding cancel to worker")
+	return b.sendCancelToWorker(inflight.WorkerID, clientID, messageID)
+}
+
+// workerHasCapability reports whether a connected worker announced a capability
+func (b *Broker) workerHasCapability(workerID, capability string) bool {
+	b.mutex.RLock()
+	worker, exists := b.workers[workerID]
+	b.mutex.RUnlock()
+	if !exists {
+		return false
+	}
+
+	worker.mutex.RLock()
+	defer worker.mutex.RUnlock()
+	return worker.Info.HasCapability(capability)
+}
+
+// sendCancelToWorker tells a worker a client gave up on a request
+func (b *Broker) sendCancelToWorker(workerID, clientID, messageID string) error {
+	frames, err := EncodeWorkerMessage(&WorkerMessage{
+		Protocol: HERMES_WORKER,
+		Command:  HERMES_CANCEL,
+		ClientID: clientID,
+		Body:     []byte(messageID),
+	}, b.peerFraming(workerID))
+	if err != nil {
+		return fmt.Errorf("failed to serialize cancel message: %w", err)
+	}
+
+	if err := b.sendFrames(workerID, frames); err != nil {
+		return fmt.Errorf("failed to send cancel to worker: %w", err)
+	}
+	return nil
+}
+
+// inflightKey identifies a request by its client, message IDs are only unique per client
+func inflightKey(clientID, messageID string) string {
+	return clientID + "/" + messageID
+}
+
+// replyMessageID returns the message ID of a service response, empty for other replies
+func replyMessageID(reply []byte) string {
+	var response struct {
+		MessageID string `json:"message_id"`
+	}
+	if json.Unmarshal(reply, &response) != nil {
+		return ""
+	}
+	return response.MessageID
+}
+
 // sendToWorker sends a message to a worker
 func (b *Broker) sendToWorker(workerID, clientID string, body []byte) error {
-	if b.socket == nil {
-		// In test scenarios, socket may be nil - just skip sending
-		b.logger.Debug().Msg("Socket not available - skipping worker message")
-		return nil
+	if b.workerHasCapability(workerID, CapabilityCompression) {
+		body = compressBody(body, b.getPayloadLimits().CompressThreshold)
 	}
 
 	workerMsg := &WorkerMessage{
@@ -676,12 +1258,12 @@ func (b *Broker) sendToWorker(workerID, clientID string, body []byte) error {
 		ClientID: clientID,
 	}
 
-	msgBytes, err := SerializeMessage(workerMsg)
+	frames, err := EncodeWorkerMessage(workerMsg, b.peerFraming(workerID))
 	if err != nil {
 		return fmt.Errorf("failed to serialize worker message: %w", err)
 	}
 
-	err = b.socket.Send(zmq4.NewMsgFrom([]byte(workerID), []byte(""), msgBytes))
+	err = b.sendFrames(workerID, frames)
 	if err != nil {
 		return fmt.Errorf("failed to send message to worker: %w", err)
 	}
@@ -694,15 +1276,13 @@ func (b *Broker) sendToWorker(workerID, clientID string, body []byte) error {
 	return nil
 }
 
-// sendToClient sends a message to a client
-func (b *Broker) sendToClient(clientID string, body []byte) error {
-	if b.socket == nil {
-		// In test scenarios, socket may be nil - just skip sending
-		b.logger.Debug().Msg("Socket not available - skipping client message")
-		return nil
+// sendToClient sends a reply from service to a client
+func (b *Broker) sendToClient(clientID, service string, body []byte) error {
+	if b.clientHasCapability(clientID, CapabilityCompression) {
+		body = compressBody(body, b.getPayloadLimits().CompressThreshold)
 	}
 
-	err := b.socket.Send(zmq4.NewMsgFrom([]byte(clientID), []byte(""), body))
+	err := b.sendFrames(clientID, EncodeClientReply(service, body, b.peerFraming(clientID)))
 	if err != nil {
 		return fmt.Errorf("failed to send message to client: %w", err)
 	}
@@ -718,15 +1298,54 @@ func (b *Broker) sendToClient(clientID string, body []byte) error {
 	return nil
 }
 
+// sendFrames sends frames to a peer, prefixed with its routing identity
+func (b *Broker) sendFrames(peer string, frames [][]byte) error {
+	parts := make([][]byte, 0, len(frames)+1)
+	parts = append(parts, []byte(peer))
+	parts = append(parts, frames...)
+	return b.socket.Send(zmq4.NewMsgFrom(parts...))
+}
+
+// setPeerFraming remembers the framing a peer spoke
+func (b *Broker) setPeerFraming(peer string, framing Framing) {
+	b.mutex.Lock()
+	b.framings[peer] = framing
+	b.mutex.Unlock()
+}
+
+// peerFraming returns the framing a peer last spoke, FramingMDP for peers not heard from
+func (b *Broker) peerFraming(peer string) Framing {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if framing, exists := b.framings[peer]; exists {
+		return framing
+	}
+	return FramingMDP
+}
+
+// clientService returns the service a client last requested
+func (b *Broker) clientService(clientID string) string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.clientServices[clientID]
+}
 
 // checkWorkerLiveness checks and removes expired workers
 func (b *Broker) checkWorkerLiveness() {
-	now := time.Now()
+	now := b.clock.Now()
 	expiredWorkers := make([]string, 0)
 
 	// Add grace period to prevent race conditions with late-arriving heartbeats
 	gracePeriod := time.Duration(30 * time.Second) // 30 seconds grace period
 
+	b.mutex.Lock()
+	for key, inflight := range b.inflight {
+		if now.Sub(inflight.Timestamp) > inflightRetention {
+			delete(b.inflight, key)
+		}
+	}
+	b.mutex.Unlock()
+
 	b.mutex.RLock()
 	for workerID, worker := range b.workers {
 		worker.mutex.RLock()
@@ -747,6 +1366,42 @@ func (b *Broker) checkWorkerLiveness() {
 	}
 }
 
+// expireClients forgets the clients not heard from within clientRetention that have no
+// requests left, and with them what they announced and the framing they spoke
+func (b *Broker) expireClients() {
+	now := b.clock.Now()
+	expiredClients := make([]string, 0)
+
+	b.mutex.RLock()
+	for clientID, lastSeen := range b.clients {
+		if now.Sub(lastSeen) > clientRetention {
+			expiredClients = append(expiredClients, clientID)
+		}
+	}
+	b.mutex.RUnlock()
+
+	for _, clientID := range expiredClients {
+		if b.clientInflight(clientID) > 0 {
+			continue
+		}
+
+		b.mutex.Lock()
+		if lastSeen, exists := b.clients[clientID]; exists && now.Sub(lastSeen) > clientRetention {
+			delete(b.clients, clientID)
+			delete(b.clientServices, clientID)
+			delete(b.clientInfo, clientID)
+			if _, isWorker := b.workers[clientID]; !isWorker {
+				delete(b.framings, clientID)
+			}
+		}
+		b.mutex.Unlock()
+
+		b.logger.Debug().
+			Str("client_id", clientID).
+			Msg("Client expired - removing")
+	}
+}
+
 // removeWorker removes a worker from all data structures
 func (b *Broker) removeWorker(workerID string) error {
 	b.mutex.Lock()
@@ -780,6 +1435,13 @@ func (b *Broker) removeWorker(workerID string) error {
 	// Remove from workers map
 	delete(b.workers, workerID)
 
+	// Its unanswered requests can no longer be cancelled
+	for key, inflight := range b.inflight {
+		if inflight.WorkerID == workerID {
+			delete(b.inflight, key)
+		}
+	}
+
 	b.logger.Info().
 		Str("worker_id", workerID).
 		Str("service", worker.Service).
@@ -796,6 +1458,11 @@ func (b *Broker) GetStats() *BrokerStats {
 	stats := *b.stats
 	stats.Services = len(b.services)
 	stats.Workers = len(b.workers)
+	for _, service := range b.services {
+		service.mutex.RLock()
+		stats.Queued += len(service.Requests)
+		service.mutex.RUnlock()
+	}
 	return &stats
 }
 
@@ -816,6 +1483,7 @@ func (b *Broker) GetServices() map[string]*ServiceInfo {
 			Name:        service.Name,
 			Description: service.Description,
 			Workers:     workers,
+			Queued:      len(service.Requests),
 			Status:      "active",
 		}
 		if len(service.Workers) > 0 {
@@ -845,6 +1513,11 @@ func (b *Broker) GetWorkers() map[string]*WorkerInfo {
 			Liveness: worker.Liveness,
 			Requests: worker.Requests,
 		}
+		if worker.Info != nil {
+			workers[identity].Version = worker.Info.Version
+			workers[identity].Capabilities = append([]string(nil), worker.Info.Capabilities...)
+			workers[identity].Schema = worker.Info.Schema
+		}
 		worker.mutex.RUnlock()
 	}
 
@@ -963,17 +1636,47 @@ func (b *Broker) socketReader() {
 			// Send message to router
 			select {
 			case b.messagesCh <- rawMsg:
+				continue
 			case <-b.ctx.Done():
 				return
 			case <-b.shutdownCh:
 				return
 			default:
-				b.logger.Warn().Msg("Message channel full, dropping message")
 			}
+
+			// The router is behind. Worker traffic waits for it, a lost reply or heartbeat
+			// would leave a client waiting or expire a healthy worker, while the socket's
+			// high watermark holds back further messages. Client requests are shed
+			if isWorkerFrame(rawMsg) {
+				select {
+				case b.messagesCh <- rawMsg:
+				case <-b.ctx.Done():
+					return
+				case <-b.shutdownCh:
+					return
+				}
+				continue
+			}
+			b.mutex.Lock()
+			b.stats.Dropped++
+			b.mutex.Unlock()
+			b.logger.Warn().Msg("Message channel full, dropping client message")
 		}
 	}
 }
 
+// isWorkerFrame reports whether a message received on the ROUTER socket comes from a worker
+func isWorkerFrame(rawMsg zmq4.Msg) bool {
+	if len(rawMsg.Frames) < 3 {
+		return false
+	}
+	if string(rawMsg.Frames[2]) == HERMES_WORKER {
+		return true
+	}
+	_, _, err := DecodeWorkerMessage(rawMsg.Frames[2:])
+	return err == nil
+}
+
 // messageRouter routes messages to appropriate event channels
 func (b *Broker) messageRouter() {
 	b.logger.Info().Msg("Starting broker message router")
@@ -1020,31 +1723,59 @@ func (b *Broker) routeMessageToChannels(rawMsg zmq4.Msg) error {
 		Int("parts_count", len(msg)).
 		Msg("Routing message")
 
+	limits := b.getPayloadLimits()
+	if err := checkFrameSizes(msg[2:], limits.MaxFrameSize); err != nil {
+		// Only a worker's reply gets through, as an error reply in its place
+		if msg, err = b.refuseOversizedFrames(sender, msg, err); err != nil {
+			return err
+		}
+	}
+
 	// Try to parse as worker message first
-	var workerMsg WorkerMessage
-	if err := json.Unmarshal(msg[2], &workerMsg); err == nil && workerMsg.Protocol == HERMES_WORKER {
+	if workerMsg, framing, err := DecodeWorkerMessage(msg[2:]); err == nil {
+		b.setPeerFraming(sender, framing)
+		body, err := decompressBody(workerMsg.Body, limits.MaxBodySize)
+		if err != nil {
+			if workerMsg.Command != HERMES_REPLY {
+				b.countRefused(err)
+				return fmt.Errorf("dropped message from worker %s: %w", sender, err)
+			}
+			body = b.refusedReply(sender, b.workerService(sender), workerMsg.Body, err)
+		}
 		event := &WorkerEvent{
 			Type:     workerMsg.Command,
 			WorkerID: sender,
 			Service:  workerMsg.Service,
 			ClientID: workerMsg.ClientID,
-			Body:     workerMsg.Body,
+			Body:     body,
+			Info:     workerMsg.Info,
 		}
-		if len(msg) > 3 {
-			event.ExtraParts = msg[3:]
-		}
-		
+
+		// Worker events are never dropped, a lost reply would leave its client waiting and
+		// its worker marked busy, so the router waits for the worker event handler instead
 		select {
 		case b.workerEventsCh <- event:
-		default:
-			return fmt.Errorf("worker event channel full")
+		case <-b.ctx.Done():
+		case <-b.shutdownCh:
 		}
 		return nil
+	} else if string(msg[2]) == HERMES_WORKER {
+		return fmt.Errorf("malformed worker message from %s: %w", sender, err)
 	}
 
 	// Try to parse as client message
-	var clientMsg ClientMessage
-	if err := json.Unmarshal(msg[2], &clientMsg); err == nil && clientMsg.Protocol == HERMES_CLIENT {
+	if clientMsg, framing, err := DecodeClientMessage(msg[2:]); err == nil {
+		b.setPeerFraming(sender, framing)
+		body, err := decompressBody(clientMsg.Body, limits.MaxBodySize)
+		if err != nil {
+			b.sendToClient(sender, clientMsg.Service, b.refusedReply(sender, clientMsg.Service, clientMsg.Body, err))
+			return fmt.Errorf("refused request from client %s: %w", sender, err)
+		}
+		if isCompressed(clientMsg.Body) && clientMsg.MessageID == "" {
+			// The message ID of an MDP request is read from its body, compressed until now
+			clientMsg.MessageID = replyMessageID(body)
+		}
+		clientMsg.Body = body
 		event := &ClientEvent{
 			Type:      clientMsg.Command,
 			ClientID:  sender,
@@ -1052,18 +1783,83 @@ func (b *Broker) routeMessageToChannels(rawMsg zmq4.Msg) error {
 			MessageID: clientMsg.MessageID,
 			Body:      clientMsg.Body,
 		}
-		
+
 		select {
 		case b.clientEventsCh <- event:
 		default:
-			return fmt.Errorf("client event channel full")
+			// Turn the client away rather than stall the workers' replies behind it
+			b.mutex.Lock()
+			b.stats.Dropped++
+			b.mutex.Unlock()
+			if clientMsg.Service != HERMES_CANCEL_SERVICE {
+				b.sendErrorReply(sender, clientMsg.Service, clientMsg.MessageID, clientMsg.Body, ErrBrokerBusy)
+			}
+			return fmt.Errorf("client event channel full, dropped request from %s", sender)
 		}
 		return nil
+	} else if string(msg[2]) == HERMES_CLIENT {
+		return fmt.Errorf("malformed client message from %s: %w", sender, err)
 	}
 
 	return fmt.Errorf("unknown message format from %s", sender)
 }
 
+// refuseOversizedFrames deals with a message holding a frame over the size limit. A worker's
+// reply is passed on with an error reply for its body, so its client hears why and the
+// worker is free again; a client's request is answered with an error; anything else is dropped
+func (b *Broker) refuseOversizedFrames(sender string, msg [][]byte, reason error) ([][]byte, error) {
+	frames := msg[2:]
+	if command, _, body, ok := workerEnvelope(frames); ok && command == HERMES_REPLY {
+		refused := append([][]byte(nil), msg[:6]...)
+		refused = append(refused, b.refusedReply(sender, b.workerService(sender), body, reason))
+		return refused, nil
+	}
+
+	if len(frames) >= 3 && string(frames[0]) == HERMES_CLIENT && checkFrameSizes(frames[1:2], b.getPayloadLimits().MaxFrameSize) == nil {
+		service := string(frames[1])
+		b.sendToClient(sender, service, b.refusedReply(sender, service, frames[2], reason))
+		return nil, fmt.Errorf("refused request from client %s: %w", sender, reason)
+	}
+	b.countRefused(reason)
+	return nil, fmt.Errorf("dropped message from %s: %w", sender, reason)
+}
+
+// refusedReply returns the error reply standing in for a body the broker will not pass on,
+// correlated with it as far as the start of the body tells
+func (b *Broker) refusedReply(sender, service string, body []byte, reason error) []byte {
+	b.countRefused(reason)
+	b.logger.Warn().
+		Str("sender", sender).
+		Str("service", service).
+		Int("body_size", len(body)).
+		Err(reason).
+		Msg("Message refused")
+
+	messageID, nonce := peekIdentifiers(body)
+	reply, _ := SerializeServiceResponse(CreateServiceResponseWithNonce(messageID, service, nonce, false, nil, reason))
+	return reply
+}
+
+// countRefused counts a message refused for its size, refusedReply counts those it answers
+func (b *Broker) countRefused(reason error) {
+	if !errors.Is(reason, ErrMessageTooLarge) {
+		return
+	}
+	b.mutex.Lock()
+	b.stats.Oversized++
+	b.mutex.Unlock()
+}
+
+// workerService returns the service a connected worker registered for
+func (b *Broker) workerService(workerID string) string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if worker, exists := b.workers[workerID]; exists {
+		return worker.Service
+	}
+	return ""
+}
+
 // workerEventHandler handles worker events from workerEventsCh
 func (b *Broker) workerEventHandler() {
 	b.logger.Info().Msg("Starting broker worker event handler")
@@ -1090,9 +1886,11 @@ func (b *Broker) workerEventHandler() {
 func (b *Broker) processWorkerEvent(event *WorkerEvent) error {
 	switch event.Type {
 	case HERMES_READY:
-		return b.handleWorkerReady(event.WorkerID, event.Service)
+		return b.handleWorkerReady(event.WorkerID, event.Service, event.Info)
 	case HERMES_REPLY:
 		return b.handleWorkerReply(event.WorkerID, event.ClientID, event.Body)
+	case HERMES_PARTIAL:
+		return b.handleWorkerPartial(event.WorkerID, event.ClientID, event.Body)
 	case HERMES_HEARTBEAT:
 		return b.handleWorkerHeartbeat(event.WorkerID)
 	case HERMES_DISCONNECT:
@@ -1146,7 +1944,7 @@ func (b *Broker) heartbeatManager() {
 	b.logger.Info().Msg("Starting broker heartbeat manager")
 	defer b.logger.Info().Msg("Broker heartbeat manager stopped")
 
-	ticker := time.NewTicker(b.heartbeat)
+	ticker := b.clock.NewTicker(b.heartbeat)
 	defer ticker.Stop()
 
 	for {
@@ -1155,9 +1953,32 @@ func (b *Broker) heartbeatManager() {
 			return
 		case <-b.shutdownCh:
 			return
-		case t := <-ticker.C:
-			b.heartbeatCh <- t
+		case t := <-ticker.C():
+			// Nothing has to take the tick, liveness must be checked regardless
+			select {
+			case b.heartbeatCh <- t:
+			default:
+			}
 			b.checkWorkerLiveness()
+			b.expireClients()
+		}
+	}
+}
+
+// queueExpirer periodically expires queued requests, so a service whose workers stopped
+// taking requests answers its clients instead of holding them
+func (b *Broker) queueExpirer() {
+	ticker := b.clock.NewTicker(queueSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-b.shutdownCh:
+			return
+		case <-ticker.C():
+			b.expireQueuedRequests()
 		}
 	}
 }
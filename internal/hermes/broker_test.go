@@ -15,10 +15,29 @@
 package hermes
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/destiny/zmq4/v25"
 )
 
+// newTestBroker starts a broker on the in-memory transport, driven by a clock that only
+// moves when told to, so handlers called directly send on a real socket
+func newTestBroker(tb testing.TB) *Broker {
+	tb.Helper()
+	broker := NewBroker("inproc://broker")
+	broker.SetTransport(NewMemoryTransport())
+	broker.SetClock(NewManualClock(time.Unix(0, 0)))
+	if err := broker.Start(); err != nil {
+		tb.Fatalf("Failed to start broker: %v", err)
+	}
+	tb.Cleanup(func() { broker.Stop() })
+	return broker
+}
+
 // MockRequestHandler implements RequestHandler for testing
 type MockRequestHandler struct {
 	responses map[string][]byte
@@ -62,9 +81,6 @@ func (m *MockRequestHandler) GetCallCount() int {
 }
 
 func TestBrokerBasicOperations(t *testing.T) {
-	// Note: These tests would require actual ZMQ sockets for full integration testing
-	// Here we test the data structures and logic
-
 	t.Run("NewBroker", func(t *testing.T) {
 		broker := NewBroker("tcp://localhost:5555")
 		
@@ -86,7 +102,7 @@ func TestBrokerBasicOperations(t *testing.T) {
 	})
 
 	t.Run("BrokerStats", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		stats := broker.GetStats()
 		if stats == nil {
@@ -104,7 +120,7 @@ func TestBrokerBasicOperations(t *testing.T) {
 	})
 
 	t.Run("ServiceManagement", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		// Initially no services
 		services := broker.GetServices()
@@ -113,7 +129,7 @@ func TestBrokerBasicOperations(t *testing.T) {
 		}
 		
 		// Simulate adding a worker (this would normally happen via handleWorkerReady)
-		err := broker.handleWorkerReady("worker1", "test.service")
+		err := broker.handleWorkerReady("worker1", "test.service", nil)
 		if err != nil {
 			t.Errorf("Failed to handle worker ready: %v", err)
 		}
@@ -154,10 +170,10 @@ func TestBrokerBasicOperations(t *testing.T) {
 	})
 
 	t.Run("WorkerHeartbeat", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		// Add a worker
-		err := broker.handleWorkerReady("worker1", "test.service")
+		err := broker.handleWorkerReady("worker1", "test.service", nil)
 		if err != nil {
 			t.Errorf("Failed to handle worker ready: %v", err)
 		}
@@ -184,10 +200,10 @@ func TestBrokerBasicOperations(t *testing.T) {
 	})
 
 	t.Run("WorkerRemoval", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		// Add a worker
-		err := broker.handleWorkerReady("worker1", "test.service")
+		err := broker.handleWorkerReady("worker1", "test.service", nil)
 		if err != nil {
 			t.Errorf("Failed to handle worker ready: %v", err)
 		}
@@ -224,7 +240,7 @@ func TestMessageRouting(t *testing.T) {
 	// These tests focus on message parsing and routing logic
 	
 	t.Run("ParseWorkerMessage", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		// Create a worker message
 		msg := &WorkerMessage{
@@ -252,12 +268,22 @@ func TestMessageRouting(t *testing.T) {
 	})
 
 	t.Run("ParseClientMessage", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
-		
-		// First register a service
-		err := broker.handleWorkerReady("worker1", "test.service")
+		broker := newTestBroker(t)
+		worker, err := broker.transport.Dial(context.Background(), "inproc://broker", "worker1", "", 0)
 		if err != nil {
-			t.Errorf("Failed to handle worker ready: %v", err)
+			t.Fatalf("Failed to dial broker: %v", err)
+		}
+		defer worker.Close()
+		
+		// First register a service, over the socket so the broker can route to worker1
+		ready := zmq4.NewMsgFrom([]byte(""), []byte(HERMES_WORKER), []byte(HERMES_READY), []byte("test.service"))
+		if err := worker.Send(ready); err != nil {
+			t.Fatalf("Failed to send READY: %v", err)
+		}
+		for start := time.Now(); len(broker.GetWorkers()) == 0; time.Sleep(time.Millisecond) {
+			if time.Since(start) > 2*time.Second {
+				t.Fatal("Worker did not register")
+			}
 		}
 		
 		// Create a client message
@@ -280,12 +306,25 @@ func TestMessageRouting(t *testing.T) {
 			t.Errorf("Failed to route client message: %v", err)
 		}
 		
-		// In a real scenario, this would result in a message being sent to the worker
-		// Here we just verify the message was processed without error
+		// The request reaches worker1 through the in-memory transport
+		received := make(chan zmq4.Msg, 1)
+		go func() {
+			if msg, err := worker.Recv(); err == nil {
+				received <- msg
+			}
+		}()
+		select {
+		case msg := <-received:
+			if !bytes.Contains(msg.Frames[len(msg.Frames)-1], []byte("test request")) {
+				t.Errorf("Expected the request to reach worker1, got %q", msg.Frames)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Worker did not receive the request")
+		}
 	})
 
 	t.Run("InvalidMessage", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		// Invalid JSON
 		invalidJSON := []byte(`{"invalid": json}`)
@@ -299,15 +338,15 @@ func TestMessageRouting(t *testing.T) {
 
 func TestServiceOperations(t *testing.T) {
 	t.Run("ServiceCreation", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		// Register multiple workers for the same service
-		err := broker.handleWorkerReady("worker1", "test.service")
+		err := broker.handleWorkerReady("worker1", "test.service", nil)
 		if err != nil {
 			t.Errorf("Failed to register worker1: %v", err)
 		}
 		
-		err = broker.handleWorkerReady("worker2", "test.service")
+		err = broker.handleWorkerReady("worker2", "test.service", nil)
 		if err != nil {
 			t.Errorf("Failed to register worker2: %v", err)
 		}
@@ -327,15 +366,15 @@ func TestServiceOperations(t *testing.T) {
 	})
 
 	t.Run("MultipleServices", func(t *testing.T) {
-		broker := NewBroker("tcp://localhost:5555")
+		broker := newTestBroker(t)
 		
 		// Register workers for different services
-		err := broker.handleWorkerReady("worker1", "service.a")
+		err := broker.handleWorkerReady("worker1", "service.a", nil)
 		if err != nil {
 			t.Errorf("Failed to register worker1: %v", err)
 		}
 		
-		err = broker.handleWorkerReady("worker2", "service.b")
+		err = broker.handleWorkerReady("worker2", "service.b", nil)
 		if err != nil {
 			t.Errorf("Failed to register worker2: %v", err)
 		}
@@ -356,14 +395,39 @@ func TestServiceOperations(t *testing.T) {
 	})
 }
 
+func TestIsWorkerFrame(t *testing.T) {
+	jsonWorker, _ := EncodeWorkerMessage(&WorkerMessage{Protocol: HERMES_WORKER, Command: HERMES_HEARTBEAT}, FramingJSON)
+	jsonClient, _ := EncodeClientMessage(&ClientMessage{Protocol: HERMES_CLIENT, Command: HERMES_REQ, Service: "echo"}, FramingJSON)
+
+	tests := []struct {
+		name   string
+		frames [][]byte
+		worker bool
+	}{
+		{"mdp worker", append([][]byte{[]byte("w")}, FormatMDPWorkerFrame(HERMES_HEARTBEAT, "", nil)...), true},
+		{"json worker", append([][]byte{[]byte("w")}, jsonWorker...), true},
+		{"mdp client", append([][]byte{[]byte("c")}, FormatMDPClientFrame("echo", []byte("hi"))...), false},
+		{"json client", append([][]byte{[]byte("c")}, jsonClient...), false},
+		{"too short", [][]byte{[]byte("c"), []byte("")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWorkerFrame(zmq4.NewMsgFrom(tt.frames...)); got != tt.worker {
+				t.Errorf("Expected %v, got %v", tt.worker, got)
+			}
+		})
+	}
+}
+
 // Benchmark tests for broker operations
 func BenchmarkWorkerRegistration(b *testing.B) {
-	broker := NewBroker("tcp://localhost:5555")
+	broker := newTestBroker(b)
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		workerID := fmt.Sprintf("worker%d", i)
-		err := broker.handleWorkerReady(workerID, "test.service")
+		err := broker.handleWorkerReady(workerID, "test.service", nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -371,7 +435,7 @@ func BenchmarkWorkerRegistration(b *testing.B) {
 }
 
 func BenchmarkMessageRouting(b *testing.B) {
-	broker := NewBroker("tcp://localhost:5555")
+	broker := newTestBroker(b)
 	
 	// Create a serialized message
 	msg := &WorkerMessage{
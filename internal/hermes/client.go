@@ -17,7 +17,9 @@ package hermes
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +29,31 @@ import (
 	"lucas/internal/logger"
 )
 
+// ServiceError is returned for a request the service answered with success false
+type ServiceError struct {
+	Service string
+	Message string
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("service error: %s", e.Message)
+}
+
+// IsRetryable reports whether err is the broker turning a request away for want of a
+// worker or capacity, so the same request may succeed later
+func IsRetryable(err error) bool {
+	var serviceErr *ServiceError
+	if !errors.As(err, &serviceErr) {
+		return false
+	}
+	switch serviceErr.Message {
+	case "hub worker not available", ErrWorkerBusy.Error(), ErrTooManyInflight.Error(),
+		ErrServiceQueueFull.Error(), ErrRequestExpired.Error(), ErrBrokerBusy.Error():
+		return true
+	}
+	return false
+}
+
 // PendingClientRequest represents a pending client request
 type PendingClientRequest struct {
 	MessageID string
@@ -38,14 +65,32 @@ type PendingClientRequest struct {
 	Timeout   time.Duration
 	Nonce     string    // Add nonce for correlation
 	FireAndForget bool  // If true, don't wait for response
+	Partial   chan []byte // Streamed requests: partial replies, in order
+}
+
+// StreamReply is one reply to a streamed request: partial replies carry progress and are
+// followed by the final reply, or by Err when the request failed
+type StreamReply struct {
+	Body    []byte // ServiceResponse
+	Partial bool
+	Err     error
 }
 
+// streamBuffer is how many partial replies a streamed request buffers for a slow reader.
+// A reader that falls further behind has its stream ended with ErrPartialRepliesLost
+const streamBuffer = 64
+
+// ErrPartialRepliesLost ends a stream whose reader fell too far behind to take every
+// partial reply, so the caller knows the progress it read is incomplete
+var ErrPartialRepliesLost = errors.New("stream reader too slow, partial replies lost")
+
 // ClientStats represents client statistics
 type ClientStats struct {
 	RequestsSent     int       `json:"requests_sent"`
 	ResponsesReceived int       `json:"responses_received"`
 	RequestsFailed   int       `json:"requests_failed"`
 	RequestsTimeout  int       `json:"requests_timeout"`
+	RequestsCancelled int      `json:"requests_cancelled"`
 	LastRequest      time.Time `json:"last_request"`
 	LastResponse     time.Time `json:"last_response"`
 	StartTime        time.Time `json:"start_time"`
@@ -56,11 +101,21 @@ type ClientStats struct {
 type HermesClient struct {
 	broker       string
 	identity     string
-	socket       zmq4.Socket
+	socket       Socket
+	transport    Transport
+	clock        Clock
 	timeout      time.Duration
 	retries      int
+	framing      Framing
 	pending      map[string]*PendingClientRequest  // Keyed by message ID
 	pendingNonces map[string]*PendingClientRequest // Keyed by nonce for optional correlation
+	management    map[string]chan []byte           // Waiting requests to services the broker answers, keyed by service
+	managementMutex sync.Mutex                     // One management request at a time
+	managementToken string                         // Presented with management requests, none when empty
+	capabilities  []string                         // Announced in the client's hello
+	brokerInfo    *PeerInfo                        // The broker's answer to the hello, nil until answered
+	lastHello     time.Time                        // When the hello was last sent, it is repeated every helloInterval
+	payload       PayloadLimits
 	ctx          context.Context
 	cancel       context.CancelFunc
 	logger       zerolog.Logger
@@ -76,6 +131,7 @@ type HermesClient struct {
 	shutdownCh      chan struct{}                    // Shutdown signal
 	errorsCh        chan error                       // Error notifications
 	reconnectCh     chan struct{}                    // Reconnection requests
+	socketCh        chan struct{}                    // Signalled when a new socket is connected
 }
 
 // NewClient creates a new Hermes client with channel-based architecture
@@ -85,10 +141,15 @@ func NewClient(broker, identity string) *HermesClient {
 	return &HermesClient{
 		broker:        broker,
 		identity:      identity,
+		transport:     ZMQTransport{},
+		clock:         systemClock{},
 		timeout:       30 * time.Second, // Default request timeout
 		retries:       3,                // Default retry count
+		framing:       FramingMDP,
 		pending:       make(map[string]*PendingClientRequest),
 		pendingNonces: make(map[string]*PendingClientRequest),
+		management:    make(map[string]chan []byte),
+		payload:       DefaultPayloadLimits(),
 		ctx:           ctx,
 		cancel:        cancel,
 		logger:        logger.New(),
@@ -105,9 +166,42 @@ func NewClient(broker, identity string) *HermesClient {
 		shutdownCh:  make(chan struct{}, 1),               // Single shutdown signal
 		errorsCh:    make(chan error, 50),                 // Buffered error notifications
 		reconnectCh: make(chan struct{}, 1),               // Single reconnection signal
+		socketCh:    make(chan struct{}, 1),               // Single new socket signal
 	}
 }
 
+// SetTransport sets the transport the client dials the broker with, ZeroMQ by default. Call it before Start
+func (c *HermesClient) SetTransport(transport Transport) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.transport = transport
+}
+
+// SetClock sets the clock that paces reconnects, send retries and the sweep of timed out
+// requests, the system clock by default. The time left before a request context's deadline
+// is measured on it, though the context itself still ends in real time. Call it before Start
+func (c *HermesClient) SetClock(clock Clock) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.clock = clock
+	c.stats.StartTime = clock.Now()
+}
+
+// SetPayloadLimits sets the size limits on the requests the client sends and the replies it
+// accepts, and the threshold from which it compresses requests for brokers announcing compression
+func (c *HermesClient) SetPayloadLimits(limits PayloadLimits) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.payload = limits
+}
+
+// getPayloadLimits returns the client's current payload limits
+func (c *HermesClient) getPayloadLimits() PayloadLimits {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.payload
+}
+
 // SetTimeout sets the request timeout
 func (c *HermesClient) SetTimeout(timeout time.Duration) {
 	c.mutex.Lock()
@@ -122,6 +216,14 @@ func (c *HermesClient) SetRetries(retries int) {
 	c.retries = retries
 }
 
+// SetFraming sets how requests to the broker are framed, FramingJSON for brokers that
+// predate multipart MDP framing
+func (c *HermesClient) SetFraming(framing Framing) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.framing = framing
+}
+
 // Start starts the client with channel-based architecture
 func (c *HermesClient) Start() error {
 	c.logger.Info().
@@ -141,6 +243,7 @@ func (c *HermesClient) Start() error {
 	go c.timeoutManager()     // Manage timeouts using timeoutCh
 	go c.errorHandler()       // Handle errors from errorsCh
 	go c.reconnectManager()   // Handle reconnection requests
+	go c.hello()              // Announce the client's version and capabilities
 
 	return nil
 }
@@ -174,11 +277,8 @@ func (c *HermesClient) Stop() error {
 	c.pending = make(map[string]*PendingClientRequest)
 	c.mutex.Unlock()
 
-	if c.socket != nil {
-		if err := c.socket.Close(); err != nil {
-			c.logger.Error().Err(err).Msg("Error closing client socket")
-		}
-		c.socket = nil
+	if err := c.closeSocket(); err != nil {
+		c.logger.Error().Err(err).Msg("Error closing client socket")
 	}
 
 	c.logger.Info().Msg("Hermes client stopped")
@@ -202,20 +302,15 @@ func (c *HermesClient) connect() error {
 				Int("max_retries", maxRetries).
 				Dur("delay", delay).
 				Msg("Retrying broker connection")
-			time.Sleep(delay)
+			if !sleepContext(c.ctx, c.clock, delay) {
+				return fmt.Errorf("client stopped while connecting: %w", c.ctx.Err())
+			}
 		}
 
 		// Create DEALER socket for asynchronous request-response
-		socket := zmq4.NewDealer(c.ctx)
-
-		// Set high watermark option if available
-		if err := socket.SetOption(zmq4.OptionHWM, 1000); err != nil {
-			c.logger.Warn().Err(err).Msg("Failed to set high watermark - continuing without it")
-		}
-
-		// Connect to broker
-		if err := socket.Dial(c.broker); err != nil {
-			socket.Close()
+		// The identity is how the broker addresses this peer
+		socket, err := c.transport.Dial(c.ctx, c.broker, c.identity, "", 0)
+		if err != nil {
 			if attempt == maxRetries-1 {
 				return fmt.Errorf("failed to connect to broker after %d attempts: %w", maxRetries, err)
 			}
@@ -226,7 +321,13 @@ func (c *HermesClient) connect() error {
 			continue
 		}
 
+		c.mutex.Lock()
 		c.socket = socket
+		c.mutex.Unlock()
+		select {
+		case c.socketCh <- struct{}{}:
+		default:
+		}
 		c.logger.Info().
 			Int("attempt", attempt+1).
 			Msg("Connected to Hermes broker")
@@ -236,127 +337,77 @@ func (c *HermesClient) connect() error {
 	return fmt.Errorf("failed to connect to broker after %d attempts", maxRetries)
 }
 
-// Request sends a synchronous request to a service
-func (c *HermesClient) Request(service string, body []byte) ([]byte, error) {
-	return c.RequestWithTimeout(service, body, c.timeout)
-}
+// Request sends a request to a service and waits for its reply until ctx is done. Without a
+// deadline on ctx the client's timeout applies. A request the caller gives up on is withdrawn
+// at the broker, which cancels it at the worker if one is already handling it
+func (c *HermesClient) Request(ctx context.Context, service string, body []byte) ([]byte, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	messageID, body := c.prepareRequest(ctx, body)
 
-// RequestWithTimeout sends a synchronous request with custom timeout
-func (c *HermesClient) RequestWithTimeout(service string, body []byte, timeout time.Duration) ([]byte, error) {
-	messageID := GenerateMessageID()
-	
 	c.logger.Info().
 		Str("service", service).
 		Str("message_id", messageID).
 		Int("body_size", len(body)).
-		Dur("timeout", timeout).
-		Int("pending_requests", len(c.pending)).
+		Int("pending_requests", c.GetPendingCount()).
 		Msg("Sending request to service")
 
-	// Create pending request
-	pending := &PendingClientRequest{
-		MessageID: messageID,
-		Service:   service,
-		Body:      body,
-		Response:  make(chan []byte, 1),
-		Error:     make(chan error, 1),
-		Timestamp: time.Now(),
-		Timeout:   timeout,
-	}
+	pending := c.newPendingRequest(ctx, service, messageID, body)
 
 	// Store pending request
 	c.mutex.Lock()
 	c.pending[messageID] = pending
 	c.mutex.Unlock()
 
-	// Try request with retries
-	var lastError error
-	for attempt := 0; attempt <= c.retries; attempt++ {
-		if attempt > 0 {
-			c.logger.Warn().
-				Str("service", service).
-				Str("message_id", messageID).
-				Int("attempt", attempt).
-				Msg("Retrying request")
-		}
-
-		// Send request
-		if err := c.sendRequest(service, messageID, body); err != nil {
-			lastError = err
-			continue
-		}
-
-		// Wait for response or timeout
-		select {
-		case response := <-pending.Response:
-			// Calculate and store latency
-			latency := time.Since(pending.Timestamp)
-			c.recordLatency(latency)
-			
-			c.logger.Info().
-				Str("service", service).
-				Str("message_id", messageID).
-				Dur("latency", latency).
-				Int("response_size", len(response)).
-				Int("attempt", attempt).
-				Msg("Received successful response")
-			
-			// Clean up pending request
-			c.mutex.Lock()
-			delete(c.pending, messageID)
-			c.stats.ResponsesReceived++
-			c.stats.LastResponse = time.Now()
-			c.mutex.Unlock()
-			
-			return response, nil
-		case err := <-pending.Error:
-			lastError = err
-		case <-time.After(timeout):
-			c.logger.Warn().
-				Str("service", service).
-				Str("message_id", messageID).
-				Dur("timeout", timeout).
-				Msg("Request timeout")
-			lastError = fmt.Errorf("request timeout after %v", timeout)
-			c.mutex.Lock()
-			c.stats.RequestsTimeout++
-			c.mutex.Unlock()
-		case <-c.ctx.Done():
-			lastError = fmt.Errorf("client shutting down")
-		}
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, messageID)
+		c.mutex.Unlock()
+	}()
 
-		// Exponential backoff for retries
-		if attempt < c.retries {
-			backoff := time.Duration(attempt+1) * time.Second
-			time.Sleep(backoff)
-		}
+	if err := c.sendWithRetries(ctx, service, messageID, body); err != nil {
+		c.logger.Error().
+			Str("service", service).
+			Str("message_id", messageID).
+			Err(err).
+			Msg("Request failed")
+		return nil, err
 	}
 
-	// Clean up pending request
-	c.mutex.Lock()
-	delete(c.pending, messageID)
-	c.stats.RequestsFailed++
-	c.mutex.Unlock()
-
-	close(pending.Response)
-	close(pending.Error)
-
-	if lastError == nil {
-		lastError = fmt.Errorf("request failed after %d retries", c.retries)
+	response, err := c.awaitReply(ctx, pending)
+	if err != nil {
+		c.logger.Warn().
+			Str("service", service).
+			Str("message_id", messageID).
+			Err(err).
+			Msg("Request failed")
+		return nil, err
 	}
 
-	c.logger.Error().
+	c.logger.Info().
 		Str("service", service).
 		Str("message_id", messageID).
-		Err(lastError).
-		Msg("Request failed")
+		Dur("latency", c.clock.Now().Sub(pending.Timestamp)).
+		Int("response_size", len(response)).
+		Msg("Received successful response")
 
-	return nil, lastError
+	return response, nil
 }
 
-// RequestAsync sends an asynchronous request to a service
-func (c *HermesClient) RequestAsync(service string, body []byte, callback func([]byte, error)) error {
-	messageID := GenerateMessageID()
+// RequestWithTimeout sends a synchronous request with custom timeout
+func (c *HermesClient) RequestWithTimeout(service string, body []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.Request(ctx, service, body)
+}
+
+// RequestAsync sends an asynchronous request to a service. The callback runs once with the
+// reply or the error, including the request being cancelled through ctx
+func (c *HermesClient) RequestAsync(ctx context.Context, service string, body []byte, callback func([]byte, error)) error {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+
+	messageID, body := c.prepareRequest(ctx, body)
 	
 	c.logger.Debug().
 		Str("service", service).
@@ -364,16 +415,7 @@ func (c *HermesClient) RequestAsync(service string, body []byte, callback func([
 		Int("body_size", len(body)).
 		Msg("Sending async request to service")
 
-	// Create pending request
-	pending := &PendingClientRequest{
-		MessageID: messageID,
-		Service:   service,
-		Body:      body,
-		Response:  make(chan []byte, 1),
-		Error:     make(chan error, 1),
-		Timestamp: time.Now(),
-		Timeout:   c.timeout,
-	}
+	pending := c.newPendingRequest(ctx, service, messageID, body)
 
 	// Store pending request
 	c.mutex.Lock()
@@ -382,6 +424,7 @@ func (c *HermesClient) RequestAsync(service string, body []byte, callback func([
 
 	// Send request
 	if err := c.sendRequest(service, messageID, body); err != nil {
+		cancel()
 		c.mutex.Lock()
 		delete(c.pending, messageID)
 		c.stats.RequestsFailed++
@@ -391,48 +434,112 @@ func (c *HermesClient) RequestAsync(service string, body []byte, callback func([
 
 	// Handle response asynchronously
 	go func() {
+		defer cancel()
+		response, err := c.awaitReply(ctx, pending)
+
+		c.mutex.Lock()
+		delete(c.pending, messageID)
+		c.mutex.Unlock()
+
+		callback(response, err)
+	}()
+
+	return nil
+}
+
+// RequestStream sends a request asking its worker for partial replies and returns the
+// channel they arrive on, in order. The final reply or an error ends the stream and closes
+// the channel; workers that cannot stream just send the final reply. The caller reads until
+// the channel closes or cancels ctx, which withdraws the request like Request does
+func (c *HermesClient) RequestStream(ctx context.Context, service string, body []byte) (<-chan StreamReply, error) {
+	ctx, cancel := c.withDefaultTimeout(ctx)
+
+	messageID, body := c.prepareRequest(ctx, body)
+	body = streamRequest(body)
+
+	c.logger.Debug().
+		Str("service", service).
+		Str("message_id", messageID).
+		Int("body_size", len(body)).
+		Msg("Sending streamed request to service")
+
+	pending := c.newPendingRequest(ctx, service, messageID, body)
+	pending.Partial = make(chan []byte, streamBuffer)
+
+	c.mutex.Lock()
+	c.pending[messageID] = pending
+	c.mutex.Unlock()
+
+	if err := c.sendWithRetries(ctx, service, messageID, body); err != nil {
+		cancel()
+		c.mutex.Lock()
+		delete(c.pending, messageID)
+		c.mutex.Unlock()
+		return nil, err
+	}
+
+	replies := make(chan StreamReply, streamBuffer)
+	go func() {
+		defer close(replies)
+		defer cancel()
 		defer func() {
 			c.mutex.Lock()
 			delete(c.pending, messageID)
 			c.mutex.Unlock()
-			close(pending.Response)
-			close(pending.Error)
 		}()
 
-		select {
-		case response := <-pending.Response:
-			// Calculate and store latency
-			latency := time.Since(pending.Timestamp)
-			c.recordLatency(latency)
-			
-			c.mutex.Lock()
-			c.stats.ResponsesReceived++
-			c.stats.LastResponse = time.Now()
-			c.mutex.Unlock()
-			
-			callback(response, nil)
-		case err := <-pending.Error:
-			c.mutex.Lock()
-			c.stats.RequestsFailed++
-			c.mutex.Unlock()
-			callback(nil, err)
-		case <-time.After(pending.Timeout):
-			c.mutex.Lock()
-			c.stats.RequestsTimeout++
-			c.mutex.Unlock()
-			callback(nil, fmt.Errorf("request timeout after %v", pending.Timeout))
-		case <-c.ctx.Done():
-			callback(nil, fmt.Errorf("client shutting down"))
+		final := make(chan StreamReply, 1)
+		go func() {
+			response, err := c.awaitReply(ctx, pending)
+			final <- StreamReply{Body: response, Err: err}
+		}()
+
+		for {
+			select {
+			case partial := <-pending.Partial:
+				if !deliverStreamReply(ctx, replies, StreamReply{Body: partial, Partial: true}) {
+					<-final
+					return
+				}
+			case reply := <-final:
+				// Partial replies are queued before the final one, so none is overtaken
+				for len(pending.Partial) > 0 {
+					deliverStreamReply(ctx, replies, StreamReply{Body: <-pending.Partial, Partial: true})
+				}
+				deliverStreamReply(ctx, replies, reply)
+				return
+			}
 		}
 	}()
 
-	return nil
+	return replies, nil
+}
+
+// deliverStreamReply hands a reply to the reader of a stream, giving up once ctx is done
+// and the reader has no room for it
+func deliverStreamReply(ctx context.Context, replies chan<- StreamReply, reply StreamReply) bool {
+	select {
+	case replies <- reply:
+		return true
+	default:
+	}
+	select {
+	case replies <- reply:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // RequestFireAndForget sends a request that doesn't wait for a response (fire-and-forget)
-// Uses nonce-based correlation for optional response matching
-func (c *HermesClient) RequestFireAndForget(service string, body []byte, nonce string) error {
-	messageID := GenerateMessageID()
+// Uses nonce-based correlation for optional response matching. A deadline on ctx still
+// reaches the worker, which abandons the request once it passes
+func (c *HermesClient) RequestFireAndForget(ctx context.Context, service string, body []byte, nonce string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("request not sent: %w", err)
+	}
+
+	messageID, body := c.prepareRequest(ctx, body)
 	
 	c.logger.Debug().
 		Str("service", service).
@@ -448,7 +555,7 @@ func (c *HermesClient) RequestFireAndForget(service string, body []byte, nonce s
 		Body:          body,
 		Response:      nil, // No response channel for fire-and-forget
 		Error:         nil, // No error channel for fire-and-forget
-		Timestamp:     time.Now(),
+		Timestamp:     c.clock.Now(),
 		Timeout:       5 * time.Second, // Short timeout for cleanup only
 		Nonce:         nonce,
 		FireAndForget: true,
@@ -472,16 +579,10 @@ func (c *HermesClient) RequestFireAndForget(service string, body []byte, nonce s
 		return err
 	}
 
-	// Update stats - consider fire-and-forget as successful send
-	c.mutex.Lock()
-	c.stats.RequestsSent++
-	c.stats.LastRequest = time.Now()
-	c.mutex.Unlock()
-
 	// Clean up nonce after timeout (to prevent memory leaks)
 	if nonce != "" {
 		go func() {
-			time.Sleep(pending.Timeout)
+			sleepContext(c.ctx, c.clock, pending.Timeout)
 			c.mutex.Lock()
 			delete(c.pendingNonces, nonce)
 			c.mutex.Unlock()
@@ -491,33 +592,394 @@ func (c *HermesClient) RequestFireAndForget(service string, body []byte, nonce s
 	return nil
 }
 
-// sendRequest sends a request to the broker
-func (c *HermesClient) sendRequest(service, messageID string, body []byte) error {
-	if c.socket == nil {
-		return fmt.Errorf("socket not initialized")
+// RequestWithNonce sends a request and waits until ctx is done for the response carrying
+// the same nonce, for services that answer with the nonce of the request body rather than
+// the message ID
+func (c *HermesClient) RequestWithNonce(ctx context.Context, service string, body []byte, nonce string) ([]byte, error) {
+	if nonce == "" {
+		return nil, fmt.Errorf("nonce is required")
 	}
 
-	msg := &ClientMessage{
-		Protocol:  HERMES_CLIENT,
-		Command:   HERMES_REQ,
-		Service:   service,
+	ctx, cancel := c.withDefaultTimeout(ctx)
+	defer cancel()
+
+	messageID, body := c.prepareRequest(ctx, body)
+	pending := c.newPendingRequest(ctx, service, messageID, body)
+	pending.Nonce = nonce
+
+	c.mutex.Lock()
+	c.pendingNonces[nonce] = pending
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pendingNonces, nonce)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.sendRequest(service, messageID, body); err != nil {
+		c.mutex.Lock()
+		c.stats.RequestsFailed++
+		c.mutex.Unlock()
+		return nil, err
+	}
+
+	return c.awaitReply(ctx, pending)
+}
+
+// withDefaultTimeout bounds ctx by the client's timeout unless it has a deadline already
+func (c *HermesClient) withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+
+	c.mutex.RLock()
+	timeout := c.timeout
+	c.mutex.RUnlock()
+	return context.WithTimeout(ctx, timeout)
+}
+
+// newPendingRequest creates the pending entry a reply is delivered to, expiring with ctx
+func (c *HermesClient) newPendingRequest(ctx context.Context, service, messageID string, body []byte) *PendingClientRequest {
+	pending := &PendingClientRequest{
 		MessageID: messageID,
+		Service:   service,
 		Body:      body,
+		Response:  make(chan []byte, 1),
+		Error:     make(chan error, 1),
+		Timestamp: c.clock.Now(),
+		Timeout:   c.timeout,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		pending.Timeout = deadline.Sub(c.clock.Now())
 	}
+	return pending
+}
 
-	msgBytes, err := SerializeMessage(msg)
+// sendWithRetries sends a request, retrying failed sends with backoff while ctx allows
+func (c *HermesClient) sendWithRetries(ctx context.Context, service, messageID string, body []byte) error {
+	var lastError error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			c.logger.Warn().
+				Str("service", service).
+				Str("message_id", messageID).
+				Int("attempt", attempt).
+				Err(lastError).
+				Msg("Retrying request")
+
+			// Linear backoff between attempts
+			select {
+			case <-c.clock.After(time.Duration(attempt) * time.Second):
+			case <-ctx.Done():
+				return fmt.Errorf("request not sent: %w", ctx.Err())
+			case <-c.ctx.Done():
+				return fmt.Errorf("client shutting down")
+			}
+		}
+
+		if lastError = c.sendRequest(service, messageID, body); lastError == nil {
+			return nil
+		}
+		if errors.Is(lastError, ErrMessageTooLarge) {
+			break // No retry makes it smaller
+		}
+	}
+
+	c.mutex.Lock()
+	c.stats.RequestsFailed++
+	c.mutex.Unlock()
+	return lastError
+}
+
+// awaitReply waits for the reply to a sent request until ctx is done, withdrawing the
+// request at the broker when the caller gives up on it
+func (c *HermesClient) awaitReply(ctx context.Context, pending *PendingClientRequest) ([]byte, error) {
+	select {
+	case response, ok := <-pending.Response:
+		if !ok {
+			// Expired by the timeout manager
+			return nil, fmt.Errorf("request timeout after %v", pending.Timeout)
+		}
+		c.recordLatency(c.clock.Now().Sub(pending.Timestamp))
+		c.mutex.Lock()
+		c.stats.ResponsesReceived++
+		c.stats.LastResponse = c.clock.Now()
+		c.mutex.Unlock()
+		return response, nil
+	case err, ok := <-pending.Error:
+		if !ok {
+			return nil, fmt.Errorf("request timeout after %v", pending.Timeout)
+		}
+		c.mutex.Lock()
+		c.stats.RequestsFailed++
+		c.mutex.Unlock()
+		return nil, err
+	case <-ctx.Done():
+		c.sendCancel(pending.MessageID)
+		c.mutex.Lock()
+		if ctx.Err() == context.DeadlineExceeded {
+			c.stats.RequestsTimeout++
+		} else {
+			c.stats.RequestsCancelled++
+		}
+		c.mutex.Unlock()
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("request timeout after %v: %w", pending.Timeout.Round(time.Millisecond), ctx.Err())
+		}
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	case <-c.ctx.Done():
+		return nil, fmt.Errorf("client shutting down")
+	}
+}
+
+// sendCancel asks the broker to withdraw a request, best effort: a request already answered
+// is unaffected and the cancel itself is not answered
+func (c *HermesClient) sendCancel(messageID string) {
+	if err := c.sendMessage(HERMES_CANCEL_SERVICE, messageID, []byte(messageID)); err != nil {
+		c.logger.Debug().
+			Str("message_id", messageID).
+			Err(err).
+			Msg("Failed to send cancel")
+		return
+	}
+
+	c.logger.Debug().
+		Str("message_id", messageID).
+		Msg("Request cancelled")
+}
+
+// prepareRequest returns the message ID a request is known by and the body to send. For
+// service requests the ID is the body's own message ID, assigned when missing, and the
+// deadline of ctx is written to the body's timeout so the worker can abandon stale work.
+// Other bodies are sent as they are under a generated ID
+func (c *HermesClient) prepareRequest(ctx context.Context, body []byte) (string, []byte) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil || fields["service"] == nil {
+		return GenerateMessageID(), body
+	}
+
+	changed := false
+	var messageID string
+	json.Unmarshal(fields["message_id"], &messageID)
+	if messageID == "" {
+		messageID = GenerateMessageID()
+		fields["message_id"], _ = json.Marshal(messageID)
+		changed = true
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		// Timeouts are in whole seconds, rounded up so the worker never gives up early
+		seconds := int(math.Ceil(deadline.Sub(c.clock.Now()).Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		var timeout int
+		json.Unmarshal(fields["timeout"], &timeout)
+		if timeout <= 0 || timeout > seconds {
+			fields["timeout"], _ = json.Marshal(seconds)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return messageID, body
+	}
+	prepared, err := json.Marshal(fields)
 	if err != nil {
-		return fmt.Errorf("failed to serialize client message: %w", err)
+		return messageID, body
 	}
+	return messageID, prepared
+}
 
-	err = c.socket.Send(zmq4.NewMsgFrom([]byte(""), msgBytes))
+// streamRequest marks a service request as taking partial replies, other bodies are sent
+// as they are
+func streamRequest(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil || fields["service"] == nil {
+		return body
+	}
+	fields["stream"] = json.RawMessage("true")
+	streamed, err := json.Marshal(fields)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return body
+	}
+	return streamed
+}
+
+// SetCapabilities sets the capabilities the client announces in its hello
+func (c *HermesClient) SetCapabilities(capabilities ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.capabilities = append([]string(nil), capabilities...)
+}
+
+// BrokerInfo returns the version and capabilities the broker answered the client's hello
+// with, nil until it answered
+func (c *HermesClient) BrokerInfo() *PeerInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.brokerInfo
+}
+
+// Hello announces the client's version and capabilities to the broker and returns the
+// broker's. A broker that predates negotiation answers with an error and is taken to be
+// a legacy broker
+func (c *HermesClient) Hello(timeout time.Duration) (*PeerInfo, error) {
+	c.mutex.RLock()
+	announce := &PeerInfo{
+		Version:      HERMES_PROTOCOL_VERSION,
+		Capabilities: append([]string{CapabilityCancel, CapabilityCompression}, c.capabilities...),
+	}
+	c.mutex.RUnlock()
+
+	body, err := json.Marshal(announce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hello: %w", err)
+	}
+	reply, err := c.brokerRequest(HERMES_HELLO_SERVICE, body, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PeerInfo{}
+	if json.Unmarshal(reply, info) != nil || info.Version == 0 {
+		info = legacyPeerInfo()
+	}
+
+	c.mutex.Lock()
+	c.brokerInfo = info
+	c.mutex.Unlock()
+	return info, nil
+}
+
+// hello sends the client's hello in the background, only MDP framing can match its reply
+func (c *HermesClient) hello() {
+	c.mutex.RLock()
+	framing := c.framing
+	c.mutex.RUnlock()
+	if framing != FramingMDP {
+		return
+	}
+
+	c.mutex.Lock()
+	c.lastHello = c.clock.Now()
+	c.mutex.Unlock()
+
+	info, err := c.Hello(5 * time.Second)
+	if err != nil {
+		c.logger.Debug().Err(err).Msg("Broker did not answer hello")
+		return
+	}
+	c.logger.Debug().
+		Int("version", info.Version).
+		Strs("capabilities", info.Capabilities).
+		Msg("Broker answered hello")
+}
+
+// helloDue reports whether the client should repeat its hello, so the broker keeps what it
+// announced while the client sends nothing else
+func (c *HermesClient) helloDue() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return !c.lastHello.IsZero() && c.clock.Now().Sub(c.lastHello) >= helloInterval
+}
+
+// SetManagementToken sets the token presented with management requests, such as an API key
+// the broker checks before answering
+func (c *HermesClient) SetManagementToken(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.managementToken = token
+}
+
+// Management sends a request to one of the broker's mmi.* services and returns the reply,
+// a status code like MMI_OK or a JSON document depending on the service
+func (c *HermesClient) Management(service string, body []byte, timeout time.Duration) ([]byte, error) {
+	if !IsManagementService(service) {
+		return nil, fmt.Errorf("not a management service: %s", service)
+	}
+
+	c.mutex.RLock()
+	token := c.managementToken
+	c.mutex.RUnlock()
+	if token != "" {
+		var err error
+		body, err = json.Marshal(ManagementRequest{Argument: string(body), Token: token})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode management request: %w", err)
+		}
+	}
+	return c.brokerRequest(service, body, timeout)
+}
+
+// brokerRequest sends a request to a service the broker answers itself and waits for the reply
+func (c *HermesClient) brokerRequest(service string, body []byte, timeout time.Duration) ([]byte, error) {
+	c.mutex.RLock()
+	framing := c.framing
+	c.mutex.RUnlock()
+	if framing != FramingMDP {
+		return nil, fmt.Errorf("%s requests need MDP framing", service)
+	}
+
+	// Replies are matched by service, so only one request may be outstanding
+	c.managementMutex.Lock()
+	defer c.managementMutex.Unlock()
+
+	reply := make(chan []byte, 1)
+	c.mutex.Lock()
+	c.management[service] = reply
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.management, service)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.sendRequest(service, "", body); err != nil {
+		return nil, err
+	}
+
+	select {
+	case response := <-reply:
+		return response, nil
+	case <-c.clock.After(timeout):
+		return nil, fmt.Errorf("%s request timeout after %v", service, timeout)
+	case <-c.ctx.Done():
+		return nil, fmt.Errorf("client shutting down")
+	}
+}
+
+// handleManagementReply hands a management reply to the request waiting for it
+func (c *HermesClient) handleManagementReply(service string, response []byte) error {
+	c.mutex.RLock()
+	reply, exists := c.management[service]
+	c.mutex.RUnlock()
+
+	if !exists {
+		c.logger.Warn().
+			Str("service", service).
+			Msg("Received management reply without a waiting request")
+		return nil
+	}
+
+	select {
+	case reply <- response:
+	default:
+	}
+	return nil
+}
+
+// sendRequest sends a request to the broker
+func (c *HermesClient) sendRequest(service, messageID string, body []byte) error {
+	if err := c.sendMessage(service, messageID, body); err != nil {
+		return err
 	}
 
 	c.mutex.Lock()
 	c.stats.RequestsSent++
-	c.stats.LastRequest = time.Now()
+	c.stats.LastRequest = c.clock.Now()
 	c.mutex.Unlock()
 
 	c.logger.Debug().
@@ -529,6 +991,46 @@ func (c *HermesClient) sendRequest(service, messageID string, body []byte) error
 	return nil
 }
 
+// sendMessage frames a message for a service and sends it to the broker
+func (c *HermesClient) sendMessage(service, messageID string, body []byte) error {
+	socket, err := c.connectedSocket()
+	if err != nil {
+		return err
+	}
+
+	c.mutex.RLock()
+	framing := c.framing
+	limits := c.payload
+	compress := c.brokerInfo.HasCapability(CapabilityCompression)
+	c.mutex.RUnlock()
+
+	if limits.MaxBodySize > 0 && len(body) > limits.MaxBodySize {
+		return fmt.Errorf("request of %d bytes exceeds %d: %w", len(body), limits.MaxBodySize, ErrMessageTooLarge)
+	}
+	if compress {
+		body = compressBody(body, limits.CompressThreshold)
+	}
+
+	msg := &ClientMessage{
+		Protocol:  HERMES_CLIENT,
+		Command:   HERMES_REQ,
+		Service:   service,
+		MessageID: messageID,
+		Body:      body,
+	}
+
+	frames, err := EncodeClientMessage(msg, framing)
+	if err != nil {
+		return fmt.Errorf("failed to serialize client message: %w", err)
+	}
+
+	err = socket.Send(zmq4.NewMsgFrom(frames...))
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	return nil
+}
+
 
 // handleResponse handles a response from the broker
 func (c *HermesClient) handleResponse(responseBytes []byte) error {
@@ -574,7 +1076,7 @@ func (c *HermesClient) handleServiceResponse(resp *ServiceResponse) error {
 				c.mutex.Lock()
 				delete(c.pendingNonces, resp.Nonce)
 				c.stats.ResponsesReceived++
-				c.stats.LastResponse = time.Now()
+				c.stats.LastResponse = c.clock.Now()
 				c.mutex.Unlock()
 				
 				c.logger.Debug().
@@ -611,6 +1113,10 @@ func (c *HermesClient) handleServiceResponse(resp *ServiceResponse) error {
 		Bool("success", resp.Success).
 		Msg("Handling service response")
 
+	if resp.Partial {
+		return c.handlePartialResponse(pending, resp)
+	}
+
 	// Convert response to bytes
 	respBytes, err := json.Marshal(resp)
 	if err != nil {
@@ -629,7 +1135,7 @@ func (c *HermesClient) handleServiceResponse(resp *ServiceResponse) error {
 		}
 	} else {
 		select {
-		case pending.Error <- fmt.Errorf("service error: %s", resp.Error):
+		case pending.Error <- &ServiceError{Service: resp.Service, Message: resp.Error}:
 		default:
 		}
 	}
@@ -637,10 +1143,50 @@ func (c *HermesClient) handleServiceResponse(resp *ServiceResponse) error {
 	return nil
 }
 
+// handlePartialResponse hands a partial reply to the streamed request it belongs to
+func (c *HermesClient) handlePartialResponse(pending *PendingClientRequest, resp *ServiceResponse) error {
+	if pending.Partial == nil {
+		c.logger.Debug().
+			Str("message_id", resp.MessageID).
+			Msg("Received partial reply for a request that is not streamed - ignoring")
+		return nil
+	}
+
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal partial reply: %w", err)
+	}
+
+	select {
+	case pending.Partial <- respBytes:
+		return nil
+	default:
+	}
+
+	// Blocking here would stall replies to every other request, so the stream ends instead
+	// and the worker is told to stop
+	c.logger.Warn().
+		Str("message_id", resp.MessageID).
+		Int("sequence", resp.Sequence).
+		Msg("Stream reader too slow - ending stream")
+
+	c.mutex.Lock()
+	if c.pending[resp.MessageID] == pending {
+		delete(c.pending, resp.MessageID)
+	}
+	c.mutex.Unlock()
+
+	select {
+	case pending.Error <- ErrPartialRepliesLost:
+	default:
+	}
+	c.sendCancel(resp.MessageID)
+	return nil
+}
 
 // cleanupTimeoutRequests removes requests that have exceeded their timeout
 func (c *HermesClient) cleanupTimeoutRequests() {
-	now := time.Now()
+	now := c.clock.Now()
 	expiredRequests := make([]string, 0)
 
 	c.mutex.RLock()
@@ -706,7 +1252,35 @@ func (c *HermesClient) GetStats() *ClientStats {
 
 // IsConnected returns whether the client is connected
 func (c *HermesClient) IsConnected() bool {
-	return c.socket != nil
+	return c.getSocket() != nil
+}
+
+// getSocket returns the socket to the broker, nil while disconnected
+func (c *HermesClient) getSocket() Socket {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.socket
+}
+
+// connectedSocket returns the socket to send on, ErrNotConnected while there is none
+func (c *HermesClient) connectedSocket() (Socket, error) {
+	if socket := c.getSocket(); socket != nil {
+		return socket, nil
+	}
+	return nil, ErrNotConnected
+}
+
+// closeSocket closes the socket to the broker
+func (c *HermesClient) closeSocket() error {
+	c.mutex.Lock()
+	socket := c.socket
+	c.socket = nil
+	c.mutex.Unlock()
+
+	if socket == nil {
+		return nil
+	}
+	return socket.Close()
 }
 
 // GetPendingCount returns the number of pending requests
@@ -749,18 +1323,17 @@ func (c *HermesClient) attemptReconnection() {
 	c.logger.Info().Msg("Attempting client reconnection to broker")
 	
 	// Close existing socket
-	if c.socket != nil {
-		c.socket.Close()
-		c.socket = nil
-	}
+	c.closeSocket()
 	
 	// Try to reconnect
 	if err := c.connect(); err != nil {
 		c.logger.Error().Err(err).Msg("Failed to reconnect client to broker")
 		// Sleep before trying again to avoid busy reconnection loop
-		time.Sleep(5 * time.Second)
+		sleepContext(c.ctx, c.clock, 5*time.Second)
 	} else {
 		c.logger.Info().Msg("Client successfully reconnected to broker")
+		// The broker may be another one, or restarted, so announce the client again
+		go c.hello()
 	}
 }
 
@@ -778,13 +1351,19 @@ func (c *HermesClient) socketReader() {
 		case <-c.shutdownCh:
 			return
 		default:
-			if c.socket == nil {
-				time.Sleep(100 * time.Millisecond)
+			socket := c.getSocket()
+			if socket == nil {
+				// Wait for the reconnect to hand over a socket, polling in case it is missed
+				select {
+				case <-c.socketCh:
+				case <-c.clock.After(100 * time.Millisecond):
+				case <-c.ctx.Done():
+				}
 				continue
 			}
 
 			// Receive message from broker (non-blocking)
-			rawMsg, err := c.socket.Recv()
+			rawMsg, err := socket.Recv()
 			if err != nil {
 				if c.isTemporaryError(err) {
 					time.Sleep(10 * time.Millisecond)
@@ -852,16 +1431,65 @@ func (c *HermesClient) processMessage(rawMsg zmq4.Msg) error {
 	}
 
 	empty := msg[0] // Should be empty frame
-	response := msg[1] // Response body
 
 	if len(empty) != 0 {
 		return fmt.Errorf("received message without empty delimiter")
 	}
 
+	service, response, err := DecodeClientReply(msg[1:])
+	if err != nil {
+		return err
+	}
+
+	limits := c.getPayloadLimits()
+	if err := checkFrameSizes(msg[1:], limits.MaxFrameSize); err != nil {
+		return c.refuseReply(service, response, err)
+	}
+	body, err := decompressBody(response, limits.MaxBodySize)
+	if err != nil {
+		return c.refuseReply(service, response, err)
+	}
+	response = body
+
+	// Management and hello replies carry no message ID, they are matched by service
+	if IsManagementService(service) || service == HERMES_HELLO_SERVICE {
+		return c.handleManagementReply(service, response)
+	}
+
 	// Handle response
 	return c.handleResponse(response)
 }
 
+// refuseReply fails the request waiting for a reply the client will not take, as far as the
+// start of the reply tells which one that is
+func (c *HermesClient) refuseReply(service string, reply []byte, reason error) error {
+	messageID, nonce := peekIdentifiers(reply)
+
+	c.mutex.RLock()
+	pending, exists := c.pending[messageID]
+	if !exists && nonce != "" {
+		pending, exists = c.pendingNonces[nonce]
+	}
+	c.mutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("dropped reply from %s: %w", service, reason)
+	}
+
+	c.logger.Warn().
+		Str("service", service).
+		Str("message_id", messageID).
+		Int("reply_size", len(reply)).
+		Err(reason).
+		Msg("Reply refused")
+
+	select {
+	case pending.Error <- fmt.Errorf("reply from %s refused: %w", service, reason):
+	default:
+	}
+	return nil
+}
+
 // requestHandler handles outgoing requests from requestCh
 func (c *HermesClient) requestHandler() {
 	c.logger.Info().Msg("Starting client request handler")
@@ -897,12 +1525,12 @@ func (c *HermesClient) responseHandler() {
 			return
 		case resp := <-c.responseCh:
 			// Calculate and store latency
-			latency := time.Since(resp.Timestamp)
+			latency := c.clock.Now().Sub(resp.Timestamp)
 			c.recordLatency(latency)
 			
 			c.mutex.Lock()
 			c.stats.ResponsesReceived++
-			c.stats.LastResponse = time.Now()
+			c.stats.LastResponse = c.clock.Now()
 			c.mutex.Unlock()
 		}
 	}
@@ -913,7 +1541,7 @@ func (c *HermesClient) timeoutManager() {
 	c.logger.Info().Msg("Starting client timeout manager")
 	defer c.logger.Info().Msg("Client timeout manager stopped")
 
-	ticker := time.NewTicker(5 * time.Second) // Check every 5 seconds
+	ticker := c.clock.NewTicker(5 * time.Second) // Check every 5 seconds
 	defer ticker.Stop()
 
 	for {
@@ -922,8 +1550,11 @@ func (c *HermesClient) timeoutManager() {
 			return
 		case <-c.shutdownCh:
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			c.cleanupTimeoutRequests()
+			if c.helloDue() {
+				go c.hello()
+			}
 		case messageID := <-c.timeoutCh:
 			c.handleTimeout(messageID)
 		}
@@ -0,0 +1,210 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hermes
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock paces the heartbeats, liveness checks, reconnect backoff and timeout sweeps of
+// Hermes peers. Request contexts keep their own deadlines
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// systemClock is the wall clock Hermes peers use unless given another
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time   { return t.ticker.C }
+func (t systemTicker) Reset(d time.Duration) { t.ticker.Reset(d) }
+func (t systemTicker) Stop()                 { t.ticker.Stop() }
+
+// sleepContext waits for d on clock, returning early with false when ctx ends
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-clock.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ManualClock is a Clock that only moves when told to, so tests decide when heartbeats
+// are due and requests expire. Like time.Ticker, a ticker that is not read in time
+// drops the ticks it missed
+type ManualClock struct {
+	mutex   sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*manualTimer
+}
+
+// manualTimer is a pending After or an active ticker of a ManualClock
+type manualTimer struct {
+	clock  *ManualClock
+	at     time.Time
+	period time.Duration // Zero for After
+	ch     chan time.Time
+}
+
+// NewManualClock creates a clock standing at start
+func NewManualClock(start time.Time) *ManualClock {
+	c := &ManualClock{now: start}
+	c.changed = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Now returns the clock's time
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// After returns a channel receiving the time once the clock has advanced by d
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timer := &manualTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.ch <- c.now
+		return timer.ch
+	}
+	c.add(timer)
+	return timer.ch
+}
+
+// NewTicker returns a ticker firing each time the clock has advanced by another d
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("hermes: non-positive interval for ManualClock.NewTicker")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timer := &manualTimer{clock: c, at: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.add(timer)
+	return timer
+}
+
+// Advance moves the clock forward by d, firing what falls due in order
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].at.After(end) {
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = timer.at
+
+		select {
+		case timer.ch <- c.now:
+		default:
+		}
+		if timer.period > 0 {
+			timer.at = timer.at.Add(timer.period)
+			c.insert(timer)
+		}
+	}
+	c.now = end
+}
+
+// Waiters returns how many Afters and tickers are waiting on the clock
+func (c *ManualClock) Waiters() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil waits until at least n Afters and tickers are waiting on the clock, so a
+// test advances it only once the goroutines it means to drive are listening
+func (c *ManualClock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// add registers a timer, the caller holds the mutex
+func (c *ManualClock) add(timer *manualTimer) {
+	c.insert(timer)
+	c.changed.Broadcast()
+}
+
+// insert keeps the timers ordered by when they fire, the caller holds the mutex
+func (c *ManualClock) insert(timer *manualTimer) {
+	i := sort.Search(len(c.timers), func(i int) bool { return c.timers[i].at.After(timer.at) })
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = timer
+}
+
+// remove unregisters a timer, the caller holds the mutex
+func (c *ManualClock) remove(timer *manualTimer) {
+	for i, t := range c.timers {
+		if t == timer {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Reset makes the ticker fire d after the clock's current time, then every d
+func (t *manualTimer) Reset(d time.Duration) {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	t.clock.remove(t)
+	t.period = d
+	t.at = t.clock.now.Add(d)
+	t.clock.add(t)
+}
+
+// Stop stops the ticker, it fires no more
+func (t *manualTimer) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	t.clock.remove(t)
+}
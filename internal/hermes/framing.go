@@ -0,0 +1,222 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hermes
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Framing selects how Hermes messages are laid out in ZMQ frames
+type Framing string
+
+const (
+	// FramingMDP sends RFC 7/MDP 0.1 multipart frames, which any MDP client or worker understands
+	FramingMDP Framing = "mdp"
+	// FramingJSON sends each message as a single JSON-encoded frame, as Hermes did before
+	// multipart framing. Kept for peers that have not migrated yet
+	FramingJSON Framing = "json"
+)
+
+// ParseFraming parses a framing name, the empty name selects FramingMDP
+func ParseFraming(name string) (Framing, error) {
+	switch Framing(name) {
+	case "", FramingMDP:
+		return FramingMDP, nil
+	case FramingJSON:
+		return FramingJSON, nil
+	default:
+		return "", fmt.Errorf("unknown framing: %s", name)
+	}
+}
+
+// EncodeWorkerMessage lays out a worker message in frames, starting with the empty delimiter
+func EncodeWorkerMessage(msg *WorkerMessage, framing Framing) ([][]byte, error) {
+	if framing == FramingJSON {
+		msgBytes, err := SerializeMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize worker message: %w", err)
+		}
+		return [][]byte{[]byte(""), msgBytes}, nil
+	}
+
+	var info []byte
+	if msg.Info != nil {
+		var err error
+		if info, err = json.Marshal(msg.Info); err != nil {
+			return nil, fmt.Errorf("failed to serialize peer info: %w", err)
+		}
+	}
+
+	switch msg.Command {
+	case HERMES_READY:
+		return FormatMDPWorkerFrame(msg.Command, msg.Service, info), nil
+	case HERMES_REQUEST, HERMES_REPLY, HERMES_PARTIAL, HERMES_CANCEL:
+		return FormatMDPWorkerFrame(msg.Command, msg.ClientID, msg.Body), nil
+	case HERMES_HEARTBEAT:
+		return FormatMDPWorkerFrame(msg.Command, "", info), nil
+	case HERMES_DISCONNECT:
+		return FormatMDPWorkerFrame(msg.Command, "", nil), nil
+	default:
+		return nil, fmt.Errorf("unknown worker command: %q", msg.Command)
+	}
+}
+
+// DecodeWorkerMessage parses the frames following the empty delimiter of a worker message,
+// reporting which framing the peer used
+func DecodeWorkerMessage(frames [][]byte) (*WorkerMessage, Framing, error) {
+	if len(frames) == 0 {
+		return nil, "", fmt.Errorf("empty message parts")
+	}
+
+	if string(frames[0]) != HERMES_WORKER {
+		var msg WorkerMessage
+		if err := json.Unmarshal(frames[0], &msg); err != nil {
+			return nil, "", fmt.Errorf("failed to parse worker message: %w", err)
+		}
+		if msg.Protocol != HERMES_WORKER {
+			return nil, "", fmt.Errorf("invalid protocol: %s", msg.Protocol)
+		}
+		// Large bodies may follow the JSON envelope in their own frame
+		if len(frames) > 1 && len(frames[1]) > 0 {
+			msg.Body = frames[1]
+		}
+		return &msg, FramingJSON, nil
+	}
+
+	if len(frames) < 2 {
+		return nil, "", fmt.Errorf("worker message without command")
+	}
+	msg := &WorkerMessage{
+		Protocol: HERMES_WORKER,
+		Command:  string(frames[1]),
+	}
+
+	switch msg.Command {
+	case HERMES_READY:
+		if len(frames) < 3 {
+			return nil, "", fmt.Errorf("READY without service name")
+		}
+		msg.Service = string(frames[2])
+		// Brokers that predate negotiation ignore the extra frame, so it cannot break them
+		if len(frames) > 3 && len(frames[3]) > 0 {
+			var info PeerInfo
+			if err := json.Unmarshal(frames[3], &info); err != nil {
+				return nil, "", fmt.Errorf("READY with malformed peer info: %w", err)
+			}
+			msg.Info = &info
+		}
+	case HERMES_REQUEST, HERMES_REPLY, HERMES_PARTIAL, HERMES_CANCEL:
+		if len(frames) < 4 || len(frames[3]) != 0 {
+			return nil, "", fmt.Errorf("worker message without client envelope")
+		}
+		msg.ClientID = string(frames[2])
+		if len(frames) > 4 {
+			msg.Body = frames[4]
+		}
+	case HERMES_HEARTBEAT:
+		// Only brokers answering a worker that announced compression add their info
+		if len(frames) > 2 && len(frames[2]) > 0 {
+			var info PeerInfo
+			if err := json.Unmarshal(frames[2], &info); err != nil {
+				return nil, "", fmt.Errorf("HEARTBEAT with malformed peer info: %w", err)
+			}
+			msg.Info = &info
+		}
+	case HERMES_DISCONNECT:
+	default:
+		return nil, "", fmt.Errorf("unknown worker command: %q", msg.Command)
+	}
+
+	return msg, FramingMDP, nil
+}
+
+// EncodeClientMessage lays out a client request in frames, starting with the empty delimiter
+func EncodeClientMessage(msg *ClientMessage, framing Framing) ([][]byte, error) {
+	if framing == FramingJSON {
+		msgBytes, err := SerializeMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize client message: %w", err)
+		}
+		return [][]byte{[]byte(""), msgBytes}, nil
+	}
+
+	return FormatMDPClientFrame(msg.Service, msg.Body), nil
+}
+
+// DecodeClientMessage parses the frames following the empty delimiter of a client request,
+// reporting which framing the peer used. MDP requests carry no message ID of their own,
+// it is taken from the body when the body is a service request
+func DecodeClientMessage(frames [][]byte) (*ClientMessage, Framing, error) {
+	if len(frames) == 0 {
+		return nil, "", fmt.Errorf("empty message parts")
+	}
+
+	if string(frames[0]) != HERMES_CLIENT {
+		var msg ClientMessage
+		if err := json.Unmarshal(frames[0], &msg); err != nil {
+			return nil, "", fmt.Errorf("failed to parse client message: %w", err)
+		}
+		if msg.Protocol != HERMES_CLIENT {
+			return nil, "", fmt.Errorf("invalid protocol: %s", msg.Protocol)
+		}
+		return &msg, FramingJSON, nil
+	}
+
+	if len(frames) < 2 {
+		return nil, "", fmt.Errorf("client request without service name")
+	}
+	msg := &ClientMessage{
+		Protocol: HERMES_CLIENT,
+		Command:  HERMES_REQ,
+		Service:  string(frames[1]),
+	}
+	if len(frames) > 2 {
+		msg.Body = frames[2]
+	}
+
+	var request struct {
+		MessageID string `json:"message_id"`
+	}
+	if json.Unmarshal(msg.Body, &request) == nil {
+		msg.MessageID = request.MessageID
+	}
+
+	return msg, FramingMDP, nil
+}
+
+// EncodeClientReply lays out a reply to a client in frames, starting with the empty delimiter
+func EncodeClientReply(service string, body []byte, framing Framing) [][]byte {
+	if framing == FramingJSON {
+		return [][]byte{[]byte(""), body}
+	}
+	return FormatMDPClientFrame(service, body)
+}
+
+// DecodeClientReply returns the service and body of a reply from the frames following the
+// empty delimiter. Replies in JSON framing do not name their service
+func DecodeClientReply(frames [][]byte) (string, []byte, error) {
+	if len(frames) == 0 {
+		return "", nil, fmt.Errorf("empty message parts")
+	}
+
+	if string(frames[0]) != HERMES_CLIENT {
+		return "", frames[0], nil
+	}
+	if len(frames) < 3 {
+		return "", nil, fmt.Errorf("client reply without body")
+	}
+	return string(frames[1]), frames[2], nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hermes
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// helloInterval is how often a client repeats its hello, well within the broker's
+// clientRetention
+const helloInterval = 10 * time.Minute
+
+// brokerCapabilities are the capabilities the broker announces to clients in its hello reply
+var brokerCapabilities = []string{CapabilityCancel, CapabilityCompression}
+
+// BrokerInfo returns the PeerInfo the broker answers hellos with
+func (b *Broker) BrokerInfo() *PeerInfo {
+	return &PeerInfo{
+		Version:      HERMES_PROTOCOL_VERSION,
+		Capabilities: append([]string(nil), brokerCapabilities...),
+	}
+}
+
+// handleHello records what a client announced and answers with the broker's own PeerInfo.
+// A hello that does not parse is recorded as a legacy client, it still gets the answer
+func (b *Broker) handleHello(clientID string, body []byte) error {
+	info := &PeerInfo{}
+	if err := json.Unmarshal(body, info); err != nil || info.Version == 0 {
+		info = legacyPeerInfo()
+	}
+
+	b.mutex.Lock()
+	b.clientInfo[clientID] = info
+	b.mutex.Unlock()
+
+	b.logger.Debug().
+		Str("client_id", clientID).
+		Int("version", info.Version).
+		Strs("capabilities", info.Capabilities).
+		Msg("Client hello")
+
+	reply, err := json.Marshal(b.BrokerInfo())
+	if err != nil {
+		return err
+	}
+	return b.sendToClient(clientID, HERMES_HELLO_SERVICE, reply)
+}
+
+// GetClientInfo returns what a client announced in its hello, false for clients that sent
+// none, which are taken to be legacy clients
+func (b *Broker) GetClientInfo(clientID string) (*PeerInfo, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	info, exists := b.clientInfo[clientID]
+	if !exists {
+		return legacyPeerInfo(), false
+	}
+	copied := *info
+	copied.Capabilities = append([]string(nil), info.Capabilities...)
+	return &copied, true
+}
+
+// clientHasCapability reports whether a client announced a capability in its hello
+func (b *Broker) clientHasCapability(clientID, capability string) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.clientInfo[clientID].HasCapability(capability)
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/destiny/zmq4/v25"
+)
+
+// MemoryTransport connects Hermes peers in the same process, for tests that need whole
+// broker, worker and client conversations without real sockets. It behaves like the
+// ZeroMQ transport where Hermes relies on it: dialing an endpoint nobody listens on
+// fails, a dialed socket follows its endpoint to a broker that listens on it again, and
+// messages to a peer that is gone are dropped
+type MemoryTransport struct {
+	mutex   sync.Mutex
+	routers map[string]*memoryRouter
+}
+
+// NewMemoryTransport creates an in-process transport with no endpoints bound
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{
+		routers: make(map[string]*memoryRouter),
+	}
+}
+
+// Listen binds a ROUTER socket to endpoint, which must not be bound already
+func (t *MemoryTransport) Listen(ctx context.Context, endpoint string) (Socket, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, bound := t.routers[endpoint]; bound {
+		return nil, fmt.Errorf("memory transport: address already in use: %s", endpoint)
+	}
+	router := &memoryRouter{
+		transport: t,
+		endpoint:  endpoint,
+		inbox:     newMemoryQueue(ctx),
+		peers:     make(map[string]*memoryDealer),
+	}
+	t.routers[endpoint] = router
+	return router, nil
+}
+
+// Dial connects a DEALER socket to the ROUTER bound to endpoint. serverKey and maxRetries
+// are ignored, brokers are not authenticated and the outcome of a dial is known at once
+func (t *MemoryTransport) Dial(ctx context.Context, endpoint, identity, serverKey string, maxRetries int) (Socket, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, bound := t.routers[endpoint]; !bound {
+		return nil, fmt.Errorf("memory transport: connection refused: %s", endpoint)
+	}
+	return &memoryDealer{
+		transport: t,
+		endpoint:  endpoint,
+		identity:  identity,
+		inbox:     newMemoryQueue(ctx),
+	}, nil
+}
+
+// Reachable reports whether a ROUTER is bound to endpoint
+func (t *MemoryTransport) Reachable(endpoint string, timeout time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, bound := t.routers[endpoint]
+	return bound
+}
+
+// Disconnect drops the peer with identity from the ROUTER bound to endpoint, as if its
+// connection broke. Messages to it are dropped until it sends again
+func (t *MemoryTransport) Disconnect(endpoint, identity string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if router, bound := t.routers[endpoint]; bound {
+		delete(router.peers, identity)
+	}
+}
+
+// memoryRouter is the ROUTER end of a MemoryTransport endpoint
+type memoryRouter struct {
+	transport *MemoryTransport
+	endpoint  string
+	inbox     *memoryQueue
+	peers     map[string]*memoryDealer // Guarded by the transport's mutex
+}
+
+// Send delivers a message to the peer its first frame names
+func (r *memoryRouter) Send(msg zmq4.Msg) error {
+	if len(msg.Frames) == 0 {
+		return fmt.Errorf("memory transport: message without recipient")
+	}
+	if r.inbox.isClosed() {
+		return context.Canceled
+	}
+
+	r.transport.mutex.Lock()
+	peer, exists := r.peers[string(msg.Frames[0])]
+	r.transport.mutex.Unlock()
+
+	if exists {
+		peer.inbox.push(copyFrames(msg.Frames[1:]))
+	}
+	return nil
+}
+
+// Recv returns the next message, led by its sender's identity
+func (r *memoryRouter) Recv() (zmq4.Msg, error) {
+	return r.inbox.pop()
+}
+
+// Close unbinds the endpoint, its peers lose the connection
+func (r *memoryRouter) Close() error {
+	r.transport.mutex.Lock()
+	if r.transport.routers[r.endpoint] == r {
+		delete(r.transport.routers, r.endpoint)
+	}
+	r.peers = make(map[string]*memoryDealer)
+	r.transport.mutex.Unlock()
+
+	r.inbox.close()
+	return nil
+}
+
+// memoryDealer is the DEALER end of a connection to a MemoryTransport endpoint
+type memoryDealer struct {
+	transport *MemoryTransport
+	endpoint  string
+	identity  string
+	inbox     *memoryQueue
+}
+
+// Send delivers a message to the ROUTER bound to the endpoint now, which knows this
+// socket by its identity from then on. Without one the message is dropped
+func (d *memoryDealer) Send(msg zmq4.Msg) error {
+	if d.inbox.isClosed() {
+		return context.Canceled
+	}
+
+	d.transport.mutex.Lock()
+	router, bound := d.transport.routers[d.endpoint]
+	if bound {
+		// A later socket with the same identity takes over, like a peer reconnecting
+		router.peers[d.identity] = d
+	}
+	d.transport.mutex.Unlock()
+
+	if bound {
+		router.inbox.push(append([][]byte{[]byte(d.identity)}, copyFrames(msg.Frames)...))
+	}
+	return nil
+}
+
+// Recv returns the next message from the ROUTER
+func (d *memoryDealer) Recv() (zmq4.Msg, error) {
+	return d.inbox.pop()
+}
+
+// Close disconnects the socket
+func (d *memoryDealer) Close() error {
+	d.transport.mutex.Lock()
+	if router, bound := d.transport.routers[d.endpoint]; bound && router.peers[d.identity] == d {
+		delete(router.peers, d.identity)
+	}
+	d.transport.mutex.Unlock()
+
+	d.inbox.close()
+	return nil
+}
+
+// copyFrames copies a message so sender and receiver never share its frames
+func copyFrames(frames [][]byte) [][]byte {
+	copied := make([][]byte, len(frames))
+	for i, frame := range frames {
+		copied[i] = append([]byte(nil), frame...)
+	}
+	return copied
+}
+
+// memoryQueue is an unbounded message queue whose reader blocks until a message
+// arrives or the queue is closed or its context ends
+type memoryQueue struct {
+	mutex    sync.Mutex
+	messages [][][]byte
+	notify   chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// newMemoryQueue creates a queue that closes when ctx ends
+func newMemoryQueue(ctx context.Context) *memoryQueue {
+	ctx, cancel := context.WithCancel(ctx)
+	return &memoryQueue{
+		notify: make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// push appends a message, messages to a closed queue are dropped
+func (q *memoryQueue) push(frames [][]byte) {
+	if q.isClosed() {
+		return
+	}
+
+	q.mutex.Lock()
+	q.messages = append(q.messages, frames)
+	q.mutex.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for the next message
+func (q *memoryQueue) pop() (zmq4.Msg, error) {
+	for {
+		if err := q.ctx.Err(); err != nil {
+			return zmq4.Msg{}, err
+		}
+
+		q.mutex.Lock()
+		if len(q.messages) > 0 {
+			frames := q.messages[0]
+			q.messages = q.messages[1:]
+			q.mutex.Unlock()
+			return zmq4.NewMsgFrom(frames...), nil
+		}
+		q.mutex.Unlock()
+
+		select {
+		case <-q.notify:
+		case <-q.ctx.Done():
+		}
+	}
+}
+
+// close ends the queue, its reader fails with context.Canceled like a closed zmq4 socket
+func (q *memoryQueue) close() {
+	q.cancel()
+}
+
+// isClosed reports whether the queue was closed or its context ended
+func (q *memoryQueue) isClosed() bool {
+	return q.ctx.Err() != nil
+}
@@ -215,7 +215,7 @@ func validateWorkerMessage(msg *WorkerMessage) error {
 		if msg.Service == "" {
 			return fmt.Errorf("service required for READY command")
 		}
-	case HERMES_REPLY:
+	case HERMES_REPLY, HERMES_PARTIAL:
 		if msg.ClientID == "" {
 			return fmt.Errorf("client_id required for REPLY command")
 		}
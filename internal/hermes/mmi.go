@@ -0,0 +1,124 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hermes
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Majordomo Management Interface (RFC 8/MMI) services answered by the broker itself
+const (
+	MMI_PREFIX  = "mmi."
+	MMI_SERVICE = "mmi.service" // body: service name, reply: MMI_OK when it has workers
+	MMI_WORKERS = "mmi.workers" // body: optional service name, reply: JSON list of WorkerInfo
+	MMI_STATS   = "mmi.stats"   // reply: JSON BrokerStats
+
+	// Reply codes
+	MMI_OK              = "200"
+	MMI_UNAUTHORIZED    = "401"
+	MMI_NOT_FOUND       = "404"
+	MMI_NOT_IMPLEMENTED = "501"
+)
+
+// ManagementAuthorizer decides whether a client may use the mmi.* services from the
+// token it presented, which is empty when the request carried none
+type ManagementAuthorizer func(clientID, token string) bool
+
+// ManagementRequest is the body of an mmi.* request that carries a token. Any other body
+// is the bare argument RFC 8 describes, such as the service name of mmi.service
+type ManagementRequest struct {
+	Argument string `json:"argument,omitempty"`
+	Token    string `json:"token"`
+}
+
+// parseManagementRequest splits an mmi.* request body into its argument and token
+func parseManagementRequest(body []byte) (argument, token string) {
+	var request ManagementRequest
+	if json.Unmarshal(body, &request) == nil && request.Token != "" {
+		return strings.TrimSpace(request.Argument), request.Token
+	}
+	return strings.TrimSpace(string(body)), ""
+}
+
+// IsManagementService reports whether a service name belongs to the management interface
+func IsManagementService(service string) bool {
+	return strings.HasPrefix(service, MMI_PREFIX)
+}
+
+// SetManagementAuthorizer restricts the mmi.* services to the clients authorize accepts.
+// Without an authorizer every client may use them
+func (b *Broker) SetManagementAuthorizer(authorize ManagementAuthorizer) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.authorizeManagement = authorize
+}
+
+// handleManagementRequest answers an mmi.* request from the broker's own state
+func (b *Broker) handleManagementRequest(clientID string, msg *ClientMessage) error {
+	b.mutex.RLock()
+	authorize := b.authorizeManagement
+	b.mutex.RUnlock()
+
+	argument, token := parseManagementRequest(msg.Body)
+	if authorize != nil && !authorize(clientID, token) {
+		b.logger.Warn().
+			Str("client_id", clientID).
+			Str("service", msg.Service).
+			Msg("Management request from unauthorized client")
+		return b.sendToClient(clientID, msg.Service, []byte(MMI_UNAUTHORIZED))
+	}
+
+	reply, err := b.managementReply(msg.Service, argument)
+	if err != nil {
+		return err
+	}
+
+	b.logger.Debug().
+		Str("client_id", clientID).
+		Str("service", msg.Service).
+		Msg("Answered management request")
+	return b.sendToClient(clientID, msg.Service, reply)
+}
+
+// managementReply builds the reply body of an mmi.* service
+func (b *Broker) managementReply(service, argument string) ([]byte, error) {
+	switch service {
+	case MMI_SERVICE:
+		if info, exists := b.GetServices()[argument]; exists && len(info.Workers) > 0 {
+			return []byte(MMI_OK), nil
+		}
+		return []byte(MMI_NOT_FOUND), nil
+
+	case MMI_WORKERS:
+		workers := make([]*WorkerInfo, 0)
+		for _, worker := range b.GetWorkers() {
+			if argument == "" || worker.Service == argument {
+				workers = append(workers, worker)
+			}
+		}
+		sort.Slice(workers, func(i, j int) bool {
+			return workers[i].Identity < workers[j].Identity
+		})
+		return json.Marshal(workers)
+
+	case MMI_STATS:
+		return json.Marshal(b.GetStats())
+
+	default:
+		return []byte(MMI_NOT_IMPLEMENTED), nil
+	}
+}
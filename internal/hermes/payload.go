@@ -0,0 +1,200 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hermes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PayloadLimits bounds the messages a peer accepts and decides which bodies it compresses,
+// a zero value disables that limit
+type PayloadLimits struct {
+	MaxFrameSize      int // Largest frame taken off the socket, compressed bodies count as sent
+	MaxBodySize       int // Largest body once decompressed
+	CompressThreshold int // Bodies this large or larger are gzipped for peers announcing compression
+}
+
+// DefaultPayloadLimits returns the limits a new broker, worker or client starts with
+func DefaultPayloadLimits() PayloadLimits {
+	return PayloadLimits{
+		MaxFrameSize:      8 << 20,
+		MaxBodySize:       32 << 20,
+		CompressThreshold: 4 << 10,
+	}
+}
+
+// ErrMessageTooLarge is what peers answer or fail messages exceeding their limits with
+var ErrMessageTooLarge = errors.New("message too large")
+
+// gzipMagic starts every gzip stream. No JSON body starts with it, so compressed bodies
+// need no marker of their own and peers that never compress are unaffected
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// peekSize is how much of a body is read for its message ID and nonce when the body as a
+// whole is refused
+const peekSize = 4096
+
+// isCompressed reports whether a body is gzipped
+func isCompressed(body []byte) bool {
+	return bytes.HasPrefix(body, gzipMagic)
+}
+
+// compressBody gzips a body of at least threshold bytes. Bodies that would not shrink are
+// sent as they are
+func compressBody(body []byte, threshold int) []byte {
+	if threshold <= 0 || len(body) < threshold || isCompressed(body) {
+		return body
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(body); err != nil {
+		return body
+	}
+	if err := writer.Close(); err != nil {
+		return body
+	}
+	if compressed.Len() >= len(body) {
+		return body
+	}
+	return compressed.Bytes()
+}
+
+// decompressBody returns a body as its sender meant it, gunzipped when compressed. A body
+// larger than maxSize fails with ErrMessageTooLarge however well it compressed, and is
+// never inflated beyond that
+func decompressBody(body []byte, maxSize int) ([]byte, error) {
+	if !isCompressed(body) {
+		if maxSize > 0 && len(body) > maxSize {
+			return nil, fmt.Errorf("body of %d bytes exceeds %d: %w", len(body), maxSize, ErrMessageTooLarge)
+		}
+		return body, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("malformed compressed body: %w", err)
+	}
+	defer reader.Close()
+
+	var source io.Reader = reader
+	if maxSize > 0 {
+		source = io.LimitReader(reader, int64(maxSize)+1)
+	}
+	plain, err := io.ReadAll(source)
+	if err != nil {
+		return nil, fmt.Errorf("malformed compressed body: %w", err)
+	}
+	if maxSize > 0 && len(plain) > maxSize {
+		return nil, fmt.Errorf("body exceeds %d bytes once decompressed: %w", maxSize, ErrMessageTooLarge)
+	}
+	return plain, nil
+}
+
+// checkFrameSizes fails with ErrMessageTooLarge when a frame is larger than maxSize
+func checkFrameSizes(frames [][]byte, maxSize int) error {
+	if maxSize <= 0 {
+		return nil
+	}
+	for _, frame := range frames {
+		if len(frame) > maxSize {
+			return fmt.Errorf("frame of %d bytes exceeds %d: %w", len(frame), maxSize, ErrMessageTooLarge)
+		}
+	}
+	return nil
+}
+
+// workerEnvelope returns the command, client and body of MDP worker frames addressed to a
+// client, [protocol, command, client, empty, body], without looking into the body
+func workerEnvelope(frames [][]byte) (command, clientID string, body []byte, ok bool) {
+	if len(frames) < 5 || string(frames[0]) != HERMES_WORKER || len(frames[3]) != 0 {
+		return "", "", nil, false
+	}
+	switch command := string(frames[1]); command {
+	case HERMES_REQUEST, HERMES_REPLY, HERMES_PARTIAL:
+		return command, string(frames[2]), frames[4], true
+	default:
+		return "", "", nil, false
+	}
+}
+
+// peekIdentifiers returns the message ID and nonce of a service request or response from
+// the start of its body, compressed or not, so a body refused as a whole can still be
+// answered. Both come early in bodies Hermes encodes, either is empty when not found there
+func peekIdentifiers(body []byte) (messageID, nonce string) {
+	prefix := body
+	if isCompressed(body) {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return "", ""
+		}
+		prefix, _ = io.ReadAll(io.LimitReader(reader, peekSize))
+	} else if len(prefix) > peekSize {
+		prefix = prefix[:peekSize]
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(prefix))
+	depth := 0
+	expectKey := true
+	key := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return messageID, nonce
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 1 {
+					expectKey = true
+				}
+			}
+			if depth == 0 {
+				return messageID, nonce
+			}
+			continue
+		}
+		if depth != 1 {
+			continue
+		}
+
+		if expectKey {
+			key, _ = token.(string)
+			expectKey = false
+			continue
+		}
+		if value, ok := token.(string); ok {
+			switch key {
+			case "message_id":
+				messageID = value
+			case "nonce":
+				nonce = value
+			}
+		}
+		expectKey = true
+		if messageID != "" && nonce != "" {
+			return messageID, nonce
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package hermes
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 )
@@ -31,11 +32,24 @@ const (
 	HERMES_REPLY      = "\x03"  // Reply from worker to broker
 	HERMES_HEARTBEAT  = "\x04"  // Heartbeat between worker and broker
 	HERMES_DISCONNECT = "\x05"  // Worker disconnecting
+	HERMES_CANCEL     = "\x06"  // Broker tells a worker the client gave up on a request (Hermes extension)
+	HERMES_PARTIAL    = "\x07"  // Partial reply from worker to broker, more follow (Hermes extension)
 
 	// Client commands (RFC 7/MDP standard)
 	HERMES_REQ = "\x01"  // Client request
 	HERMES_REP = "\x02"  // Client reply
 
+	// Clients cancel a request by sending its message ID to this service, answered by the broker
+	HERMES_CANCEL_SERVICE = "hermes.cancel"
+
+	// Clients announce their PeerInfo to this service, the broker answers with its own
+	HERMES_HELLO_SERVICE = "hermes.hello"
+
+	// Protocol version spoken by this build. Version 1 is a peer that announced nothing,
+	// from version 2 peers announce their version and capabilities on READY and hello
+	HERMES_PROTOCOL_VERSION = 2
+	HERMES_LEGACY_VERSION   = 1
+
 	// Service lifecycle (extended)
 	HERMES_SERVICE_UP   = "SERVICE_UP"
 	HERMES_SERVICE_DOWN = "SERVICE_DOWN"
@@ -48,6 +62,42 @@ const (
 	MDP_HEARTBEAT_LIVENESS  = 3     // Heartbeats before considering worker dead
 )
 
+// Capabilities a peer may announce
+const (
+	CapabilityCancel      = "cancel"      // Abandons requests on CANCEL
+	CapabilityNonce       = "nonce"       // Deduplicates requests by nonce
+	CapabilityStatePush   = "state_push"  // Pushes device state without being asked
+	CapabilityStreaming   = "streaming"   // Sends partial replies before the final one
+	CapabilityCompression = "compression" // Accepts compressed bodies
+	CapabilityDiscover    = "discover"    // hub.control: discovers devices on the hub's LAN
+	CapabilityAdopt       = "adopt"       // hub.control: configures discovered devices
+)
+
+// PeerInfo is what a worker announces on READY and a client in its hello
+type PeerInfo struct {
+	Version      int      `json:"version"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Schema       int      `json:"schema,omitempty"` // Version of the service's action schema, 0 when unversioned
+}
+
+// legacyPeerInfo describes a peer that announced nothing
+func legacyPeerInfo() *PeerInfo {
+	return &PeerInfo{Version: HERMES_LEGACY_VERSION}
+}
+
+// HasCapability reports whether the peer announced a capability
+func (p *PeerInfo) HasCapability(capability string) bool {
+	if p == nil {
+		return false
+	}
+	for _, c := range p.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
 // Message represents a Hermes protocol message
 type Message struct {
 	Protocol  string    `json:"protocol"`
@@ -61,11 +111,12 @@ type Message struct {
 
 // WorkerMessage represents a message from worker to broker
 type WorkerMessage struct {
-	Protocol string `json:"protocol"`
-	Command  string `json:"command"`
-	Service  string `json:"service,omitempty"`
-	Body     []byte `json:"body,omitempty"`
-	ClientID string `json:"client_id,omitempty"`
+	Protocol string    `json:"protocol"`
+	Command  string    `json:"command"`
+	Service  string    `json:"service,omitempty"`
+	Body     []byte    `json:"body,omitempty"`
+	ClientID string    `json:"client_id,omitempty"`
+	Info     *PeerInfo `json:"info,omitempty"` // READY, and HEARTBEAT from a broker answering a worker announcing compression
 }
 
 // ClientMessage represents a message from client to broker
@@ -85,6 +136,8 @@ type ServiceRequest struct {
 	Payload   json.RawMessage `json:"payload"`
 	Nonce     string          `json:"nonce,omitempty"`
 	Timeout   int             `json:"timeout,omitempty"` // seconds
+	HubID     string          `json:"hub_id,omitempty"`  // hub.control requests: the hub that must handle it
+	Stream    bool            `json:"stream,omitempty"`  // the client takes partial replies ahead of the final one
 }
 
 // ServiceResponse represents a service response
@@ -95,6 +148,8 @@ type ServiceResponse struct {
 	Data      interface{} `json:"data,omitempty"`
 	Error     string      `json:"error,omitempty"`
 	Nonce     string      `json:"nonce,omitempty"`
+	Partial   bool        `json:"partial,omitempty"`  // more replies to the same request follow
+	Sequence  int         `json:"sequence,omitempty"` // position of a partial reply, from 1
 }
 
 // ServiceInfo represents information about a service
@@ -103,20 +158,24 @@ type ServiceInfo struct {
 	Description  string    `json:"description"`
 	Capabilities []string  `json:"capabilities"`
 	Workers      []string  `json:"workers"`
+	Queued       int       `json:"queued"`
 	LastSeen     time.Time `json:"last_seen"`
 	Status       string    `json:"status"`
 }
 
 // WorkerInfo represents information about a worker
 type WorkerInfo struct {
-	Identity string    `json:"identity"`
-	Service  string    `json:"service"`
-	Address  string    `json:"address,omitempty"`
-	Expiry   time.Time `json:"expiry"`
-	LastPing time.Time `json:"last_ping"`
-	Status   string    `json:"status"`
-	Liveness int       `json:"liveness"`
-	Requests int       `json:"requests"`
+	Identity     string    `json:"identity"`
+	Service      string    `json:"service"`
+	Address      string    `json:"address,omitempty"`
+	Expiry       time.Time `json:"expiry"`
+	LastPing     time.Time `json:"last_ping"`
+	Status       string    `json:"status"`
+	Liveness     int       `json:"liveness"`
+	Requests     int       `json:"requests"`
+	Version      int       `json:"version"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+	Schema       int       `json:"schema,omitempty"`
 }
 
 // BrokerStats represents broker statistics
@@ -125,6 +184,13 @@ type BrokerStats struct {
 	Workers            int       `json:"workers"`
 	Requests           int       `json:"requests"`
 	Responses          int       `json:"responses"`
+	Cancelled          int       `json:"cancelled"`
+	Rejected           int       `json:"rejected"`  // Turned away by a queue or in-flight limit
+	Expired            int       `json:"expired"`   // Queued past their TTL or timeout
+	Dropped            int       `json:"dropped"`   // Lost to full internal channels
+	Queued             int       `json:"queued"`    // Currently waiting for a worker
+	Partials           int       `json:"partials"`  // Partial replies forwarded to clients
+	Oversized          int       `json:"oversized"` // Refused for exceeding a frame or body size limit
 	HeartbeatsReceived int       `json:"heartbeats_received"`
 	HeartbeatsSent     int       `json:"heartbeats_sent"`
 	StartTime          time.Time `json:"start_time"`
@@ -137,6 +203,21 @@ type RequestHandler interface {
 	Handle(request []byte) ([]byte, error)
 }
 
+// ContextRequestHandler is a RequestHandler that can abandon work once the request's
+// deadline passes or its client cancels it
+type ContextRequestHandler interface {
+	RequestHandler
+	HandleContext(ctx context.Context, request []byte) ([]byte, error)
+}
+
+// StreamingRequestHandler is a ContextRequestHandler that reports progress on requests
+// asking for it. Each call to progress sends its data to the client as a partial reply,
+// in order, ahead of the final reply HandleStream returns
+type StreamingRequestHandler interface {
+	ContextRequestHandler
+	HandleStream(ctx context.Context, request []byte, progress func(data interface{}) error) ([]byte, error)
+}
+
 // ServiceRegistry interface for managing services
 type ServiceRegistry interface {
 	RegisterService(name string, info *ServiceInfo) error
@@ -187,33 +268,43 @@ func IsValidMDPClientCommand(command string) bool {
 }
 
 // FormatMDPWorkerFrame formats a worker message frame according to RFC 7/MDP
-// Frame format: [empty, protocol, command, service?, body?]
-func FormatMDPWorkerFrame(command, service string, body []byte) [][]byte {
+// Frame format: [empty, protocol, command, service] for READY, followed by the body
+// holding the worker's PeerInfo when it has one (Hermes extension),
+// [empty, protocol, command, client, empty, body] for REQUEST, REPLY, PARTIAL and CANCEL,
+// [empty, protocol, command] for HEARTBEAT and DISCONNECT, a broker's HEARTBEAT followed
+// by its PeerInfo when it has one (Hermes extension).
+// For REQUEST and REPLY the address is the client, for READY the service
+func FormatMDPWorkerFrame(command, address string, body []byte) [][]byte {
 	frames := [][]byte{
-		[]byte(""),           // Empty frame
+		[]byte(""),                // Empty frame
 		[]byte(MDP_WORKER_HEADER), // Protocol header
-		[]byte(command),      // Command
+		[]byte(command),           // Command
 	}
-	
-	if service != "" {
-		frames = append(frames, []byte(service))
-	}
-	
-	if len(body) > 0 {
-		frames = append(frames, body)
+
+	switch command {
+	case HERMES_READY:
+		frames = append(frames, []byte(address))
+		if len(body) > 0 {
+			frames = append(frames, body)
+		}
+	case HERMES_REQUEST, HERMES_REPLY, HERMES_PARTIAL, HERMES_CANCEL:
+		frames = append(frames, []byte(address), []byte(""), body)
+	case HERMES_HEARTBEAT:
+		if len(body) > 0 {
+			frames = append(frames, body)
+		}
 	}
-	
+
 	return frames
 }
 
 // FormatMDPClientFrame formats a client message frame according to RFC 7/MDP
-// Frame format: [empty, protocol, command, service, body]
-func FormatMDPClientFrame(command, service string, body []byte) [][]byte {
+// Frame format: [empty, protocol, service, body], the same for requests and replies
+func FormatMDPClientFrame(service string, body []byte) [][]byte {
 	return [][]byte{
-		[]byte(""),           // Empty frame
+		[]byte(""),                // Empty frame
 		[]byte(MDP_CLIENT_HEADER), // Protocol header
-		[]byte(command),      // Command
-		[]byte(service),      // Service name
-		body,                 // Request body
+		[]byte(service),           // Service name
+		body,                      // Request or reply body
 	}
 }
@@ -0,0 +1,125 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/destiny/zmq4/v25"
+	"github.com/destiny/zmq4/v25/security/curve"
+	"github.com/destiny/zmq4/v25/z85"
+)
+
+// ErrNotConnected is what sending fails with while a peer has no socket, before it
+// starts or while it reconnects
+var ErrNotConnected = errors.New("socket not connected")
+
+// Socket is what Hermes peers need of a socket. zmq4 sockets satisfy it
+type Socket interface {
+	Send(msg zmq4.Msg) error
+	Recv() (zmq4.Msg, error)
+	Close() error
+}
+
+// Transport opens the sockets brokers listen on and workers and clients dial. Sockets
+// end with ctx, after which Recv fails with its error
+type Transport interface {
+	// Listen binds a ROUTER socket to endpoint. Messages it receives start with the
+	// sender's identity frame, messages sent on it with the recipient's
+	Listen(ctx context.Context, endpoint string) (Socket, error)
+
+	// Dial connects a DEALER socket to endpoint, known to the broker by identity.
+	// serverKey is the broker's Curve public key in Z85, empty when it has none, for
+	// transports that authenticate brokers. maxRetries > 0 bounds the attempts to reach
+	// an endpoint that does not answer
+	Dial(ctx context.Context, endpoint, identity, serverKey string, maxRetries int) (Socket, error)
+
+	// Reachable reports whether something listens on endpoint, without joining it as a peer
+	Reachable(endpoint string, timeout time.Duration) bool
+}
+
+// socketHWM is the high watermark of the sockets ZMQTransport opens
+const socketHWM = 1000
+
+// ZMQTransport is the ZeroMQ transport Hermes peers use unless given another. With
+// ClientKeys set, brokers dialed with a server key are authenticated with CURVE
+type ZMQTransport struct {
+	ClientKeys *curve.KeyPair
+}
+
+// Listen binds a zmq4 ROUTER socket to endpoint
+func (ZMQTransport) Listen(ctx context.Context, endpoint string) (Socket, error) {
+	socket := zmq4.NewRouter(ctx)
+	// Not every build supports the option, sockets work without it
+	socket.SetOption(zmq4.OptionHWM, socketHWM)
+
+	if err := socket.Listen(endpoint); err != nil {
+		socket.Close()
+		return nil, err
+	}
+	return socket, nil
+}
+
+// Dial connects a zmq4 DEALER socket to endpoint
+func (t ZMQTransport) Dial(ctx context.Context, endpoint, identity, serverKey string, maxRetries int) (Socket, error) {
+	options := []zmq4.Option{zmq4.WithID(zmq4.SocketIdentity(identity))}
+	if maxRetries > 0 {
+		options = append(options, zmq4.WithDialerMaxRetries(maxRetries))
+	}
+	if t.ClientKeys != nil && serverKey != "" {
+		public, err := z85.DecodeString(serverKey)
+		if err != nil || len(public) != curve.KeySize {
+			return nil, fmt.Errorf("invalid server key for %s", endpoint)
+		}
+		var key [curve.KeySize]byte
+		copy(key[:], public)
+		options = append(options, zmq4.WithSecurity(curve.NewClientSecurity(t.ClientKeys, key)))
+	}
+	socket := zmq4.NewDealer(ctx, options...)
+	socket.SetOption(zmq4.OptionHWM, socketHWM)
+
+	if err := socket.Dial(endpoint); err != nil {
+		socket.Close()
+		return nil, err
+	}
+	return socket, nil
+}
+
+// Reachable reports whether a tcp:// or ipc:// endpoint accepts connections. The probe
+// connection closes before a ZMTP handshake, so the broker never sees a peer. Endpoints
+// that cannot be probed, such as inproc://, count as reachable and are found out by dialing
+func (ZMQTransport) Reachable(endpoint string, timeout time.Duration) bool {
+	network, address := "tcp", ""
+	switch scheme, rest, _ := strings.Cut(endpoint, "://"); scheme {
+	case "tcp":
+		address = rest
+	case "ipc":
+		network, address = "unix", rest
+	default:
+		return true
+	}
+
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -39,16 +39,35 @@ const (
 	WorkerStateReconnecting
 )
 
+// workerLiveness is how many heartbeat intervals may pass without a word from the broker
+// before the worker reconnects, as tolerant as the broker is of silent workers
+const workerLiveness = 10
+
+// defaultPrimaryCheck is how often a worker that failed over probes its primary broker
+const defaultPrimaryCheck = 30 * time.Second
+
+// failoverDialRetries bounds the dial attempts per broker when there are others to fail over to
+const failoverDialRetries = 2
+
+// cancelRetention is how long a cancel for a request not started yet is remembered
+const cancelRetention = time.Minute
+
 // HermesWorker implements the Hermes Majordomo Protocol worker with channel-based architecture
 type HermesWorker struct {
 	broker          string
+	brokers         []string      // Brokers in order of preference, the first is the primary
+	serverKeys      map[string]string // Curve public key of each broker, by endpoint
+	primaryCheck    time.Duration // How often to probe the primary while failed over
 	service         string
 	identity        string
-	socket          zmq4.Socket
+	socket          Socket
+	transport       Transport
+	clock           Clock
 	heartbeat       time.Duration
 	reconnect       time.Duration
 	liveness        int
 	handler         RequestHandler
+	framing         Framing
 	state           WorkerState
 	ctx             context.Context
 	cancel          context.CancelFunc
@@ -58,11 +77,18 @@ type HermesWorker struct {
 	requestCount    int
 	reconnectAttempt int           // Track reconnection attempts for backoff
 	maxReconnectDelay time.Duration // Maximum backoff delay
+	inflight        map[string]context.CancelFunc // Requests being handled, keyed by client and message ID
+	cancelled       map[string]time.Time          // Cancels that arrived before their request started
+	capabilities    []string                      // Announced on READY besides the worker's own
+	schema          int                           // Action schema version announced on READY
+	payload         PayloadLimits
+	brokerInfo      *PeerInfo                     // What the current broker announced, nil when it announced nothing
 	
 	// Channel-based architecture
 	messagesCh      chan zmq4.Msg     // Incoming messages from broker
 	heartbeatCh     chan time.Time    // Heartbeat events
 	reconnectCh     chan struct{}     // Reconnection requests
+	socketCh        chan struct{}     // Signalled when a new socket is connected
 	shutdownCh      chan struct{}     // Shutdown signal
 	errorsCh        chan error        // Error notifications
 	statsCh         chan *WorkerStats // Stats updates
@@ -89,12 +115,20 @@ func NewWorker(broker, service, identity string, handler RequestHandler) *Hermes
 	
 	return &HermesWorker{
 		broker:            broker,
+		brokers:           []string{broker},
+		primaryCheck:      defaultPrimaryCheck,
 		service:           service,
 		identity:          identity,
 		handler:           handler,
+		transport:         ZMQTransport{},
+		clock:             systemClock{},
 		heartbeat:         GetMDPHeartbeatInterval(), // Use RFC 7/MDP standard interval
 		reconnect:         5 * time.Second,           // Default reconnection interval
-		liveness:          MDP_HEARTBEAT_LIVENESS,    // Use RFC 7/MDP standard liveness
+		liveness:          workerLiveness,
+		framing:           FramingMDP,
+		inflight:          make(map[string]context.CancelFunc),
+		cancelled:         make(map[string]time.Time),
+		payload:           DefaultPayloadLimits(),
 		state:             WorkerStateDisconnected,
 		ctx:               ctx,
 		cancel:            cancel,
@@ -108,6 +142,7 @@ func NewWorker(broker, service, identity string, handler RequestHandler) *Hermes
 		messagesCh:  make(chan zmq4.Msg, 100),     // Buffered for high throughput
 		heartbeatCh: make(chan time.Time, 10),     // Buffered heartbeat events
 		reconnectCh: make(chan struct{}, 1),       // Single reconnection signal
+		socketCh:    make(chan struct{}, 1),       // Single new socket signal
 		shutdownCh:  make(chan struct{}, 1),       // Single shutdown signal
 		errorsCh:    make(chan error, 50),         // Buffered error notifications
 		statsCh:     make(chan *WorkerStats, 10),  // Buffered stats updates
@@ -121,6 +156,118 @@ func (w *HermesWorker) SetHeartbeat(interval time.Duration) {
 	w.heartbeat = interval
 }
 
+// SetFraming sets how messages to the broker are framed, FramingJSON for brokers that
+// predate multipart MDP framing
+func (w *HermesWorker) SetFraming(framing Framing) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.framing = framing
+}
+
+// SetBrokers sets the brokers to fail over between, in order of preference. The worker
+// connects to the first one reachable and returns to the primary once it is back
+func (w *HermesWorker) SetBrokers(brokers []string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(brokers) == 0 {
+		return
+	}
+	w.brokers = append([]string(nil), brokers...)
+	w.broker = w.brokers[0]
+}
+
+// SetServerKeys sets the Curve public key of each broker, by endpoint, which the worker
+// dials that broker with so each one is authenticated by its own key
+func (w *HermesWorker) SetServerKeys(keys map[string]string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.serverKeys = make(map[string]string, len(keys))
+	for endpoint, key := range keys {
+		w.serverKeys[endpoint] = key
+	}
+}
+
+// SetCapabilities sets the capabilities the worker announces on READY on top of those the
+// worker itself provides, such as cancel. Takes effect on the next READY
+func (w *HermesWorker) SetCapabilities(capabilities ...string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.capabilities = append([]string(nil), capabilities...)
+}
+
+// SetSchema sets the version of the service's action schema the worker announces on READY
+func (w *HermesWorker) SetSchema(version int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.schema = version
+}
+
+// peerInfo returns what the worker announces on READY
+func (w *HermesWorker) peerInfo() *PeerInfo {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	capabilities := []string{CapabilityCancel, CapabilityCompression}
+	if _, ok := w.handler.(StreamingRequestHandler); ok {
+		capabilities = append(capabilities, CapabilityStreaming)
+	}
+	for _, capability := range w.capabilities {
+		if capability != CapabilityCancel && capability != CapabilityCompression && capability != CapabilityStreaming {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	return &PeerInfo{
+		Version:      HERMES_PROTOCOL_VERSION,
+		Capabilities: capabilities,
+		Schema:       w.schema,
+	}
+}
+
+// SetPayloadLimits sets the size limits on the requests the worker accepts and the threshold
+// from which it compresses replies for brokers announcing compression
+func (w *HermesWorker) SetPayloadLimits(limits PayloadLimits) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.payload = limits
+}
+
+// getPayloadLimits returns the worker's current payload limits
+func (w *HermesWorker) getPayloadLimits() PayloadLimits {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.payload
+}
+
+// SetTransport sets the transport the worker dials brokers with, ZeroMQ by default. Call it before Start
+func (w *HermesWorker) SetTransport(transport Transport) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.transport = transport
+}
+
+// SetClock sets the clock that paces heartbeats, liveness, reconnect backoff and primary
+// probes, the system clock by default. Call it before Start
+func (w *HermesWorker) SetClock(clock Clock) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.clock = clock
+	w.stats.StartTime = clock.Now()
+}
+
+// SetPrimaryCheckInterval sets how often a worker that failed over probes its primary broker
+func (w *HermesWorker) SetPrimaryCheckInterval(interval time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.primaryCheck = interval
+}
+
+// GetBroker returns the broker the worker is connected to, or last tried
+func (w *HermesWorker) GetBroker() string {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.broker
+}
+
 // SetReconnectInterval sets the reconnection interval
 func (w *HermesWorker) SetReconnectInterval(interval time.Duration) {
 	w.mutex.Lock()
@@ -131,7 +278,7 @@ func (w *HermesWorker) SetReconnectInterval(interval time.Duration) {
 // Start starts the worker with channel-based architecture
 func (w *HermesWorker) Start() error {
 	w.logger.Info().
-		Str("broker", w.broker).
+		Strs("brokers", w.brokers).
 		Str("service", w.service).
 		Str("identity", w.identity).
 		Msg("Starting Hermes worker with channel-based architecture")
@@ -146,6 +293,8 @@ func (w *HermesWorker) Start() error {
 	go w.heartbeatManager()  // Manage heartbeats using heartbeatCh
 	go w.errorHandler()      // Handle errors from errorsCh
 	go w.statsManager()      // Manage stats updates from statsCh
+	go w.reconnectManager()  // Reconnect on requests from reconnectCh
+	go w.primaryMonitor()    // Return to the primary broker after a failover
 
 	return nil
 }
@@ -169,11 +318,8 @@ func (w *HermesWorker) Stop() error {
 
 	w.cancel()
 
-	if w.socket != nil {
-		if err := w.socket.Close(); err != nil {
-			w.logger.Error().Err(err).Msg("Error closing worker socket")
-		}
-		w.socket = nil
+	if err := w.closeSocket(); err != nil {
+		w.logger.Error().Err(err).Msg("Error closing worker socket")
 	}
 
 	// Close channels (done by workers when they see shutdown signal)
@@ -181,19 +327,22 @@ func (w *HermesWorker) Stop() error {
 	return nil
 }
 
-// connect establishes connection to the broker with retry logic
+// connect establishes connection to the broker with retry logic. Each attempt tries the
+// brokers in order of preference, so the primary is always preferred when reachable
 func (w *HermesWorker) connect() error {
 	w.mutex.Lock()
 	w.state = WorkerStateConnecting
+	brokers := w.brokers
 	w.mutex.Unlock()
 
 	w.logger.Info().
-		Str("broker", w.broker).
+		Strs("brokers", brokers).
 		Msg("Connecting to Hermes broker")
 
 	maxRetries := 10
 	baseDelay := 250 * time.Millisecond
 	
+	var lastErr error
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
 			delay := time.Duration(attempt) * baseDelay
@@ -202,78 +351,83 @@ func (w *HermesWorker) connect() error {
 				Int("max_retries", maxRetries).
 				Dur("delay", delay).
 				Msg("Retrying broker connection")
-			time.Sleep(delay)
-		}
-
-		// Create DEALER socket
-		socket := zmq4.NewDealer(w.ctx)
-
-		// Set high watermark option if available
-		if err := socket.SetOption(zmq4.OptionHWM, 1000); err != nil {
-			w.logger.Warn().Err(err).Msg("Failed to set high watermark - continuing without it")
+			if !sleepContext(w.ctx, w.clock, delay) {
+				return fmt.Errorf("worker stopped while connecting: %w", w.ctx.Err())
+			}
 		}
 
-		// Connect to broker
-		if err := socket.Dial(w.broker); err != nil {
-			socket.Close()
-			if attempt == maxRetries-1 {
-				return fmt.Errorf("failed to connect to broker after %d attempts: %w", maxRetries, err)
+		for i, broker := range brokers {
+			if err := w.connectTo(broker); err != nil {
+				lastErr = err
+				w.logger.Warn().
+					Err(err).
+					Str("broker", broker).
+					Int("attempt", attempt+1).
+					Msg("Failed to connect to broker, will retry")
+				continue
 			}
-			w.logger.Warn().
-				Err(err).
-				Int("attempt", attempt+1).
-				Msg("Failed to connect to broker, will retry")
-			continue
-		}
 
-		w.socket = socket
-		w.liveness = 10
+			w.mutex.Lock()
+			w.state = WorkerStateReady
+			w.mutex.Unlock()
 
-		// Send READY message to register with broker
-		if err := w.sendReady(); err != nil {
-			socket.Close()
-			w.socket = nil
-			if attempt == maxRetries-1 {
-				return fmt.Errorf("failed to send READY message after %d attempts: %w", maxRetries, err)
-			}
-			w.logger.Warn().
-				Err(err).
+			w.logger.Info().
+				Str("broker", broker).
+				Bool("primary", i == 0).
 				Int("attempt", attempt+1).
-				Msg("Failed to send READY message, will retry")
-			continue
+				Msg("Connected to Hermes broker and ready for requests")
+			return nil
 		}
+	}
 
-		w.logger.Info().
-			Int("attempt", attempt+1).
-			Msg("Connected to Hermes broker and ready for requests")
-		return nil
+	return fmt.Errorf("failed to connect to broker after %d attempts: %w", maxRetries, lastErr)
+}
+
+// connectTo dials one broker and registers with it
+func (w *HermesWorker) connectTo(broker string) error {
+	// Create DEALER socket, the identity is how the broker addresses this peer
+	maxRetries := 0
+	w.mutex.RLock()
+	if len(w.brokers) > 1 {
+		// Give up on an unreachable broker quickly so the next one is tried
+		maxRetries = failoverDialRetries
 	}
+	serverKey := w.serverKeys[broker]
+	w.mutex.RUnlock()
 
-	return fmt.Errorf("failed to connect to broker after %d attempts", maxRetries)
+	socket, err := w.transport.Dial(w.ctx, broker, w.identity, serverKey, maxRetries)
+	if err != nil {
+		return err
+	}
 
 	w.mutex.Lock()
-	w.state = WorkerStateReady
+	w.socket = socket
+	w.broker = broker
+	w.liveness = workerLiveness
+	w.brokerInfo = nil
 	w.mutex.Unlock()
 
-	w.logger.Info().Msg("Connected to Hermes broker and ready for requests")
+	// Send READY message to register with broker
+	if err := w.sendReady(); err != nil {
+		socket.Close()
+		w.mutex.Lock()
+		w.socket = nil
+		w.mutex.Unlock()
+		return fmt.Errorf("failed to send READY message: %w", err)
+	}
+
+	select {
+	case w.socketCh <- struct{}{}:
+	default:
+	}
 	return nil
 }
 
-
 // handleMessage handles a message from the broker
 func (w *HermesWorker) handleMessage(msgParts [][]byte) error {
-	if len(msgParts) == 0 {
-		return fmt.Errorf("empty message parts")
-	}
-
-	// Parse worker message
-	var workerMsg WorkerMessage
-	if err := json.Unmarshal(msgParts[0], &workerMsg); err != nil {
-		return fmt.Errorf("failed to parse worker message: %w", err)
-	}
-
-	if workerMsg.Protocol != HERMES_WORKER {
-		return fmt.Errorf("invalid protocol: %s", workerMsg.Protocol)
+	workerMsg, _, err := DecodeWorkerMessage(msgParts)
+	if err != nil {
+		return err
 	}
 
 	w.logger.Debug().
@@ -283,9 +437,13 @@ func (w *HermesWorker) handleMessage(msgParts [][]byte) error {
 
 	switch workerMsg.Command {
 	case HERMES_REQUEST:
-		return w.handleRequest(workerMsg.ClientID, workerMsg.Body, msgParts[1:])
+		body, err := decompressBody(workerMsg.Body, w.getPayloadLimits().MaxBodySize)
+		if err != nil {
+			return w.refuseRequest(workerMsg.ClientID, workerMsg.Body, err)
+		}
+		return w.handleRequest(workerMsg.ClientID, body)
 	case HERMES_HEARTBEAT:
-		return w.handleHeartbeat()
+		return w.handleHeartbeat(workerMsg.Info)
 	case HERMES_DISCONNECT:
 		return w.handleDisconnect()
 	default:
@@ -294,14 +452,43 @@ func (w *HermesWorker) handleMessage(msgParts [][]byte) error {
 }
 
 // handleRequest handles a service request
-func (w *HermesWorker) handleRequest(clientID string, body []byte, extraParts [][]byte) error {
+func (w *HermesWorker) handleRequest(clientID string, body []byte) error {
+	var request struct {
+		MessageID string `json:"message_id"`
+		Nonce     string `json:"nonce"`
+		Timeout   int    `json:"timeout"`
+		Stream    bool   `json:"stream"`
+	}
+	json.Unmarshal(body, &request)
+
+	// The request runs under its deadline, and its client may cancel it meanwhile
+	ctx, cancel := context.WithCancel(w.ctx)
+	if request.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(w.ctx, time.Duration(request.Timeout)*time.Second)
+	}
+	defer cancel()
+
+	key := inflightKey(clientID, request.MessageID)
 	w.mutex.Lock()
 	w.state = WorkerStateWorking
 	w.requestCount++
-	w.stats.LastRequest = time.Now()
+	w.stats.LastRequest = w.clock.Now()
 	requestNum := w.requestCount
+	if request.MessageID != "" {
+		w.inflight[key] = cancel
+		if _, cancelledEarly := w.cancelled[key]; cancelledEarly {
+			delete(w.cancelled, key)
+			cancel()
+		}
+	}
 	w.mutex.Unlock()
 
+	defer func() {
+		w.mutex.Lock()
+		delete(w.inflight, key)
+		w.mutex.Unlock()
+	}()
+
 	w.logger.Info().
 		Str("client_id", clientID).
 		Int("request_num", requestNum).
@@ -309,18 +496,25 @@ func (w *HermesWorker) handleRequest(clientID string, body []byte, extraParts []
 		Str("service", w.service).
 		Msg("Worker processing service request")
 
-	// Use body from extra parts if available (for large messages)
-	requestBody := body
-	if len(extraParts) > 0 && len(extraParts[0]) > 0 {
-		requestBody = extraParts[0]
-	}
-
 	// Process request using handler
 	var response []byte
 	var err error
 	
-	if w.handler != nil {
-		response, err = w.handler.Handle(requestBody)
+	if ctx.Err() != nil {
+		err = requestContextError(ctx)
+	} else if handler, ok := w.handler.(StreamingRequestHandler); ok && request.Stream {
+		sequence := 0
+		response, err = handler.HandleStream(ctx, body, func(data interface{}) error {
+			sequence++
+			partial := CreateServiceResponseWithNonce(request.MessageID, w.service, request.Nonce, true, data, nil)
+			partial.Partial = true
+			partial.Sequence = sequence
+			return w.sendPartial(clientID, partial)
+		})
+	} else if handler, ok := w.handler.(ContextRequestHandler); ok {
+		response, err = handler.HandleContext(ctx, body)
+	} else if w.handler != nil {
+		response, err = w.handler.Handle(body)
 	} else {
 		err = fmt.Errorf("no request handler configured")
 	}
@@ -342,8 +536,8 @@ func (w *HermesWorker) handleRequest(clientID string, body []byte, extraParts []
 			Err(err).
 			Msg("Request processing failed")
 
-		// Send error response
-		errorResp := CreateServiceResponse("", w.service, false, nil, err)
+		// Send error response, correlated with the request so its client can tell
+		errorResp := CreateServiceResponseWithNonce(request.MessageID, w.service, request.Nonce, false, nil, err)
 		response, _ = SerializeServiceResponse(errorResp)
 	}
 
@@ -367,18 +561,76 @@ func (w *HermesWorker) handleRequest(clientID string, body []byte, extraParts []
 	return nil
 }
 
-// handleHeartbeat handles heartbeat from broker
-func (w *HermesWorker) handleHeartbeat() error {
+// handleCancel cancels a request its client gave up on. A cancel that overtook its request
+// is remembered so the request is not started at all
+func (w *HermesWorker) handleCancel(clientID, messageID string) {
+	key := inflightKey(clientID, messageID)
+
+	w.mutex.Lock()
+	cancel, running := w.inflight[key]
+	if !running {
+		now := w.clock.Now()
+		for k, at := range w.cancelled {
+			if now.Sub(at) > cancelRetention {
+				delete(w.cancelled, k)
+			}
+		}
+		w.cancelled[key] = now
+	}
+	w.mutex.Unlock()
+
+	if running {
+		cancel()
+	}
+
+	w.logger.Debug().
+		Str("client_id", clientID).
+		Str("message_id", messageID).
+		Bool("running", running).
+		Msg("Request cancelled by client")
+}
+
+// requestContextError describes why a request's context ended
+func requestContextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("request deadline exceeded")
+	}
+	return fmt.Errorf("request cancelled")
+}
+
+// refuseRequest answers a request the worker will not handle, correlated with it as far as
+// the start of its body tells
+func (w *HermesWorker) refuseRequest(clientID string, body []byte, reason error) error {
+	w.mutex.Lock()
+	w.stats.RequestsFailed++
+	w.mutex.Unlock()
+
+	w.logger.Warn().
+		Str("client_id", clientID).
+		Int("body_size", len(body)).
+		Err(reason).
+		Msg("Request refused")
+
+	messageID, nonce := peekIdentifiers(body)
+	reply, _ := SerializeServiceResponse(CreateServiceResponseWithNonce(messageID, w.service, nonce, false, nil, reason))
+	return w.sendReply(clientID, reply)
+}
+
+// handleHeartbeat handles heartbeat from broker, which carries the broker's PeerInfo when it
+// answers a READY announcing compression
+func (w *HermesWorker) handleHeartbeat(info *PeerInfo) error {
 	// Update heartbeat received statistics
 	w.mutex.Lock()
 	w.stats.HeartbeatsReceived++
-	w.stats.LastHeartbeatReceived = time.Now()
+	w.stats.LastHeartbeatReceived = w.clock.Now()
+	if info != nil {
+		w.brokerInfo = info
+	}
 	w.mutex.Unlock()
 
 	w.logger.Debug().
 		Int("total_received", w.stats.HeartbeatsReceived).
 		Msg("Received heartbeat response from broker")
-	w.liveness = 10
 	return nil
 }
 
@@ -391,22 +643,24 @@ func (w *HermesWorker) handleDisconnect() error {
 
 // sendReady sends READY message to broker
 func (w *HermesWorker) sendReady() error {
-	if w.socket == nil {
-		return fmt.Errorf("socket not initialized")
+	socket, err := w.connectedSocket()
+	if err != nil {
+		return err
 	}
 
 	msg := &WorkerMessage{
 		Protocol: HERMES_WORKER,
 		Command:  HERMES_READY,
 		Service:  w.service,
+		Info:     w.peerInfo(),
 	}
 
-	msgBytes, err := SerializeMessage(msg)
+	frames, err := EncodeWorkerMessage(msg, w.getFraming())
 	if err != nil {
 		return fmt.Errorf("failed to serialize READY message: %w", err)
 	}
 
-	err = w.socket.Send(zmq4.NewMsgFrom([]byte(""), msgBytes))
+	err = socket.Send(zmq4.NewMsgFrom(frames...))
 	if err != nil {
 		return fmt.Errorf("failed to send READY message: %w", err)
 	}
@@ -418,41 +672,76 @@ func (w *HermesWorker) sendReady() error {
 	return nil
 }
 
-// sendReply sends reply to broker
+// sendReply sends reply to broker. A reply over the body size limit is replaced by an error
+// reply, the client learns why rather than waiting for it in vain
 func (w *HermesWorker) sendReply(clientID string, body []byte) error {
-	if w.socket == nil {
-		return fmt.Errorf("socket not initialized")
+	if maxSize := w.getPayloadLimits().MaxBodySize; maxSize > 0 && len(body) > maxSize {
+		reason := fmt.Errorf("reply of %d bytes exceeds %d: %w", len(body), maxSize, ErrMessageTooLarge)
+		w.logger.Warn().
+			Str("client_id", clientID).
+			Err(reason).
+			Msg("Reply refused")
+		messageID, nonce := peekIdentifiers(body)
+		body, _ = SerializeServiceResponse(CreateServiceResponseWithNonce(messageID, w.service, nonce, false, nil, reason))
+	}
+	return w.sendClientMessage(HERMES_REPLY, "REPLY", clientID, body)
+}
+
+// sendPartial sends a partial reply to broker, ahead of the final reply to the same request
+func (w *HermesWorker) sendPartial(clientID string, partial *ServiceResponse) error {
+	body, err := SerializeServiceResponse(partial)
+	if err != nil {
+		return fmt.Errorf("failed to serialize partial reply: %w", err)
+	}
+	if maxSize := w.getPayloadLimits().MaxBodySize; maxSize > 0 && len(body) > maxSize {
+		return fmt.Errorf("partial reply of %d bytes exceeds %d: %w", len(body), maxSize, ErrMessageTooLarge)
+	}
+	return w.sendClientMessage(HERMES_PARTIAL, "PARTIAL", clientID, body)
+}
+
+// sendClientMessage sends a message addressed to a client to broker
+func (w *HermesWorker) sendClientMessage(command, name, clientID string, body []byte) error {
+	socket, err := w.connectedSocket()
+	if err != nil {
+		return err
 	}
 
+	w.mutex.RLock()
+	if w.brokerInfo.HasCapability(CapabilityCompression) {
+		body = compressBody(body, w.payload.CompressThreshold)
+	}
+	w.mutex.RUnlock()
+
 	msg := &WorkerMessage{
 		Protocol: HERMES_WORKER,
-		Command:  HERMES_REPLY,
+		Command:  command,
 		ClientID: clientID,
 		Body:     body,
 	}
 
-	msgBytes, err := SerializeMessage(msg)
+	frames, err := EncodeWorkerMessage(msg, w.getFraming())
 	if err != nil {
-		return fmt.Errorf("failed to serialize REPLY message: %w", err)
+		return fmt.Errorf("failed to serialize %s message: %w", name, err)
 	}
 
-	err = w.socket.Send(zmq4.NewMsgFrom([]byte(""), msgBytes))
+	err = socket.Send(zmq4.NewMsgFrom(frames...))
 	if err != nil {
-		return fmt.Errorf("failed to send REPLY message: %w", err)
+		return fmt.Errorf("failed to send %s message: %w", name, err)
 	}
 
 	w.logger.Debug().
 		Str("client_id", clientID).
 		Int("body_size", len(body)).
-		Msg("Sent REPLY message to broker")
+		Msgf("Sent %s message to broker", name)
 
 	return nil
 }
 
 // sendHeartbeat sends heartbeat to broker
 func (w *HermesWorker) sendHeartbeat() error {
-	if w.socket == nil {
-		return fmt.Errorf("socket not initialized")
+	socket, err := w.connectedSocket()
+	if err != nil {
+		return err
 	}
 
 	msg := &WorkerMessage{
@@ -460,12 +749,12 @@ func (w *HermesWorker) sendHeartbeat() error {
 		Command:  HERMES_HEARTBEAT,
 	}
 
-	msgBytes, err := SerializeMessage(msg)
+	frames, err := EncodeWorkerMessage(msg, w.getFraming())
 	if err != nil {
 		return fmt.Errorf("failed to serialize HEARTBEAT message: %w", err)
 	}
 
-	err = w.socket.Send(zmq4.NewMsgFrom([]byte(""), msgBytes))
+	err = socket.Send(zmq4.NewMsgFrom(frames...))
 	if err != nil {
 		return fmt.Errorf("failed to send HEARTBEAT message: %w", err)
 	}
@@ -473,7 +762,7 @@ func (w *HermesWorker) sendHeartbeat() error {
 	// Update heartbeat statistics
 	w.mutex.Lock()
 	w.stats.HeartbeatsSent++
-	w.stats.LastHeartbeatSent = time.Now()
+	w.stats.LastHeartbeatSent = w.clock.Now()
 	w.mutex.Unlock()
 
 	w.logger.Debug().
@@ -484,8 +773,9 @@ func (w *HermesWorker) sendHeartbeat() error {
 
 // sendDisconnect sends disconnect message to broker
 func (w *HermesWorker) sendDisconnect() error {
-	if w.socket == nil {
-		return nil // Already disconnected
+	socket, err := w.connectedSocket()
+	if err != nil {
+		return err
 	}
 
 	msg := &WorkerMessage{
@@ -493,13 +783,13 @@ func (w *HermesWorker) sendDisconnect() error {
 		Command:  HERMES_DISCONNECT,
 	}
 
-	msgBytes, err := SerializeMessage(msg)
+	frames, err := EncodeWorkerMessage(msg, w.getFraming())
 	if err != nil {
 		w.logger.Error().Err(err).Msg("Failed to serialize DISCONNECT message")
 		return nil // Don't fail shutdown on serialization error
 	}
 
-	err = w.socket.Send(zmq4.NewMsgFrom([]byte(""), msgBytes))
+	err = socket.Send(zmq4.NewMsgFrom(frames...))
 	if err != nil {
 		w.logger.Error().Err(err).Msg("Failed to send DISCONNECT message")
 		return nil // Don't fail shutdown on send error
@@ -541,13 +831,12 @@ func (w *HermesWorker) reconnectToBroker() {
 		Msg("Reconnecting to broker with exponential backoff")
 
 	// Close existing socket
-	if w.socket != nil {
-		w.socket.Close()
-		w.socket = nil
-	}
+	w.closeSocket()
 
 	// Wait before reconnecting
-	time.Sleep(actualDelay)
+	if !sleepContext(w.ctx, w.clock, actualDelay) {
+		return
+	}
 
 	// Attempt to reconnect
 	if err := w.connect(); err != nil {
@@ -610,6 +899,41 @@ func (w *HermesWorker) IsConnected() bool {
 	return w.state == WorkerStateReady || w.state == WorkerStateWorking
 }
 
+// getSocket returns the socket to the current broker, nil while disconnected
+func (w *HermesWorker) getSocket() Socket {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.socket
+}
+
+// connectedSocket returns the socket to send on, ErrNotConnected while there is none
+func (w *HermesWorker) connectedSocket() (Socket, error) {
+	if socket := w.getSocket(); socket != nil {
+		return socket, nil
+	}
+	return nil, ErrNotConnected
+}
+
+// closeSocket closes the socket to the current broker
+func (w *HermesWorker) closeSocket() error {
+	w.mutex.Lock()
+	socket := w.socket
+	w.socket = nil
+	w.mutex.Unlock()
+
+	if socket == nil {
+		return nil
+	}
+	return socket.Close()
+}
+
+// getFraming returns how messages to the broker are framed
+func (w *HermesWorker) getFraming() Framing {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.framing
+}
+
 // GetService returns the service name this worker provides
 func (w *HermesWorker) GetService() string {
 	return w.service
@@ -662,13 +986,19 @@ func (w *HermesWorker) socketReader() {
 		case <-w.shutdownCh:
 			return
 		default:
-			if w.socket == nil {
-				time.Sleep(w.reconnect)
+			socket := w.getSocket()
+			if socket == nil {
+				// Wait for the reconnect to hand over a socket, polling in case it is missed
+				select {
+				case <-w.socketCh:
+				case <-w.clock.After(w.reconnect):
+				case <-w.ctx.Done():
+				}
 				continue
 			}
 
 			// Receive message from broker (non-blocking)
-			rawMsg, err := w.socket.Recv()
+			rawMsg, err := socket.Recv()
 			if err != nil {
 				if w.isTemporaryError(err) {
 					w.mutex.RLock()
@@ -691,6 +1021,14 @@ func (w *HermesWorker) socketReader() {
 				continue
 			}
 			
+			// Cancels take effect at once rather than waiting behind the request they cancel
+			if len(rawMsg.Frames) > 1 && len(rawMsg.Frames[0]) == 0 {
+				if msg, _, err := DecodeWorkerMessage(rawMsg.Frames[1:]); err == nil && msg.Command == HERMES_CANCEL {
+					w.handleCancel(msg.ClientID, string(msg.Body))
+					continue
+				}
+			}
+
 			// Send message to processor
 			select {
 			case w.messagesCh <- rawMsg:
@@ -751,7 +1089,16 @@ func (w *HermesWorker) processMessage(rawMsg zmq4.Msg) error {
 	}
 
 	// Reset liveness on any valid message
-	w.liveness = MDP_HEARTBEAT_LIVENESS
+	w.mutex.Lock()
+	w.liveness = workerLiveness
+	w.mutex.Unlock()
+
+	if err := checkFrameSizes(msg[1:], w.getPayloadLimits().MaxFrameSize); err != nil {
+		if command, clientID, body, ok := workerEnvelope(msg[1:]); ok && command == HERMES_REQUEST {
+			return w.refuseRequest(clientID, body, err)
+		}
+		return fmt.Errorf("dropped message from broker: %w", err)
+	}
 
 	// Parse and handle message
 	return w.handleMessage(msg[1:])
@@ -762,10 +1109,9 @@ func (w *HermesWorker) heartbeatManager() {
 	w.logger.Info().Msg("Starting worker heartbeat manager")
 	defer w.logger.Info().Msg("Worker heartbeat manager stopped")
 
-	// Add jitter to prevent synchronization issues (±5 seconds)
-	jitter := time.Duration(rand.Intn(10000)-5000) * time.Millisecond
-	interval := w.heartbeat + jitter
-	ticker := time.NewTicker(interval)
+	// Add jitter to prevent synchronization issues
+	interval := w.heartbeat + w.heartbeatJitter()
+	ticker := w.clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -774,36 +1120,61 @@ func (w *HermesWorker) heartbeatManager() {
 			return
 		case <-w.shutdownCh:
 			return
-		case t := <-ticker.C:
-			w.heartbeatCh <- t
-			w.mutex.RLock()
+		case t := <-ticker.C():
+			select {
+			case w.heartbeatCh <- t:
+			default:
+			}
+
+			// Every interval without a message from the broker costs a life
+			w.mutex.Lock()
 			state := w.state
-			w.mutex.RUnlock()
+			connected := state == WorkerStateReady || state == WorkerStateWorking
+			if connected {
+				w.liveness--
+			}
+			liveness := w.liveness
+			w.mutex.Unlock()
 
-			if state == WorkerStateReady && w.socket != nil {
+			// A reconnection already under way is not asked for again, it would tear
+			// down the connection it is about to make
+			if connected && liveness <= 0 {
+				w.logger.Warn().
+					Str("broker", w.GetBroker()).
+					Msg("Broker silent for too long - reconnecting")
+				select {
+				case w.reconnectCh <- struct{}{}:
+				default:
+				}
+			} else if state == WorkerStateReady {
 				if err := w.sendHeartbeat(); err != nil {
 					select {
 					case w.errorsCh <- fmt.Errorf("heartbeat failed: %w", err):
 					default:
 					}
-					w.liveness--
-					if w.liveness <= 0 {
-						select {
-						case w.reconnectCh <- struct{}{}:
-						default:
-						}
-					}
 				}
 			}
 			
 			// Update ticker with new jitter
-			jitter := time.Duration(rand.Intn(10000)-5000) * time.Millisecond
-			newInterval := w.heartbeat + jitter
+			newInterval := w.heartbeat + w.heartbeatJitter()
 			ticker.Reset(newInterval)
 		}
 	}
 }
 
+// heartbeatJitter returns a random offset of up to ±5 seconds, and at most a quarter
+// of the heartbeat interval so short intervals stay positive
+func (w *HermesWorker) heartbeatJitter() time.Duration {
+	spread := 5 * time.Second
+	if w.heartbeat/4 < spread {
+		spread = w.heartbeat / 4
+	}
+	if spread <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(2*spread))) - spread
+}
+
 // errorHandler handles errors from errorsCh
 func (w *HermesWorker) errorHandler() {
 	w.logger.Info().Msg("Starting worker error handler")
@@ -829,6 +1200,86 @@ func (w *HermesWorker) errorHandler() {
 	}
 }
 
+// reconnectManager reconnects whenever a reconnection is requested on reconnectCh
+func (w *HermesWorker) reconnectManager() {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.shutdownCh:
+			return
+		case <-w.reconnectCh:
+			w.reconnectToBroker()
+		}
+	}
+}
+
+// primaryMonitor probes the primary broker while the worker is connected to another one
+// and moves back to it once it accepts connections again
+func (w *HermesWorker) primaryMonitor() {
+	w.mutex.RLock()
+	interval := w.primaryCheck
+	failover := len(w.brokers) > 1
+	w.mutex.RUnlock()
+
+	if !failover || interval <= 0 {
+		return
+	}
+
+	ticker := w.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.shutdownCh:
+			return
+		case <-ticker.C():
+			w.mutex.RLock()
+			primary, broker := w.brokers[0], w.broker
+			w.mutex.RUnlock()
+
+			if broker == primary || !w.IsConnected() || !w.transport.Reachable(primary, interval) {
+				continue
+			}
+
+			w.logger.Info().
+				Str("primary", primary).
+				Str("broker", broker).
+				Msg("Primary broker is back - failing back")
+
+			w.switchToPrimary()
+		}
+	}
+}
+
+// switchToPrimary leaves the current broker and connects again, which prefers the primary
+func (w *HermesWorker) switchToPrimary() {
+	w.mutex.Lock()
+	if w.state != WorkerStateReady {
+		w.mutex.Unlock()
+		return // Busy with a request or already reconnecting, try at the next probe
+	}
+	w.state = WorkerStateReconnecting
+	w.mutex.Unlock()
+
+	// Leave the current broker cleanly so it stops routing to this worker
+	w.sendDisconnect()
+	w.closeSocket()
+
+	if err := w.connect(); err != nil {
+		w.logger.Error().Err(err).Msg("Failed to reconnect after leaving broker")
+		w.mutex.Lock()
+		w.state = WorkerStateDisconnected
+		w.mutex.Unlock()
+		select {
+		case w.reconnectCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // statsManager manages statistics updates from statsCh
 func (w *HermesWorker) statsManager() {
 	w.logger.Info().Msg("Starting worker stats manager")
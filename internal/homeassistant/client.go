@@ -0,0 +1,447 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package homeassistant
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lucas/internal"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"lucas/internal/logger"
+	"lucas/internal/websocket"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrAuthInvalid is returned when Home Assistant rejects the access token
+var ErrAuthInvalid = errors.New("home assistant rejected the access token")
+
+// EntityState is the state object of a Home Assistant entity
+type EntityState struct {
+	EntityID    string                 `json:"entity_id"`
+	State       string                 `json:"state"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	LastChanged string                 `json:"last_changed,omitempty"`
+}
+
+// Domain returns the domain part of the entity ID
+func (s EntityState) Domain() string {
+	domain, _, _ := strings.Cut(s.EntityID, ".")
+	return domain
+}
+
+// FriendlyName returns the friendly_name attribute, falling back to the entity ID
+func (s EntityState) FriendlyName() string {
+	if name, ok := s.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return s.EntityID
+}
+
+// wsMessage is a message on the Home Assistant WebSocket API
+type wsMessage struct {
+	ID          int             `json:"id,omitempty"`
+	Type        string          `json:"type"`
+	AccessToken string          `json:"access_token,omitempty"`
+	EventType   string          `json:"event_type,omitempty"`
+	Success     *bool           `json:"success,omitempty"`
+	Event       json.RawMessage `json:"event,omitempty"`
+	Message     string          `json:"message,omitempty"`
+}
+
+// stateChangedEvent is the event body of a state_changed event
+type stateChangedEvent struct {
+	EventType string `json:"event_type"`
+	Data      struct {
+		EntityID string       `json:"entity_id"`
+		NewState *EntityState `json:"new_state"`
+	} `json:"data"`
+}
+
+// HAClient represents a client for the Home Assistant REST and WebSocket APIs
+type HAClient struct {
+	baseURL      string
+	token        string
+	httpClient   *http.Client
+	states       map[string]EntityState
+	listeners    map[string][]func(EntityState)
+	conn         *websocket.Conn
+	mutex        sync.Mutex
+	started      bool
+	stopCh       chan struct{}
+	closeOnce    sync.Once
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+	debugMode    bool
+	testMode     bool
+	logger       zerolog.Logger
+}
+
+// NewHAClient creates a new Home Assistant client for a base URL and long-lived token
+func NewHAClient(baseURL, token string, options internal.FnModeOptions) *HAClient {
+	client := &HAClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		states:       make(map[string]EntityState),
+		listeners:    make(map[string][]func(EntityState)),
+		stopCh:       make(chan struct{}),
+		reconnectMin: time.Second,
+		reconnectMax: 30 * time.Second,
+		debugMode:    options.Debug,
+		testMode:     options.Test,
+		logger:       logger.New(),
+	}
+	if options.Debug {
+		logger.SetLevel(logger.LOG_DEBUG)
+	}
+
+	return client
+}
+
+// Start subscribes to state_changed events in the background, reconnecting as needed
+func (c *HAClient) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.started || c.testMode {
+		return
+	}
+	c.started = true
+	go c.eventLoop()
+}
+
+// Subscribe registers a callback for state changes of an entity
+func (c *HAClient) Subscribe(entityID string, callback func(EntityState)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.listeners[entityID] = append(c.listeners[entityID], callback)
+}
+
+// States returns all entity states
+func (c *HAClient) States() ([]EntityState, error) {
+	if c.testMode {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		states := make([]EntityState, 0, len(c.states))
+		for _, state := range c.states {
+			states = append(states, state)
+		}
+		return states, nil
+	}
+
+	var states []EntityState
+	if err := c.rest("GET", StatesPath, nil, &states); err != nil {
+		return nil, err
+	}
+
+	for _, state := range states {
+		c.updateState(state)
+	}
+	return states, nil
+}
+
+// State returns the state of an entity, served from the event cache when possible
+func (c *HAClient) State(entityID string) (*EntityState, error) {
+	c.mutex.Lock()
+	state, cached := c.states[entityID]
+	if c.testMode && !cached {
+		state = EntityState{EntityID: entityID, State: "off", Attributes: map[string]interface{}{}}
+		c.states[entityID] = state
+		cached = true
+	}
+	c.mutex.Unlock()
+	if cached {
+		return &state, nil
+	}
+
+	if err := c.rest("GET", StatesPath+"/"+url.PathEscape(entityID), nil, &state); err != nil {
+		return nil, err
+	}
+	c.updateState(state)
+	return &state, nil
+}
+
+// CallService calls a Home Assistant service and returns the states it changed
+func (c *HAClient) CallService(domain, service string, data map[string]interface{}) ([]EntityState, error) {
+	if c.testMode {
+		return c.simulateService(domain, service, data), nil
+	}
+
+	var changed []EntityState
+	path := fmt.Sprintf("%s/%s/%s", ServicesPath, url.PathEscape(domain), url.PathEscape(service))
+	if err := c.rest("POST", path, data, &changed); err != nil {
+		return nil, err
+	}
+
+	for _, state := range changed {
+		c.updateState(state)
+	}
+	return changed, nil
+}
+
+// Close stops the event subscription
+func (c *HAClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stopCh)
+		c.mutex.Lock()
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.mutex.Unlock()
+	})
+}
+
+// rest performs an authenticated REST call
+func (c *HAClient) rest(method, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return ErrAuthInvalid
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("not found: %s", path)
+	case resp.StatusCode >= 300:
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// eventLoop keeps a state_changed subscription open until Close
+func (c *HAClient) eventLoop() {
+	backoff := c.reconnectMin
+	for {
+		connectedAt := time.Now()
+		err := c.subscribe()
+		if errors.Is(err, ErrAuthInvalid) {
+			c.logger.Error().
+				Str("url", c.baseURL).
+				Msg("Home Assistant rejected the access token, state updates disabled")
+			return
+		}
+
+		select {
+		case <-c.stopCh:
+			return
+		default:
+		}
+
+		if err != nil {
+			c.logger.Warn().
+				Err(err).
+				Dur("retry_in", backoff).
+				Msg("Home Assistant event subscription lost")
+		}
+
+		// A long lived connection starts the backoff over
+		if time.Since(connectedAt) > c.reconnectMax {
+			backoff = c.reconnectMin
+		}
+
+		select {
+		case <-c.stopCh:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > c.reconnectMax {
+			backoff = c.reconnectMax
+		}
+	}
+}
+
+// subscribe authenticates, subscribes and reads events until the connection drops
+func (c *HAClient) subscribe() error {
+	conn, _, err := websocket.Dial(c.websocketURL(), &websocket.DialOptions{Timeout: 10 * time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	c.mutex.Lock()
+	select {
+	case <-c.stopCh:
+		c.mutex.Unlock()
+		return nil
+	default:
+	}
+	c.conn = conn
+	c.mutex.Unlock()
+
+	if err := c.authenticate(conn); err != nil {
+		return err
+	}
+
+	if err := conn.WriteJSON(wsMessage{ID: 1, Type: MessageSubscribe, EventType: EventStateChanged}); err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	// Resynchronise states that changed while disconnected
+	if _, err := c.States(); err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to refresh Home Assistant states")
+	}
+
+	c.logger.Info().
+		Str("url", c.baseURL).
+		Msg("Subscribed to Home Assistant state changes")
+
+	for {
+		var message wsMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return err
+		}
+
+		switch message.Type {
+		case MessageResult:
+			if message.Success != nil && !*message.Success {
+				return fmt.Errorf("subscription rejected")
+			}
+		case MessageEvent:
+			var event stateChangedEvent
+			if err := json.Unmarshal(message.Event, &event); err != nil {
+				continue
+			}
+			if event.EventType == EventStateChanged && event.Data.NewState != nil {
+				c.updateState(*event.Data.NewState)
+			}
+		}
+	}
+}
+
+// authenticate runs the auth_required / auth / auth_ok handshake
+func (c *HAClient) authenticate(conn *websocket.Conn) error {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var message wsMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		return fmt.Errorf("failed to read auth request: %w", err)
+	}
+	if message.Type != MessageAuthRequired {
+		return fmt.Errorf("unexpected message %s", message.Type)
+	}
+
+	if err := conn.WriteJSON(wsMessage{Type: MessageAuth, AccessToken: c.token}); err != nil {
+		return fmt.Errorf("failed to send auth: %w", err)
+	}
+
+	if err := conn.ReadJSON(&message); err != nil {
+		return fmt.Errorf("failed to read auth result: %w", err)
+	}
+	switch message.Type {
+	case MessageAuthOK:
+		return nil
+	case MessageAuthInvalid:
+		return ErrAuthInvalid
+	default:
+		return fmt.Errorf("unexpected message %s", message.Type)
+	}
+}
+
+// updateState caches a state and notifies listeners
+func (c *HAClient) updateState(state EntityState) {
+	c.mutex.Lock()
+	c.states[state.EntityID] = state
+	listeners := append([]func(EntityState){}, c.listeners[state.EntityID]...)
+	c.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
+// websocketURL derives the WebSocket API URL from the base URL
+func (c *HAClient) websocketURL() string {
+	wsURL := c.baseURL + WebSocketPath
+	if strings.HasPrefix(wsURL, "https://") {
+		return "wss://" + strings.TrimPrefix(wsURL, "https://")
+	}
+	return "ws://" + strings.TrimPrefix(wsURL, "http://")
+}
+
+// simulateService applies a service call to the cached states in test mode
+func (c *HAClient) simulateService(domain, service string, data map[string]interface{}) []EntityState {
+	entityID, _ := data["entity_id"].(string)
+	current, _ := c.State(entityID)
+
+	state := EntityState{EntityID: entityID, State: current.State, Attributes: map[string]interface{}{}}
+	for key, value := range current.Attributes {
+		state.Attributes[key] = value
+	}
+
+	switch service {
+	case "turn_on", "open_cover", "unlock":
+		state.State = "on"
+	case "turn_off", "close_cover", "lock":
+		state.State = "off"
+	case "toggle":
+		if state.State == "on" {
+			state.State = "off"
+		} else {
+			state.State = "on"
+		}
+	case "volume_set":
+		state.Attributes["volume_level"] = data["volume_level"]
+	case "volume_mute":
+		state.Attributes["is_volume_muted"] = data["is_volume_muted"]
+	}
+	state.LastChanged = time.Now().UTC().Format(time.RFC3339)
+
+	c.logger.Info().
+		Str("domain", domain).
+		Str("service", service).
+		Str("entity_id", entityID).
+		Msg("Test mode: Simulating Home Assistant service call")
+
+	c.updateState(state)
+	return []EntityState{state}
+}
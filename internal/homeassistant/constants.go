@@ -0,0 +1,83 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package homeassistant
+
+import "lucas/internal/device"
+
+// Home Assistant API paths
+const (
+	StatesPath    = "/api/states"
+	ServicesPath  = "/api/services"
+	WebSocketPath = "/api/websocket"
+)
+
+// WebSocket API message types
+const (
+	MessageAuthRequired = "auth_required"
+	MessageAuth         = "auth"
+	MessageAuthOK       = "auth_ok"
+	MessageAuthInvalid  = "auth_invalid"
+	MessageSubscribe    = "subscribe_events"
+	MessageResult       = "result"
+	MessageEvent        = "event"
+	EventStateChanged   = "state_changed"
+)
+
+// OptionEntityID is the device option naming the Home Assistant entity
+const OptionEntityID = "entity_id"
+
+// StateUnavailable is reported by Home Assistant when an entity cannot be reached
+const StateUnavailable = "unavailable"
+
+// ControlActionCallService calls a service in the entity's own domain
+const ControlActionCallService device.ControlAction = "call_service"
+
+// powerServices lists the services used for power actions in each domain
+type powerServices struct {
+	on     string
+	off    string
+	toggle string
+}
+
+// domainPowerServices overrides the turn_on/turn_off/toggle defaults
+var domainPowerServices = map[string]powerServices{
+	"cover": {on: "open_cover", off: "close_cover", toggle: "toggle"},
+	"lock":  {on: "unlock", off: "lock"},
+	"scene": {on: "turn_on"},
+}
+
+// defaultPowerServices are shared by light, switch, fan, media_player and most other domains
+var defaultPowerServices = powerServices{on: "turn_on", off: "turn_off", toggle: "toggle"}
+
+// standbyStates are entity states reported as standby by power_status
+var standbyStates = map[string]bool{
+	"off":            true,
+	"standby":        true,
+	"closed":         true,
+	"unknown":        true,
+	StateUnavailable: true,
+}
+
+// Capabilities returns the Lucas capabilities for an entity domain
+func Capabilities(domain string) []string {
+	switch domain {
+	case "media_player":
+		return []string{"power_control", "audio_control", "media_control"}
+	case "light", "switch", "fan", "input_boolean", "cover", "lock", "climate", "scene", "script":
+		return []string{"power_control"}
+	default:
+		return []string{"state"}
+	}
+}
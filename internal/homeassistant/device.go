@@ -0,0 +1,310 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package homeassistant
+
+import (
+	"fmt"
+	"lucas/internal/device"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HAEntity implements the Device interface for a single Home Assistant entity
+type HAEntity struct {
+	client   *HAClient
+	entityID string
+	domain   string
+	info     device.DeviceInfo
+	mutex    sync.RWMutex
+}
+
+// NewHAEntity creates a device for an entity ID such as light.living_room
+func NewHAEntity(client *HAClient, address, entityID string) (*HAEntity, error) {
+	domain, object, found := strings.Cut(entityID, ".")
+	if !found || domain == "" || object == "" {
+		return nil, fmt.Errorf("invalid entity_id: %q", entityID)
+	}
+
+	entity := &HAEntity{
+		client:   client,
+		entityID: entityID,
+		domain:   domain,
+		info: device.DeviceInfo{
+			ID:           "", // Will be set from configuration
+			Name:         "", // Will be set from configuration
+			Type:         "homeassistant",
+			Model:        "Home Assistant " + domain,
+			Address:      address,
+			Status:       "unknown", // Updated from state_changed events
+			Capabilities: Capabilities(domain),
+		},
+	}
+	client.Subscribe(entityID, entity.onStateChanged)
+
+	return entity, nil
+}
+
+// GetDeviceInfo returns information about this entity
+func (e *HAEntity) GetDeviceInfo() device.DeviceInfo {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.info
+}
+
+// EntityID returns the Home Assistant entity ID
+func (e *HAEntity) EntityID() string {
+	return e.entityID
+}
+
+// Process handles JSON action requests and translates them to service calls
+func (e *HAEntity) Process(actionJSON []byte) (*device.ActionResponse, error) {
+	request, err := device.ParseActionRequest(actionJSON)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	switch request.Type {
+	case device.ActionTypeRemote:
+		return e.processRemoteAction(request)
+	case device.ActionTypeControl:
+		return e.processControlAction(request)
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported action type: %s", request.Type),
+		}, nil
+	}
+}
+
+// processRemoteAction maps remote actions to services of the entity domain
+func (e *HAEntity) processRemoteAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	services, exists := domainPowerServices[e.domain]
+	if !exists {
+		services = defaultPowerServices
+	}
+
+	var service string
+	data := map[string]interface{}{}
+
+	switch device.RemoteAction(request.Action) {
+	case device.RemoteActionPower:
+		service = services.toggle
+	case device.RemoteActionPowerOn:
+		service = services.on
+	case device.RemoteActionPowerOff:
+		service = services.off
+	}
+
+	if e.domain == "media_player" {
+		switch device.RemoteAction(request.Action) {
+		case device.RemoteActionVolumeUp:
+			service = "volume_up"
+		case device.RemoteActionVolumeDown:
+			service = "volume_down"
+		case device.RemoteActionChannelUp:
+			service = "media_next_track"
+		case device.RemoteActionChannelDown:
+			service = "media_previous_track"
+		case device.RemoteActionMute:
+			muted := false
+			if state, err := e.client.State(e.entityID); err == nil {
+				muted, _ = state.Attributes["is_volume_muted"].(bool)
+			}
+			service = "volume_mute"
+			data["is_volume_muted"] = !muted
+		}
+	}
+
+	if service == "" {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported remote action for %s: %s", e.domain, request.Action),
+		}, nil
+	}
+
+	if _, err := e.callService(service, data); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("remote request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    fmt.Sprintf("Remote action '%s' executed successfully", request.Action),
+	}, nil
+}
+
+// processControlAction handles state queries and parameterised service calls
+func (e *HAEntity) processControlAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	controlAction := device.ControlAction(request.Action)
+
+	switch controlAction {
+	case device.ControlActionPowerStatus, device.ControlActionSystemInfo, device.ControlActionVolumeInfo:
+		state, err := e.client.State(e.entityID)
+		if controlAction == device.ControlActionPowerStatus {
+			status := device.PowerStatusStandby
+			if err == nil && !standbyStates[state.State] {
+				status = device.PowerStatusActive
+			}
+			return &device.ActionResponse{
+				Success: true,
+				Data:    device.PowerStatusData(status),
+			}, nil
+		}
+		if err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("control request failed: %v", err),
+			}, nil
+		}
+		if controlAction == device.ControlActionVolumeInfo {
+			return &device.ActionResponse{
+				Success: true,
+				Data: map[string]interface{}{
+					"volume_level":    state.Attributes["volume_level"],
+					"is_volume_muted": state.Attributes["is_volume_muted"],
+				},
+			}, nil
+		}
+		return &device.ActionResponse{
+			Success: true,
+			Data:    state,
+		}, nil
+	}
+
+	service, data, err := e.buildServiceCall(controlAction, request.Parameters)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid parameters: %v", err),
+		}, nil
+	}
+
+	changed, err := e.callService(service, data)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("control request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    changed,
+	}, nil
+}
+
+// buildServiceCall builds the service and data for parameterised control actions
+func (e *HAEntity) buildServiceCall(action device.ControlAction, params map[string]interface{}) (string, map[string]interface{}, error) {
+	switch action {
+	case device.ControlActionSetVolume:
+		if e.domain != "media_player" {
+			return "", nil, fmt.Errorf("set_volume is only supported by media_player entities")
+		}
+		level, err := volumeParam(params["volume"])
+		if err != nil {
+			return "", nil, err
+		}
+		return "volume_set", map[string]interface{}{"volume_level": float64(level) / 100}, nil
+
+	case device.ControlActionSetMute:
+		if e.domain != "media_player" {
+			return "", nil, fmt.Errorf("set_mute is only supported by media_player entities")
+		}
+		var mute bool
+		switch s := params["status"].(type) {
+		case bool:
+			mute = s
+		case string:
+			parsed, err := strconv.ParseBool(s)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid status parameter: %s", s)
+			}
+			mute = parsed
+		default:
+			return "", nil, fmt.Errorf("status parameter is required for set_mute action")
+		}
+		return "volume_mute", map[string]interface{}{"is_volume_muted": mute}, nil
+
+	case ControlActionCallService:
+		service, _ := params["service"].(string)
+		if domain, name, found := strings.Cut(service, "."); found {
+			// Only services of the entity's own domain may be called through it
+			if domain != e.domain {
+				return "", nil, fmt.Errorf("service %s is outside the %s domain", service, e.domain)
+			}
+			service = name
+		}
+		if service == "" {
+			return "", nil, fmt.Errorf("service parameter is required for call_service action")
+		}
+		data := map[string]interface{}{}
+		if extra, ok := params["data"].(map[string]interface{}); ok {
+			for key, value := range extra {
+				data[key] = value
+			}
+		}
+		return service, data, nil
+	}
+
+	return "", nil, fmt.Errorf("unsupported control action: %s", action)
+}
+
+// callService calls a service of the entity domain targeting this entity
+func (e *HAEntity) callService(service string, data map[string]interface{}) ([]EntityState, error) {
+	data["entity_id"] = e.entityID
+	return e.client.CallService(e.domain, service, data)
+}
+
+// onStateChanged updates the device status from Home Assistant state
+func (e *HAEntity) onStateChanged(state EntityState) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if state.State == StateUnavailable {
+		e.info.Status = "offline"
+	} else {
+		e.info.Status = "online"
+	}
+}
+
+// volumeParam validates a 0-100 volume parameter
+func volumeParam(volume interface{}) (int, error) {
+	var level int
+	switch v := volume.(type) {
+	case int:
+		level = v
+	case float64:
+		level = int(v)
+	case string:
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid volume parameter: %s", v)
+		}
+		level = parsed
+	case nil:
+		return 0, fmt.Errorf("volume parameter is required for set_volume action")
+	default:
+		return 0, fmt.Errorf("invalid volume parameter type")
+	}
+	if level < 0 || level > 100 {
+		return 0, fmt.Errorf("volume must be between 0 and 100")
+	}
+	return level, nil
+}
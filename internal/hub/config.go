@@ -16,6 +16,7 @@ package hub
 
 import (
 	"fmt"
+	"lucas/internal/hermes"
 	"os"
 	"strings"
 
@@ -35,6 +36,22 @@ type GatewayConfig struct {
 	Endpoint     string `yaml:"endpoint"`      // ZMQ endpoint (required)
 	HTTPEndpoint string `yaml:"http_endpoint"` // HTTP API endpoint (optional - auto-discovered if not set)
 	PublicKey    string `yaml:"public_key"`
+	Framing      string `yaml:"framing,omitempty"` // Hermes framing: mdp (default) or json for gateways without multipart MDP
+
+	// Failover lists further gateways, in order of preference, tried when the primary is unreachable
+	Failover []GatewayEndpoint `yaml:"failover,omitempty"`
+}
+
+// GatewayEndpoint is a gateway the hub can connect to, with the Curve key it is paired with
+type GatewayEndpoint struct {
+	Endpoint  string `yaml:"endpoint"`
+	PublicKey string `yaml:"public_key"`
+}
+
+// Endpoints returns the primary gateway followed by the failover gateways, in order of preference
+func (g *GatewayConfig) Endpoints() []GatewayEndpoint {
+	endpoints := []GatewayEndpoint{{Endpoint: g.Endpoint, PublicKey: g.PublicKey}}
+	return append(endpoints, g.Failover...)
 }
 
 // HubConfig contains hub identity and keys
@@ -47,12 +64,14 @@ type HubConfig struct {
 
 // DeviceConfig represents a single device configuration
 type DeviceConfig struct {
-	ID           string   `yaml:"id"`
-	Type         string   `yaml:"type"`
-	Model        string   `yaml:"model"`
-	Address      string   `yaml:"address"`
-	Credential   string   `yaml:"credential"`
-	Capabilities []string `yaml:"capabilities"`
+	ID           string            `yaml:"id"`
+	Type         string            `yaml:"type"`
+	Model        string            `yaml:"model"`
+	Address      string            `yaml:"address"`
+	Credential   string            `yaml:"credential"`
+	MAC          string            `yaml:"mac,omitempty"`
+	Options      map[string]string `yaml:"options,omitempty"`
+	Capabilities []string          `yaml:"capabilities"`
 }
 
 // LoadConfig loads configuration from a YAML file
@@ -84,6 +103,23 @@ func (c *Config) Validate() error {
 	if c.Gateway.PublicKey == "" {
 		return fmt.Errorf("gateway.public_key is required")
 	}
+	if _, err := hermes.ParseFraming(c.Gateway.Framing); err != nil {
+		return fmt.Errorf("gateway.framing: %w", err)
+	}
+
+	gatewayEndpoints := map[string]bool{c.Gateway.Endpoint: true}
+	for i, gateway := range c.Gateway.Failover {
+		if gateway.Endpoint == "" {
+			return fmt.Errorf("gateway.failover[%d].endpoint is required", i)
+		}
+		if gateway.PublicKey == "" {
+			return fmt.Errorf("gateway.failover[%d].public_key is required", i)
+		}
+		if gatewayEndpoints[gateway.Endpoint] {
+			return fmt.Errorf("duplicate gateway endpoint: %s", gateway.Endpoint)
+		}
+		gatewayEndpoints[gateway.Endpoint] = true
+	}
 
 	// Validate hub config
 	if c.Hub.PublicKey == "" {
@@ -238,6 +274,22 @@ func (c *Config) UpdateGatewayInfo(endpoint, publicKey string) {
 	}
 }
 
+// AddFailoverGateway adds a gateway to try after the primary. A gateway that is already
+// configured keeps its place and has its key updated
+func (c *Config) AddFailoverGateway(endpoint, publicKey string) {
+	if endpoint == c.Gateway.Endpoint {
+		c.UpdateGatewayInfo("", publicKey)
+		return
+	}
+	for i := range c.Gateway.Failover {
+		if c.Gateway.Failover[i].Endpoint == endpoint {
+			c.Gateway.Failover[i].PublicKey = publicKey
+			return
+		}
+	}
+	c.Gateway.Failover = append(c.Gateway.Failover, GatewayEndpoint{Endpoint: endpoint, PublicKey: publicKey})
+}
+
 // GetHTTPEndpoint returns the HTTP endpoint, deriving it from ZMQ endpoint if not explicitly set
 func (c *Config) GetHTTPEndpoint() string {
 	if c.Gateway.HTTPEndpoint != "" {
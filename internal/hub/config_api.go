@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 )
@@ -27,6 +28,10 @@ type ConfigAPIServer struct {
 	daemon *Daemon
 	server *http.Server
 	logger zerolog.Logger
+
+	// Bravia PIN pairings awaiting a PIN, keyed by device ID
+	pairingMutex sync.Mutex
+	pairings     map[string]braviaPairing
 }
 
 // DeviceConfigRequest represents a device configuration request
@@ -45,8 +50,9 @@ type DeviceConfigResponse struct {
 // NewConfigAPIServer creates a new configuration API server
 func NewConfigAPIServer(daemon *Daemon, port int) *ConfigAPIServer {
 	server := &ConfigAPIServer{
-		daemon: daemon,
-		logger: daemon.logger.With().Str("component", "config_api").Logger(),
+		daemon:   daemon,
+		logger:   daemon.logger.With().Str("component", "config_api").Logger(),
+		pairings: make(map[string]braviaPairing),
 	}
 
 	router := mux.NewRouter()
@@ -55,6 +61,11 @@ func NewConfigAPIServer(daemon *Daemon, port int) *ConfigAPIServer {
 	router.HandleFunc("/devices/configure", server.handleDeviceConfigure).Methods("POST")
 	router.HandleFunc("/devices/list", server.handleDeviceList).Methods("GET")
 	router.HandleFunc("/devices/reload", server.handleDeviceReload).Methods("POST")
+	router.HandleFunc("/devices/discover", server.handleDeviceDiscover).Methods("GET")
+	router.HandleFunc("/devices/discovered/{id}/adopt", server.handleDeviceAdopt).Methods("POST")
+	router.HandleFunc("/devices/{id}/state", server.handleDeviceState).Methods("GET")
+	router.HandleFunc("/devices/{id}/pair/start", server.handlePairStart).Methods("POST")
+	router.HandleFunc("/devices/{id}/pair/complete", server.handlePairComplete).Methods("POST")
 	
 	// Health check
 	router.HandleFunc("/health", server.handleHealth).Methods("GET")
@@ -139,6 +150,63 @@ func (s *ConfigAPIServer) handleDeviceList(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// handleDeviceState returns the status and pushed state of a device
+func (s *ConfigAPIServer) handleDeviceState(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+
+	info, err := s.daemon.deviceManager.GetDeviceInfo(deviceID)
+	if err != nil {
+		s.sendError(w, http.StatusNotFound, "Device not found", err)
+		return
+	}
+
+	s.sendSuccess(w, "Device state retrieved successfully", map[string]interface{}{
+		"device_id": deviceID,
+		"status":    info.Status,
+		"state":     s.daemon.deviceManager.GetDeviceState(deviceID),
+	})
+}
+
+// handleDeviceDiscover searches the LAN for devices that can be adopted
+func (s *ConfigAPIServer) handleDeviceDiscover(w http.ResponseWriter, r *http.Request) {
+	discovered, err := s.daemon.deviceManager.DiscoverDevices()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, "Failed to discover devices", err)
+		return
+	}
+
+	s.sendSuccess(w, "Device discovery finished", map[string]interface{}{
+		"discovered": discovered,
+		"count":      len(discovered),
+	})
+}
+
+// handleDeviceAdopt adds a discovered device to the configuration
+func (s *ConfigAPIServer) handleDeviceAdopt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DeviceID   string `json:"device_id"`
+		Credential string `json:"credential"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.sendError(w, http.StatusBadRequest, "Invalid JSON format", err)
+			return
+		}
+	}
+
+	config, err := s.daemon.deviceManager.AdoptDevice(mux.Vars(r)["id"], req.DeviceID, req.Credential)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, "Failed to adopt device", err)
+		return
+	}
+
+	s.sendSuccess(w, "Device adopted successfully", map[string]interface{}{
+		"device_id": config.ID,
+		"type":      config.Type,
+		"address":   config.Address,
+	})
+}
+
 // handleDeviceReload reloads devices from current configuration
 func (s *ConfigAPIServer) handleDeviceReload(w http.ResponseWriter, r *http.Request) {
 	s.logger.Info().Msg("Device reload requested")
@@ -155,10 +223,18 @@ func (s *ConfigAPIServer) handleDeviceReload(w http.ResponseWriter, r *http.Requ
 
 // handleHealth returns the health status of the hub
 func (s *ConfigAPIServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	var gateways []string
+	for _, gateway := range s.daemon.config.Gateway.Endpoints() {
+		gateways = append(gateways, gateway.Endpoint)
+	}
+
 	s.sendSuccess(w, "Hub is healthy", map[string]interface{}{
-		"status":       "healthy",
-		"device_count": len(s.daemon.config.Devices),
-		"hub_id":       s.daemon.config.Hub.ID,
+		"status":            "healthy",
+		"device_count":      len(s.daemon.config.Devices),
+		"hub_id":            s.daemon.config.Hub.ID,
+		"gateways":          gateways,
+		"gateway_connected": s.daemon.workerService.IsConnected(),
+		"active_gateway":    s.daemon.workerService.ActiveGateway(),
 	})
 }
 
@@ -0,0 +1,89 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hub
+
+import (
+	"fmt"
+	"sync"
+)
+
+// deviceConfigStore writes device learned settings back into the hub configuration
+type deviceConfigStore struct {
+	dm       *DeviceManager
+	deviceID string
+}
+
+// configStoreMutex serialises configuration writes made by devices
+var configStoreMutex sync.Mutex
+
+// SetCredential replaces the credential of the device and saves the configuration
+func (s *deviceConfigStore) SetCredential(credential string) error {
+	return s.update(func(config *DeviceConfig) {
+		config.Credential = credential
+	})
+}
+
+// SetOption stores an option for the device and saves the configuration
+func (s *deviceConfigStore) SetOption(key, value string) error {
+	return s.update(func(config *DeviceConfig) {
+		if config.Options == nil {
+			config.Options = make(map[string]string)
+		}
+		if value == "" {
+			delete(config.Options, key)
+			return
+		}
+		config.Options[key] = value
+	})
+}
+
+// update applies fn to the device configuration and persists the result
+func (s *deviceConfigStore) update(fn func(config *DeviceConfig)) error {
+	configStoreMutex.Lock()
+	defer configStoreMutex.Unlock()
+
+	// Devices and the config API read the configuration under the device manager lock,
+	// so it is held until the change is saved
+	s.dm.mutex.Lock()
+	defer s.dm.mutex.Unlock()
+	config := s.dm.config
+	configPath := s.dm.configPath
+
+	for i := range config.Devices {
+		if config.Devices[i].ID != s.deviceID {
+			continue
+		}
+
+		fn(&config.Devices[i])
+
+		if configPath == "" {
+			s.dm.logger.Warn().
+				Str("device_id", s.deviceID).
+				Msg("No configuration path set, device settings kept in memory only")
+			return nil
+		}
+
+		if err := SaveConfig(config, configPath); err != nil {
+			return fmt.Errorf("failed to save device configuration: %w", err)
+		}
+
+		s.dm.logger.Info().
+			Str("device_id", s.deviceID).
+			Msg("Device configuration updated")
+		return nil
+	}
+
+	return fmt.Errorf("device not found: %s", s.deviceID)
+}
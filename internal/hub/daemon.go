@@ -87,6 +87,7 @@ func NewDaemon(configPath string, debug, testMode bool) (*Daemon, error) {
 
 	// Initialize device manager
 	daemon.deviceManager = NewDeviceManager(config)
+	daemon.deviceManager.SetConfigPath(configPath)
 
 	// Initialize worker service
 	daemon.workerService = NewWorkerService(config, daemon.deviceManager)
@@ -322,7 +323,7 @@ func (d *Daemon) processDeviceAction(msg *GatewayMessage, action json.RawMessage
 	}
 
 	// Process device action with nonce-based deduplication
-	response, err := d.deviceManager.ProcessDeviceActionWithNonce(msg.DeviceID, msg.Nonce, action)
+	response, err := d.deviceManager.ProcessDeviceActionWithNonce(d.ctx, msg.DeviceID, msg.Nonce, action)
 	if err != nil {
 		d.logger.Error().
 			Str("message_id", msg.ID).
@@ -401,6 +402,7 @@ func (d *Daemon) performHealthCheck() {
 	d.logger.Info().
 		Bool("gateway_reachable", gatewayReachable).
 		Bool("worker_connected", d.workerService.IsConnected()).
+		Str("active_gateway", d.workerService.ActiveGateway()).
 		Int("device_count", deviceCount).
 		Msg("Health check completed")
 }
@@ -434,6 +436,7 @@ func (d *Daemon) GetStatus() map[string]interface{} {
 		"debug":             d.debug,
 		"test_mode":         d.testMode,
 		"gateway_connected": d.workerService.IsConnected(),
+		"active_gateway":    d.workerService.ActiveGateway(),
 		"device_count":      d.deviceManager.GetDeviceCount(),
 		"devices":           d.deviceManager.GetAllDeviceInfo(),
 		"nonce_cache":       d.deviceManager.GetNonceStats(),
@@ -469,7 +472,7 @@ func (d *Daemon) ReloadConfig(configPath string) error {
 
 // ProcessDeviceAction provides external access to device action processing
 func (d *Daemon) ProcessDeviceAction(deviceID string, actionJSON []byte) (*device.ActionResponse, error) {
-	return d.deviceManager.ProcessDeviceAction(deviceID, actionJSON)
+	return d.deviceManager.ProcessDeviceAction(d.ctx, deviceID, actionJSON)
 }
 
 // GetDevices returns information about all managed devices
@@ -0,0 +1,289 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hub
+
+import (
+	"bufio"
+	"fmt"
+	"lucas/internal/cast"
+	"lucas/internal/logger"
+	"lucas/internal/ssdp"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SSDP search targets of the device types the hub can adopt
+const (
+	SearchTargetBravia  = "urn:schemas-sony-com:service:ScalarWebAPI:1"
+	SearchTargetSamsung = "urn:samsung.com:device:RemoteControlReceiver:1"
+	SearchTargetWebOS   = "urn:lge-com:service:webos-second-screen:1"
+	SearchTargetDIAL    = "urn:dial-multiscreen-org:service:dial:1"
+)
+
+// discoveryTargets maps search targets to device types, most specific first. A device
+// answering for several targets, like a TV that is also a DIAL server, takes the first type
+var discoveryTargets = []struct {
+	st         string
+	deviceType string
+}{
+	{SearchTargetBravia, "bravia"},
+	{SearchTargetSamsung, "samsung"},
+	{SearchTargetWebOS, "webos"},
+	{SearchTargetDIAL, "cast"},
+}
+
+// arpTable is where the kernel lists the MAC addresses of LAN neighbours
+const arpTable = "/proc/net/arp"
+
+// DiscoveredDevice is a device found on the LAN
+type DiscoveredDevice struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	Model        string `json:"model"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Address      string `json:"address"`
+	MAC          string `json:"mac,omitempty"`
+	Location     string `json:"location"`
+	Configured   bool   `json:"configured"`
+	DeviceID     string `json:"device_id,omitempty"` // configured device at the same address
+}
+
+// Config returns the device configuration that adopts the discovered device
+func (d DiscoveredDevice) Config(deviceID, credential string) DeviceConfig {
+	if deviceID == "" {
+		deviceID = suggestDeviceID(d.Type, d.Name)
+	}
+	config := DeviceConfig{
+		ID:         deviceID,
+		Type:       d.Type,
+		Model:      d.Model,
+		Address:    d.Address,
+		Credential: credential,
+		MAC:        d.MAC,
+	}
+
+	// A Cast device's DIAL server is found on the port its description is served from
+	if d.Type == "cast" {
+		if location, err := url.Parse(d.Location); err == nil {
+			if port, err := strconv.Atoi(location.Port()); err == nil && port != cast.DefaultDIALPort {
+				config.Options = map[string]string{cast.OptionDIALPort: strconv.Itoa(port)}
+			}
+		}
+	}
+	return config
+}
+
+// DeviceDiscovery finds adoptable devices with SSDP and remembers the last results
+type DeviceDiscovery struct {
+	mutex      sync.Mutex
+	search     ssdp.SearchOptions
+	httpClient *http.Client
+	arpTable   string
+	results    map[string]DiscoveredDevice
+	logger     zerolog.Logger
+}
+
+// NewDeviceDiscovery creates a discovery that searches the LAN's SSDP multicast group
+func NewDeviceDiscovery() *DeviceDiscovery {
+	return &DeviceDiscovery{
+		search:     ssdp.SearchOptions{Timeout: ssdp.DefaultSearchTimeout},
+		httpClient: &http.Client{Timeout: 3 * time.Second},
+		arpTable:   arpTable,
+		results:    make(map[string]DiscoveredDevice),
+		logger:     logger.New(),
+	}
+}
+
+// SetSearchOptions replaces where and how long searches run, the targets are fixed
+func (dd *DeviceDiscovery) SetSearchOptions(options ssdp.SearchOptions) {
+	dd.mutex.Lock()
+	defer dd.mutex.Unlock()
+	dd.search = options
+}
+
+// Discover searches the LAN and marks the devices already in configured
+func (dd *DeviceDiscovery) Discover(configured []DeviceConfig) ([]DiscoveredDevice, error) {
+	return dd.DiscoverWithProgress(configured, nil)
+}
+
+// DiscoverWithProgress is Discover reporting each device to found as soon as it is
+// described. A device answering several search targets may be reported more than once,
+// the returned devices are the final word
+func (dd *DeviceDiscovery) DiscoverWithProgress(configured []DeviceConfig, found func(DiscoveredDevice)) ([]DiscoveredDevice, error) {
+	dd.mutex.Lock()
+	options := dd.search
+	dd.mutex.Unlock()
+
+	options.Targets = make([]string, len(discoveryTargets))
+	for i, target := range discoveryTargets {
+		options.Targets[i] = target.st
+	}
+	responses, err := ssdp.Search(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for devices: %w", err)
+	}
+
+	// One device per address, typed by its most specific search target
+	byAddress := make(map[string]DiscoveredDevice)
+	priority := make(map[string]int)
+	for _, response := range responses {
+		rank := targetRank(response.ST)
+		if rank < 0 {
+			continue
+		}
+		address := hostOf(response.Location, response.Address)
+		if current, exists := priority[address]; exists && current <= rank {
+			continue
+		}
+
+		device := DiscoveredDevice{
+			ID:       response.UUID(),
+			Type:     discoveryTargets[rank].deviceType,
+			Address:  address,
+			Location: response.Location,
+		}
+		if description, err := ssdp.Describe(dd.httpClient, response.Location); err == nil {
+			device.Name = description.FriendlyName
+			device.Model = description.ModelName
+			device.Manufacturer = description.Manufacturer
+			if device.ID == "" {
+				device.ID = description.UDN
+			}
+		} else {
+			dd.logger.Debug().
+				Str("location", response.Location).
+				Err(err).
+				Msg("Failed to fetch device description")
+		}
+		if device.ID == "" {
+			device.ID = address
+		}
+		device.MAC = lookupMAC(dd.arpTable, device.Address)
+		for _, config := range configured {
+			if hostOf(config.Address, config.Address) == device.Address {
+				device.Configured = true
+				device.DeviceID = config.ID
+				break
+			}
+		}
+		byAddress[address] = device
+		priority[address] = rank
+		if found != nil {
+			found(device)
+		}
+	}
+
+	devices := make([]DiscoveredDevice, 0, len(byAddress))
+	for _, device := range byAddress {
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Address < devices[j].Address
+	})
+
+	dd.mutex.Lock()
+	dd.results = make(map[string]DiscoveredDevice, len(devices))
+	for _, device := range devices {
+		dd.results[device.ID] = device
+	}
+	dd.mutex.Unlock()
+
+	dd.logger.Info().
+		Int("responses", len(responses)).
+		Int("devices", len(devices)).
+		Msg("Device discovery finished")
+	return devices, nil
+}
+
+// Find returns a device from the last discovery
+func (dd *DeviceDiscovery) Find(id string) (DiscoveredDevice, bool) {
+	dd.mutex.Lock()
+	defer dd.mutex.Unlock()
+	found, exists := dd.results[id]
+	return found, exists
+}
+
+// targetRank returns the index of a search target in discoveryTargets, -1 if unknown
+func targetRank(st string) int {
+	for i, target := range discoveryTargets {
+		if target.st == st {
+			return i
+		}
+	}
+	return -1
+}
+
+// hostOf returns the host of a URL or host:port, or fallback when there is none
+func hostOf(address, fallback string) string {
+	if parsed, err := url.Parse(address); err == nil && parsed.Hostname() != "" {
+		return parsed.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	if address != "" && !strings.Contains(address, "/") {
+		return address
+	}
+	return fallback
+}
+
+// lookupMAC finds the MAC address of a LAN neighbour in the kernel's ARP table
+func lookupMAC(path, ip string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	// IP address, HW type, Flags, HW address, Mask, Device
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 4 && fields[0] == ip && fields[3] != "00:00:00:00:00:00" {
+			return fields[3]
+		}
+	}
+	return ""
+}
+
+// suggestDeviceID builds a configuration ID from the device type and name
+func suggestDeviceID(deviceType, name string) string {
+	var id strings.Builder
+	id.WriteString(deviceType)
+	separator := true
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if separator {
+				id.WriteByte('_')
+			}
+			id.WriteRune(r)
+			separator = false
+		default:
+			separator = true
+		}
+	}
+	return id.String()
+}
@@ -15,14 +15,22 @@
 package hub
 
 import (
+	"context"
 	"fmt"
 	"lucas/internal"
+	"strconv"
 	"sync"
 	"time"
 
 	"lucas/internal/bravia"
+	"lucas/internal/broadlink"
+	"lucas/internal/cast"
 	"lucas/internal/device"
+	"lucas/internal/homeassistant"
 	"lucas/internal/logger"
+	"lucas/internal/samsung"
+	"lucas/internal/webos"
+	"lucas/internal/wol"
 
 	"github.com/rs/zerolog"
 )
@@ -31,9 +39,18 @@ import (
 type DeviceManager struct {
 	devices    map[string]device.Device
 	config     *Config
+	configPath string
 	mutex      sync.RWMutex
 	logger     zerolog.Logger
 	nonceCache *NonceCache
+	haClients  map[string]*homeassistant.HAClient
+	discovery  *DeviceDiscovery
+	debug      bool
+	testMode   bool
+
+	// State pushed by devices that implement device.EventSource, keyed by device ID
+	stateMutex sync.RWMutex
+	states     map[string]map[string]interface{}
 }
 
 // NewDeviceManager creates a new device manager
@@ -43,13 +60,29 @@ func NewDeviceManager(config *Config) *DeviceManager {
 		config:     config,
 		logger:     logger.New(),
 		nonceCache: NewNonceCache(50, time.Hour), // 50 nonces per device, 1 hour expiration
+		haClients:  make(map[string]*homeassistant.HAClient),
+		discovery:  NewDeviceDiscovery(),
+		states:     make(map[string]map[string]interface{}),
 	}
 }
 
+// SetConfigPath sets the file that device learned settings are saved to
+func (dm *DeviceManager) SetConfigPath(path string) {
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	dm.configPath = path
+}
+
+// configStore returns the configuration store for a device
+func (dm *DeviceManager) configStore(deviceID string) device.ConfigStore {
+	return &deviceConfigStore{dm: dm, deviceID: deviceID}
+}
+
 // Initialize loads and initializes all devices from configuration
 func (dm *DeviceManager) Initialize(debug, testMode bool) error {
 	dm.mutex.Lock()
 	defer dm.mutex.Unlock()
+	dm.debug, dm.testMode = debug, testMode
 
 	dm.logger.Info().
 		Int("device_count", len(dm.config.Devices)).
@@ -66,6 +99,7 @@ func (dm *DeviceManager) Initialize(debug, testMode bool) error {
 		}
 
 		dm.devices[deviceConfig.ID] = device
+		dm.startEvents(deviceConfig.ID, device)
 		dm.logger.Info().
 			Str("device_id", deviceConfig.ID).
 			Str("device_type", deviceConfig.Type).
@@ -87,13 +121,133 @@ func (dm *DeviceManager) createDevice(config DeviceConfig, debug, testMode bool)
 		if config.Credential == "" {
 			return nil, fmt.Errorf("credential is required for bravia device")
 		}
-		return bravia.NewBraviaRemote(config.Address, config.Credential, internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode))), nil
+		retry, err := bravia.ParseRetryPolicy(config.Options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bravia device configuration: %w", err)
+		}
+		remote := bravia.NewBraviaRemote(config.Address, config.Credential, internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode)))
+		remote.SetRetryPolicy(retry)
+		remote.SetConfigStore(dm.configStore(config.ID))
+		// A MAC learned from the TV is cached in the options when none is configured
+		mac := config.MAC
+		if mac == "" {
+			mac = config.Options[bravia.OptionMAC]
+		}
+		remote.SetWakeOnLAN(mac, config.Options[bravia.OptionBroadcast])
+		remote.SetNotifications(config.Options[bravia.OptionNotifications] != "false")
+		return remote, nil
+
+	case "samsung":
+		remote := samsung.NewSamsungRemote(config.Address, config.Credential, config.MAC, dm.configStore(config.ID), internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode)))
+		remote.Client().SetName(config.Options["name"])
+		return remote, nil
+
+	case "webos":
+		return webos.NewWebOSRemote(config.Address, config.Credential, config.MAC, dm.configStore(config.ID), internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode))), nil
+
+	case "wol":
+		wolConfig, err := wol.ParseConfig(config.MAC, config.Options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wol device configuration: %w", err)
+		}
+		return wol.NewWOLDevice(config.Address, *wolConfig, internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode))), nil
+
+	case "homeassistant":
+		client := dm.homeAssistantClient(config.Address, config.Credential, debug, testMode)
+		entity, err := homeassistant.NewHAEntity(client, config.Address, config.Options[homeassistant.OptionEntityID])
+		if err != nil {
+			return nil, fmt.Errorf("invalid homeassistant device configuration: %w", err)
+		}
+		return entity, nil
+
+	case "ir":
+		irDevice, err := broadlink.NewIRDevice(config.Address, config.MAC, config.Options, dm.configStore(config.ID), internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ir device configuration: %w", err)
+		}
+		return irDevice, nil
+
+	case "cast":
+		castPort, err := portOption(config.Options, cast.OptionCastPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cast device configuration: %w", err)
+		}
+		dialPort, err := portOption(config.Options, cast.OptionDIALPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cast device configuration: %w", err)
+		}
+		return cast.NewCastDevice(config.Address, castPort, dialPort, internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode))), nil
 
 	default:
 		return nil, fmt.Errorf("unsupported device type: %s", config.Type)
 	}
 }
 
+// homeAssistantClient returns the client shared by entities of one Home Assistant instance
+func (dm *DeviceManager) homeAssistantClient(url, token string, debug, testMode bool) *homeassistant.HAClient {
+	key := url + "\x00" + token
+	if client, exists := dm.haClients[key]; exists {
+		return client
+	}
+
+	client := homeassistant.NewHAClient(url, token, *internal.NewModeOptions(internal.WithDebug(debug), internal.WithTest(testMode)))
+	client.Start()
+	dm.haClients[key] = client
+	return client
+}
+
+// portOption reads an optional port from device options, zero when unset
+func portOption(options map[string]string, key string) (int, error) {
+	value, exists := options[key]
+	if !exists || value == "" {
+		return 0, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid %s: %s", key, value)
+	}
+	return port, nil
+}
+
+// startEvents subscribes to state changes pushed by a device
+func (dm *DeviceManager) startEvents(deviceID string, dev device.Device) {
+	source, ok := dev.(device.EventSource)
+	if !ok {
+		return
+	}
+	source.StartEvents(func(event device.StateEvent) {
+		dm.recordState(deviceID, event)
+	})
+}
+
+// recordState stores a state change pushed by a device
+func (dm *DeviceManager) recordState(deviceID string, event device.StateEvent) {
+	dm.stateMutex.Lock()
+	if dm.states[deviceID] == nil {
+		dm.states[deviceID] = make(map[string]interface{})
+	}
+	dm.states[deviceID][event.Property] = event.Value
+	dm.stateMutex.Unlock()
+
+	dm.logger.Debug().
+		Str("device_id", deviceID).
+		Str("property", event.Property).
+		Interface("value", event.Value).
+		Msg("Device state changed")
+}
+
+// GetDeviceState returns the state last pushed by a device, empty for devices without events
+func (dm *DeviceManager) GetDeviceState(id string) map[string]interface{} {
+	dm.stateMutex.RLock()
+	defer dm.stateMutex.RUnlock()
+
+	state := make(map[string]interface{}, len(dm.states[id]))
+	for property, value := range dm.states[id] {
+		state[property] = value
+	}
+	return state
+}
+
 // GetDevice returns a device by ID
 func (dm *DeviceManager) GetDevice(id string) (device.Device, error) {
 	dm.mutex.RLock()
@@ -145,8 +299,9 @@ func (dm *DeviceManager) GetAllDeviceInfo() map[string]device.DeviceInfo {
 	return deviceInfos
 }
 
-// ProcessDeviceAction processes an action for a specific device with nonce deduplication
-func (dm *DeviceManager) ProcessDeviceAction(deviceID string, actionJSON []byte) (*device.ActionResponse, error) {
+// ProcessDeviceAction processes an action for a specific device. Devices that implement
+// device.ContextProcessor give up once ctx ends
+func (dm *DeviceManager) ProcessDeviceAction(ctx context.Context, deviceID string, actionJSON []byte) (*device.ActionResponse, error) {
 	dev, err := dm.GetDevice(deviceID)
 	if err != nil {
 		return &device.ActionResponse{
@@ -160,7 +315,12 @@ func (dm *DeviceManager) ProcessDeviceAction(deviceID string, actionJSON []byte)
 		RawJSON("action", actionJSON).
 		Msg("Processing device action")
 
-	response, err := dev.Process(actionJSON)
+	var response *device.ActionResponse
+	if processor, ok := dev.(device.ContextProcessor); ok {
+		response, err = processor.ProcessContext(ctx, actionJSON)
+	} else {
+		response, err = dev.Process(actionJSON)
+	}
 	if err != nil {
 		dm.logger.Error().
 			Str("device_id", deviceID).
@@ -181,7 +341,7 @@ func (dm *DeviceManager) ProcessDeviceAction(deviceID string, actionJSON []byte)
 }
 
 // ProcessDeviceActionWithNonce processes an action for a specific device with nonce-based deduplication
-func (dm *DeviceManager) ProcessDeviceActionWithNonce(deviceID, nonce string, actionJSON []byte) (*device.ActionResponse, error) {
+func (dm *DeviceManager) ProcessDeviceActionWithNonce(ctx context.Context, deviceID, nonce string, actionJSON []byte) (*device.ActionResponse, error) {
 	// Check if we've seen this nonce before for this device
 	if cachedResponse, found := dm.nonceCache.CheckNonce(deviceID, nonce); found {
 		dm.logger.Info().
@@ -204,13 +364,14 @@ func (dm *DeviceManager) ProcessDeviceActionWithNonce(deviceID, nonce string, ac
 	}
 
 	// Process the action normally
-	response, err := dm.ProcessDeviceAction(deviceID, actionJSON)
+	response, err := dm.ProcessDeviceAction(ctx, deviceID, actionJSON)
 	if err != nil {
 		return response, err
 	}
 
-	// Cache the response with the nonce (only if nonce is provided)
-	if nonce != "" {
+	// Cache the response with the nonce (only if nonce is provided). An action cut short
+	// by its context is not cached so that a retry runs it again
+	if nonce != "" && ctx.Err() == nil {
 		dm.nonceCache.StoreResponse(deviceID, nonce, response)
 		dm.logger.Debug().
 			Str("device_id", deviceID).
@@ -225,8 +386,6 @@ func (dm *DeviceManager) ProcessDeviceActionWithNonce(deviceID, nonce string, ac
 // Shutdown gracefully shuts down all devices
 func (dm *DeviceManager) Shutdown() {
 	dm.mutex.Lock()
-	defer dm.mutex.Unlock()
-
 	dm.logger.Info().
 		Int("device_count", len(dm.devices)).
 		Msg("Shutting down device manager")
@@ -236,22 +395,178 @@ func (dm *DeviceManager) Shutdown() {
 		dm.nonceCache.Shutdown()
 	}
 
-	// For now, we just clear the devices map
-	// In the future, we might want to add cleanup logic for each device type
-	dm.devices = make(map[string]device.Device)
+	retired := dm.takeDevices()
+	dm.mutex.Unlock()
+	retired.stop()
 
 	dm.logger.Info().Msg("Device manager shutdown complete")
 }
 
+// retiredDevices are devices taken out of the device manager that still have to be stopped
+type retiredDevices struct {
+	devices   []device.Device
+	haClients []*homeassistant.HAClient
+}
+
+// takeDevices removes all devices, leaving the nonce cache running. Callers hold the
+// lock and call stop on the result once they released it: stopping waits for event
+// goroutines, which may need the lock to save what they learned
+func (dm *DeviceManager) takeDevices() retiredDevices {
+	var retired retiredDevices
+	for _, client := range dm.haClients {
+		retired.haClients = append(retired.haClients, client)
+	}
+	dm.haClients = make(map[string]*homeassistant.HAClient)
+
+	for _, dev := range dm.devices {
+		retired.devices = append(retired.devices, dev)
+	}
+	dm.devices = make(map[string]device.Device)
+
+	dm.stateMutex.Lock()
+	dm.states = make(map[string]map[string]interface{})
+	dm.stateMutex.Unlock()
+	return retired
+}
+
+// stop closes the connections the retired devices held open for events
+func (r retiredDevices) stop() {
+	// Stop Home Assistant event subscriptions shared by entity devices
+	for _, client := range r.haClients {
+		client.Close()
+	}
+	for _, dev := range r.devices {
+		if source, ok := dev.(device.EventSource); ok {
+			source.StopEvents()
+		}
+	}
+}
+
+// AddDevice creates a device, adds it to the configuration and saves the configuration.
+// Capabilities left empty are filled in from the device
+func (dm *DeviceManager) AddDevice(config DeviceConfig) (DeviceConfig, error) {
+	if config.ID == "" {
+		return DeviceConfig{}, fmt.Errorf("device id is required")
+	}
+
+	configStoreMutex.Lock()
+	defer configStoreMutex.Unlock()
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	if _, exists := dm.devices[config.ID]; exists {
+		return DeviceConfig{}, fmt.Errorf("device %s already exists", config.ID)
+	}
+	dev, err := dm.createDevice(config, dm.debug, dm.testMode)
+	if err != nil {
+		return DeviceConfig{}, fmt.Errorf("failed to create device %s: %w", config.ID, err)
+	}
+	if len(config.Capabilities) == 0 {
+		config.Capabilities = dev.GetDeviceInfo().Capabilities
+	}
+
+	dm.config.Devices = append(dm.config.Devices, config)
+	if dm.configPath != "" {
+		if err := SaveConfig(dm.config, dm.configPath); err != nil {
+			dm.config.Devices = dm.config.Devices[:len(dm.config.Devices)-1]
+			return DeviceConfig{}, fmt.Errorf("failed to save device configuration: %w", err)
+		}
+	}
+
+	dm.devices[config.ID] = dev
+	dm.startEvents(config.ID, dev)
+	dm.logger.Info().
+		Str("device_id", config.ID).
+		Str("device_type", config.Type).
+		Str("device_address", config.Address).
+		Msg("Device added")
+	return config, nil
+}
+
+// GetDeviceConfig returns a copy of the configuration of a device
+func (dm *DeviceManager) GetDeviceConfig(id string) (*DeviceConfig, error) {
+	dm.mutex.RLock()
+	defer dm.mutex.RUnlock()
+	return dm.config.GetDevice(id)
+}
+
+// SetDeviceCredential saves a new credential for a configured device and recreates the device with it
+func (dm *DeviceManager) SetDeviceCredential(deviceID, credential string) error {
+	if err := dm.configStore(deviceID).SetCredential(credential); err != nil {
+		return err
+	}
+
+	dm.mutex.Lock()
+	config, err := dm.config.GetDevice(deviceID)
+	if err != nil {
+		dm.mutex.Unlock()
+		return err
+	}
+	dev, err := dm.createDevice(*config, dm.debug, dm.testMode)
+	if err != nil {
+		dm.mutex.Unlock()
+		return fmt.Errorf("failed to create device %s: %w", deviceID, err)
+	}
+
+	var retired retiredDevices
+	if old, exists := dm.devices[deviceID]; exists {
+		retired.devices = append(retired.devices, old)
+	}
+	dm.devices[deviceID] = dev
+	dm.startEvents(deviceID, dev)
+	dm.mutex.Unlock()
+	retired.stop()
+
+	dm.logger.Info().
+		Str("device_id", deviceID).
+		Msg("Device credential updated")
+	return nil
+}
+
+// Discovery returns the LAN discovery used to find devices to adopt
+func (dm *DeviceManager) Discovery() *DeviceDiscovery {
+	return dm.discovery
+}
+
+// DiscoverDevices searches the LAN for devices, marking those already configured
+func (dm *DeviceManager) DiscoverDevices() ([]DiscoveredDevice, error) {
+	return dm.DiscoverDevicesWithProgress(nil)
+}
+
+// DiscoverDevicesWithProgress is DiscoverDevices reporting each device to found as it is described
+func (dm *DeviceManager) DiscoverDevicesWithProgress(found func(DiscoveredDevice)) ([]DiscoveredDevice, error) {
+	dm.mutex.RLock()
+	configured := append([]DeviceConfig(nil), dm.config.Devices...)
+	dm.mutex.RUnlock()
+	return dm.discovery.DiscoverWithProgress(configured, found)
+}
+
+// AdoptDevice adds a device found by the last discovery to the configuration.
+// An empty deviceID is derived from the device's type and name
+func (dm *DeviceManager) AdoptDevice(discoveredID, deviceID, credential string) (DeviceConfig, error) {
+	found, exists := dm.discovery.Find(discoveredID)
+	if !exists {
+		return DeviceConfig{}, fmt.Errorf("device %s was not found by the last discovery", discoveredID)
+	}
+	if found.Configured {
+		return DeviceConfig{}, fmt.Errorf("device %s is already configured as %s", discoveredID, found.DeviceID)
+	}
+
+	return dm.AddDevice(found.Config(deviceID, credential))
+}
+
 // Reload reloads devices from the configuration
 func (dm *DeviceManager) Reload(newConfig *Config, debug, testMode bool) error {
 	dm.logger.Info().Msg("Reloading device manager with new configuration")
 
-	// Shutdown existing devices
-	dm.Shutdown()
+	// Shutdown existing devices, keeping the nonce cache so deduplication carries over
+	dm.mutex.Lock()
+	retired := dm.takeDevices()
 
 	// Update configuration
 	dm.config = newConfig
+	dm.mutex.Unlock()
+	retired.stop()
 
 	// Initialize with new configuration
 	return dm.Initialize(debug, testMode)
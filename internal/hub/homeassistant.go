@@ -0,0 +1,80 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hub
+
+import (
+	"fmt"
+	"lucas/internal"
+	"strings"
+
+	"lucas/internal/homeassistant"
+)
+
+// ImportHomeAssistantEntities builds device configurations for selected Home Assistant entities
+func ImportHomeAssistantEntities(url, token string, entityIDs []string) ([]DeviceConfig, error) {
+	client := homeassistant.NewHAClient(url, token, internal.FnModeOptions{})
+	defer client.Close()
+
+	states, err := client.States()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch home assistant states: %w", err)
+	}
+
+	byID := make(map[string]homeassistant.EntityState, len(states))
+	for _, state := range states {
+		byID[state.EntityID] = state
+	}
+
+	devices := make([]DeviceConfig, 0, len(entityIDs))
+	for _, entityID := range entityIDs {
+		state, exists := byID[entityID]
+		if !exists {
+			return nil, fmt.Errorf("entity not found in home assistant: %s", entityID)
+		}
+
+		devices = append(devices, DeviceConfig{
+			ID:         strings.ReplaceAll(entityID, ".", "_"),
+			Type:       "homeassistant",
+			Model:      state.FriendlyName(),
+			Address:    url,
+			Credential: token,
+			Options: map[string]string{
+				homeassistant.OptionEntityID: entityID,
+			},
+			Capabilities: homeassistant.Capabilities(state.Domain()),
+		})
+	}
+
+	return devices, nil
+}
+
+// AddDevices appends device configurations, skipping IDs that already exist
+func (c *Config) AddDevices(devices []DeviceConfig) (added []string, skipped []string) {
+	existing := make(map[string]bool, len(c.Devices))
+	for _, device := range c.Devices {
+		existing[device.ID] = true
+	}
+
+	for _, device := range devices {
+		if existing[device.ID] {
+			skipped = append(skipped, device.ID)
+			continue
+		}
+		c.Devices = append(c.Devices, device)
+		existing[device.ID] = true
+		added = append(added, device.ID)
+	}
+	return added, skipped
+}
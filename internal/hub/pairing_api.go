@@ -0,0 +1,159 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hub
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// braviaPairing is a pairing waiting for the PIN shown on the TV
+type braviaPairing struct {
+	address  string
+	clientID string
+}
+
+// PairRequest is the body of the pairing endpoints
+type PairRequest struct {
+	Address string `json:"address,omitempty"`
+	PIN     string `json:"pin,omitempty"`
+}
+
+// handlePairStart makes a Bravia TV display a pairing PIN
+func (s *ConfigAPIServer) handlePairStart(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+
+	var req PairRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.sendError(w, http.StatusBadRequest, "Invalid JSON format", err)
+			return
+		}
+	}
+
+	// Pairing an unknown device adds it, which needs an address
+	address := req.Address
+	clientID := bravia.NewClientID()
+	if config, err := s.daemon.deviceManager.GetDeviceConfig(deviceID); err == nil {
+		if config.Type != "bravia" {
+			s.sendError(w, http.StatusBadRequest, "PIN pairing is only supported for bravia devices", nil)
+			return
+		}
+		if address == "" {
+			address = config.Address
+		}
+		// Keep the client ID of an earlier pairing so the TV does not list the hub twice
+		if id, _, ok := bravia.ParseCookieCredential(config.Credential); ok {
+			clientID = id
+		}
+	}
+	if address == "" {
+		s.sendError(w, http.StatusBadRequest, "Address is required to pair a new device", nil)
+		return
+	}
+
+	client := s.pairingClient(address)
+	credential, err := client.RequestPIN(clientID)
+	if err != nil {
+		s.sendError(w, http.StatusBadGateway, "Failed to request pairing PIN", err)
+		return
+	}
+
+	if credential != "" {
+		// The TV already knew this client and issued a cookie straight away
+		if err := s.savePairing(deviceID, address, credential); err != nil {
+			s.sendError(w, http.StatusInternalServerError, "Failed to save pairing", err)
+			return
+		}
+		s.sendSuccess(w, "Device paired", map[string]interface{}{"device_id": deviceID, "paired": true})
+		return
+	}
+
+	s.pairingMutex.Lock()
+	s.pairings[deviceID] = braviaPairing{address: address, clientID: clientID}
+	s.pairingMutex.Unlock()
+
+	s.sendSuccess(w, "Enter the PIN shown on the TV", map[string]interface{}{"device_id": deviceID, "paired": false})
+}
+
+// handlePairComplete registers with the PIN and stores the auth cookie as the device credential
+func (s *ConfigAPIServer) handlePairComplete(w http.ResponseWriter, r *http.Request) {
+	deviceID := mux.Vars(r)["id"]
+
+	var req PairRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+	if req.PIN == "" {
+		s.sendError(w, http.StatusBadRequest, "PIN is required", nil)
+		return
+	}
+
+	s.pairingMutex.Lock()
+	pairing, exists := s.pairings[deviceID]
+	s.pairingMutex.Unlock()
+	if !exists {
+		s.sendError(w, http.StatusNotFound, "No pairing in progress for device", nil)
+		return
+	}
+
+	credential, err := s.pairingClient(pairing.address).CompletePairing(pairing.clientID, req.PIN)
+	if err != nil {
+		s.sendError(w, http.StatusBadGateway, "Pairing failed", err)
+		return
+	}
+
+	s.pairingMutex.Lock()
+	delete(s.pairings, deviceID)
+	s.pairingMutex.Unlock()
+
+	if err := s.savePairing(deviceID, pairing.address, credential); err != nil {
+		s.sendError(w, http.StatusInternalServerError, "Failed to save pairing", err)
+		return
+	}
+
+	s.logger.Info().
+		Str("device_id", deviceID).
+		Msg("Bravia device paired")
+
+	s.sendSuccess(w, "Device paired", map[string]interface{}{"device_id": deviceID, "paired": true})
+}
+
+// pairingClient creates a Bravia client without a credential for pairing
+func (s *ConfigAPIServer) pairingClient(address string) *bravia.BraviaClient {
+	return bravia.NewBraviaClient(address, "", *internal.NewModeOptions(internal.WithDebug(s.daemon.debug), internal.WithTest(s.daemon.testMode)))
+}
+
+// savePairing stores the credential, adding the device when it is not configured yet
+func (s *ConfigAPIServer) savePairing(deviceID, address, credential string) error {
+	dm := s.daemon.deviceManager
+	if _, err := dm.GetDeviceConfig(deviceID); err == nil {
+		return dm.SetDeviceCredential(deviceID, credential)
+	}
+
+	_, err := dm.AddDevice(DeviceConfig{
+		ID:         deviceID,
+		Type:       "bravia",
+		Model:      "Sony Bravia",
+		Address:    address,
+		Credential: credential,
+	})
+	return err
+}
@@ -26,6 +26,17 @@ import (
 	"lucas/internal/logger"
 )
 
+// hubControlSchema is the version of the hub.control action schema this hub speaks,
+// announced to the gateway on READY
+const hubControlSchema = 1
+
+// hubCapabilities are announced to the gateway on READY so it knows what this hub supports
+var hubCapabilities = []string{
+	hermes.CapabilityNonce,
+	hermes.CapabilityDiscover,
+	hermes.CapabilityAdopt,
+}
+
 // WorkerService integrates Hermes worker with hub functionality
 type WorkerService struct {
 	config       *Config
@@ -57,6 +68,7 @@ type ServiceWorkerStats struct {
 	LastRequest    time.Time `json:"last_request"`
 	IsConnected    bool      `json:"is_connected"`
 	WorkerIdentity string    `json:"worker_identity"`
+	Gateway        string    `json:"gateway,omitempty"`
 }
 
 // DeviceServiceHandler removed - using single HubServiceHandler for all devices
@@ -186,6 +198,21 @@ func (ws *WorkerService) registerHubWorker() error {
 	// Configure worker settings for internet reliability
 	worker.SetHeartbeat(45 * time.Second)       // Longer heartbeat interval for internet
 	worker.SetReconnectInterval(10 * time.Second) // Longer initial reconnect delay
+	var brokers []string
+	serverKeys := make(map[string]string)
+	for _, gateway := range ws.config.Gateway.Endpoints() {
+		brokers = append(brokers, gateway.Endpoint)
+		serverKeys[gateway.Endpoint] = gateway.PublicKey
+	}
+	worker.SetBrokers(brokers)       // Primary first, failover gateways in order
+	worker.SetServerKeys(serverKeys) // Each gateway is dialed with its own key
+	if framing, err := hermes.ParseFraming(ws.config.Gateway.Framing); err == nil {
+		worker.SetFraming(framing)
+	} else {
+		ws.logger.Warn().Err(err).Msg("Unknown gateway framing - using MDP")
+	}
+	worker.SetCapabilities(hubCapabilities...)
+	worker.SetSchema(hubControlSchema)
 
 	ws.mutex.Lock()
 	ws.workers[serviceName] = worker
@@ -199,6 +226,7 @@ func (ws *WorkerService) registerHubWorker() error {
 	ws.logger.Info().
 		Str("service", serviceName).
 		Str("worker_identity", workerIdentity).
+		Strs("gateways", brokers).
 		Msg("Hub worker registered")
 
 	return nil
@@ -215,6 +243,7 @@ func (ws *WorkerService) GetServiceStats() *WorkerServiceStats {
 		isConnected := worker.IsConnected()
 		if serviceStats, exists := ws.stats.ServiceStats[serviceName]; exists {
 			serviceStats.IsConnected = isConnected
+			serviceStats.Gateway = activeGateway(worker)
 		}
 		if isConnected {
 			activeWorkers++
@@ -266,6 +295,25 @@ func (ws *WorkerService) IsConnected() bool {
 	return false
 }
 
+// ActiveGateway returns the gateway endpoint the hub worker is connected to, or empty when disconnected
+func (ws *WorkerService) ActiveGateway() string {
+	ws.mutex.RLock()
+	defer ws.mutex.RUnlock()
+
+	if worker, exists := ws.workers["hub.control"]; exists {
+		return activeGateway(worker)
+	}
+	return ""
+}
+
+// activeGateway returns the broker a worker is connected to, or empty when disconnected
+func activeGateway(worker *hermes.HermesWorker) string {
+	if !worker.IsConnected() {
+		return ""
+	}
+	return worker.GetBroker()
+}
+
 // IsGatewayReachable returns whether the gateway is actually reachable (better health check)
 func (ws *WorkerService) IsGatewayReachable() bool {
 	ws.mutex.RLock()
@@ -320,12 +368,22 @@ func (ws *WorkerService) updateWorkerStats() {
 			serviceStats.RequestsFailed = workerStats.RequestsFailed
 			serviceStats.LastRequest = workerStats.LastRequest
 			serviceStats.IsConnected = isConnected
+			gateway := activeGateway(worker)
+			previousGateway := serviceStats.Gateway
+			serviceStats.Gateway = gateway
 			
 			// Detect reconnection after disconnection 
 			if !wasConnected && isConnected {
 				ws.logger.Info().
 					Str("service", serviceName).
+					Str("gateway", gateway).
 					Msg("Worker reconnected - gateway will auto-detect")
+			} else if isConnected && previousGateway != "" && gateway != previousGateway {
+				ws.logger.Info().
+					Str("service", serviceName).
+					Str("previous_gateway", previousGateway).
+					Str("gateway", gateway).
+					Msg("Worker switched gateway")
 			}
 		}
 	}
@@ -337,6 +395,18 @@ func (ws *WorkerService) updateWorkerStats() {
 
 // Handle implements the hermes.RequestHandler interface for hub service
 func (hsh *HubServiceHandler) Handle(request []byte) ([]byte, error) {
+	return hsh.HandleContext(context.Background(), request)
+}
+
+// HandleContext implements the hermes.ContextRequestHandler interface, ctx ends when the
+// request's deadline passes or the gateway cancels it
+func (hsh *HubServiceHandler) HandleContext(ctx context.Context, request []byte) ([]byte, error) {
+	return hsh.HandleStream(ctx, request, nil)
+}
+
+// HandleStream implements the hermes.StreamingRequestHandler interface: discover reports
+// each device it finds through progress before the final reply. A nil progress reports nothing
+func (hsh *HubServiceHandler) HandleStream(ctx context.Context, request []byte, progress func(data interface{}) error) ([]byte, error) {
 	startTime := time.Now()
 	
 	hsh.mutex.Lock()
@@ -367,13 +437,17 @@ func (hsh *HubServiceHandler) Handle(request []byte) ([]byte, error) {
 
 	switch serviceReq.Action {
 	case "execute":
-		response, err = hsh.handleExecuteAction(&serviceReq)
+		response, err = hsh.handleExecuteAction(ctx, &serviceReq)
 	case "list":
 		response, err = hsh.handleListAction(&serviceReq)
 	case "status":
 		response, err = hsh.handleStatusAction(&serviceReq)
 	case "info":
 		response, err = hsh.handleInfoAction(&serviceReq)
+	case "discover":
+		response, err = hsh.handleDiscoverAction(&serviceReq, progress)
+	case "adopt":
+		response, err = hsh.handleAdoptAction(&serviceReq)
 	default:
 		hsh.recordError()
 		return nil, fmt.Errorf("unknown action: %s", serviceReq.Action)
@@ -414,7 +488,7 @@ func (hsh *HubServiceHandler) Handle(request []byte) ([]byte, error) {
 }
 
 // handleExecuteAction handles device command execution through hub
-func (hsh *HubServiceHandler) handleExecuteAction(req *hermes.ServiceRequest) (*hermes.ServiceResponse, error) {
+func (hsh *HubServiceHandler) handleExecuteAction(ctx context.Context, req *hermes.ServiceRequest) (*hermes.ServiceResponse, error) {
 	// Parse device command from payload
 	var deviceCmd struct {
 		DeviceID string          `json:"device_id"`
@@ -429,27 +503,47 @@ func (hsh *HubServiceHandler) handleExecuteAction(req *hermes.ServiceRequest) (*
 		return nil, fmt.Errorf("device_id is required")
 	}
 
+	// A command the gateway stopped waiting for is not worth starting
+	if ctx.Err() != nil {
+		return nil, abandonedError(ctx, deviceCmd.DeviceID)
+	}
+
 	// Execute device action with nonce support
+	type result struct {
+		response interface{}
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		if req.Nonce != "" {
+			// Use nonce-based deduplication
+			deviceResponse, deviceErr := hsh.deviceMgr.ProcessDeviceActionWithNonce(
+				ctx,
+				deviceCmd.DeviceID,
+				req.Nonce,
+				deviceCmd.Action,
+			)
+			done <- result{deviceResponse, deviceErr}
+		} else {
+			// Standard processing without nonce
+			deviceResponse, deviceErr := hsh.deviceMgr.ProcessDeviceAction(
+				ctx,
+				deviceCmd.DeviceID,
+				deviceCmd.Action,
+			)
+			done <- result{deviceResponse, deviceErr}
+		}
+	}()
+
+	// Devices that take the context stop once the gateway stops waiting. Others are left to
+	// finish on their own, their response still lands in the nonce cache for a retry
 	var response interface{}
 	var err error
-	
-	if req.Nonce != "" {
-		// Use nonce-based deduplication
-		deviceResponse, deviceErr := hsh.deviceMgr.ProcessDeviceActionWithNonce(
-			deviceCmd.DeviceID,
-			req.Nonce,
-			deviceCmd.Action,
-		)
-		response = deviceResponse
-		err = deviceErr
-	} else {
-		// Standard processing without nonce
-		deviceResponse, deviceErr := hsh.deviceMgr.ProcessDeviceAction(
-			deviceCmd.DeviceID,
-			deviceCmd.Action,
-		)
-		response = deviceResponse
-		err = deviceErr
+	select {
+	case r := <-done:
+		response, err = r.response, r.err
+	case <-ctx.Done():
+		return nil, abandonedError(ctx, deviceCmd.DeviceID)
 	}
 
 	return hermes.CreateServiceResponseWithNonce(
@@ -462,6 +556,14 @@ func (hsh *HubServiceHandler) handleExecuteAction(req *hermes.ServiceRequest) (*
 	), nil
 }
 
+// abandonedError describes a device command given up on because its request's context ended
+func abandonedError(ctx context.Context, deviceID string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("device command for %s abandoned: request deadline exceeded", deviceID)
+	}
+	return fmt.Errorf("device command for %s abandoned: request cancelled", deviceID)
+}
+
 // handleListAction handles device listing requests
 func (hsh *HubServiceHandler) handleListAction(req *hermes.ServiceRequest) (*hermes.ServiceResponse, error) {
 	hsh.logger.Info().
@@ -530,6 +632,87 @@ func (hsh *HubServiceHandler) handleListAction(req *hermes.ServiceRequest) (*her
 	), nil
 }
 
+// handleDiscoverAction searches the hub's LAN for devices that can be adopted, reporting
+// each one to progress as it is found
+func (hsh *HubServiceHandler) handleDiscoverAction(req *hermes.ServiceRequest, progress func(data interface{}) error) (*hermes.ServiceResponse, error) {
+	var found func(DiscoveredDevice)
+	if progress != nil {
+		found = func(device DiscoveredDevice) {
+			// Progress is best effort, the final reply still lists every device
+			if err := progress(map[string]interface{}{"found": device, "hub_id": hsh.config.Hub.ID}); err != nil {
+				hsh.logger.Debug().
+					Str("device_address", device.Address).
+					Err(err).
+					Msg("Failed to report discovered device")
+			}
+		}
+	}
+
+	discovered, err := hsh.deviceMgr.DiscoverDevicesWithProgress(found)
+	if err != nil {
+		return nil, err
+	}
+
+	// Not "devices", which the broker takes for a device list response
+	return hermes.CreateServiceResponseWithNonce(
+		req.MessageID,
+		req.Service,
+		req.Nonce,
+		true,
+		map[string]interface{}{
+			"discovered": discovered,
+			"count":      len(discovered),
+			"hub_id":     hsh.config.Hub.ID,
+		},
+		nil,
+	), nil
+}
+
+// handleAdoptAction adds a discovered device to the hub configuration
+func (hsh *HubServiceHandler) handleAdoptAction(req *hermes.ServiceRequest) (*hermes.ServiceResponse, error) {
+	var adoptReq struct {
+		ID         string `json:"id"`
+		DeviceID   string `json:"device_id"`
+		Credential string `json:"credential"`
+	}
+	if err := json.Unmarshal(req.Payload, &adoptReq); err != nil {
+		return nil, fmt.Errorf("failed to parse adopt request: %w", err)
+	}
+	if adoptReq.ID == "" {
+		return nil, fmt.Errorf("id of the discovered device is required")
+	}
+
+	config, err := hsh.deviceMgr.AdoptDevice(adoptReq.ID, adoptReq.DeviceID, adoptReq.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	hsh.logger.Info().
+		Str("discovered_id", adoptReq.ID).
+		Str("device_id", config.ID).
+		Str("device_type", config.Type).
+		Msg("Hub adopted discovered device")
+
+	return hermes.CreateServiceResponseWithNonce(
+		req.MessageID,
+		req.Service,
+		req.Nonce,
+		true,
+		map[string]interface{}{
+			"device": map[string]interface{}{
+				"id":           config.ID,
+				"name":         config.Model,
+				"type":         config.Type,
+				"model":        config.Model,
+				"address":      config.Address,
+				"capabilities": config.Capabilities,
+			},
+			"hub_id": hsh.config.Hub.ID,
+		},
+		nil,
+	), nil
+}
+
 // handleStatusAction handles hub status requests
 func (hsh *HubServiceHandler) handleStatusAction(req *hermes.ServiceRequest) (*hermes.ServiceResponse, error) {
 	hsh.mutex.RLock()
@@ -0,0 +1,401 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samsung
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/device"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"lucas/internal/logger"
+	"lucas/internal/websocket"
+
+	"github.com/rs/zerolog"
+)
+
+// SamsungEvent is a message received on the remote control channel
+type SamsungEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// SamsungCommand is a message sent on the remote control channel
+type SamsungCommand struct {
+	Method string                 `json:"method"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// SamsungClient represents a client for the Samsung Tizen remote API
+type SamsungClient struct {
+	httpClient *http.Client
+	address    string
+	name       string
+	token      string
+	store      device.ConfigStore
+	conn       *websocket.Conn
+	events     chan SamsungEvent
+	mutex      sync.Mutex
+	debugMode  bool
+	testMode   bool
+	simulated  bool
+	logger     zerolog.Logger
+}
+
+// NewSamsungClient creates a new Samsung client, token may be empty before pairing
+func NewSamsungClient(address, token string, store device.ConfigStore, options internal.FnModeOptions) *SamsungClient {
+	client := &SamsungClient{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		address:   address,
+		name:      DefaultClientName,
+		token:     token,
+		store:     store,
+		debugMode: options.Debug,
+		testMode:  options.Test,
+		simulated: true,
+		logger:    logger.New(),
+	}
+	if options.Debug {
+		logger.SetLevel(logger.LOG_DEBUG)
+	}
+
+	return client
+}
+
+// SetName sets the client name shown on the TV pairing prompt
+func (c *SamsungClient) SetName(name string) {
+	if name != "" {
+		c.name = name
+	}
+}
+
+// Token returns the current pairing token
+func (c *SamsungClient) Token() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.token
+}
+
+// SendKey sends a remote key press, pairing first when needed
+func (c *SamsungClient) SendKey(key SamsungKey) error {
+	command := SamsungCommand{
+		Method: MethodRemoteControl,
+		Params: map[string]interface{}{
+			"Cmd":          "Click",
+			"DataOfCmd":    string(key),
+			"Option":       "false",
+			"TypeOfRemote": "SendRemoteKey",
+		},
+	}
+
+	if c.testMode {
+		c.simulatePairing()
+		c.mutex.Lock()
+		switch key {
+		case KeyPowerOff:
+			c.simulated = false
+		case KeyPowerOn:
+			c.simulated = true
+		case KeyPower:
+			c.simulated = !c.simulated
+		}
+		c.mutex.Unlock()
+		c.logger.Info().
+			Str("key", string(key)).
+			Str("address", c.address).
+			Msg("Test mode: Remote key simulated")
+		return nil
+	}
+
+	return c.send(command)
+}
+
+// GetInstalledApps requests the list of installed applications
+func (c *SamsungClient) GetInstalledApps() (json.RawMessage, error) {
+	if c.testMode {
+		c.simulatePairing()
+		return json.RawMessage(`{"data":[{"appId":"111299001912","name":"YouTube","app_type":2}]}`), nil
+	}
+
+	// Drop stale events so the reply we wait for is fresh
+	c.drainEvents()
+
+	command := SamsungCommand{
+		Method: MethodChannelEmit,
+		Params: map[string]interface{}{
+			"event": EventInstalledApp,
+			"to":    "host",
+		},
+	}
+	if err := c.send(command); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	events := c.events
+	c.mutex.Unlock()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil, fmt.Errorf("connection closed while waiting for app list")
+			}
+			if event.Event == EventInstalledApp {
+				return event.Data, nil
+			}
+		case <-timeout:
+			return nil, fmt.Errorf("timed out waiting for app list")
+		}
+	}
+}
+
+// GetDeviceInfo queries the REST device description, which includes PowerState
+func (c *SamsungClient) GetDeviceInfo() (map[string]interface{}, error) {
+	if c.testMode {
+		c.mutex.Lock()
+		powerState := "standby"
+		if c.simulated {
+			powerState = "on"
+		}
+		c.mutex.Unlock()
+		return map[string]interface{}{
+			"name": "[TV] Samsung Simulator",
+			"device": map[string]interface{}{
+				"modelName":  "SIMULATOR",
+				"PowerState": powerState,
+			},
+		}, nil
+	}
+
+	host := c.host()
+	resp, err := c.httpClient.Get(fmt.Sprintf("http://%s%s", net.JoinHostPort(host, InsecurePort), DeviceInfoPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device info request failed with status %d", resp.StatusCode)
+	}
+
+	var info map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to parse device info: %w", err)
+	}
+	return info, nil
+}
+
+// Close closes the remote control channel
+func (c *SamsungClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// send writes a command, reconnecting once if the channel went away
+func (c *SamsungClient) send(command SamsungCommand) error {
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		conn, err := c.connection()
+		if err != nil {
+			return err
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.WriteJSON(command); err != nil {
+			lastErr = err
+			c.Close()
+			continue
+		}
+
+		c.logger.Debug().
+			Str("method", command.Method).
+			Interface("params", command.Params).
+			Msg("Samsung command sent")
+		return nil
+	}
+	return fmt.Errorf("failed to send command: %w", lastErr)
+}
+
+// connection returns the open channel, connecting and pairing if required
+func (c *SamsungClient) connection() (*websocket.Conn, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		return c.conn, nil
+	}
+
+	conn, _, err := websocket.Dial(c.channelURL(), &websocket.DialOptions{
+		// Tizen TVs serve a self-signed certificate
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		Timeout:   10 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote channel: %w", err)
+	}
+
+	// The TV answers with ms.channel.connect once the pairing prompt is accepted
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	for {
+		var event SamsungEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to read channel handshake: %w", err)
+		}
+
+		switch event.Event {
+		case EventConnect:
+			var data struct {
+				Token string `json:"token"`
+			}
+			json.Unmarshal(event.Data, &data)
+			if data.Token != "" && data.Token != c.token {
+				c.token = data.Token
+				c.persistToken(data.Token)
+			}
+		case EventUnauthorized, EventTimeout:
+			conn.Close()
+			return nil, fmt.Errorf("pairing rejected by TV: %s", event.Event)
+		default:
+			continue
+		}
+		break
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	c.conn = conn
+	c.events = make(chan SamsungEvent, 16)
+	go c.readLoop(conn, c.events)
+
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Connected to Samsung remote channel")
+
+	return conn, nil
+}
+
+// readLoop forwards channel events until the connection closes
+func (c *SamsungClient) readLoop(conn *websocket.Conn, events chan SamsungEvent) {
+	defer close(events)
+	for {
+		var event SamsungEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			c.mutex.Lock()
+			if c.conn == conn {
+				c.conn = nil
+			}
+			c.mutex.Unlock()
+			c.logger.Debug().Err(err).Msg("Samsung remote channel closed")
+			return
+		}
+
+		select {
+		case events <- event:
+		default:
+			// Nobody is waiting, drop the event
+		}
+	}
+}
+
+// drainEvents discards queued events
+func (c *SamsungClient) drainEvents() {
+	c.mutex.Lock()
+	events := c.events
+	c.mutex.Unlock()
+	if events == nil {
+		return
+	}
+	for {
+		select {
+		case <-events:
+		default:
+			return
+		}
+	}
+}
+
+// channelURL builds the remote channel URL for the configured address
+func (c *SamsungClient) channelURL() string {
+	host, port, err := net.SplitHostPort(c.address)
+	if err != nil {
+		host, port = c.address, SecurePort
+	}
+
+	scheme := "wss"
+	if port == InsecurePort {
+		scheme = "ws"
+	}
+
+	query := url.Values{}
+	query.Set("name", base64.StdEncoding.EncodeToString([]byte(c.name)))
+	if c.token != "" {
+		query.Set("token", c.token)
+	}
+
+	return fmt.Sprintf("%s://%s%s?%s", scheme, net.JoinHostPort(host, port), RemoteChannelPath, query.Encode())
+}
+
+// host returns the address without a port
+func (c *SamsungClient) host() string {
+	if host, _, err := net.SplitHostPort(c.address); err == nil {
+		return host
+	}
+	return c.address
+}
+
+// simulatePairing hands out a token the first time a test mode client connects
+func (c *SamsungClient) simulatePairing() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.token != "" {
+		return
+	}
+	c.token = "test-token"
+	c.persistToken(c.token)
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Test mode: Pairing simulated")
+}
+
+// persistToken saves a newly issued token to the device configuration
+func (c *SamsungClient) persistToken(token string) {
+	if c.store == nil {
+		return
+	}
+	if err := c.store.SetCredential(token); err != nil {
+		c.logger.Error().Err(err).Msg("Failed to save Samsung pairing token")
+		return
+	}
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Saved Samsung pairing token")
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samsung
+
+// SamsungKey represents a Tizen remote key code
+type SamsungKey string
+
+// Remote Control Key Codes for Samsung Tizen TVs
+const (
+	KeyPower    SamsungKey = "KEY_POWER"
+	KeyPowerOn  SamsungKey = "KEY_POWERON"
+	KeyPowerOff SamsungKey = "KEY_POWEROFF"
+
+	KeyVolumeUp   SamsungKey = "KEY_VOLUP"
+	KeyVolumeDown SamsungKey = "KEY_VOLDOWN"
+	KeyMute       SamsungKey = "KEY_MUTE"
+
+	KeyChannelUp   SamsungKey = "KEY_CHUP"
+	KeyChannelDown SamsungKey = "KEY_CHDOWN"
+
+	KeyUp    SamsungKey = "KEY_UP"
+	KeyDown  SamsungKey = "KEY_DOWN"
+	KeyLeft  SamsungKey = "KEY_LEFT"
+	KeyRight SamsungKey = "KEY_RIGHT"
+	KeyEnter SamsungKey = "KEY_ENTER"
+
+	KeyHome   SamsungKey = "KEY_HOME"
+	KeyMenu   SamsungKey = "KEY_MENU"
+	KeyReturn SamsungKey = "KEY_RETURN"
+	KeySource SamsungKey = "KEY_SOURCE"
+
+	KeyHDMI1 SamsungKey = "KEY_HDMI1"
+	KeyHDMI2 SamsungKey = "KEY_HDMI2"
+	KeyHDMI3 SamsungKey = "KEY_HDMI3"
+	KeyHDMI4 SamsungKey = "KEY_HDMI4"
+)
+
+// Ports and paths of the Samsung remote API
+const (
+	SecurePort   = "8002"
+	InsecurePort = "8001"
+
+	RemoteChannelPath = "/api/v2/channels/samsung.remote.control"
+	DeviceInfoPath    = "/api/v2/"
+)
+
+// Events exchanged on the remote control channel
+const (
+	EventConnect      = "ms.channel.connect"
+	EventUnauthorized = "ms.channel.unauthorized"
+	EventTimeout      = "ms.channel.timeOut"
+	EventInstalledApp = "ed.installedApp.get"
+
+	MethodRemoteControl = "ms.remote.control"
+	MethodChannelEmit   = "ms.channel.emit"
+)
+
+// DefaultClientName is the name shown on the TV when pairing
+const DefaultClientName = "Lucas"
@@ -0,0 +1,228 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samsung
+
+import (
+	"encoding/json"
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/device"
+	"lucas/internal/wol"
+	"net"
+)
+
+// SamsungRemote implements the Device interface for Samsung Tizen TVs
+type SamsungRemote struct {
+	client *SamsungClient
+	mac    net.HardwareAddr
+	info   device.DeviceInfo
+}
+
+// NewSamsungRemote creates a new SamsungRemote device, mac is optional and enables Wake-on-LAN
+func NewSamsungRemote(address, token, mac string, store device.ConfigStore, options *internal.FnModeOptions) *SamsungRemote {
+	var opts internal.FnModeOptions
+	if options != nil {
+		opts = *options
+	}
+
+	// An invalid MAC only disables Wake-on-LAN
+	hw, _ := net.ParseMAC(mac)
+
+	return &SamsungRemote{
+		client: NewSamsungClient(address, token, store, opts),
+		mac:    hw,
+		info: device.DeviceInfo{
+			ID:      "", // Will be set from configuration
+			Name:    "", // Will be set from configuration
+			Type:    "samsung_tv",
+			Model:   "Samsung Tizen",
+			Address: address,
+			Status:  "unknown", // Will be determined by hub
+			Capabilities: []string{
+				"remote_control",
+				"system_control",
+				"app_control",
+			},
+		},
+	}
+}
+
+// GetDeviceInfo returns information about this Samsung device
+func (sr *SamsungRemote) GetDeviceInfo() device.DeviceInfo {
+	return sr.info
+}
+
+// Client returns the underlying Samsung client
+func (sr *SamsungRemote) Client() *SamsungClient {
+	return sr.client
+}
+
+// Process handles JSON action requests and routes them to appropriate methods
+func (sr *SamsungRemote) Process(actionJSON []byte) (*device.ActionResponse, error) {
+	request, err := device.ParseActionRequest(actionJSON)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	switch request.Type {
+	case device.ActionTypeRemote:
+		return sr.processRemoteAction(request)
+	case device.ActionTypeControl:
+		return sr.processControlAction(request)
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported action type: %s", request.Type),
+		}, nil
+	}
+}
+
+// processRemoteAction handles remote control actions
+func (sr *SamsungRemote) processRemoteAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	remoteAction := device.RemoteAction(request.Action)
+
+	key, exists := remoteActionMap[remoteAction]
+	if !exists {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported remote action: %s", request.Action),
+		}, nil
+	}
+
+	// A TV in deep standby drops the WebSocket, so wake it first when we can. A TV only
+	// dozing keeps the WebSocket and needs the key as well
+	if remoteAction == device.RemoteActionPowerOn && sr.mac != nil && !sr.client.testMode {
+		if err := wol.Wake(sr.mac, ""); err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("wake-on-lan failed: %v", err),
+			}, nil
+		}
+		if err := sr.client.SendKey(key); err != nil {
+			// The TV is still booting from the magic packet
+			sr.client.logger.Debug().
+				Err(err).
+				Msg("Power on key not sent after wake-on-lan")
+		}
+		return &device.ActionResponse{
+			Success: true,
+			Data:    fmt.Sprintf("Remote action '%s' executed successfully", request.Action),
+		}, nil
+	}
+
+	if err := sr.client.SendKey(key); err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("remote request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    fmt.Sprintf("Remote action '%s' executed successfully", request.Action),
+	}, nil
+}
+
+// processControlAction handles API control actions
+func (sr *SamsungRemote) processControlAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	switch device.ControlAction(request.Action) {
+	case device.ControlActionPowerStatus:
+		info, err := sr.client.GetDeviceInfo()
+		if err != nil {
+			// The REST API is down while the TV is in standby
+			return &device.ActionResponse{
+				Success: true,
+				Data:    device.PowerStatusData(device.PowerStatusStandby),
+			}, nil
+		}
+		status := device.PowerStatusActive
+		if deviceInfo, ok := info["device"].(map[string]interface{}); ok {
+			if state, ok := deviceInfo["PowerState"].(string); ok && state != "on" {
+				status = device.PowerStatusStandby
+			}
+		}
+		return &device.ActionResponse{
+			Success: true,
+			Data:    device.PowerStatusData(status),
+		}, nil
+
+	case device.ControlActionSystemInfo:
+		info, err := sr.client.GetDeviceInfo()
+		if err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("control request failed: %v", err),
+			}, nil
+		}
+		return &device.ActionResponse{
+			Success: true,
+			Data:    info,
+		}, nil
+
+	case device.ControlActionAppList:
+		apps, err := sr.client.GetInstalledApps()
+		if err != nil {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   fmt.Sprintf("control request failed: %v", err),
+			}, nil
+		}
+		var data interface{}
+		if err := json.Unmarshal(apps, &data); err != nil {
+			return &device.ActionResponse{
+				Success: true,
+				Data:    string(apps),
+			}, nil
+		}
+		return &device.ActionResponse{
+			Success: true,
+			Data:    data,
+		}, nil
+
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported control action: %s", request.Action),
+		}, nil
+	}
+}
+
+// remoteActionMap maps RemoteAction to SamsungKey
+var remoteActionMap = map[device.RemoteAction]SamsungKey{
+	device.RemoteActionPower:       KeyPower,
+	device.RemoteActionPowerOn:     KeyPowerOn,
+	device.RemoteActionPowerOff:    KeyPowerOff,
+	device.RemoteActionVolumeUp:    KeyVolumeUp,
+	device.RemoteActionVolumeDown:  KeyVolumeDown,
+	device.RemoteActionMute:        KeyMute,
+	device.RemoteActionChannelUp:   KeyChannelUp,
+	device.RemoteActionChannelDown: KeyChannelDown,
+	device.RemoteActionUp:          KeyUp,
+	device.RemoteActionDown:        KeyDown,
+	device.RemoteActionLeft:        KeyLeft,
+	device.RemoteActionRight:       KeyRight,
+	device.RemoteActionConfirm:     KeyEnter,
+	device.RemoteActionHome:        KeyHome,
+	device.RemoteActionMenu:        KeyMenu,
+	device.RemoteActionBack:        KeyReturn,
+	device.RemoteActionInput:       KeySource,
+	device.RemoteActionHDMI1:       KeyHDMI1,
+	device.RemoteActionHDMI2:       KeyHDMI2,
+	device.RemoteActionHDMI3:       KeyHDMI3,
+	device.RemoteActionHDMI4:       KeyHDMI4,
+}
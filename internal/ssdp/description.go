@@ -0,0 +1,50 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssdp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Description is the device part of a UPnP device description
+type Description struct {
+	FriendlyName string `xml:"device>friendlyName" json:"friendly_name"`
+	Manufacturer string `xml:"device>manufacturer" json:"manufacturer"`
+	ModelName    string `xml:"device>modelName" json:"model_name"`
+	UDN          string `xml:"device>UDN" json:"udn"`
+}
+
+// Describe fetches the device description a search response points to
+func Describe(client *http.Client, location string) (*Description, error) {
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch device description: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device description failed with status %d", resp.StatusCode)
+	}
+
+	var description Description
+	if err := xml.NewDecoder(resp.Body).Decode(&description); err != nil {
+		return nil, fmt.Errorf("failed to parse device description: %w", err)
+	}
+	description.UDN = strings.TrimPrefix(description.UDN, "uuid:")
+	return &description, nil
+}
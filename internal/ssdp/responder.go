@@ -0,0 +1,146 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssdp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Service is a search target a Responder answers for
+type Service struct {
+	ST       string
+	USN      string
+	Location string
+	Server   string
+}
+
+// Responder answers M-SEARCH requests for a fixed set of services, standing in for
+// devices in tests and simulators
+type Responder struct {
+	conn     *net.UDPConn
+	mutex    sync.Mutex
+	services []Service
+	searches int
+	done     chan struct{}
+}
+
+// NewResponder starts a responder on address. A multicast group is joined on the
+// loopback interface, any other address is listened on directly
+func NewResponder(address string, services ...Service) (*Responder, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve responder address: %w", err)
+	}
+
+	var conn *net.UDPConn
+	if udpAddr.IP.IsMulticast() {
+		loopback, err := loopbackInterface()
+		if err != nil {
+			return nil, err
+		}
+		conn, err = net.ListenMulticastUDP("udp4", loopback, udpAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join multicast group: %w", err)
+		}
+	} else {
+		conn, err = net.ListenUDP("udp4", udpAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+	}
+
+	r := &Responder{
+		conn:     conn,
+		services: services,
+		done:     make(chan struct{}),
+	}
+	go r.serve()
+	return r, nil
+}
+
+// Address returns the address the responder listens on
+func (r *Responder) Address() string {
+	return r.conn.LocalAddr().String()
+}
+
+// Searches returns how many M-SEARCH requests have been received
+func (r *Responder) Searches() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.searches
+}
+
+// Close stops the responder
+func (r *Responder) Close() {
+	r.conn.Close()
+	<-r.done
+}
+
+// serve answers each M-SEARCH with one response per matching service
+func (r *Responder) serve() {
+	defer close(r.done)
+
+	buffer := make([]byte, 2048)
+	for {
+		n, from, err := r.conn.ReadFromUDP(buffer)
+		if err != nil {
+			return
+		}
+
+		request, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buffer[:n])))
+		if err != nil || request.Method != "M-SEARCH" || request.Header.Get("Man") != `"ssdp:discover"` {
+			continue
+		}
+		target := request.Header.Get("St")
+
+		r.mutex.Lock()
+		r.searches++
+		services := r.services
+		r.mutex.Unlock()
+
+		for _, service := range services {
+			if target != SearchTargetAll && target != service.ST {
+				continue
+			}
+			response := fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+				"CACHE-CONTROL: max-age=1800\r\n"+
+				"EXT:\r\n"+
+				"LOCATION: %s\r\n"+
+				"SERVER: %s\r\n"+
+				"ST: %s\r\n"+
+				"USN: %s\r\n\r\n", service.Location, service.Server, service.ST, service.USN)
+			r.conn.WriteToUDP([]byte(response), from)
+		}
+	}
+}
+
+// loopbackInterface returns the loopback interface multicast tests run on
+func loopbackInterface() (*net.Interface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for i := range interfaces {
+		if interfaces[i].Flags&net.FlagLoopback != 0 && interfaces[i].Flags&net.FlagUp != 0 {
+			return &interfaces[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no loopback interface")
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssdp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// MulticastAddress is the SSDP multicast group and port
+	MulticastAddress = "239.255.255.250:1900"
+	// SearchTargetAll asks every device to answer
+	SearchTargetAll = "ssdp:all"
+	// DefaultSearchTimeout is how long a search waits for answers
+	DefaultSearchTimeout = 3 * time.Second
+)
+
+// Response is a device's answer to an M-SEARCH
+type Response struct {
+	ST       string `json:"st"`
+	USN      string `json:"usn"`
+	Location string `json:"location"`
+	Server   string `json:"server,omitempty"`
+	Address  string `json:"address"` // IP the answer came from
+}
+
+// UUID returns the uuid part of the USN, which identifies the device across its services
+func (r Response) UUID() string {
+	uuid, _, _ := strings.Cut(strings.TrimPrefix(r.USN, "uuid:"), "::")
+	return uuid
+}
+
+// SearchOptions configures an M-SEARCH
+type SearchOptions struct {
+	Targets      []string      // search targets, ssdp:all when empty
+	Address      string        // where the search is sent, MulticastAddress when empty
+	LocalAddress string        // address the search is sent from, any when empty
+	Timeout      time.Duration // how long to collect answers, DefaultSearchTimeout when zero
+}
+
+// Search sends an M-SEARCH for each target and collects the answers until the timeout.
+// Devices answering for several targets are reported once per target
+func Search(options SearchOptions) ([]Response, error) {
+	if options.Address == "" {
+		options.Address = MulticastAddress
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultSearchTimeout
+	}
+	if len(options.Targets) == 0 {
+		options.Targets = []string{SearchTargetAll}
+	}
+
+	destination, err := net.ResolveUDPAddr("udp4", options.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve search address: %w", err)
+	}
+	local := &net.UDPAddr{}
+	if options.LocalAddress != "" {
+		if local, err = net.ResolveUDPAddr("udp4", options.LocalAddress); err != nil {
+			return nil, fmt.Errorf("failed to resolve local address: %w", err)
+		}
+	}
+
+	conn, err := net.ListenUDP("udp4", local)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search socket: %w", err)
+	}
+	defer conn.Close()
+
+	// Devices wait up to MX seconds before answering, to spread the replies
+	mx := int(options.Timeout / time.Second)
+	if mx < 1 {
+		mx = 1
+	}
+	for _, target := range options.Targets {
+		request := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\n"+
+			"HOST: %s\r\n"+
+			"MAN: \"ssdp:discover\"\r\n"+
+			"MX: %d\r\n"+
+			"ST: %s\r\n\r\n", MulticastAddress, mx, target)
+		if _, err := conn.WriteToUDP([]byte(request), destination); err != nil {
+			return nil, fmt.Errorf("failed to send search: %w", err)
+		}
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(options.Timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set search deadline: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var responses []Response
+	buffer := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return responses, nil
+			}
+			return responses, fmt.Errorf("failed to read search response: %w", err)
+		}
+
+		response, ok := parseResponse(buffer[:n])
+		if !ok {
+			continue
+		}
+		response.Address = from.IP.String()
+
+		key := response.USN + "\x00" + response.ST
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		responses = append(responses, response)
+	}
+}
+
+// parseResponse parses an M-SEARCH answer, ignoring anything else on the socket
+func parseResponse(data []byte) (Response, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return Response{}, false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Location") == "" {
+		return Response{}, false
+	}
+
+	return Response{
+		ST:       resp.Header.Get("St"),
+		USN:      resp.Header.Get("Usn"),
+		Location: resp.Header.Get("Location"),
+		Server:   resp.Header.Get("Server"),
+	}, true
+}
@@ -0,0 +1,450 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webos
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/device"
+	"net"
+	"sync"
+	"time"
+
+	"lucas/internal/logger"
+	"lucas/internal/websocket"
+
+	"github.com/rs/zerolog"
+)
+
+// WebOSMessage is an SSAP message exchanged with the TV
+type WebOSMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	URI     string          `json:"uri,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// WebOSClient represents a client for the LG webOS SSAP API
+type WebOSClient struct {
+	address        string
+	clientKey      string
+	store          device.ConfigStore
+	conn           *websocket.Conn
+	pointer        *websocket.Conn
+	pending        map[string]chan WebOSMessage
+	nextID         int
+	mutex          sync.Mutex
+	connectMutex   sync.Mutex
+	requestTimeout time.Duration
+	pairingTimeout time.Duration
+	debugMode      bool
+	testMode       bool
+	simulator      *simulator
+	logger         zerolog.Logger
+}
+
+// NewWebOSClient creates a new webOS client, clientKey may be empty before pairing
+func NewWebOSClient(address, clientKey string, store device.ConfigStore, options internal.FnModeOptions) *WebOSClient {
+	client := &WebOSClient{
+		address:        address,
+		clientKey:      clientKey,
+		store:          store,
+		pending:        make(map[string]chan WebOSMessage),
+		requestTimeout: 10 * time.Second,
+		pairingTimeout: 60 * time.Second,
+		debugMode:      options.Debug,
+		testMode:       options.Test,
+		logger:         logger.New(),
+	}
+	if options.Test {
+		client.simulator = newSimulator()
+	}
+	if options.Debug {
+		logger.SetLevel(logger.LOG_DEBUG)
+	}
+
+	return client
+}
+
+// ClientKey returns the current pairing key
+func (c *WebOSClient) ClientKey() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.clientKey
+}
+
+// Request sends an SSAP request and returns the response payload
+func (c *WebOSClient) Request(uri WebOSURI, payload interface{}) (map[string]interface{}, error) {
+	if c.testMode {
+		c.simulatePairing()
+		result, err := c.simulator.request(uri, payload)
+		c.logger.Info().
+			Str("uri", string(uri)).
+			Str("address", c.address).
+			Msg("Test mode: SSAP request simulated")
+		return result, err
+	}
+
+	if _, err := c.connection(); err != nil {
+		return nil, err
+	}
+
+	message := WebOSMessage{
+		Type: MessageRequest,
+		URI:  string(uri),
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		message.Payload = data
+	}
+
+	response, err := c.roundTrip(message, c.requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if response.Type == MessageError {
+		return nil, fmt.Errorf("request %s failed: %s", uri, response.Error)
+	}
+
+	var result map[string]interface{}
+	if len(response.Payload) > 0 {
+		if err := json.Unmarshal(response.Payload, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse response payload: %w", err)
+		}
+	}
+	if ok, exists := result["returnValue"].(bool); exists && !ok {
+		return result, fmt.Errorf("request %s failed: %v", uri, result["errorText"])
+	}
+
+	return result, nil
+}
+
+// SendButton presses a button on the pointer input socket
+func (c *WebOSClient) SendButton(button WebOSButton) error {
+	if c.testMode {
+		c.simulatePairing()
+		c.logger.Info().
+			Str("button", string(button)).
+			Str("address", c.address).
+			Msg("Test mode: Button press simulated")
+		return nil
+	}
+
+	pointer, err := c.pointerConnection()
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("type:button\nname:%s\n\n", button)
+	if err := pointer.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		c.mutex.Lock()
+		c.pointer = nil
+		c.mutex.Unlock()
+		pointer.Close()
+		return fmt.Errorf("failed to send button: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the SSAP and pointer connections
+func (c *WebOSClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pointer != nil {
+		c.pointer.Close()
+		c.pointer = nil
+	}
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// connection returns the open SSAP connection, connecting and registering if required
+func (c *WebOSClient) connection() (*websocket.Conn, error) {
+	// Hold callers back until registration finishes
+	c.connectMutex.Lock()
+	defer c.connectMutex.Unlock()
+
+	c.mutex.Lock()
+	if c.conn != nil {
+		conn := c.conn
+		c.mutex.Unlock()
+		return conn, nil
+	}
+
+	conn, _, err := websocket.Dial(c.socketURL(), &websocket.DialOptions{
+		// webOS serves a self-signed certificate on the secure port
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		Timeout:   10 * time.Second,
+	})
+	if err != nil {
+		c.mutex.Unlock()
+		return nil, fmt.Errorf("failed to connect to TV: %w", err)
+	}
+	c.conn = conn
+	clientKey := c.clientKey
+	c.mutex.Unlock()
+
+	go c.readLoop(conn)
+
+	if err := c.register(clientKey); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Connected to webOS TV")
+
+	return conn, nil
+}
+
+// register pairs with the TV, the user accepts a prompt the first time
+func (c *WebOSClient) register(clientKey string) error {
+	payload := map[string]interface{}{
+		"forcePairing": false,
+		"pairingType":  "PROMPT",
+		"manifest": map[string]interface{}{
+			"manifestVersion": 1,
+			"appVersion":      "1.1",
+			"permissions":     registrationPermissions,
+		},
+	}
+	if clientKey != "" {
+		payload["client-key"] = clientKey
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registration: %w", err)
+	}
+
+	id, responses := c.newPending()
+	defer c.removePending(id)
+
+	message := WebOSMessage{Type: MessageRegister, ID: id, Payload: data}
+	if err := c.write(message); err != nil {
+		return err
+	}
+
+	timeout := time.After(c.pairingTimeout)
+	for {
+		select {
+		case response, ok := <-responses:
+			if !ok {
+				return fmt.Errorf("connection closed during registration")
+			}
+			switch response.Type {
+			case MessageRegistered:
+				var registered struct {
+					ClientKey string `json:"client-key"`
+				}
+				json.Unmarshal(response.Payload, &registered)
+				c.updateClientKey(registered.ClientKey)
+				return nil
+			case MessageError:
+				return fmt.Errorf("registration rejected: %s", response.Error)
+			default:
+				// A PROMPT response means the TV is waiting for the user
+				c.logger.Info().
+					Str("address", c.address).
+					Msg("Waiting for pairing to be accepted on the TV")
+			}
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for pairing")
+		}
+	}
+}
+
+// roundTrip sends a message and waits for the matching reply
+func (c *WebOSClient) roundTrip(message WebOSMessage, timeout time.Duration) (WebOSMessage, error) {
+	id, responses := c.newPending()
+	defer c.removePending(id)
+
+	message.ID = id
+	if err := c.write(message); err != nil {
+		return WebOSMessage{}, err
+	}
+
+	select {
+	case response, ok := <-responses:
+		if !ok {
+			return WebOSMessage{}, fmt.Errorf("connection closed before response")
+		}
+		return response, nil
+	case <-time.After(timeout):
+		return WebOSMessage{}, fmt.Errorf("timed out waiting for response to %s", message.URI)
+	}
+}
+
+// write sends a message on the SSAP connection
+func (c *WebOSClient) write(message WebOSMessage) error {
+	c.mutex.Lock()
+	conn := c.conn
+	c.mutex.Unlock()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteJSON(message); err != nil {
+		c.Close()
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	c.logger.Debug().
+		Str("type", message.Type).
+		Str("id", message.ID).
+		Str("uri", message.URI).
+		Msg("SSAP message sent")
+	return nil
+}
+
+// readLoop dispatches incoming messages to the pending requests by ID
+func (c *WebOSClient) readLoop(conn *websocket.Conn) {
+	for {
+		var message WebOSMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			c.mutex.Lock()
+			if c.conn == conn {
+				c.conn = nil
+			}
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			c.mutex.Unlock()
+			c.logger.Debug().Err(err).Msg("webOS connection closed")
+			return
+		}
+
+		c.mutex.Lock()
+		ch, exists := c.pending[message.ID]
+		c.mutex.Unlock()
+		if !exists {
+			continue
+		}
+
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+}
+
+// newPending allocates a request ID and its reply channel
+func (c *WebOSClient) newPending() (string, chan WebOSMessage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.nextID++
+	id := fmt.Sprintf("lucas_%d", c.nextID)
+	ch := make(chan WebOSMessage, 4)
+	c.pending[id] = ch
+	return id, ch
+}
+
+// removePending forgets a request ID
+func (c *WebOSClient) removePending(id string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.pending, id)
+}
+
+// pointerConnection returns the pointer input socket, opening it if required
+func (c *WebOSClient) pointerConnection() (*websocket.Conn, error) {
+	c.mutex.Lock()
+	pointer := c.pointer
+	c.mutex.Unlock()
+	if pointer != nil {
+		return pointer, nil
+	}
+
+	result, err := c.Request(GetPointerInput, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pointer input socket: %w", err)
+	}
+	socketPath, _ := result["socketPath"].(string)
+	if socketPath == "" {
+		return nil, fmt.Errorf("TV did not return a pointer input socket")
+	}
+
+	pointer, _, err = websocket.Dial(socketPath, &websocket.DialOptions{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		Timeout:   10 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to pointer input socket: %w", err)
+	}
+
+	c.mutex.Lock()
+	c.pointer = pointer
+	c.mutex.Unlock()
+	return pointer, nil
+}
+
+// socketURL builds the SSAP WebSocket URL for the configured address
+func (c *WebOSClient) socketURL() string {
+	host, port, err := net.SplitHostPort(c.address)
+	if err != nil {
+		host, port = c.address, InsecurePort
+	}
+
+	scheme := "ws"
+	if port == SecurePort {
+		scheme = "wss"
+	}
+	return fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(host, port))
+}
+
+// updateClientKey stores a newly issued client key
+func (c *WebOSClient) updateClientKey(clientKey string) {
+	c.mutex.Lock()
+	changed := clientKey != "" && clientKey != c.clientKey
+	if changed {
+		c.clientKey = clientKey
+	}
+	c.mutex.Unlock()
+
+	if !changed || c.store == nil {
+		return
+	}
+	if err := c.store.SetCredential(clientKey); err != nil {
+		c.logger.Error().Err(err).Msg("Failed to save webOS client key")
+		return
+	}
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Saved webOS client key")
+}
+
+// simulatePairing hands out a client key the first time a test mode client connects
+func (c *WebOSClient) simulatePairing() {
+	if c.ClientKey() != "" {
+		return
+	}
+	c.logger.Info().
+		Str("address", c.address).
+		Msg("Test mode: Pairing simulated")
+	c.updateClientKey("test-client-key")
+}
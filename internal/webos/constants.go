@@ -0,0 +1,103 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webos
+
+// WebOSURI represents an SSAP request URI
+type WebOSURI string
+
+// WebOSButton represents a pointer input socket button name
+type WebOSButton string
+
+// SSAP request URIs for LG webOS TVs
+const (
+	// System URIs
+	TurnOff         WebOSURI = "ssap://system/turnOff"
+	GetSystemInfo   WebOSURI = "ssap://system/getSystemInfo"
+	GetPowerState   WebOSURI = "ssap://com.webos.service.tvpower/power/getPowerState"
+	LaunchApp       WebOSURI = "ssap://system.launcher/launch"
+	GetPointerInput WebOSURI = "ssap://com.webos.service.networkinput/getPointerInputSocket"
+
+	// Audio URIs
+	VolumeUp   WebOSURI = "ssap://audio/volumeUp"
+	VolumeDown WebOSURI = "ssap://audio/volumeDown"
+	GetVolume  WebOSURI = "ssap://audio/getVolume"
+	SetVolume  WebOSURI = "ssap://audio/setVolume"
+	SetMute    WebOSURI = "ssap://audio/setMute"
+
+	// TV URIs
+	ChannelUp      WebOSURI = "ssap://tv/channelUp"
+	ChannelDown    WebOSURI = "ssap://tv/channelDown"
+	GetChannelList WebOSURI = "ssap://tv/getChannelList"
+	SwitchInput    WebOSURI = "ssap://tv/switchInput"
+
+	// Application URIs
+	ListLaunchPoints     WebOSURI = "ssap://com.webos.applicationManager/listLaunchPoints"
+	GetForegroundAppInfo WebOSURI = "ssap://com.webos.applicationManager/getForegroundAppInfo"
+)
+
+// Pointer input socket buttons
+const (
+	ButtonUp    WebOSButton = "UP"
+	ButtonDown  WebOSButton = "DOWN"
+	ButtonLeft  WebOSButton = "LEFT"
+	ButtonRight WebOSButton = "RIGHT"
+	ButtonEnter WebOSButton = "ENTER"
+	ButtonHome  WebOSButton = "HOME"
+	ButtonMenu  WebOSButton = "MENU"
+	ButtonBack  WebOSButton = "BACK"
+	ButtonMute  WebOSButton = "MUTE"
+)
+
+// SSAP message types
+const (
+	MessageRegister   = "register"
+	MessageRegistered = "registered"
+	MessageRequest    = "request"
+	MessageResponse   = "response"
+	MessageError      = "error"
+)
+
+// Default ports of the SSAP WebSocket server
+const (
+	InsecurePort = "3000"
+	SecurePort   = "3001"
+)
+
+// InputPickerAppID is the webOS application that shows the input list
+const InputPickerAppID = "com.webos.app.inputpicker"
+
+// registrationPermissions are requested when pairing with the TV
+var registrationPermissions = []string{
+	"LAUNCH",
+	"LAUNCH_WEBAPP",
+	"APP_TO_APP",
+	"CONTROL_AUDIO",
+	"CONTROL_DISPLAY",
+	"CONTROL_INPUT_JOYSTICK",
+	"CONTROL_INPUT_MEDIA_PLAYBACK",
+	"CONTROL_INPUT_TV",
+	"CONTROL_POWER",
+	"READ_APP_STATUS",
+	"READ_CURRENT_CHANNEL",
+	"READ_INPUT_DEVICE_LIST",
+	"READ_INSTALLED_APPS",
+	"READ_NETWORK_STATE",
+	"READ_POWER_STATE",
+	"READ_RUNNING_APPS",
+	"READ_TV_CHANNEL_LIST",
+	"WRITE_NOTIFICATION_TOAST",
+	"CONTROL_TV_SCREEN",
+	"READ_SETTINGS",
+}
@@ -0,0 +1,291 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webos
+
+import (
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/device"
+	"lucas/internal/wol"
+	"net"
+	"strconv"
+)
+
+// WebOSRemote implements the Device interface for LG webOS TVs
+type WebOSRemote struct {
+	client *WebOSClient
+	mac    net.HardwareAddr
+	info   device.DeviceInfo
+}
+
+// NewWebOSRemote creates a new WebOSRemote device, mac is optional and enables power_on
+func NewWebOSRemote(address, clientKey, mac string, store device.ConfigStore, options *internal.FnModeOptions) *WebOSRemote {
+	var opts internal.FnModeOptions
+	if options != nil {
+		opts = *options
+	}
+
+	// An invalid MAC only disables Wake-on-LAN
+	hw, _ := net.ParseMAC(mac)
+
+	return &WebOSRemote{
+		client: NewWebOSClient(address, clientKey, store, opts),
+		mac:    hw,
+		info: device.DeviceInfo{
+			ID:      "", // Will be set from configuration
+			Name:    "", // Will be set from configuration
+			Type:    "webos_tv",
+			Model:   "LG webOS",
+			Address: address,
+			Status:  "unknown", // Will be determined by hub
+			Capabilities: []string{
+				"remote_control",
+				"system_control",
+				"audio_control",
+				"content_control",
+				"app_control",
+			},
+		},
+	}
+}
+
+// GetDeviceInfo returns information about this webOS device
+func (wr *WebOSRemote) GetDeviceInfo() device.DeviceInfo {
+	return wr.info
+}
+
+// Client returns the underlying webOS client
+func (wr *WebOSRemote) Client() *WebOSClient {
+	return wr.client
+}
+
+// Process handles JSON action requests and routes them to appropriate methods
+func (wr *WebOSRemote) Process(actionJSON []byte) (*device.ActionResponse, error) {
+	request, err := device.ParseActionRequest(actionJSON)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	switch request.Type {
+	case device.ActionTypeRemote:
+		return wr.processRemoteAction(request)
+	case device.ActionTypeControl:
+		return wr.processControlAction(request)
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported action type: %s", request.Type),
+		}, nil
+	}
+}
+
+// processRemoteAction handles remote control actions
+func (wr *WebOSRemote) processRemoteAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	remoteAction := device.RemoteAction(request.Action)
+
+	command, exists := remoteActionMap[remoteAction]
+	if !exists {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported remote action: %s", request.Action),
+		}, nil
+	}
+
+	var err error
+	switch {
+	case command.wake:
+		err = wr.wake()
+	case command.button != "":
+		err = wr.client.SendButton(command.button)
+	default:
+		_, err = wr.client.Request(command.uri, command.payload)
+	}
+
+	// power toggles: a TV that cannot be reached is off, so wake it
+	if err != nil && remoteAction == device.RemoteActionPower && wr.mac != nil {
+		err = wr.wake()
+	}
+
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("remote request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    fmt.Sprintf("Remote action '%s' executed successfully", request.Action),
+	}, nil
+}
+
+// processControlAction handles API control actions
+func (wr *WebOSRemote) processControlAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	controlAction := device.ControlAction(request.Action)
+
+	uri, exists := controlActionMap[controlAction]
+	if !exists {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported control action: %s", request.Action),
+		}, nil
+	}
+
+	payload, err := buildControlPayload(controlAction, request.Parameters)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid parameters: %v", err),
+		}, nil
+	}
+
+	result, err := wr.client.Request(uri, payload)
+	if controlAction == device.ControlActionPowerStatus {
+		// The SSAP socket is closed while the TV is off
+		status := device.PowerStatusStandby
+		if err == nil && result["state"] == "Active" {
+			status = device.PowerStatusActive
+		}
+		return &device.ActionResponse{
+			Success: true,
+			Data:    device.PowerStatusData(status),
+		}, nil
+	}
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("control request failed: %v", err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    result,
+	}, nil
+}
+
+// wake powers the TV on with Wake-on-LAN
+func (wr *WebOSRemote) wake() error {
+	if wr.client.testMode {
+		wr.client.simulator.mutex.Lock()
+		wr.client.simulator.power = true
+		wr.client.simulator.mutex.Unlock()
+		return nil
+	}
+	if wr.mac == nil {
+		return fmt.Errorf("power_on requires the TV mac address for Wake-on-LAN")
+	}
+	return wol.Wake(wr.mac, "")
+}
+
+// buildControlPayload builds the SSAP payload for control actions
+func buildControlPayload(action device.ControlAction, requestParams map[string]interface{}) (map[string]interface{}, error) {
+	switch action {
+	case device.ControlActionSetVolume:
+		volume, exists := requestParams["volume"]
+		if !exists {
+			return nil, fmt.Errorf("volume parameter is required for set_volume action")
+		}
+		var level int
+		switch v := volume.(type) {
+		case int:
+			level = v
+		case float64:
+			level = int(v)
+		case string:
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid volume parameter: %s", v)
+			}
+			level = parsed
+		default:
+			return nil, fmt.Errorf("invalid volume parameter type")
+		}
+		if level < 0 || level > 100 {
+			return nil, fmt.Errorf("volume must be between 0 and 100")
+		}
+		return map[string]interface{}{"volume": level}, nil
+
+	case device.ControlActionSetMute:
+		status, exists := requestParams["status"]
+		if !exists {
+			return nil, fmt.Errorf("status parameter is required for set_mute action")
+		}
+		var mute bool
+		switch s := status.(type) {
+		case bool:
+			mute = s
+		case string:
+			parsed, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid status parameter: %s", s)
+			}
+			mute = parsed
+		default:
+			return nil, fmt.Errorf("invalid status parameter type")
+		}
+		return map[string]interface{}{"mute": mute}, nil
+	}
+
+	return nil, nil
+}
+
+// remoteCommand describes how a RemoteAction is sent to the TV
+type remoteCommand struct {
+	uri     WebOSURI
+	payload map[string]interface{}
+	button  WebOSButton
+	wake    bool
+}
+
+// remoteActionMap maps RemoteAction to SSAP requests or pointer buttons
+var remoteActionMap = map[device.RemoteAction]remoteCommand{
+	device.RemoteActionPower:       {uri: TurnOff},
+	device.RemoteActionPowerOn:     {wake: true},
+	device.RemoteActionPowerOff:    {uri: TurnOff},
+	device.RemoteActionVolumeUp:    {uri: VolumeUp},
+	device.RemoteActionVolumeDown:  {uri: VolumeDown},
+	device.RemoteActionMute:        {button: ButtonMute},
+	device.RemoteActionChannelUp:   {uri: ChannelUp},
+	device.RemoteActionChannelDown: {uri: ChannelDown},
+	device.RemoteActionUp:          {button: ButtonUp},
+	device.RemoteActionDown:        {button: ButtonDown},
+	device.RemoteActionLeft:        {button: ButtonLeft},
+	device.RemoteActionRight:       {button: ButtonRight},
+	device.RemoteActionConfirm:     {button: ButtonEnter},
+	device.RemoteActionHome:        {button: ButtonHome},
+	device.RemoteActionMenu:        {button: ButtonMenu},
+	device.RemoteActionBack:        {button: ButtonBack},
+	device.RemoteActionInput:       {uri: LaunchApp, payload: map[string]interface{}{"id": InputPickerAppID}},
+	device.RemoteActionHDMI1:       {uri: SwitchInput, payload: map[string]interface{}{"inputId": "HDMI_1"}},
+	device.RemoteActionHDMI2:       {uri: SwitchInput, payload: map[string]interface{}{"inputId": "HDMI_2"}},
+	device.RemoteActionHDMI3:       {uri: SwitchInput, payload: map[string]interface{}{"inputId": "HDMI_3"}},
+	device.RemoteActionHDMI4:       {uri: SwitchInput, payload: map[string]interface{}{"inputId": "HDMI_4"}},
+}
+
+// controlActionMap maps ControlAction to SSAP request URIs
+var controlActionMap = map[device.ControlAction]WebOSURI{
+	device.ControlActionPowerStatus:    GetPowerState,
+	device.ControlActionSystemInfo:     GetSystemInfo,
+	device.ControlActionVolumeInfo:     GetVolume,
+	device.ControlActionPlayingContent: GetForegroundAppInfo,
+	device.ControlActionAppList:        ListLaunchPoints,
+	device.ControlActionContentList:    GetChannelList,
+	device.ControlActionSetVolume:      SetVolume,
+	device.ControlActionSetMute:        SetMute,
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webos
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// simulator keeps TV state for test mode so reads reflect earlier writes
+type simulator struct {
+	mutex  sync.Mutex
+	power  bool
+	volume int
+	muted  bool
+	input  string
+}
+
+// newSimulator creates a simulator for a TV that is switched on
+func newSimulator() *simulator {
+	return &simulator{
+		power:  true,
+		volume: 10,
+		input:  "HDMI_1",
+	}
+}
+
+// request answers an SSAP request from the simulated state
+func (s *simulator) request(uri WebOSURI, payload interface{}) (map[string]interface{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	params := map[string]interface{}{}
+	if payload != nil {
+		data, _ := json.Marshal(payload)
+		json.Unmarshal(data, &params)
+	}
+
+	switch uri {
+	case TurnOff:
+		s.power = false
+	case GetPowerState:
+		state := "Active"
+		if !s.power {
+			state = "Suspend"
+		}
+		return map[string]interface{}{"returnValue": true, "state": state}, nil
+	case VolumeUp:
+		if s.volume < 100 {
+			s.volume++
+		}
+	case VolumeDown:
+		if s.volume > 0 {
+			s.volume--
+		}
+	case SetVolume:
+		volume, ok := params["volume"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("volume is required")
+		}
+		s.volume = int(volume)
+	case SetMute:
+		muted, ok := params["mute"].(bool)
+		if !ok {
+			return nil, fmt.Errorf("mute is required")
+		}
+		s.muted = muted
+	case GetVolume:
+		return map[string]interface{}{
+			"returnValue": true,
+			"volume":      s.volume,
+			"muted":       s.muted,
+			"scenario":    "mastervolume_tv_speaker",
+		}, nil
+	case SwitchInput:
+		if input, ok := params["inputId"].(string); ok {
+			s.input = input
+		}
+	case GetSystemInfo:
+		return map[string]interface{}{
+			"returnValue": true,
+			"modelName":   "SIMULATOR",
+			"features":    map[string]interface{}{"3d": false, "dvr": true},
+		}, nil
+	case GetForegroundAppInfo:
+		return map[string]interface{}{
+			"returnValue": true,
+			"appId":       "com.webos.app.hdmi" + s.input[len(s.input)-1:],
+		}, nil
+	case ListLaunchPoints:
+		return map[string]interface{}{
+			"returnValue": true,
+			"launchPoints": []interface{}{
+				map[string]interface{}{"id": "youtube.leanback.v4", "title": "YouTube"},
+				map[string]interface{}{"id": "netflix", "title": "Netflix"},
+			},
+		}, nil
+	case GetChannelList:
+		return map[string]interface{}{
+			"returnValue":  true,
+			"channelList":  []interface{}{map[string]interface{}{"channelNumber": "1", "channelName": "Simulated"}},
+			"channelCount": 1,
+		}, nil
+	}
+
+	return map[string]interface{}{"returnValue": true}, nil
+}
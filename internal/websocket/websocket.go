@@ -0,0 +1,417 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package websocket implements the subset of RFC 6455 needed by device drivers
+// and their test servers
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessageType is the WebSocket frame opcode
+type MessageType int
+
+const (
+	ContinuationMessage MessageType = 0x0
+	TextMessage         MessageType = 0x1
+	BinaryMessage       MessageType = 0x2
+	CloseMessage        MessageType = 0x8
+	PingMessage         MessageType = 0x9
+	PongMessage         MessageType = 0xA
+)
+
+// DefaultMaxMessageSize limits the size of a single reassembled message
+const DefaultMaxMessageSize = 16 << 20
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrClosed is returned when the peer closed the connection
+var ErrClosed = errors.New("websocket: connection closed")
+
+// DialOptions configures an outgoing WebSocket connection
+type DialOptions struct {
+	Header    http.Header
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+}
+
+// Conn is a WebSocket connection
+type Conn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	client         bool
+	writeMutex     sync.Mutex
+	closeOnce      sync.Once
+	MaxMessageSize int64
+}
+
+// Dial opens a WebSocket connection to a ws:// or wss:// URL
+func Dial(rawURL string, options *DialOptions) (*Conn, *http.Response, error) {
+	if options == nil {
+		options = &DialOptions{}
+	}
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse websocket url: %w", err)
+	}
+
+	host := u.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var netConn net.Conn
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		netConn, err = dialer.Dial("tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+		tlsConfig := options.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = u.Hostname()
+		}
+		netConn, err = tls.DialWithDialer(dialer, "tcp", host, tlsConfig)
+	default:
+		return nil, nil, fmt.Errorf("unsupported websocket scheme: %s", u.Scheme)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		netConn.Close()
+		return nil, nil, fmt.Errorf("failed to generate websocket key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for name, values := range options.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	netConn.SetDeadline(time.Now().Add(timeout))
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, fmt.Errorf("failed to send handshake: %w", err)
+	}
+
+	reader := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, fmt.Errorf("failed to read handshake response: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		netConn.Close()
+		return nil, resp, fmt.Errorf("handshake failed with status %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, resp, fmt.Errorf("handshake failed: invalid accept key")
+	}
+	netConn.SetDeadline(time.Time{})
+
+	return newConn(netConn, reader, true), resp, nil
+}
+
+// Upgrade upgrades an HTTP server request to a WebSocket connection
+func Upgrade(w http.ResponseWriter, r *http.Request, header http.Header) (*Conn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, fmt.Errorf("not a websocket upgrade request")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, fmt.Errorf("missing websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	b.WriteString("Upgrade: websocket\r\n")
+	b.WriteString("Connection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	for name, values := range header {
+		for _, value := range values {
+			b.WriteString(name + ": " + value + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	return newConn(netConn, rw.Reader, false), nil
+}
+
+// newConn wraps an established connection
+func newConn(netConn net.Conn, reader *bufio.Reader, client bool) *Conn {
+	return &Conn{
+		conn:           netConn,
+		reader:         reader,
+		client:         client,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// ReadMessage reads the next data message, answering pings transparently
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var messageType MessageType
+	var message []byte
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			c.Close()
+			return 0, nil, ErrClosed
+		case ContinuationMessage:
+			if messageType == 0 {
+				return 0, nil, fmt.Errorf("websocket: unexpected continuation frame")
+			}
+		default:
+			messageType = opcode
+			message = message[:0]
+		}
+
+		message = append(message, payload...)
+		if int64(len(message)) > c.MaxMessageSize {
+			return 0, nil, fmt.Errorf("websocket: message exceeds %d bytes", c.MaxMessageSize)
+		}
+		if fin {
+			return messageType, message, nil
+		}
+	}
+}
+
+// WriteMessage writes a single frame message
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	return c.writeFrame(messageType, data)
+}
+
+// ReadJSON reads the next message and decodes it as JSON
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteJSON encodes v as JSON and writes it as a text message
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(TextMessage, data)
+}
+
+// SetReadDeadline sets the deadline for future reads
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future writes
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close sends a close frame and closes the underlying connection
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.writeFrame(CloseMessage, []byte{0x03, 0xE8}) // 1000 normal closure
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// readFrame reads a single frame from the connection
+func (c *Conn) readFrame() (bool, MessageType, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+			return false, 0, nil, ErrClosed
+		}
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := MessageType(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if length < 0 || length > c.MaxMessageSize {
+		return false, 0, nil, fmt.Errorf("websocket: frame exceeds %d bytes", c.MaxMessageSize)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// writeFrame writes a single final frame, masking it when acting as a client
+func (c *Conn) writeFrame(opcode MessageType, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|byte(opcode))
+
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return fmt.Errorf("failed to generate mask: %w", err)
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for a key
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains reports whether a comma separated header contains a token
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
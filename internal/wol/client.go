@@ -0,0 +1,365 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"lucas/internal"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"lucas/internal/logger"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// WOLClient sends Wake-on-LAN packets and probes host reachability
+type WOLClient struct {
+	httpClient *http.Client
+	address    string
+	config     Config
+	debugMode  bool
+	testMode   bool
+	simulated  bool
+	mutex      sync.Mutex
+	logger     zerolog.Logger
+}
+
+// NewWOLClient creates a new Wake-on-LAN client
+func NewWOLClient(address string, config Config, options internal.FnModeOptions) *WOLClient {
+	client := &WOLClient{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		address:   address,
+		config:    config,
+		debugMode: options.Debug,
+		testMode:  options.Test,
+		logger:    logger.New(),
+	}
+	if options.Debug {
+		logger.SetLevel(logger.LOG_DEBUG)
+	}
+
+	return client
+}
+
+// MagicPacket builds the 102 byte magic packet for a MAC address
+func MagicPacket(mac net.HardwareAddr) []byte {
+	packet := make([]byte, 0, 102)
+	packet = append(packet, bytes.Repeat([]byte{0xFF}, 6)...)
+	for i := 0; i < 16; i++ {
+		packet = append(packet, mac...)
+	}
+	return packet
+}
+
+// Wake sends a magic packet for mac to the broadcast address
+func Wake(mac net.HardwareAddr, broadcast string) error {
+	if broadcast == "" {
+		broadcast = defaultBroadcast
+	}
+
+	conn, err := net.Dial("udp", broadcast)
+	if err != nil {
+		return fmt.Errorf("failed to open broadcast socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(MagicPacket(mac)); err != nil {
+		return fmt.Errorf("failed to send magic packet: %w", err)
+	}
+
+	return nil
+}
+
+// SendMagicPacket broadcasts a magic packet to wake the host
+func (c *WOLClient) SendMagicPacket() error {
+	if c.testMode {
+		c.mutex.Lock()
+		c.simulated = true
+		c.mutex.Unlock()
+		c.logger.Info().
+			Str("mac", c.config.MAC.String()).
+			Str("broadcast", c.config.Broadcast).
+			Msg("Test mode: Magic packet simulated")
+		return nil
+	}
+
+	if err := Wake(c.config.MAC, c.config.Broadcast); err != nil {
+		return err
+	}
+
+	c.logger.Debug().
+		Str("mac", c.config.MAC.String()).
+		Str("broadcast", c.config.Broadcast).
+		Msg("Magic packet sent")
+
+	return nil
+}
+
+// IsReachable reports whether the host answers the configured probe
+func (c *WOLClient) IsReachable() bool {
+	if c.testMode {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.simulated
+	}
+
+	host, ports := c.probeTarget()
+
+	if c.config.Probe == ProbeICMP {
+		reachable, err := c.pingICMP(host)
+		if err == nil {
+			return reachable
+		}
+		// Raw sockets usually need elevated privileges, fall back to TCP
+		c.logger.Debug().
+			Err(err).
+			Str("host", host).
+			Msg("ICMP probe unavailable, falling back to TCP")
+	}
+
+	return c.probeTCP(host, ports)
+}
+
+// Shutdown runs the configured power off hook
+func (c *WOLClient) Shutdown() error {
+	if c.testMode {
+		c.mutex.Lock()
+		c.simulated = false
+		c.mutex.Unlock()
+		c.logger.Info().
+			Str("address", c.address).
+			Str("method", c.config.Shutdown.Method).
+			Msg("Test mode: Shutdown simulated")
+		return nil
+	}
+
+	switch c.config.Shutdown.Method {
+	case ShutdownHTTP:
+		return c.shutdownHTTP()
+	case ShutdownSSH:
+		return c.shutdownSSH()
+	default:
+		return fmt.Errorf("no shutdown hook configured")
+	}
+}
+
+// probeTarget splits the device address into host and the ports to probe
+func (c *WOLClient) probeTarget() (string, []int) {
+	host, portStr, err := net.SplitHostPort(c.address)
+	if err != nil {
+		return c.address, c.config.ProbePorts
+	}
+
+	ports := c.config.ProbePorts
+	if port, err := strconv.Atoi(portStr); err == nil {
+		ports = append([]int{port}, ports...)
+	}
+	return host, ports
+}
+
+// probeTCP dials each port, a refused connection still proves the host is up
+func (c *WOLClient) probeTCP(host string, ports []int) bool {
+	for _, port := range ports {
+		address := net.JoinHostPort(host, strconv.Itoa(port))
+		conn, err := net.DialTimeout("tcp", address, c.config.ProbeTimeout)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+		c.logger.Debug().
+			Err(err).
+			Str("address", address).
+			Msg("TCP probe failed")
+	}
+	return false
+}
+
+// pingICMP sends a single ICMP echo request over a raw socket
+func (c *WOLClient) pingICMP(host string) (bool, error) {
+	conn, err := net.DialTimeout("ip4:icmp", host, c.config.ProbeTimeout)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	id := uint16(os.Getpid() & 0xffff)
+	request := make([]byte, 8)
+	request[0] = 8 // echo request
+	binary.BigEndian.PutUint16(request[4:], id)
+	binary.BigEndian.PutUint16(request[6:], 1)
+	binary.BigEndian.PutUint16(request[2:], icmpChecksum(request))
+
+	if err := conn.SetDeadline(time.Now().Add(c.config.ProbeTimeout)); err != nil {
+		return false, err
+	}
+	if _, err := conn.Write(request); err != nil {
+		return false, err
+	}
+
+	reply := make([]byte, 512)
+	for {
+		n, err := conn.Read(reply)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return false, nil
+			}
+			return false, err
+		}
+		// Skip the IPv4 header when the kernel hands it to us
+		data := reply[:n]
+		if n >= 20 && data[0]>>4 == 4 {
+			data = data[int(data[0]&0x0f)*4:]
+		}
+		if len(data) >= 8 && data[0] == 0 && binary.BigEndian.Uint16(data[4:]) == id {
+			return true, nil
+		}
+	}
+}
+
+// icmpChecksum computes the internet checksum of an ICMP message
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+// shutdownHTTP calls the configured HTTP shutdown endpoint
+func (c *WOLClient) shutdownHTTP() error {
+	req, err := http.NewRequest(c.config.Shutdown.HTTPMethod, c.config.Shutdown.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create shutdown request: %w", err)
+	}
+	if c.config.Shutdown.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Shutdown.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send shutdown request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("shutdown request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// shutdownSSH runs the configured shutdown command over SSH
+func (c *WOLClient) shutdownSSH() error {
+	var auth []ssh.AuthMethod
+	if c.config.Shutdown.SSHKey != "" {
+		key, err := os.ReadFile(c.config.Shutdown.SSHKey)
+		if err != nil {
+			return fmt.Errorf("failed to read ssh key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return fmt.Errorf("failed to parse ssh key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if c.config.Shutdown.SSHPassword != "" {
+		auth = append(auth, ssh.Password(c.config.Shutdown.SSHPassword))
+	}
+
+	hostKeyCallback, err := c.hostKeyCallback()
+	if err != nil {
+		return err
+	}
+
+	host, _ := c.probeTarget()
+	sshConfig := &ssh.ClientConfig{
+		User:            c.config.Shutdown.SSHUser,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         10 * time.Second,
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(c.config.Shutdown.SSHPort))
+	client, err := ssh.Dial("tcp", address, sshConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect via ssh: %w", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open ssh session: %w", err)
+	}
+	defer session.Close()
+
+	// The connection usually drops while the host goes down
+	if err := session.Run(c.config.Shutdown.SSHCommand); err != nil {
+		var exitErr *ssh.ExitMissingError
+		if errors.As(err, &exitErr) || errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("shutdown command failed: %w", err)
+	}
+
+	return nil
+}
+
+// hostKeyCallback verifies the host against the configured fingerprint or known_hosts file.
+// It refuses to connect when neither is configured
+func (c *WOLClient) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if fingerprint := c.config.Shutdown.SSHHostKey; fingerprint != "" {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != fingerprint {
+				return fmt.Errorf("host key %s of %s does not match the configured fingerprint", ssh.FingerprintSHA256(key), hostname)
+			}
+			return nil
+		}, nil
+	}
+
+	if c.config.Shutdown.SSHKnownHosts != "" {
+		callback, err := knownhosts.New(c.config.Shutdown.SSHKnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read known hosts: %w", err)
+		}
+		return callback, nil
+	}
+
+	return nil, fmt.Errorf("no ssh host key configured, set %s or %s", OptionSSHHostKey, OptionSSHKnownHosts)
+}
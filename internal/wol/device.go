@@ -0,0 +1,152 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wol
+
+import (
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/device"
+)
+
+// WOLDevice implements the Device interface for hosts woken via Wake-on-LAN
+type WOLDevice struct {
+	client *WOLClient
+	config Config
+	info   device.DeviceInfo
+}
+
+// NewWOLDevice creates a new WOLDevice
+func NewWOLDevice(address string, config Config, options *internal.FnModeOptions) *WOLDevice {
+	var opts internal.FnModeOptions
+	if options != nil {
+		opts = *options
+	}
+
+	capabilities := []string{"power_control"}
+	if config.Shutdown.Method != ShutdownNone {
+		capabilities = append(capabilities, "shutdown")
+	}
+
+	return &WOLDevice{
+		client: NewWOLClient(address, config, opts),
+		config: config,
+		info: device.DeviceInfo{
+			ID:           "", // Will be set from configuration
+			Name:         "", // Will be set from configuration
+			Type:         "wol",
+			Model:        "Wake-on-LAN",
+			Address:      address,
+			Status:       "unknown", // Will be determined by hub
+			Capabilities: capabilities,
+		},
+	}
+}
+
+// GetDeviceInfo returns information about this Wake-on-LAN device
+func (d *WOLDevice) GetDeviceInfo() device.DeviceInfo {
+	return d.info
+}
+
+// Process handles JSON action requests and routes them to appropriate methods
+func (d *WOLDevice) Process(actionJSON []byte) (*device.ActionResponse, error) {
+	request, err := device.ParseActionRequest(actionJSON)
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	switch request.Type {
+	case device.ActionTypeRemote:
+		return d.processRemoteAction(request)
+	case device.ActionTypeControl:
+		return d.processControlAction(request)
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported action type: %s", request.Type),
+		}, nil
+	}
+}
+
+// processRemoteAction handles power actions
+func (d *WOLDevice) processRemoteAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	action := device.RemoteAction(request.Action)
+
+	if action == device.RemoteActionPower {
+		// Toggle based on the probed state
+		if d.client.IsReachable() {
+			action = device.RemoteActionPowerOff
+		} else {
+			action = device.RemoteActionPowerOn
+		}
+	}
+
+	var err error
+	switch action {
+	case device.RemoteActionPowerOn:
+		err = d.client.SendMagicPacket()
+	case device.RemoteActionPowerOff:
+		if d.config.Shutdown.Method == ShutdownNone {
+			return &device.ActionResponse{
+				Success: false,
+				Error:   "power_off is not supported: no shutdown hook configured",
+			}, nil
+		}
+		err = d.client.Shutdown()
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported remote action: %s", request.Action),
+		}, nil
+	}
+
+	if err != nil {
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("%s failed: %v", action, err),
+		}, nil
+	}
+
+	return &device.ActionResponse{
+		Success: true,
+		Data:    fmt.Sprintf("Remote action '%s' executed successfully", request.Action),
+	}, nil
+}
+
+// processControlAction handles status queries
+func (d *WOLDevice) processControlAction(request *device.ActionRequest) (*device.ActionResponse, error) {
+	switch device.ControlAction(request.Action) {
+	case device.ControlActionPowerStatus:
+		return &device.ActionResponse{
+			Success: true,
+			Data:    d.powerStatus(),
+		}, nil
+	default:
+		return &device.ActionResponse{
+			Success: false,
+			Error:   fmt.Sprintf("unsupported control action: %s", request.Action),
+		}, nil
+	}
+}
+
+// powerStatus reports the power state in the Bravia getPowerStatus shape
+func (d *WOLDevice) powerStatus() map[string]interface{} {
+	if d.client.IsReachable() {
+		return device.PowerStatusData(device.PowerStatusActive)
+	}
+	return device.PowerStatusData(device.PowerStatusStandby)
+}
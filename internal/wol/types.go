@@ -0,0 +1,203 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wol
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Option keys recognised in the device configuration options map
+const (
+	OptionBroadcast     = "broadcast"
+	OptionProbe         = "probe"
+	OptionProbePorts    = "probe_ports"
+	OptionProbeTimeout  = "probe_timeout"
+	OptionShutdown      = "shutdown"
+	OptionShutdownURL   = "shutdown_url"
+	OptionHTTPMethod    = "shutdown_http_method"
+	OptionHTTPToken     = "shutdown_token"
+	OptionSSHUser       = "ssh_user"
+	OptionSSHPassword   = "ssh_password"
+	OptionSSHKey        = "ssh_key"
+	OptionSSHPort       = "ssh_port"
+	OptionSSHCommand    = "ssh_command"
+	OptionSSHHostKey    = "ssh_host_key"
+	OptionSSHKnownHosts = "ssh_known_hosts"
+)
+
+// Probe methods used to check whether a host is powered on
+const (
+	ProbeTCP  = "tcp"
+	ProbeICMP = "icmp"
+)
+
+// Shutdown hook methods
+const (
+	ShutdownNone = ""
+	ShutdownHTTP = "http"
+	ShutdownSSH  = "ssh"
+)
+
+const (
+	defaultBroadcast    = "255.255.255.255:9"
+	defaultProbeTimeout = 2 * time.Second
+	defaultSSHCommand   = "sudo shutdown -h now"
+)
+
+var defaultProbePorts = []int{22, 80, 443, 445, 3389}
+
+// Config holds the Wake-on-LAN device settings
+type Config struct {
+	MAC          net.HardwareAddr
+	Broadcast    string
+	Probe        string
+	ProbePorts   []int
+	ProbeTimeout time.Duration
+	Shutdown     ShutdownConfig
+}
+
+// ShutdownConfig holds the optional power off hook settings
+type ShutdownConfig struct {
+	Method        string
+	URL           string
+	HTTPMethod    string
+	Token         string
+	SSHUser       string
+	SSHPassword   string
+	SSHKey        string
+	SSHPort       int
+	SSHCommand    string
+	SSHHostKey    string // SHA256 fingerprint of the host key, as printed by ssh-keygen -l
+	SSHKnownHosts string // known_hosts file listing the host key
+}
+
+// ParseConfig builds a Config from a MAC address and the device options map
+func ParseConfig(mac string, options map[string]string) (*Config, error) {
+	if mac == "" {
+		return nil, fmt.Errorf("mac address is required")
+	}
+
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mac address: %w", err)
+	}
+	if len(hw) != 6 {
+		return nil, fmt.Errorf("invalid mac address: expected 6 bytes, got %d", len(hw))
+	}
+
+	config := &Config{
+		MAC:          hw,
+		Broadcast:    defaultBroadcast,
+		Probe:        ProbeTCP,
+		ProbePorts:   defaultProbePorts,
+		ProbeTimeout: defaultProbeTimeout,
+		Shutdown: ShutdownConfig{
+			HTTPMethod: "POST",
+			SSHPort:    22,
+			SSHCommand: defaultSSHCommand,
+		},
+	}
+
+	if options == nil {
+		return config, nil
+	}
+
+	if broadcast := options[OptionBroadcast]; broadcast != "" {
+		if _, _, err := net.SplitHostPort(broadcast); err != nil {
+			broadcast = net.JoinHostPort(broadcast, "9")
+		}
+		config.Broadcast = broadcast
+	}
+
+	switch probe := strings.ToLower(options[OptionProbe]); probe {
+	case "":
+	case ProbeTCP, ProbeICMP:
+		config.Probe = probe
+	default:
+		return nil, fmt.Errorf("unsupported probe method: %s", probe)
+	}
+
+	if ports := options[OptionProbePorts]; ports != "" {
+		config.ProbePorts = nil
+		for _, field := range strings.Split(ports, ",") {
+			port, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || port <= 0 || port > 65535 {
+				return nil, fmt.Errorf("invalid probe port: %s", field)
+			}
+			config.ProbePorts = append(config.ProbePorts, port)
+		}
+	}
+
+	if timeout := options[OptionProbeTimeout]; timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid probe timeout: %w", err)
+		}
+		config.ProbeTimeout = d
+	}
+
+	switch method := strings.ToLower(options[OptionShutdown]); method {
+	case ShutdownNone:
+	case ShutdownHTTP:
+		if options[OptionShutdownURL] == "" {
+			return nil, fmt.Errorf("%s is required for http shutdown", OptionShutdownURL)
+		}
+		config.Shutdown.Method = method
+	case ShutdownSSH:
+		if options[OptionSSHUser] == "" {
+			return nil, fmt.Errorf("%s is required for ssh shutdown", OptionSSHUser)
+		}
+		if options[OptionSSHPassword] == "" && options[OptionSSHKey] == "" {
+			return nil, fmt.Errorf("%s or %s is required for ssh shutdown", OptionSSHPassword, OptionSSHKey)
+		}
+		// Without a known host key anyone on the LAN could pose as the host and collect the credentials
+		if options[OptionSSHHostKey] == "" && options[OptionSSHKnownHosts] == "" {
+			return nil, fmt.Errorf("%s or %s is required for ssh shutdown", OptionSSHHostKey, OptionSSHKnownHosts)
+		}
+		if fingerprint := options[OptionSSHHostKey]; fingerprint != "" && !strings.HasPrefix(fingerprint, "SHA256:") {
+			return nil, fmt.Errorf("invalid %s: expected a SHA256: fingerprint", OptionSSHHostKey)
+		}
+		config.Shutdown.Method = method
+	default:
+		return nil, fmt.Errorf("unsupported shutdown method: %s", method)
+	}
+
+	config.Shutdown.URL = options[OptionShutdownURL]
+	config.Shutdown.Token = options[OptionHTTPToken]
+	if method := options[OptionHTTPMethod]; method != "" {
+		config.Shutdown.HTTPMethod = strings.ToUpper(method)
+	}
+	config.Shutdown.SSHUser = options[OptionSSHUser]
+	config.Shutdown.SSHPassword = options[OptionSSHPassword]
+	config.Shutdown.SSHKey = options[OptionSSHKey]
+	config.Shutdown.SSHHostKey = options[OptionSSHHostKey]
+	config.Shutdown.SSHKnownHosts = options[OptionSSHKnownHosts]
+	if port := options[OptionSSHPort]; port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("invalid ssh port: %s", port)
+		}
+		config.Shutdown.SSHPort = p
+	}
+	if command := options[OptionSSHCommand]; command != "" {
+		config.Shutdown.SSHCommand = command
+	}
+
+	return config, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"lucas/internal/device"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appTV serves the app and input JSON-RPC methods and records what was activated
+type appTV struct {
+	mutex    sync.Mutex
+	apps     []bravia.Application
+	listed   int
+	launched []string
+	played   []string
+}
+
+func (tv *appTV) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tv.mutex.Lock()
+		defer tv.mutex.Unlock()
+
+		var payload struct {
+			Method string              `json:"method"`
+			Params []map[string]string `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		var result interface{} = []interface{}{}
+		switch r.URL.Path + "#" + payload.Method {
+		case "/sony/appControl#getApplicationList":
+			tv.listed++
+			result = []interface{}{tv.apps}
+		case "/sony/appControl#setActiveApp":
+			tv.launched = append(tv.launched, payload.Params[0]["uri"])
+		case "/sony/avContent#setPlayContent":
+			tv.played = append(tv.played, payload.Params[0]["uri"])
+		case "/sony/avContent#getCurrentExternalInputsStatus":
+			result = []interface{}{[]map[string]interface{}{
+				{"uri": "extInput:hdmi?port=1", "title": "HDMI 1", "label": "Console", "connection": true, "status": "true"},
+				{"uri": "extInput:hdmi?port=2", "title": "HDMI 2", "label": "", "connection": false},
+			}}
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": []interface{}{12, "No Such Method"}, "id": 1})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "id": 1})
+	}
+}
+
+func newAppRemote(t *testing.T, tv *appTV) *bravia.BraviaRemote {
+	server := httptest.NewServer(tv.handler(t))
+	t.Cleanup(server.Close)
+	return bravia.NewBraviaRemote(strings.TrimPrefix(server.URL, "http://"), "test-credential", &internal.FnModeOptions{})
+}
+
+func control(t *testing.T, remote *bravia.BraviaRemote, action string, params map[string]interface{}) *device.ActionResponse {
+	request, err := json.Marshal(device.ActionRequest{Type: device.ActionTypeControl, Action: action, Parameters: params})
+	require.NoError(t, err)
+	response, err := remote.Process(request)
+	require.NoError(t, err)
+	return response
+}
+
+func TestBraviaRemote_LaunchApp(t *testing.T) {
+	tv := &appTV{apps: []bravia.Application{
+		{Title: "Netflix", URI: "com.sony.dtv.netflix"},
+		{Title: "YouTube", URI: "com.sony.dtv.youtube"},
+		{Title: "YouTube Kids", URI: "com.sony.dtv.youtubekids"},
+		{Title: "Prime Video", URI: "com.sony.dtv.primevideo"},
+	}}
+	remote := newAppRemote(t, tv)
+
+	t.Run("launches by uri", func(t *testing.T) {
+		response := control(t, remote, "launch_app", map[string]interface{}{"uri": "com.sony.dtv.netflix"})
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, []string{"com.sony.dtv.netflix"}, tv.launched)
+		assert.Equal(t, 0, tv.listed)
+	})
+
+	t.Run("launches by fuzzy title", func(t *testing.T) {
+		for query, uri := range map[string]string{
+			"youtube":     "com.sony.dtv.youtube",
+			"prime":       "com.sony.dtv.primevideo",
+			"youtubekids": "com.sony.dtv.youtubekids",
+			"NETFLIX":     "com.sony.dtv.netflix",
+		} {
+			response := control(t, remote, "launch_app", map[string]interface{}{"app": query})
+			require.True(t, response.Success, response.Error)
+			assert.Equal(t, uri, tv.launched[len(tv.launched)-1], query)
+		}
+		assert.Equal(t, 1, tv.listed, "app list is cached")
+	})
+
+	t.Run("rejects ambiguous titles", func(t *testing.T) {
+		response := control(t, remote, "launch_app", map[string]interface{}{"app": "you"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "ambiguous")
+	})
+
+	t.Run("refreshes the cache for unknown titles", func(t *testing.T) {
+		tv.apps = append(tv.apps, bravia.Application{Title: "Spotify", URI: "com.sony.dtv.spotify"})
+		response := control(t, remote, "launch_app", map[string]interface{}{"app": "spotify"})
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "com.sony.dtv.spotify", tv.launched[len(tv.launched)-1])
+
+		response = control(t, remote, "launch_app", map[string]interface{}{"app": "plex"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "invalid parameters")
+	})
+
+	t.Run("validates parameters", func(t *testing.T) {
+		response := control(t, remote, "launch_app", nil)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "invalid parameters")
+
+		response = control(t, remote, "launch_app", map[string]interface{}{"uri": "x", "app": "y"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "mutually exclusive")
+	})
+}
+
+func TestBraviaRemote_SetInput(t *testing.T) {
+	tv := &appTV{}
+	remote := newAppRemote(t, tv)
+
+	valid := []struct {
+		params map[string]interface{}
+		uri    string
+	}{
+		{map[string]interface{}{"input": "hdmi", "port": 2}, "extInput:hdmi?port=2"},
+		{map[string]interface{}{"input": "HDMI3"}, "extInput:hdmi?port=3"},
+		{map[string]interface{}{"input": "composite"}, "extInput:composite?port=1"},
+		{map[string]interface{}{"input": "screen_mirroring"}, "extInput:widi?port=1"},
+		{map[string]interface{}{"uri": "extInput:cec?type=recorder&port=3&logicalAddr=1"}, "extInput:cec?type=recorder&port=3&logicalAddr=1"},
+	}
+	for _, tc := range valid {
+		response := control(t, remote, "set_input", tc.params)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, tc.uri, tv.played[len(tv.played)-1])
+	}
+
+	invalid := []map[string]interface{}{
+		nil,
+		{"input": "hdmi"},
+		{"input": "hdmi", "port": 7},
+		{"input": "hdmi", "port": 1.5},
+		{"input": "hdmi2", "port": 2},
+		{"input": "scart"},
+		{"uri": "http://example.com"},
+	}
+	for _, params := range invalid {
+		response := control(t, remote, "set_input", params)
+		assert.False(t, response.Success, "%v", params)
+		assert.Contains(t, response.Error, "invalid parameters")
+	}
+	assert.Len(t, tv.played, len(valid))
+}
+
+func TestBraviaRemote_InputsStatus(t *testing.T) {
+	remote := newAppRemote(t, &appTV{})
+
+	response := control(t, remote, "get_inputs_status", nil)
+	require.True(t, response.Success, response.Error)
+
+	inputs := response.Data.(map[string]interface{})["inputs"].([]bravia.ExternalInput)
+	require.Len(t, inputs, 2)
+	assert.Equal(t, "Console", inputs[0].Label)
+	assert.True(t, inputs[0].Connection)
+	assert.False(t, inputs[1].Connection)
+}
+
+func TestBraviaRemote_AppsTestMode(t *testing.T) {
+	useTestSimulators(t)
+	remote := bravia.NewBraviaRemote("192.0.2.1", "test", &internal.FnModeOptions{Test: true})
+
+	response := control(t, remote, "launch_app", map[string]interface{}{"app": "youtube"})
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, "YouTube", response.Data.(map[string]interface{})["title"])
+
+	response = control(t, remote, "set_input", map[string]interface{}{"input": "hdmi1"})
+	assert.True(t, response.Success, response.Error)
+}
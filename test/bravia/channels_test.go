@@ -0,0 +1,138 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"lucas/internal/device"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBraviaChannels_List(t *testing.T) {
+	tv := startSimulator(t, bravia.SimulatorOptions{PSK: "0000"})
+	client := bravia.NewBraviaClient(tv.Address(), "0000", internal.FnModeOptions{})
+	client.SetRetryPolicy(fastRetry)
+
+	sources, err := client.GetSourceList()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tv:dvbt", "tv:dvbs"}, sources)
+
+	count, err := client.GetContentCount("tv:dvbt")
+	require.NoError(t, err)
+
+	channels, err := client.GetChannels("tv:dvbt")
+	require.NoError(t, err)
+	require.Len(t, channels, count)
+	assert.Greater(t, count, bravia.ChannelPageSize, "the list spans several pages")
+	assert.Equal(t, 2, tv.Calls(bravia.GetContentList))
+	assert.Equal(t, "BBC ONE", channels[0].Title)
+	assert.Equal(t, "001", channels[0].Number)
+	assert.True(t, strings.HasPrefix(channels[0].URI, "tv:dvbt?trip="))
+	for i, channel := range channels {
+		assert.Equal(t, i, channel.Index)
+	}
+}
+
+func TestBraviaChannels_Actions(t *testing.T) {
+	tv := startSimulator(t, bravia.SimulatorOptions{PSK: "0000"})
+	remote := bravia.NewBraviaRemote(tv.Address(), "0000", &internal.FnModeOptions{})
+	remote.SetRetryPolicy(fastRetry)
+
+	t.Run("channel list is cached", func(t *testing.T) {
+		response := process(t, remote, device.ActionTypeControl, "get_channels", map[string]interface{}{"source": "tv:dvbs"})
+		require.True(t, response.Success, response.Error)
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, 2, data["count"])
+
+		calls := tv.Calls(bravia.GetContentList)
+		response = process(t, remote, device.ActionTypeControl, "get_channels", nil)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, calls, tv.Calls(bravia.GetContentList))
+
+		response = process(t, remote, device.ActionTypeControl, "get_channels", map[string]interface{}{"source": "hdmi"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "invalid parameters")
+	})
+
+	t.Run("channel counts per source", func(t *testing.T) {
+		response := process(t, remote, device.ActionTypeControl, "channel_count", nil)
+		require.True(t, response.Success, response.Error)
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, 122, data["total"])
+		assert.Equal(t, 2, data["sources"].(map[string]int)["tv:dvbs"])
+	})
+
+	t.Run("set channel by name or number", func(t *testing.T) {
+		tests := []struct {
+			channel interface{}
+			title   string
+		}{
+			{"bbc two", "BBC TWO"},
+			{"Sky", "Sky News"},
+			{"jazeera", "Al Jazeera English"},
+			{4, "Channel 4"},
+			{"503", "Sky News"},
+		}
+		for _, tt := range tests {
+			response := process(t, remote, device.ActionTypeControl, "set_channel", map[string]interface{}{"channel": tt.channel})
+			require.True(t, response.Success, response.Error)
+			assert.Equal(t, tt.title, response.Data.(map[string]interface{})["title"])
+			assert.True(t, strings.HasPrefix(tv.Input(), "tv:"), tv.Input())
+		}
+	})
+
+	t.Run("set channel rejects unknown and ambiguous names", func(t *testing.T) {
+		response := process(t, remote, device.ActionTypeControl, "set_channel", map[string]interface{}{"channel": "bbc"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "ambiguous")
+
+		response = process(t, remote, device.ActionTypeControl, "set_channel", map[string]interface{}{"channel": "Cartoon Network"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "no channel matches")
+
+		response = process(t, remote, device.ActionTypeControl, "set_channel", map[string]interface{}{"uri": "extInput:hdmi?port=1"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "tv: URI")
+	})
+
+	t.Run("program info", func(t *testing.T) {
+		response := process(t, remote, device.ActionTypeControl, "set_channel", map[string]interface{}{"channel": "Channel 5"})
+		require.True(t, response.Success, response.Error)
+
+		response = process(t, remote, device.ActionTypeControl, "program_info", nil)
+		require.True(t, response.Success, response.Error)
+		info := response.Data.(bravia.ProgramInfo)
+		assert.Equal(t, "Channel 5", info.Channel)
+		assert.Equal(t, "005", info.Number)
+		assert.Equal(t, "tv:dvbt", info.Source)
+		assert.Equal(t, "Milkshake!", info.Program)
+		assert.Equal(t, 3600, info.DurationSec)
+
+		require.True(t, process(t, remote, device.ActionTypeRemote, "channel_up", nil).Success)
+		response = process(t, remote, device.ActionTypeControl, "program_info", nil)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "Local TV 6", response.Data.(bravia.ProgramInfo).Channel)
+
+		require.NoError(t, tv.SetInput("extInput:hdmi?port=1"))
+		response = process(t, remote, device.ActionTypeControl, "program_info", nil)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "not showing a broadcast channel")
+	})
+}
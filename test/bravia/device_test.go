@@ -48,6 +48,11 @@ func TestNewBraviaRemote(t *testing.T) {
 func TestBraviaRemote_Process_RemoteActions(t *testing.T) {
 	t.Run("processes remote power action successfully", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The code table lookup is answered as unsupported, leaving the static table
+			if r.URL.Path == "/sony/system" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
 			// Verify this is an IRCC request
 			assert.Equal(t, "/sony/ircc", r.URL.Path)
 			assert.Equal(t, "POST", r.Method)
@@ -72,6 +77,10 @@ func TestBraviaRemote_Process_RemoteActions(t *testing.T) {
 
 	t.Run("processes volume up action successfully", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/sony/system" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
 			assert.Equal(t, "/sony/ircc", r.URL.Path)
 			w.WriteHeader(http.StatusOK)
 		}))
@@ -0,0 +1,184 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"lucas/internal/device"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventLog collects state events pushed by a device
+type eventLog struct {
+	mutex  sync.Mutex
+	events []device.StateEvent
+}
+
+func (l *eventLog) handle(event device.StateEvent) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events = append(l.events, event)
+}
+
+// has reports whether an event set property to value
+func (l *eventLog) has(property string, value interface{}) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, event := range l.events {
+		if event.Property == property && reflect.DeepEqual(event.Value, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func controlAction(t *testing.T, remote *bravia.BraviaRemote, action string) *device.ActionResponse {
+	request, err := json.Marshal(device.ActionRequest{Type: device.ActionTypeControl, Action: action})
+	require.NoError(t, err)
+	response, err := remote.Process(request)
+	require.NoError(t, err)
+	return response
+}
+
+func startNotifications(t *testing.T, tv *bravia.FakeNotificationServer) (*bravia.BraviaRemote, *eventLog) {
+	remote := bravia.NewBraviaRemote(tv.Address(), "test", &internal.FnModeOptions{})
+	remote.SetNotificationBackoff(10*time.Millisecond, 100*time.Millisecond)
+
+	events := &eventLog{}
+	remote.StartEvents(events.handle)
+	t.Cleanup(remote.StopEvents)
+	return remote, events
+}
+
+func TestBraviaNotifications_StateEvents(t *testing.T) {
+	tv, err := bravia.NewFakeNotificationServer("test")
+	require.NoError(t, err)
+	defer tv.Close()
+
+	remote, events := startNotifications(t, tv)
+
+	for _, endpoint := range []bravia.BraviaEndpoint{bravia.SystemEndpoint, bravia.AudioEndpoint, bravia.AVContentEndpoint} {
+		require.Eventually(t, func() bool { return tv.Subscribers(endpoint) == 1 }, 2*time.Second, 5*time.Millisecond, string(endpoint))
+	}
+	require.Eventually(t, func() bool { return events.has(bravia.StatePower, "active") }, time.Second, 5*time.Millisecond)
+	assert.True(t, events.has(bravia.StateStatus, "online"))
+	assert.Equal(t, "online", remote.GetDeviceInfo().Status)
+
+	t.Run("power changes are pushed and answered from the cache", func(t *testing.T) {
+		tv.SetPower("standby")
+		require.Eventually(t, func() bool { return events.has(bravia.StatePower, "standby") }, time.Second, 5*time.Millisecond)
+
+		polls := tv.Calls(bravia.GetPowerStatus)
+		response := controlAction(t, remote, "power_status")
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, device.PowerStatusData("standby"), response.Data)
+		assert.Equal(t, polls, tv.Calls(bravia.GetPowerStatus), "power_status must not poll the TV")
+	})
+
+	t.Run("volume changes are merged per target", func(t *testing.T) {
+		tv.SetVolume("speaker", 35, true)
+		require.Eventually(t, func() bool {
+			response := controlAction(t, remote, "volume_info")
+			data, _ := json.Marshal(response.Data)
+			return string(data) == `{"id":1,"result":[[{"maxVolume":100,"minVolume":0,"mute":true,"target":"speaker","volume":35}]]}`
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 1, tv.Calls(bravia.GetVolumeInformation), "only the initial refresh reads the volume")
+	})
+
+	t.Run("playing content is pushed", func(t *testing.T) {
+		content := map[string]interface{}{"uri": "extInput:hdmi?port=2", "source": "extInput:hdmi", "title": "HDMI 2"}
+		require.NoError(t, tv.SetPlayingContent(content))
+		require.Eventually(t, func() bool { return events.has(bravia.StatePlayingContent, content) }, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestBraviaNotifications_Reconnect(t *testing.T) {
+	tv, err := bravia.NewFakeNotificationServer("test")
+	require.NoError(t, err)
+	defer tv.Close()
+
+	_, events := startNotifications(t, tv)
+	require.Eventually(t, func() bool { return tv.Subscribers(bravia.SystemEndpoint) == 1 }, 2*time.Second, 5*time.Millisecond)
+	connects := tv.Connects()
+
+	// A change made while the connection is down is picked up by the refresh after reconnecting
+	tv.DropConnections()
+	require.Eventually(t, func() bool { return tv.Subscribers(bravia.SystemEndpoint) == 0 }, time.Second, 5*time.Millisecond)
+	tv.SetPower("standby")
+
+	require.Eventually(t, func() bool { return tv.Connects() >= connects+3 }, 2*time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return events.has(bravia.StatePower, "standby") }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestBraviaNotifications_Unsupported(t *testing.T) {
+	tv, err := bravia.NewFakeNotificationServer("test")
+	require.NoError(t, err)
+	defer tv.Close()
+	tv.SetUnsupported(bravia.AudioEndpoint)
+
+	remote, _ := startNotifications(t, tv)
+	require.Eventually(t, func() bool { return tv.Subscribers(bravia.SystemEndpoint) == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	// Without a volume channel volume_info goes to the TV every time
+	for i := 1; i <= 2; i++ {
+		response := controlAction(t, remote, "volume_info")
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, i, tv.Calls(bravia.GetVolumeInformation))
+	}
+
+	// The refused endpoint is not retried
+	connects := tv.Connects()
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, connects, tv.Connects())
+}
+
+func TestBraviaNotifications_Offline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	remote := bravia.NewBraviaRemote(address, "test", &internal.FnModeOptions{})
+	remote.SetNotificationBackoff(10*time.Millisecond, 50*time.Millisecond)
+	events := &eventLog{}
+	remote.StartEvents(events.handle)
+	defer remote.StopEvents()
+
+	require.Eventually(t, func() bool { return events.has(bravia.StateStatus, "offline") }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "offline", remote.GetDeviceInfo().Status)
+}
+
+func TestBraviaNotifications_Disabled(t *testing.T) {
+	tv, err := bravia.NewFakeNotificationServer("test")
+	require.NoError(t, err)
+	defer tv.Close()
+
+	disabled := bravia.NewBraviaRemote(tv.Address(), "test", &internal.FnModeOptions{})
+	disabled.SetNotifications(false)
+	disabled.StartEvents(func(device.StateEvent) {})
+	defer disabled.StopEvents()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, tv.Connects())
+}
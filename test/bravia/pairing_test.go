@@ -0,0 +1,195 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pairingTV implements actRegister PIN pairing and cookie checks
+type pairingTV struct {
+	mutex      sync.Mutex
+	pin        string
+	registered map[string]bool
+	cookie     string
+	issued     int
+	pinShown   int
+}
+
+func (tv *pairingTV) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tv.mutex.Lock()
+		defer tv.mutex.Unlock()
+
+		var payload struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		if r.URL.Path == "/sony/accessControl" {
+			require.Equal(t, "actRegister", payload.Method)
+			var client struct {
+				ClientID string `json:"clientid"`
+				Nickname string `json:"nickname"`
+			}
+			require.NoError(t, json.Unmarshal(payload.Params[0], &client))
+			assert.Equal(t, "Lucas", client.Nickname)
+
+			_, pin, hasAuth := r.BasicAuth()
+			switch {
+			case hasAuth && pin == tv.pin:
+				tv.registered[client.ClientID] = true
+			case hasAuth:
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			case !tv.registered[client.ClientID]:
+				tv.pinShown++
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			tv.issued++
+			tv.cookie = fmt.Sprintf("cookie-%d", tv.issued)
+			http.SetCookie(w, &http.Cookie{Name: "auth", Value: tv.cookie})
+			w.Write([]byte(`{"result": [], "id": 8}`))
+			return
+		}
+
+		cookie, err := r.Cookie("auth")
+		if err != nil || cookie.Value != tv.cookie {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		assert.Empty(t, r.Header.Get("X-Auth-PSK"))
+		w.Write([]byte(`{"result": [{"status": "active"}], "id": 1}`))
+	}
+}
+
+func TestBraviaPINPairing(t *testing.T) {
+	tv := &pairingTV{pin: "1234", registered: map[string]bool{}}
+	server := httptest.NewServer(tv.handler(t))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	client := bravia.NewBraviaClient(address, "", internal.FnModeOptions{})
+	clientID := bravia.NewClientID()
+
+	credential, err := client.RequestPIN(clientID)
+	require.NoError(t, err)
+	assert.Empty(t, credential, "a new client must enter a PIN")
+	assert.Equal(t, 1, tv.pinShown)
+
+	_, err = client.CompletePairing(clientID, "0000")
+	assert.Error(t, err)
+
+	credential, err = client.CompletePairing(clientID, "1234")
+	require.NoError(t, err)
+	id, cookie, ok := bravia.ParseCookieCredential(credential)
+	require.True(t, ok)
+	assert.Equal(t, clientID, id)
+	assert.Equal(t, tv.cookie, cookie)
+
+	t.Run("cookie authenticates requests", func(t *testing.T) {
+		paired := bravia.NewBraviaClient(address, credential, internal.FnModeOptions{})
+		status, err := paired.GetPowerStatus()
+		require.NoError(t, err)
+		assert.Equal(t, "active", status)
+	})
+
+	t.Run("expired cookie is renewed without a PIN and saved", func(t *testing.T) {
+		store := &credentialStore{}
+		remote := bravia.NewBraviaRemote(address, credential, &internal.FnModeOptions{})
+		remote.SetConfigStore(store)
+
+		// The TV forgets the cookie, as it does after about two weeks
+		tv.mutex.Lock()
+		tv.cookie = "expired"
+		tv.mutex.Unlock()
+
+		response, err := remote.Process([]byte(`{"type": "control", "action": "power_status"}`))
+		require.NoError(t, err)
+		require.True(t, response.Success, response.Error)
+
+		// The renewed cookie is saved in the background
+		require.Eventually(t, func() bool {
+			_, renewed, ok := bravia.ParseCookieCredential(store.Credential())
+			tv.mutex.Lock()
+			defer tv.mutex.Unlock()
+			return ok && renewed == tv.cookie
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 1, tv.pinShown, "re-pairing must not show a PIN")
+	})
+
+	t.Run("a known client is paired straight away", func(t *testing.T) {
+		credential, err := client.RequestPIN(clientID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, credential)
+	})
+}
+
+func TestBraviaPairingTestMode(t *testing.T) {
+	useTestSimulators(t)
+	client := bravia.NewBraviaClient("192.0.2.1", "", internal.FnModeOptions{Test: true})
+	clientID := bravia.NewClientID()
+
+	credential, err := client.RequestPIN(clientID)
+	require.NoError(t, err)
+	assert.Empty(t, credential)
+
+	credential, err = client.CompletePairing(clientID, "1234")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(credential, bravia.CookieCredentialPrefix))
+
+	// The simulated TV now knows the client and pairs it again without a PIN
+	again, err := client.RequestPIN(clientID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(again, bravia.CookieCredentialPrefix))
+}
+
+// credentialStore records the credential saved by a device
+type credentialStore struct {
+	mutex      sync.Mutex
+	credential string
+}
+
+func (s *credentialStore) SetCredential(credential string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.credential = credential
+	return nil
+}
+
+func (s *credentialStore) SetOption(key, value string) error {
+	return nil
+}
+
+func (s *credentialStore) Credential() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.credential
+}
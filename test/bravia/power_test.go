@@ -0,0 +1,290 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"lucas/internal/device"
+	"lucas/internal/wol"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMAC = "fc:f1:52:01:02:03"
+
+// memoryStore records options saved by a device
+type memoryStore struct {
+	mutex   sync.Mutex
+	options map[string]string
+}
+
+func (s *memoryStore) SetCredential(credential string) error {
+	return nil
+}
+
+func (s *memoryStore) SetOption(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if value == "" {
+		delete(s.options, key)
+		return nil
+	}
+	s.options[key] = value
+	return nil
+}
+
+func (s *memoryStore) Option(key string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	value, exists := s.options[key]
+	return value, exists
+}
+
+// powerTV answers the system power methods
+type powerTV struct {
+	mutex     sync.Mutex
+	status    string
+	setCalls  []bool
+	setFails  bool
+	lagPolls  int
+	reportMAC bool
+}
+
+func (tv *powerTV) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tv.mutex.Lock()
+		defer tv.mutex.Unlock()
+
+		var payload struct {
+			Method string                   `json:"method"`
+			Params []map[string]interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		var result []interface{}
+		switch payload.Method {
+		case "getPowerStatus":
+			status := tv.status
+			if tv.lagPolls > 0 {
+				// The TV takes a few polls to settle into the requested state
+				tv.lagPolls--
+				status = "transitioning"
+			}
+			result = []interface{}{map[string]string{"status": status}}
+		case "setPowerStatus":
+			on, isBool := payload.Params[0]["status"].(bool)
+			require.True(t, isBool, "status must be a JSON boolean")
+			tv.setCalls = append(tv.setCalls, on)
+			if tv.setFails {
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": []interface{}{40005, "Display Is Turned off"}})
+				return
+			}
+			tv.status = "standby"
+			if on {
+				tv.status = "active"
+			}
+			result = []interface{}{}
+		case "getSystemInformation":
+			info := map[string]string{"model": "KD-55X85J"}
+			if tv.reportMAC {
+				info["macAddr"] = testMAC
+			}
+			result = []interface{}{info}
+		case "getNetworkSettings":
+			result = []interface{}{[]map[string]string{
+				{"netif": "wlan0", "hwAddr": "fc:f1:52:aa:bb:cc", "ipAddrV4": "10.0.0.9"},
+				{"netif": "eth0", "hwAddr": testMAC, "ipAddrV4": "127.0.0.1"},
+			}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "id": 1})
+	}
+}
+
+func remoteAction(t *testing.T, remote *bravia.BraviaRemote, action string) *device.ActionResponse {
+	request, err := json.Marshal(device.ActionRequest{Type: device.ActionTypeRemote, Action: action})
+	require.NoError(t, err)
+	response, err := remote.Process(request)
+	require.NoError(t, err)
+	return response
+}
+
+func TestBraviaRemote_PowerStatusControl(t *testing.T) {
+	for _, reportMAC := range []bool{true, false} {
+		tv := &powerTV{status: "active", lagPolls: 2, reportMAC: reportMAC}
+		server := httptest.NewServer(tv.handler(t))
+		defer server.Close()
+
+		store := &memoryStore{options: map[string]string{}}
+		remote := bravia.NewBraviaRemote(strings.TrimPrefix(server.URL, "http://"), "test", &internal.FnModeOptions{})
+		remote.SetConfigStore(store)
+		remote.SetPowerPolling(5*time.Millisecond, time.Second)
+
+		response := remoteAction(t, remote, "power_off")
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, device.PowerStatusData(device.PowerStatusStandby), response.Data)
+
+		// The MAC is learned before the TV goes to sleep, via either API
+		mac, exists := store.Option(bravia.OptionMAC)
+		assert.True(t, exists)
+		assert.Equal(t, testMAC, mac)
+
+		tv.lagPolls = 2
+		response = remoteAction(t, remote, "power_on")
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, device.PowerStatusData(device.PowerStatusActive), response.Data)
+		assert.Equal(t, []bool{false, true}, tv.setCalls)
+	}
+}
+
+func TestBraviaRemote_PowerNotConfirmed(t *testing.T) {
+	tv := &powerTV{status: "active", lagPolls: 1000}
+	server := httptest.NewServer(tv.handler(t))
+	defer server.Close()
+
+	remote := bravia.NewBraviaRemote(strings.TrimPrefix(server.URL, "http://"), "test", &internal.FnModeOptions{})
+	remote.SetWakeOnLAN(testMAC, "")
+	remote.SetPowerPolling(5*time.Millisecond, 50*time.Millisecond)
+
+	response := remoteAction(t, remote, "power_off")
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "not confirmed")
+
+	tv.setFails = true
+	response = remoteAction(t, remote, "power_on")
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "failed to set power status")
+}
+
+func TestBraviaRemote_WakeOnLANFallback(t *testing.T) {
+	// Reserve an address for the TV, nothing listens on it while it is in deep standby
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	magic, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+
+	// The TV wakes into standby and must still be switched on
+	tv := &powerTV{status: "standby"}
+	server := &httptest.Server{Config: &http.Server{Handler: tv.handler(t)}}
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		buffer := make([]byte, 256)
+		n, _, err := magic.ReadFromUDP(buffer)
+		if err != nil {
+			return
+		}
+		hw, _ := net.ParseMAC(testMAC)
+		if !bytes.Equal(wol.MagicPacket(hw), buffer[:n]) {
+			return
+		}
+		tvListener, err := net.Listen("tcp", address)
+		if err != nil {
+			return
+		}
+		server.Listener = tvListener
+		server.Start()
+	}()
+	defer func() {
+		magic.Close()
+		<-started
+		if server.Listener != nil {
+			server.Close()
+		}
+	}()
+
+	remote := bravia.NewBraviaRemote(address, "test", &internal.FnModeOptions{})
+	remote.SetWakeOnLAN(testMAC, magic.LocalAddr().String())
+	remote.SetPowerPolling(10*time.Millisecond, 3*time.Second)
+
+	response := remoteAction(t, remote, "power_on")
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, device.PowerStatusData(device.PowerStatusActive), response.Data)
+
+	tv.mutex.Lock()
+	defer tv.mutex.Unlock()
+	assert.Equal(t, []bool{true}, tv.setCalls)
+}
+
+func TestBraviaRemote_UnreachableWithoutMAC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	remote := bravia.NewBraviaRemote(address, "test", &internal.FnModeOptions{})
+	response := remoteAction(t, remote, "power_on")
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "no MAC address")
+}
+
+func TestBraviaRemote_PowerRetriesStopAtDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	remote := bravia.NewBraviaRemote(strings.TrimPrefix(server.URL, "http://"), "test", &internal.FnModeOptions{})
+	remote.SetRetryPolicy(bravia.RetryPolicy{Attempts: 5, Delay: time.Second, Timeout: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	request, err := json.Marshal(device.ActionRequest{Type: device.ActionTypeRemote, Action: "power_on"})
+	require.NoError(t, err)
+
+	start := time.Now()
+	response, err := remote.ProcessContext(ctx, request)
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Less(t, time.Since(start), time.Second, "retries must not outlast the caller's deadline")
+}
+
+func TestBraviaRemote_PowerPollStopsAtDeadline(t *testing.T) {
+	tv := &powerTV{status: "active", lagPolls: 1000}
+	server := httptest.NewServer(tv.handler(t))
+	defer server.Close()
+
+	remote := bravia.NewBraviaRemote(strings.TrimPrefix(server.URL, "http://"), "test", &internal.FnModeOptions{})
+	remote.SetPowerPolling(5*time.Millisecond, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	request, err := json.Marshal(device.ActionRequest{Type: device.ActionTypeRemote, Action: "power_off"})
+	require.NoError(t, err)
+
+	start := time.Now()
+	response, err := remote.ProcessContext(ctx, request)
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "not confirmed")
+	assert.Less(t, time.Since(start), time.Second, "the poll must not outlast the caller's deadline")
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"encoding/json"
+	"io"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var irccCodePattern = regexp.MustCompile(`<IRCCCode>(.*)</IRCCCode>`)
+
+// codeTableTV answers getRemoteControllerInfo and records IRCC codes sent
+type codeTableTV struct {
+	mutex   sync.Mutex
+	fetches int
+	sent    []string
+	fail    bool
+}
+
+func (tv *codeTableTV) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tv.mutex.Lock()
+		defer tv.mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/sony/system":
+			var payload map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "getRemoteControllerInfo", payload["method"])
+			tv.fetches++
+			if tv.fail {
+				w.Write([]byte(`{"error": [12, "getRemoteControllerInfo"], "id": 1}`))
+				return
+			}
+			w.Write([]byte(`{"result": [{"bundled": true, "type": "RM-J1100"}, [
+				{"name": "PowerOff", "value": "AAAAAQAAAAEAAAAvAw=="},
+				{"name": "ActionMenu", "value": "AAAAAgAAAMQAAABLAw=="},
+				{"name": "Options", "value": "AAAAAgAAAJcAAAA2Aw=="},
+				{"name": "VolumeUp", "value": "AAAAAQAAAAEAAAASAw=="},
+				{"name": "Num1", "value": "AAAAAQAAAAEAAAAAAw=="},
+				{"name": "Netflix", "value": "AAAAAgAAABoAAAB8Aw=="}
+			]], "id": 1}`))
+		case "/sony/ircc":
+			body, _ := io.ReadAll(r.Body)
+			match := irccCodePattern.FindSubmatch(body)
+			require.NotNil(t, match)
+			tv.sent = append(tv.sent, string(match[1]))
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func newCodeTableRemote(t *testing.T, tv *codeTableTV) *bravia.BraviaRemote {
+	server := httptest.NewServer(tv.handler(t))
+	t.Cleanup(server.Close)
+
+	address := strings.TrimPrefix(server.URL, "http://")
+	return bravia.NewBraviaRemote(address, "test-credential", &internal.FnModeOptions{})
+}
+
+func TestBraviaRemote_DynamicCodeTable(t *testing.T) {
+	tv := &codeTableTV{}
+	remote := newCodeTableRemote(t, tv)
+
+	process := func(actionType, action string) (bool, interface{}, string) {
+		response, err := remote.Process([]byte(`{"type": "` + actionType + `", "action": "` + action + `"}`))
+		require.NoError(t, err)
+		return response.Success, response.Data, response.Error
+	}
+
+	t.Run("standard actions use the TV's codes", func(t *testing.T) {
+		success, _, errMsg := process("remote", "menu")
+		require.True(t, success, errMsg)
+		success, _, errMsg = process("remote", "num1")
+		require.True(t, success, errMsg)
+
+		assert.Equal(t, []string{"AAAAAgAAAMQAAABLAw==", "AAAAAQAAAAEAAAAAAw=="}, tv.sent)
+	})
+
+	t.Run("model specific names become remote actions", func(t *testing.T) {
+		success, _, errMsg := process("remote", "netflix")
+		require.True(t, success, errMsg)
+		assert.Equal(t, "AAAAAgAAABoAAAB8Aw==", tv.sent[len(tv.sent)-1])
+	})
+
+	t.Run("actions missing from the TV table use the static codes", func(t *testing.T) {
+		success, _, errMsg := process("remote", "home")
+		require.True(t, success, errMsg)
+
+		success, _, errMsg = process("remote", "not_a_button")
+		assert.False(t, success)
+		assert.Contains(t, errMsg, "unsupported remote action")
+	})
+
+	t.Run("the table is fetched once", func(t *testing.T) {
+		assert.Equal(t, 1, tv.fetches)
+	})
+
+	t.Run("remote_codes lists the available actions", func(t *testing.T) {
+		success, data, errMsg := process("control", "remote_codes")
+		require.True(t, success, errMsg)
+
+		result := data.(map[string]interface{})
+		assert.Equal(t, "tv", result["source"])
+		assert.Contains(t, result["actions"], "netflix")
+		assert.Contains(t, result["actions"], "power")
+	})
+}
+
+func TestBraviaRemote_CodeTableFallback(t *testing.T) {
+	tv := &codeTableTV{fail: true}
+	remote := newCodeTableRemote(t, tv)
+
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "volume_up"}`))
+	require.NoError(t, err)
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, []string{"AAAAAQAAAAEAAAASAw=="}, tv.sent)
+
+	response, err = remote.Process([]byte(`{"type": "control", "action": "remote_codes"}`))
+	require.NoError(t, err)
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, "static", response.Data.(map[string]interface{})["source"])
+
+	// A failed fetch is not retried on every key press
+	assert.Equal(t, 1, tv.fetches)
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"encoding/json"
+	"errors"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetry keeps retry tests quick
+var fastRetry = bravia.RetryPolicy{Attempts: 3, Delay: time.Millisecond, Timeout: time.Second}
+
+func rpcServer(t *testing.T, handler http.HandlerFunc) (*bravia.BraviaClient, *bravia.BraviaRemote) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	client := bravia.NewBraviaClient(address, "test", internal.FnModeOptions{})
+	client.SetRetryPolicy(fastRetry)
+	remote := bravia.NewBraviaRemote(address, "test", &internal.FnModeOptions{})
+	remote.SetRetryPolicy(fastRetry)
+	return client, remote
+}
+
+func TestBraviaRPC_ErrorReplies(t *testing.T) {
+	tests := []struct {
+		reply   string
+		code    int
+		message string
+		auth    bool
+	}{
+		{`{"error": [40005, "Display Is Turned off"], "id": 1}`, bravia.ErrorCodeDisplayOff, "the display is turned off", false},
+		{`{"error": [7, "Illegal State"], "id": 1}`, bravia.ErrorCodeIllegalState, "illegal state", false},
+		{`{"error": [12, "getPowerStatus"], "id": 1}`, bravia.ErrorCodeNoSuchMethod, "not supported by this TV", false},
+		{`{"error": [403, "Forbidden"], "id": 1}`, bravia.ErrorCodeForbidden, "pair the TV again", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			client, remote := rpcServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.reply))
+			})
+
+			_, err := client.Call(bravia.SystemEndpoint, bravia.GetPowerStatus, nil)
+			var apiErr *bravia.APIError
+			require.True(t, errors.As(err, &apiErr), "got %v", err)
+			assert.Equal(t, tt.code, apiErr.Code)
+			assert.Equal(t, tt.auth, errors.Is(err, bravia.ErrAuthFailed))
+
+			// Error replies are failures, not results
+			response := controlAction(t, remote, "power_status")
+			assert.False(t, response.Success)
+			assert.Contains(t, response.Error, tt.message)
+		})
+	}
+}
+
+func TestBraviaRPC_AuthFailure(t *testing.T) {
+	client, remote := rpcServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := client.Call(bravia.SystemEndpoint, bravia.GetPowerStatus, nil)
+	assert.ErrorIs(t, err, bravia.ErrAuthFailed)
+
+	response := controlAction(t, remote, "volume_info")
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "check the pre-shared key")
+}
+
+func TestBraviaRPC_Retries(t *testing.T) {
+	var mutex sync.Mutex
+	calls := map[string]int{}
+	failures := 2
+	client, _ := rpcServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		mutex.Lock()
+		calls[payload.Method]++
+		count := calls[payload.Method]
+		mutex.Unlock()
+
+		switch {
+		case payload.Method == "getPowerStatus" && count <= failures:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case payload.Method == "getVolumeInformation" && count == 1:
+			// Slower than the per-attempt timeout
+			time.Sleep(100 * time.Millisecond)
+		case payload.Method == "setAudioVolume":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"result": [{"status": "active"}], "id": 1}`))
+	})
+
+	t.Run("transient failures are retried", func(t *testing.T) {
+		result, err := client.Call(bravia.SystemEndpoint, bravia.GetPowerStatus, nil)
+		require.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, 3, calls["getPowerStatus"])
+	})
+
+	t.Run("timed out getters are retried", func(t *testing.T) {
+		client.SetRetryPolicy(bravia.RetryPolicy{Attempts: 2, Delay: time.Millisecond, Timeout: 50 * time.Millisecond})
+		defer client.SetRetryPolicy(fastRetry)
+
+		_, err := client.Call(bravia.AudioEndpoint, bravia.GetVolumeInformation, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls["getVolumeInformation"])
+	})
+
+	t.Run("setters the TV may have applied are not resent", func(t *testing.T) {
+		_, err := client.Call(bravia.AudioEndpoint, bravia.SetAudioVolume, []map[string]string{{"target": "speaker", "volume": "+5"}})
+		require.Error(t, err)
+		assert.Equal(t, 1, calls["setAudioVolume"])
+	})
+
+	t.Run("a single attempt policy does not retry", func(t *testing.T) {
+		client.SetRetryPolicy(bravia.RetryPolicy{Attempts: 1})
+		defer client.SetRetryPolicy(fastRetry)
+
+		calls["getPowerStatus"] = 0
+		_, err := client.Call(bravia.SystemEndpoint, bravia.GetPowerStatus, nil)
+		require.Error(t, err)
+		assert.Equal(t, 1, calls["getPowerStatus"])
+	})
+}
+
+func TestParseRetryPolicy(t *testing.T) {
+	policy, err := bravia.ParseRetryPolicy(nil)
+	require.NoError(t, err)
+	assert.Equal(t, bravia.DefaultRetryPolicy, policy)
+
+	policy, err = bravia.ParseRetryPolicy(map[string]string{"retries": "0", "retry_delay": "1s", "timeout": "3s"})
+	require.NoError(t, err)
+	assert.Equal(t, bravia.RetryPolicy{Attempts: 1, Delay: time.Second, Timeout: 3 * time.Second}, policy)
+
+	for _, options := range []map[string]string{{"retries": "-1"}, {"retry_delay": "soon"}, {"timeout": "0s"}} {
+		_, err := bravia.ParseRetryPolicy(options)
+		assert.Error(t, err, options)
+	}
+}
+
+func TestBraviaRPC_VersionNegotiation(t *testing.T) {
+	var mutex sync.Mutex
+	var versions []string
+	guideCalls := 0
+	_, remote := rpcServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Method  string `json:"method"`
+			Version string `json:"version"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch payload.Method {
+		case "getSupportedApiInfo":
+			guideCalls++
+			w.Write([]byte(`{"result": [[{"service": "audio", "apis": [
+				{"name": "setAudioVolume", "versions": [{"version": "1.2"}]},
+				{"name": "getSoundSettings", "versions": [{"version": "1.0"}]}
+			]}]], "id": 1}`))
+		case "setAudioVolume":
+			versions = append(versions, payload.Version)
+			if payload.Version != "1.2" {
+				w.Write([]byte(`{"error": [14, "Unsupported Version"], "id": 1}`))
+				return
+			}
+			w.Write([]byte(`{"result": [0], "id": 1}`))
+		default:
+			w.Write([]byte(`{"error": [14, "Unsupported Version"], "id": 1}`))
+		}
+	})
+
+	setVolume := func() {
+		request := `{"type": "control", "action": "set_volume", "parameters": {"volume": 10}}`
+		response, err := remote.Process([]byte(request))
+		require.NoError(t, err)
+		require.True(t, response.Success, response.Error)
+	}
+
+	setVolume()
+	setVolume()
+	assert.Equal(t, []string{"1.0", "1.2", "1.2"}, versions, "the negotiated version is remembered")
+	assert.Equal(t, 1, guideCalls)
+
+	response := controlAction(t, remote, "get_sound_settings")
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "TV supports version 1.0")
+}
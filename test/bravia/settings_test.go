@@ -0,0 +1,125 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"lucas/internal/device"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedCall is a JSON-RPC request received by the settings test server
+type recordedCall struct {
+	Path    string
+	Method  string          `json:"method"`
+	Version string          `json:"version"`
+	Params  json.RawMessage `json:"params"`
+}
+
+func TestBraviaRemote_SettingsActions(t *testing.T) {
+	var mutex sync.Mutex
+	var last recordedCall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		last = recordedCall{Path: r.URL.Path}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&last))
+		w.Write([]byte(`{"result": [], "id": 1}`))
+	}))
+	defer server.Close()
+
+	remote := bravia.NewBraviaRemote(strings.TrimPrefix(server.URL, "http://"), "test", &internal.FnModeOptions{})
+
+	tests := []struct {
+		action     string
+		parameters map[string]interface{}
+		path       string
+		method     string
+		version    string
+		params     string
+	}{
+		{"set_text", map[string]interface{}{"text": "breaking bad"}, "/sony/appControl", "setTextForm", "1.1", `[{"text":"breaking bad"}]`},
+		{"get_text", nil, "/sony/appControl", "getTextForm", "1.1", `[{}]`},
+		{"set_scene", map[string]interface{}{"scene": "auto24psync"}, "/sony/videoScreen", "setSceneSetting", "1.0", `[{"value":"auto24pSync"}]`},
+		{"get_picture_settings", nil, "/sony/video", "getPictureQualitySettings", "1.0", `[{"target":""}]`},
+		{"set_picture_setting", map[string]interface{}{"target": "brightness", "value": 30}, "/sony/video", "setPictureQualitySettings", "1.0", `[{"settings":[{"target":"brightness","value":"30"}]}]`},
+		{"get_sound_settings", map[string]interface{}{"target": "outputTerminal"}, "/sony/audio", "getSoundSettings", "1.1", `[{"target":"outputTerminal"}]`},
+		{"set_sound_setting", map[string]interface{}{"target": "outputTerminal", "value": "speaker"}, "/sony/audio", "setSoundSettings", "1.1", `[{"settings":[{"target":"outputTerminal","value":"speaker"}]}]`},
+		{"get_power_saving", nil, "/sony/system", "getPowerSavingMode", "1.0", `[]`},
+		{"set_power_saving", map[string]interface{}{"mode": "screen_off"}, "/sony/system", "setPowerSavingMode", "1.0", `[{"mode":"pictureOff"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			request, err := json.Marshal(device.ActionRequest{Type: device.ActionTypeControl, Action: tt.action, Parameters: tt.parameters})
+			require.NoError(t, err)
+			response, err := remote.Process(request)
+			require.NoError(t, err)
+			require.True(t, response.Success, response.Error)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			assert.Equal(t, tt.path, last.Path)
+			assert.Equal(t, tt.method, last.Method)
+			assert.Equal(t, tt.version, last.Version)
+			assert.JSONEq(t, tt.params, string(last.Params))
+		})
+	}
+}
+
+func TestBraviaRemote_SettingsValidation(t *testing.T) {
+	useTestSimulators(t)
+	remote := bravia.NewBraviaRemote("192.0.2.1", "test", &internal.FnModeOptions{Test: true})
+
+	tests := []struct {
+		action     string
+		parameters map[string]interface{}
+		error      string
+	}{
+		{"set_text", nil, "text parameter is required"},
+		{"set_text", map[string]interface{}{"text": 42}, "text must be a string"},
+		{"set_text", map[string]interface{}{"text": strings.Repeat("a", bravia.MaxTextLength+1)}, "longer than"},
+		{"set_scene", map[string]interface{}{"scene": "cinema"}, "unsupported scene"},
+		{"set_scene", map[string]interface{}{"scene": true}, "scene must be a string"},
+		{"get_picture_settings", map[string]interface{}{"target": 1}, "target must be a string"},
+		{"set_picture_setting", map[string]interface{}{"value": "30"}, "target parameter is required"},
+		{"set_picture_setting", map[string]interface{}{"target": "brightness"}, "value parameter is required"},
+		{"set_picture_setting", map[string]interface{}{"target": "brightness", "value": 30.5}, "whole number"},
+		{"set_sound_setting", map[string]interface{}{"target": "outputTerminal", "value": []string{"speaker"}}, "invalid value parameter type"},
+		{"set_power_saving", map[string]interface{}{"mode": "eco"}, "unsupported power saving mode"},
+		{"set_power_saving", nil, "mode parameter is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action+" "+tt.error, func(t *testing.T) {
+			request, err := json.Marshal(device.ActionRequest{Type: device.ActionTypeControl, Action: tt.action, Parameters: tt.parameters})
+			require.NoError(t, err)
+			response, err := remote.Process(request)
+			require.NoError(t, err)
+			assert.False(t, response.Success)
+			assert.Contains(t, response.Error, "invalid parameters")
+			assert.Contains(t, response.Error, tt.error)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bravia_test
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/bravia"
+	"lucas/internal/device"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startSimulator(t *testing.T, options bravia.SimulatorOptions) *bravia.Simulator {
+	tv, err := bravia.NewSimulator(options)
+	require.NoError(t, err)
+	t.Cleanup(tv.Close)
+	return tv
+}
+
+// useTestSimulators gives the test its own simulated TVs for test mode clients
+func useTestSimulators(t *testing.T) *bravia.TestSimulators {
+	simulators := bravia.NewTestSimulators()
+	previous := bravia.SetTestSimulators(simulators)
+	t.Cleanup(func() {
+		bravia.SetTestSimulators(previous)
+		simulators.Close()
+	})
+	return simulators
+}
+
+func process(t *testing.T, remote *bravia.BraviaRemote, actionType device.ActionType, action string, parameters map[string]interface{}) *device.ActionResponse {
+	request, err := json.Marshal(device.ActionRequest{Type: actionType, Action: action, Parameters: parameters})
+	require.NoError(t, err)
+	response, err := remote.Process(request)
+	require.NoError(t, err)
+	return response
+}
+
+func TestSimulator_State(t *testing.T) {
+	tv := startSimulator(t, bravia.SimulatorOptions{PSK: "0000"})
+	remote := bravia.NewBraviaRemote(tv.Address(), "0000", &internal.FnModeOptions{})
+	remote.SetRetryPolicy(fastRetry)
+
+	t.Run("remote buttons change the volume and input", func(t *testing.T) {
+		for _, action := range []string{"volume_up", "volume_up", "hdmi3"} {
+			response := process(t, remote, device.ActionTypeRemote, action, nil)
+			require.True(t, response.Success, response.Error)
+		}
+		volume, _ := tv.Volume("speaker")
+		assert.Equal(t, 22, volume)
+		assert.Equal(t, "extInput:hdmi?port=3", tv.Input())
+		assert.Equal(t, []string{"VolumeUp", "VolumeUp", "Hdmi3"}, tv.Sent(), "codes come from the TV's own table")
+	})
+
+	t.Run("relative and absolute volume", func(t *testing.T) {
+		require.True(t, process(t, remote, device.ActionTypeControl, "set_volume", map[string]interface{}{"volume": "-2"}).Success)
+		volume, _ := tv.Volume("speaker")
+		assert.Equal(t, 20, volume)
+
+		require.True(t, process(t, remote, device.ActionTypeControl, "set_volume", map[string]interface{}{"volume": 150}).Success)
+		volume, _ = tv.Volume("speaker")
+		assert.Equal(t, 100, volume, "volume is clamped to the maximum")
+	})
+
+	t.Run("an app replaces the playing input", func(t *testing.T) {
+		response := process(t, remote, device.ActionTypeControl, "launch_app", map[string]interface{}{"app": "netflix"})
+		require.True(t, response.Success, response.Error)
+		assert.Empty(t, tv.Input())
+
+		response = controlAction(t, remote, "playing_content")
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "illegal state")
+
+		response = process(t, remote, device.ActionTypeControl, "set_input", map[string]interface{}{"input": "hdmi2"})
+		require.True(t, response.Success, response.Error)
+		assert.Empty(t, tv.App())
+		assert.Equal(t, "extInput:hdmi?port=2", tv.Input())
+	})
+
+	t.Run("standby turns the display off", func(t *testing.T) {
+		response := process(t, remote, device.ActionTypeRemote, "power_off", nil)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "standby", tv.Power())
+
+		response = process(t, remote, device.ActionTypeControl, "set_text", map[string]interface{}{"text": "lucas"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "the display is turned off")
+
+		require.True(t, process(t, remote, device.ActionTypeRemote, "power_on", nil).Success)
+		require.True(t, process(t, remote, device.ActionTypeControl, "set_text", map[string]interface{}{"text": "lucas"}).Success)
+		assert.Equal(t, "lucas", tv.Text())
+	})
+}
+
+func TestSimulator_Auth(t *testing.T) {
+	tv := startSimulator(t, bravia.SimulatorOptions{PSK: "0000", PIN: "1234"})
+
+	wrong := bravia.NewBraviaRemote(tv.Address(), "9999", &internal.FnModeOptions{})
+	response := controlAction(t, wrong, "power_status")
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "check the pre-shared key")
+
+	client := bravia.NewBraviaClient(tv.Address(), "", internal.FnModeOptions{})
+	credential, err := client.RequestPIN("lucas-sim")
+	require.NoError(t, err)
+	assert.Empty(t, credential, "an unknown client is asked for the PIN")
+
+	_, err = client.CompletePairing("lucas-sim", "4321")
+	assert.Error(t, err)
+
+	credential, err = client.CompletePairing("lucas-sim", "1234")
+	require.NoError(t, err)
+	paired := bravia.NewBraviaRemote(tv.Address(), credential, &internal.FnModeOptions{})
+	response = controlAction(t, paired, "power_status")
+	require.True(t, response.Success, response.Error)
+}
+
+func TestSimulator_Faults(t *testing.T) {
+	tv := startSimulator(t, bravia.SimulatorOptions{})
+	client := bravia.NewBraviaClient(tv.Address(), "any", internal.FnModeOptions{})
+	client.SetRetryPolicy(fastRetry)
+
+	t.Run("transient faults are retried", func(t *testing.T) {
+		tv.InjectFault(bravia.GetPowerStatus, bravia.Fault{Drop: true, Count: 2})
+		status, err := client.GetPowerStatus()
+		require.NoError(t, err)
+		assert.Equal(t, "active", status)
+		assert.Equal(t, 3, tv.Calls(bravia.GetPowerStatus))
+	})
+
+	t.Run("error replies", func(t *testing.T) {
+		tv.InjectFault(bravia.SetActiveApp, bravia.Fault{Code: bravia.ErrorCodeIllegalState})
+		defer tv.ClearFaults()
+		err := client.SetActiveApp("com.sony.dtv.com.netflix.ninja.com.netflix.ninja.MainActivity")
+		var apiErr *bravia.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, bravia.ErrorCodeIllegalState, apiErr.Code)
+	})
+
+	t.Run("IRCC faults", func(t *testing.T) {
+		tv.InjectFault(bravia.IRCCAction, bravia.Fault{Status: 500, Count: 1})
+		assert.Error(t, client.RemoteRequest(bravia.Mute))
+		require.NoError(t, client.RemoteRequest(bravia.Mute))
+		_, mute := tv.Volume("speaker")
+		assert.True(t, mute)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		tv.SetLatency(100 * time.Millisecond)
+		defer tv.SetLatency(0)
+		client.SetRetryPolicy(bravia.RetryPolicy{Attempts: 1, Timeout: 20 * time.Millisecond})
+		defer client.SetRetryPolicy(fastRetry)
+
+		_, err := client.GetPowerStatus()
+		assert.Error(t, err)
+	})
+}
+
+func TestSimulator_TestMode(t *testing.T) {
+	// Test mode clients of one address share a simulated TV
+	simulators := useTestSimulators(t)
+	remote := bravia.NewBraviaRemote("192.0.2.50", "test", &internal.FnModeOptions{Test: true})
+	response := process(t, remote, device.ActionTypeRemote, "power_on", nil)
+	require.True(t, response.Success, response.Error)
+	response = process(t, remote, device.ActionTypeControl, "set_volume", map[string]interface{}{"volume": 33})
+	require.True(t, response.Success, response.Error)
+
+	other := bravia.NewBraviaRemote("192.0.2.50", "test", &internal.FnModeOptions{Test: true})
+	response = controlAction(t, other, "volume_info")
+	require.True(t, response.Success, response.Error)
+	data, err := json.Marshal(response.Data)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"volume":33`)
+
+	assert.Equal(t, "192.0.2.50", remote.GetDeviceInfo().Address)
+
+	response = process(t, remote, device.ActionTypeRemote, "power_off", nil)
+	require.True(t, response.Success, response.Error)
+	response = controlAction(t, other, "power_status")
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, device.PowerStatusData("standby"), response.Data)
+
+	tv, err := simulators.Get("192.0.2.50", "")
+	require.NoError(t, err)
+	assert.Equal(t, "standby", tv.Power())
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadlink_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/broadlink"
+	"lucas/internal/device"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore records options saved by a device
+type memoryStore struct {
+	mutex   sync.Mutex
+	options map[string]string
+}
+
+func (s *memoryStore) SetCredential(credential string) error {
+	return nil
+}
+
+func (s *memoryStore) SetOption(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if value == "" {
+		delete(s.options, key)
+		return nil
+	}
+	s.options[key] = value
+	return nil
+}
+
+func (s *memoryStore) Option(key string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	value, exists := s.options[key]
+	return value, exists
+}
+
+var powerCode = []byte{0x26, 0x00, 0x08, 0x00, 0x48, 0x24, 0x12, 0x12, 0x12, 0x36, 0x0d, 0x05}
+
+func process(t *testing.T, d device.Device, actionType device.ActionType, action string, params map[string]interface{}) *device.ActionResponse {
+	request, err := json.Marshal(device.ActionRequest{Type: actionType, Action: action, Parameters: params})
+	require.NoError(t, err)
+
+	response, err := d.Process(request)
+	require.NoError(t, err)
+	return response
+}
+
+func newTestDevice(t *testing.T, rm4 bool, config map[string]string) (*broadlink.IRDevice, *broadlink.FakeBlaster, *memoryStore) {
+	fake, err := broadlink.NewFakeBlaster()
+	require.NoError(t, err)
+	fake.SetRM4(rm4)
+	t.Cleanup(fake.Close)
+
+	if config == nil {
+		config = map[string]string{}
+	}
+	if rm4 {
+		config[broadlink.OptionRM4] = "true"
+	}
+
+	store := &memoryStore{options: map[string]string{}}
+	irDevice, err := broadlink.NewIRDevice(fake.Address(), "34:ea:34:01:02:03", config, store, nil)
+	require.NoError(t, err)
+	irDevice.Client().SetPollInterval(10 * time.Millisecond)
+
+	return irDevice, fake, store
+}
+
+func TestIRSendConfiguredCode(t *testing.T) {
+	for _, rm4 := range []bool{false, true} {
+		irDevice, fake, _ := newTestDevice(t, rm4, map[string]string{
+			"code.power": hex.EncodeToString(powerCode),
+		})
+
+		response := process(t, irDevice, device.ActionTypeRemote, "power", nil)
+		require.True(t, response.Success, response.Error)
+		require.Len(t, fake.Sent(), 1)
+		assert.Equal(t, powerCode, fake.Sent()[0], "rm4=%v", rm4)
+
+		response = process(t, irDevice, device.ActionTypeRemote, "volume_up", nil)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "no IR code learned")
+	}
+}
+
+func TestIRLearnAndReplay(t *testing.T) {
+	irDevice, fake, store := newTestDevice(t, false, nil)
+
+	t.Run("times out without a button press", func(t *testing.T) {
+		response := process(t, irDevice, device.ActionTypeControl, "learn", map[string]interface{}{
+			"name":    "swing",
+			"timeout": 0.05,
+		})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "learning failed")
+	})
+
+	t.Run("learned code is stored and becomes a remote action", func(t *testing.T) {
+		fake.SetLearnedCode(powerCode)
+		response := process(t, irDevice, device.ActionTypeControl, "learn", map[string]interface{}{
+			"name":    "swing",
+			"timeout": 1,
+		})
+		require.True(t, response.Success, response.Error)
+
+		stored, exists := store.Option("code.swing")
+		require.True(t, exists)
+		assert.Equal(t, hex.EncodeToString(powerCode), stored)
+
+		response = process(t, irDevice, device.ActionTypeRemote, "swing", nil)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, powerCode, fake.Sent()[0])
+
+		response = process(t, irDevice, device.ActionTypeControl, "codes", nil)
+		require.True(t, response.Success)
+		assert.Equal(t, []string{"swing"}, response.Data.(map[string]interface{})["codes"])
+	})
+
+	t.Run("forget removes the code", func(t *testing.T) {
+		response := process(t, irDevice, device.ActionTypeControl, "forget", map[string]interface{}{"name": "swing"})
+		require.True(t, response.Success, response.Error)
+
+		_, exists := store.Option("code.swing")
+		assert.False(t, exists)
+
+		response = process(t, irDevice, device.ActionTypeRemote, "swing", nil)
+		assert.False(t, response.Success)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		response := process(t, irDevice, device.ActionTypeControl, "learn", map[string]interface{}{"name": "Power Button"})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "invalid parameters")
+	})
+}
+
+func TestIRReauthenticatesAfterReset(t *testing.T) {
+	irDevice, fake, _ := newTestDevice(t, false, map[string]string{
+		"code.power": hex.EncodeToString(powerCode),
+	})
+
+	response := process(t, irDevice, device.ActionTypeRemote, "power", nil)
+	require.True(t, response.Success, response.Error)
+
+	fake.Reset()
+
+	response = process(t, irDevice, device.ActionTypeRemote, "power", nil)
+	require.True(t, response.Success, response.Error)
+	assert.Len(t, fake.Sent(), 2)
+}
+
+func TestIRConfigValidation(t *testing.T) {
+	_, err := broadlink.NewIRDevice("192.0.2.1", "", map[string]string{"code.power": "not a code!"}, nil, nil)
+	assert.Error(t, err)
+
+	_, err = broadlink.NewIRDevice("192.0.2.1", "", map[string]string{"devtype": "zz"}, nil, nil)
+	assert.Error(t, err)
+
+	// Codes exported by other tools are base64
+	_, err = broadlink.NewIRDevice("192.0.2.1", "", map[string]string{"code.power": "JgAIAEgkEhISNg0F", "devtype": "0x5f36"}, nil, nil)
+	assert.NoError(t, err)
+}
+
+func TestIRTestMode(t *testing.T) {
+	irDevice, err := broadlink.NewIRDevice("192.0.2.1", "", nil, nil, &internal.FnModeOptions{Test: true})
+	require.NoError(t, err)
+	defer irDevice.Client().Close()
+	irDevice.Client().SetPollInterval(10 * time.Millisecond)
+
+	response := process(t, irDevice, device.ActionTypeControl, "learn", map[string]interface{}{"name": "power"})
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, hex.EncodeToString(broadlink.SampleIRCode), response.Data.(map[string]interface{})["code"])
+
+	response = process(t, irDevice, device.ActionTypeRemote, "power", nil)
+	assert.True(t, response.Success, response.Error)
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cast_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/cast"
+	"lucas/internal/device"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDevice(t *testing.T) (*cast.CastDevice, *cast.FakeReceiver) {
+	receiver, err := cast.NewFakeReceiver()
+	require.NoError(t, err)
+
+	castDevice := cast.NewCastDevice("127.0.0.1", receiver.CastPort(), receiver.DIALPort(), nil)
+	t.Cleanup(func() {
+		castDevice.Client().Close()
+		receiver.Close()
+	})
+	return castDevice, receiver
+}
+
+func process(t *testing.T, d device.Device, actionType device.ActionType, action string, params map[string]interface{}) *device.ActionResponse {
+	request, err := json.Marshal(device.ActionRequest{Type: actionType, Action: action, Parameters: params})
+	require.NoError(t, err)
+
+	response, err := d.Process(request)
+	require.NoError(t, err)
+	return response
+}
+
+func TestCastMessageRoundTrip(t *testing.T) {
+	message := &cast.CastMessage{
+		SourceID:      cast.DefaultSenderID,
+		DestinationID: cast.DefaultReceiverID,
+		Namespace:     cast.NamespaceReceiver,
+		PayloadType:   cast.PayloadString,
+		PayloadUTF8:   `{"type":"GET_STATUS","requestId":1}`,
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, cast.WriteMessage(&buffer, message))
+	assert.Equal(t, uint32(len(message.Marshal())), uint32(buffer.Bytes()[3]))
+
+	decoded, err := cast.ReadMessage(&buffer)
+	require.NoError(t, err)
+	assert.Equal(t, message, decoded)
+
+	_, err = cast.UnmarshalCastMessage([]byte{0x0a, 0x10, 'x'})
+	assert.Error(t, err, "truncated length delimited field")
+}
+
+func TestCastMediaControl(t *testing.T) {
+	castDevice, receiver := newTestDevice(t)
+
+	t.Run("media action before load fails", func(t *testing.T) {
+		response := process(t, castDevice, device.ActionTypeMedia, "play", nil)
+		assert.False(t, response.Success)
+	})
+
+	t.Run("load launches the default media receiver", func(t *testing.T) {
+		response := process(t, castDevice, device.ActionTypeMedia, "load", map[string]interface{}{
+			"url": "http://example.com/video.mp4",
+		})
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "PLAYING", receiver.PlayerState())
+	})
+
+	t.Run("pause and play", func(t *testing.T) {
+		response := process(t, castDevice, device.ActionTypeMedia, "pause", nil)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "PAUSED", receiver.PlayerState())
+
+		response = process(t, castDevice, device.ActionTypeMedia, "play", nil)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "PLAYING", receiver.PlayerState())
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		response := process(t, castDevice, device.ActionTypeMedia, "seek", map[string]interface{}{"position": 42})
+		require.True(t, response.Success, response.Error)
+
+		data := response.Data.(map[string]interface{})
+		status := data["status"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, float64(42), status["currentTime"])
+
+		response = process(t, castDevice, device.ActionTypeMedia, "seek", map[string]interface{}{"position": -1})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "invalid parameters")
+	})
+
+	t.Run("volume and mute", func(t *testing.T) {
+		response := process(t, castDevice, device.ActionTypeMedia, "set_volume", map[string]interface{}{"volume": 30})
+		require.True(t, response.Success, response.Error)
+		response = process(t, castDevice, device.ActionTypeMedia, "set_mute", map[string]interface{}{"status": "true"})
+		require.True(t, response.Success, response.Error)
+
+		volume, muted := receiver.Volume()
+		assert.InDelta(t, 0.3, volume, 0.001)
+		assert.True(t, muted)
+
+		response = process(t, castDevice, device.ActionTypeMedia, "set_volume", map[string]interface{}{"volume": 150})
+		assert.False(t, response.Success)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		response := process(t, castDevice, device.ActionTypeMedia, "stop", nil)
+		require.True(t, response.Success, response.Error)
+		assert.Equal(t, "", receiver.PlayerState())
+	})
+
+	t.Run("unsupported action type", func(t *testing.T) {
+		response := process(t, castDevice, device.ActionTypeRemote, "power", nil)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "unsupported action type")
+	})
+}
+
+func TestCastDIALApps(t *testing.T) {
+	castDevice, _ := newTestDevice(t)
+
+	response := process(t, castDevice, device.ActionTypeMedia, "app_status", map[string]interface{}{"app": "YouTube"})
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, "stopped", response.Data.(*cast.DIALAppStatus).State)
+
+	response = process(t, castDevice, device.ActionTypeMedia, "launch_app", map[string]interface{}{
+		"app":     "YouTube",
+		"payload": "v=dQw4w9WgXcQ",
+	})
+	require.True(t, response.Success, response.Error)
+	assert.Contains(t, response.Data.(map[string]interface{})["location"], "/apps/YouTube/run")
+
+	response = process(t, castDevice, device.ActionTypeMedia, "app_status", map[string]interface{}{"app": "YouTube"})
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, "running", response.Data.(*cast.DIALAppStatus).State)
+
+	response = process(t, castDevice, device.ActionTypeMedia, "stop_app", map[string]interface{}{"app": "YouTube"})
+	require.True(t, response.Success, response.Error)
+
+	response = process(t, castDevice, device.ActionTypeMedia, "launch_app", map[string]interface{}{"app": "Missing"})
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "not found")
+
+	response = process(t, castDevice, device.ActionTypeControl, "system_info", nil)
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, "Lucas Test Receiver", response.Data.(*cast.DIALDeviceDescription).FriendlyName)
+}
+
+func TestCastTestMode(t *testing.T) {
+	castDevice := cast.NewCastDevice("192.0.2.10", 0, 0, &internal.FnModeOptions{Test: true})
+	defer castDevice.Client().Close()
+
+	response := process(t, castDevice, device.ActionTypeMedia, "load", map[string]interface{}{
+		"url":      "http://example.com/song.mp3",
+		"autoplay": false,
+	})
+	require.True(t, response.Success, response.Error)
+
+	response = process(t, castDevice, device.ActionTypeMedia, "status", nil)
+	require.True(t, response.Success, response.Error)
+	status := response.Data.(map[string]interface{})["status"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "PAUSED", status["playerState"])
+}
@@ -0,0 +1,70 @@
+package gateway_test
+
+import (
+	"lucas/internal/gateway"
+	"testing"
+	"time"
+)
+
+func TestSearchChannels(t *testing.T) {
+	channels := []gateway.Channel{
+		{Title: "BBC ONE", Number: "001"},
+		{Title: "BBC TWO", Number: "002"},
+		{Title: "Channel 4", Number: "004"},
+		{Title: "More4", Number: "018"},
+		{Title: "4seven", Number: "049"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"BBC ONE", "BBC TWO", "Channel 4", "More4", "4seven"}},
+		{"bbc", []string{"BBC ONE", "BBC TWO"}},
+		{"4", []string{"Channel 4", "4seven", "More4"}},
+		{"18", []string{"More4"}},
+		{"channel 4", []string{"Channel 4"}},
+		{"film", nil},
+	}
+
+	for _, tt := range tests {
+		result := gateway.SearchChannels(channels, tt.query)
+		var titles []string
+		for _, channel := range result {
+			titles = append(titles, channel.Title)
+		}
+		if len(titles) != len(tt.want) {
+			t.Errorf("SearchChannels(%q) = %v, want %v", tt.query, titles, tt.want)
+			continue
+		}
+		for i := range titles {
+			if titles[i] != tt.want[i] {
+				t.Errorf("SearchChannels(%q) = %v, want %v", tt.query, titles, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestChannelCache(t *testing.T) {
+	cache := gateway.NewChannelCache(50 * time.Millisecond)
+	if _, ok := cache.Get("tv"); ok {
+		t.Fatal("Expected an empty cache to miss")
+	}
+
+	cache.Put("tv", []gateway.Channel{{Title: "BBC ONE"}})
+	if channels, ok := cache.Get("tv"); !ok || len(channels) != 1 {
+		t.Fatalf("Expected cached channels, got %v", channels)
+	}
+
+	cache.Invalidate("tv")
+	if _, ok := cache.Get("tv"); ok {
+		t.Error("Expected an invalidated list to miss")
+	}
+
+	cache.Put("tv", []gateway.Channel{{Title: "BBC ONE"}})
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := cache.Get("tv"); ok {
+		t.Error("Expected an expired list to miss")
+	}
+}
@@ -0,0 +1,76 @@
+package gateway_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lucas/internal/gateway"
+	"lucas/internal/hermes"
+)
+
+func TestLimitsDefaultEachField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.yml")
+	data := `server:
+  zmq:
+    limits:
+      queue_depth: 10
+      service_queue_depth:
+        hub.control: 100
+        stream.data: -1
+      hub_inflight: -1
+    payload:
+      compress_threshold: -1
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := gateway.LoadGatewayConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	defaultLimits := hermes.DefaultBrokerLimits()
+	limits := config.GetBrokerLimits()
+	if limits.MaxQueueDepth != 10 {
+		t.Errorf("Expected queue depth 10, got %d", limits.MaxQueueDepth)
+	}
+	if limits.ServiceQueueDepth["hub.control"] != 100 {
+		t.Errorf("Expected hub.control queue depth 100, got %d", limits.ServiceQueueDepth["hub.control"])
+	}
+	if depth, exists := limits.ServiceQueueDepth["stream.data"]; !exists || depth != 0 {
+		t.Errorf("Expected stream.data queue to be unbounded, got %d", depth)
+	}
+	if limits.MaxClientInflight != defaultLimits.MaxClientInflight {
+		t.Errorf("Expected default client inflight %d, got %d", defaultLimits.MaxClientInflight, limits.MaxClientInflight)
+	}
+	if limits.MaxWorkerInflight != 0 {
+		t.Errorf("Expected hub inflight to be disabled, got %d", limits.MaxWorkerInflight)
+	}
+	if limits.RequestTTL != 30*time.Second {
+		t.Errorf("Expected default request TTL, got %s", limits.RequestTTL)
+	}
+
+	defaultPayload := hermes.DefaultPayloadLimits()
+	payload := config.GetPayloadLimits()
+	if payload.MaxFrameSize != defaultPayload.MaxFrameSize || payload.MaxBodySize != defaultPayload.MaxBodySize {
+		t.Errorf("Expected default frame and body sizes, got %+v", payload)
+	}
+	if payload.CompressThreshold != 0 {
+		t.Errorf("Expected compression to be disabled, got %d", payload.CompressThreshold)
+	}
+}
+
+func TestLimitsRejectNegative(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.yml")
+	data := "server:\n  zmq:\n    limits:\n      queue_depth: -2\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := gateway.LoadGatewayConfig(path); err == nil {
+		t.Error("Expected a queue depth below -1 to be rejected")
+	}
+}
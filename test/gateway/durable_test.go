@@ -0,0 +1,249 @@
+package gateway_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"lucas/internal/gateway"
+	"lucas/internal/hermes"
+)
+
+func TestDurableRequestLifecycle(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	payload := json.RawMessage(`{"device_id":"tv","action":{"type":"control","action":"power_off"}}`)
+	request, err := db.CreateDurableRequest("hub_1", "tv", "execute", payload, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create durable request: %v", err)
+	}
+	if request.Status != gateway.DurableStatusPending {
+		t.Errorf("Expected pending, got %s", request.Status)
+	}
+
+	pending, err := db.GetPendingDurableRequests("hub_1")
+	if err != nil || len(pending) != 1 || pending[0].RequestID != request.RequestID {
+		t.Fatalf("Expected the request to be pending, got %v (%v)", pending, err)
+	}
+	if string(pending[0].Payload) != string(payload) {
+		t.Errorf("Payload not preserved: %s", pending[0].Payload)
+	}
+
+	if claimed, err := db.ClaimDurableRequest(request.RequestID); err != nil || !claimed {
+		t.Fatalf("Expected to claim the request, got %v (%v)", claimed, err)
+	}
+	if claimed, _ := db.ClaimDurableRequest(request.RequestID); claimed {
+		t.Error("Expected a delivered request not to be claimed twice")
+	}
+
+	if err := db.ReleaseDurableRequest(request.RequestID); err != nil {
+		t.Fatalf("Failed to release durable request: %v", err)
+	}
+	if claimed, _ := db.ClaimDurableRequest(request.RequestID); !claimed {
+		t.Fatal("Expected a released request to be claimed again")
+	}
+
+	reply := json.RawMessage(`{"success":true}`)
+	if err := db.FinishDurableRequest(request.RequestID, gateway.DurableStatusCompleted, reply, ""); err != nil {
+		t.Fatalf("Failed to finish durable request: %v", err)
+	}
+
+	stored, err := db.GetDurableRequest(request.RequestID)
+	if err != nil {
+		t.Fatalf("Failed to get durable request: %v", err)
+	}
+	if stored.Status != gateway.DurableStatusCompleted || stored.Attempts != 2 {
+		t.Errorf("Expected completed after 2 attempts, got %s after %d", stored.Status, stored.Attempts)
+	}
+	if string(stored.Reply) != string(reply) || stored.DeliveredAt == nil || stored.CompletedAt == nil {
+		t.Errorf("Outcome not recorded: %+v", stored)
+	}
+
+	if cancelled, _ := db.CancelDurableRequest(request.RequestID); cancelled {
+		t.Error("Expected a completed request not to be cancelled")
+	}
+	if _, err := db.GetDurableRequest("missing"); !errors.Is(err, gateway.ErrDurableRequestNotFound) {
+		t.Errorf("Expected ErrDurableRequestNotFound, got %v", err)
+	}
+}
+
+func TestDurableRequestExpiryAndCancel(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expiring, _ := db.CreateDurableRequest("hub_1", "tv", "execute", json.RawMessage(`{}`), 10*time.Millisecond)
+	cancelling, _ := db.CreateDurableRequest("hub_1", "tv", "execute", json.RawMessage(`{}`), time.Hour)
+	time.Sleep(20 * time.Millisecond)
+
+	if cancelled, err := db.CancelDurableRequest(cancelling.RequestID); err != nil || !cancelled {
+		t.Fatalf("Expected to cancel the request, got %v (%v)", cancelled, err)
+	}
+	if claimed, _ := db.ClaimDurableRequest(expiring.RequestID); claimed {
+		t.Error("Expected an overdue request not to be claimed")
+	}
+
+	expired, err := db.ExpireDurableRequests()
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected 1 expired request, got %d (%v)", expired, err)
+	}
+
+	for id, want := range map[string]string{
+		expiring.RequestID:   gateway.DurableStatusExpired,
+		cancelling.RequestID: gateway.DurableStatusCancelled,
+	} {
+		if request, _ := db.GetDurableRequest(id); request.Status != want {
+			t.Errorf("Expected %s, got %s", want, request.Status)
+		}
+	}
+
+	if hubs, _ := db.GetDurableRequestHubs(); len(hubs) != 0 {
+		t.Errorf("Expected no hubs with pending requests, got %v", hubs)
+	}
+}
+
+func TestReleaseStaleDurableRequests(t *testing.T) {
+	testReleaseStaleDurableRequests(t)
+}
+
+func TestReleaseStaleDurableRequestsLocalTime(t *testing.T) {
+	// Timestamps are stored in UTC, so the cutoff must not depend on the local zone
+	for _, zone := range []*time.Location{
+		time.FixedZone("UTC+8", 8*60*60),
+		time.FixedZone("UTC-7", -7*60*60),
+	} {
+		t.Run(zone.String(), func(t *testing.T) {
+			local := time.Local
+			time.Local = zone
+			defer func() { time.Local = local }()
+
+			testReleaseStaleDurableRequests(t)
+		})
+	}
+}
+
+func testReleaseStaleDurableRequests(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	request, _ := db.CreateDurableRequest("hub_1", "tv", "execute", json.RawMessage(`{}`), time.Hour)
+	db.ClaimDurableRequest(request.RequestID)
+
+	// A delivery still within its timeout may be in progress on another gateway
+	if released, err := db.ReleaseStaleDurableRequests(time.Minute); err != nil || released != 0 {
+		t.Fatalf("Expected a recent delivery to be left alone, got %d (%v)", released, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	released, err := db.ReleaseStaleDurableRequests(10 * time.Millisecond)
+	if err != nil || released != 1 {
+		t.Fatalf("Expected 1 released request, got %d (%v)", released, err)
+	}
+	if hubs, _ := db.GetDurableRequestHubs(); len(hubs) != 1 || hubs[0] != "hub_1" {
+		t.Errorf("Expected hub_1 to have pending requests, got %v", hubs)
+	}
+}
+
+// fakeHub answers hub.control requests like a hub with a single TV
+type fakeHub struct {
+	hubID string
+}
+
+func (h fakeHub) Handle(request []byte) ([]byte, error) {
+	req, err := hermes.DeserializeServiceRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	var actionErr error
+	switch req.Action {
+	case "list":
+		data = map[string]interface{}{"hub_id": h.hubID, "devices": []interface{}{}}
+	case "execute":
+		var command struct {
+			DeviceID string `json:"device_id"`
+		}
+		json.Unmarshal(req.Payload, &command)
+		if command.DeviceID == "tv" {
+			data = map[string]interface{}{"success": true, "device_id": command.DeviceID}
+		} else {
+			actionErr = fmt.Errorf("device not found: %s", command.DeviceID)
+		}
+	}
+
+	resp := hermes.CreateServiceResponseWithNonce(req.MessageID, req.Service, req.Nonce, actionErr == nil, data, actionErr)
+	return hermes.SerializeServiceResponse(resp)
+}
+
+func waitForDurableStatus(t *testing.T, db *gateway.Database, requestID, status string) *gateway.DurableRequest {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		request, err := db.GetDurableRequest(requestID)
+		if err == nil && request.Status == status {
+			return request
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Request %s did not reach %s: %+v (%v)", requestID, status, request, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestDurableRequestDeliveredOnReady(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	endpoint := "tcp://" + listener.Addr().String()
+	listener.Close()
+
+	bs := gateway.NewBrokerService(endpoint, nil, db)
+	if err := bs.Start(); err != nil {
+		t.Fatalf("Failed to start broker service: %v", err)
+	}
+	defer bs.Stop()
+
+	action := json.RawMessage(`{"type":"control","action":"power_off"}`)
+	request, err := bs.SubmitDurableDeviceCommand("hub_durable", "tv", action, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to submit durable request: %v", err)
+	}
+	failing, err := bs.SubmitDurableDeviceCommand("hub_durable", "radio", action, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to submit durable request: %v", err)
+	}
+
+	// The hub is offline, so the requests wait
+	time.Sleep(200 * time.Millisecond)
+	if stored, _ := db.GetDurableRequest(request.RequestID); stored.Status != gateway.DurableStatusPending {
+		t.Fatalf("Expected pending while the hub is offline, got %s", stored.Status)
+	}
+
+	worker := hermes.NewWorker(endpoint, "hub.control", "hub_durable", fakeHub{hubID: "hub_durable"})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start hub worker: %v", err)
+	}
+	defer worker.Stop()
+
+	completed := waitForDurableStatus(t, db, request.RequestID, gateway.DurableStatusCompleted)
+	if completed.Attempts != 1 || len(completed.Reply) == 0 {
+		t.Errorf("Expected one attempt with a reply, got %+v", completed)
+	}
+
+	failed := waitForDurableStatus(t, db, failing.RequestID, gateway.DurableStatusFailed)
+	if failed.Error != "device not found: radio" {
+		t.Errorf("Expected the hub's error, got %q", failed.Error)
+	}
+
+	// With the hub connected, new requests are delivered straight away
+	immediate, _ := bs.SubmitDurableDeviceCommand("hub_durable", "tv", action, time.Hour)
+	waitForDurableStatus(t, db, immediate.RequestID, gateway.DurableStatusCompleted)
+}
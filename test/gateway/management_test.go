@@ -0,0 +1,68 @@
+package gateway_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"lucas/internal/gateway"
+	"lucas/internal/hermes"
+)
+
+func TestManagementRequiresToken(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user, err := db.CreateUser("operator", "operator@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := db.RegisterHub("hub_1", "hub_public_key", "Hub 1", "product-key-1"); err != nil {
+		t.Fatalf("Failed to register hub: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	endpoint := "tcp://" + listener.Addr().String()
+	listener.Close()
+
+	bs := gateway.NewBrokerService(endpoint, nil, db)
+	if err := bs.Start(); err != nil {
+		t.Fatalf("Failed to start broker service: %v", err)
+	}
+	defer bs.Stop()
+
+	tests := []struct {
+		name     string
+		clientID string
+		token    string
+		want     string
+	}{
+		{"no token", "hub_1/cli", "", hermes.MMI_UNAUTHORIZED},
+		{"user api key", "tool", user.APIKey, hermes.MMI_NOT_FOUND},
+		{"hub product key", "hub_1/cli", "product-key-1", hermes.MMI_NOT_FOUND},
+		{"product key of another hub", "hub_2/cli", "product-key-1", hermes.MMI_UNAUTHORIZED},
+		{"wrong product key", "hub_1/cli", "product-key-2", hermes.MMI_UNAUTHORIZED},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := hermes.NewClient(endpoint, tt.clientID)
+			client.SetManagementToken(tt.token)
+			if err := client.Start(); err != nil {
+				t.Fatalf("Failed to start client: %v", err)
+			}
+			defer client.Stop()
+
+			reply, err := client.Management(hermes.MMI_SERVICE, []byte("missing"), 5*time.Second)
+			if err != nil {
+				t.Fatalf("Management request failed: %v", err)
+			}
+			if string(reply) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, reply)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package gateway_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"lucas/internal/gateway"
+	"lucas/internal/hermes"
+)
+
+func TestQueryHubRefusesUnsupportedAction(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	endpoint := "tcp://" + listener.Addr().String()
+	listener.Close()
+
+	bs := gateway.NewBrokerService(endpoint, nil, db)
+	if err := bs.Start(); err != nil {
+		t.Fatalf("Failed to start broker service: %v", err)
+	}
+	defer bs.Stop()
+
+	// An older hub that announces neither discover nor adopt
+	worker := hermes.NewWorker(endpoint, "hub.control", "hub_old", fakeHub{hubID: "hub_old"})
+	worker.SetCapabilities(hermes.CapabilityNonce)
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start hub worker: %v", err)
+	}
+	defer worker.Stop()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if info, ok := bs.HubInfo("hub_old"); ok {
+			if info.Version != hermes.HERMES_PROTOCOL_VERSION {
+				t.Errorf("Expected protocol version %d, got %d", hermes.HERMES_PROTOCOL_VERSION, info.Version)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Hub did not connect")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	ctx := context.Background()
+	if _, err := bs.QueryHub(ctx, "hub_old", "discover", map[string]string{}, 5*time.Second); !errors.Is(err, gateway.ErrHubUnsupported) {
+		t.Errorf("Expected discover to be refused, got %v", err)
+	}
+	if _, err := bs.QueryHub(ctx, "hub_old", "execute", map[string]string{"device_id": "tv"}, 5*time.Second); err != nil {
+		t.Errorf("Expected execute to reach the hub, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package gateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"lucas/internal/gateway"
+	"lucas/internal/hermes"
+)
+
+// streamingHub reports discovered devices one by one before its final reply
+type streamingHub struct {
+	fakeHub
+}
+
+func (h streamingHub) HandleContext(ctx context.Context, request []byte) ([]byte, error) {
+	return h.Handle(request)
+}
+
+func (h streamingHub) HandleStream(ctx context.Context, request []byte, progress func(data interface{}) error) ([]byte, error) {
+	req, err := hermes.DeserializeServiceRequest(request)
+	if err != nil || req.Action != "discover" {
+		return h.Handle(request)
+	}
+
+	devices := []map[string]interface{}{{"id": "uuid:tv"}, {"id": "uuid:speaker"}}
+	for _, device := range devices {
+		progress(map[string]interface{}{"found": device, "hub_id": h.hubID})
+	}
+	data := map[string]interface{}{"discovered": devices, "count": len(devices), "hub_id": h.hubID}
+	return hermes.SerializeServiceResponse(hermes.CreateServiceResponseWithNonce(req.MessageID, req.Service, req.Nonce, true, data, nil))
+}
+
+func TestStreamHubRelaysProgress(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	endpoint := "tcp://" + listener.Addr().String()
+	listener.Close()
+
+	bs := gateway.NewBrokerService(endpoint, nil, db)
+	if err := bs.Start(); err != nil {
+		t.Fatalf("Failed to start broker service: %v", err)
+	}
+	defer bs.Stop()
+
+	for _, hub := range []struct {
+		id      string
+		handler hermes.RequestHandler
+	}{
+		{"hub_streaming", streamingHub{fakeHub{hubID: "hub_streaming"}}},
+		{"hub_plain", fakeHub{hubID: "hub_plain"}},
+	} {
+		worker := hermes.NewWorker(endpoint, "hub.control", hub.id, hub.handler)
+		worker.SetCapabilities(hermes.CapabilityNonce, hermes.CapabilityDiscover)
+		if err := worker.Start(); err != nil {
+			t.Fatalf("Failed to start hub worker: %v", err)
+		}
+		defer worker.Stop()
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		_, streaming := bs.HubInfo("hub_streaming")
+		_, plain := bs.HubInfo("hub_plain")
+		if streaming && plain {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Hubs did not connect")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	var found []string
+	data, err := bs.StreamHub(context.Background(), "hub_streaming", "discover", map[string]string{}, 5*time.Second, func(progress json.RawMessage) {
+		var partial struct {
+			Found struct {
+				ID string `json:"id"`
+			} `json:"found"`
+		}
+		json.Unmarshal(progress, &partial)
+		found = append(found, partial.Found.ID)
+	})
+	if err != nil {
+		t.Fatalf("StreamHub failed: %v", err)
+	}
+	if len(found) != 2 || found[0] != "uuid:tv" || found[1] != "uuid:speaker" {
+		t.Errorf("Expected both devices reported in order, got %v", found)
+	}
+	var result struct {
+		Count int `json:"count"`
+	}
+	if json.Unmarshal(data, &result); result.Count != 2 {
+		t.Errorf("Expected the final result with 2 devices, got %s", data)
+	}
+
+	// A hub that does not stream just answers
+	data, err = bs.StreamHub(context.Background(), "hub_plain", "execute", map[string]string{"device_id": "tv"}, 5*time.Second, func(json.RawMessage) {
+		t.Error("Expected no progress from a hub that does not stream")
+	})
+	if err != nil || len(data) == 0 {
+		t.Errorf("Expected execute to reach the hub, got %s (%v)", data, err)
+	}
+}
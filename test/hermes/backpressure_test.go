@@ -0,0 +1,167 @@
+package hermes_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"lucas/internal/hermes"
+)
+
+func expectErrorReply(t *testing.T, reply [][]byte, messageID string, reason error) {
+	t.Helper()
+	if len(reply) != 4 {
+		t.Fatalf("Expected 4 frames, got %q", reply)
+	}
+	var resp hermes.ServiceResponse
+	if err := json.Unmarshal(reply[3], &resp); err != nil {
+		t.Fatalf("Failed to parse reply: %v", err)
+	}
+	if resp.Success || resp.MessageID != messageID || resp.Error != reason.Error() || resp.Nonce != "nonce_"+messageID {
+		t.Errorf("Expected %q for %s, got %+v", reason, messageID, resp)
+	}
+}
+
+func echoBody(messageID string) string {
+	return fmt.Sprintf(`{"message_id":%q,"service":"echo","action":"echo","payload":"hi","nonce":"nonce_%s"}`, messageID, messageID)
+}
+
+// startBusyWorker registers a raw echo worker and keeps it busy with a request from another client
+func startBusyWorker(t *testing.T, broker *hermes.Broker, endpoint string) {
+	t.Helper()
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	busy := dialDealer(t, endpoint, "busy-client")
+	sendFrames(t, busy, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_busy"))
+	recvFrames(t, worker)
+}
+
+func TestBackpressure_QueueDepth(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetLimits(hermes.BrokerLimits{MaxQueueDepth: 1})
+	startBusyWorker(t, broker, endpoint)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_1"))
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_2"))
+	expectErrorReply(t, recvFrames(t, client), "msg_2", hermes.ErrServiceQueueFull)
+
+	stats := broker.GetStats()
+	if stats.Rejected != 1 || stats.Queued != 1 {
+		t.Errorf("Expected 1 rejected and 1 queued request, got %+v", stats)
+	}
+	if queued := broker.GetServices()["echo"].Queued; queued != 1 {
+		t.Errorf("Expected 1 request queued for echo, got %d", queued)
+	}
+}
+
+func TestBackpressure_ServiceQueueDepth(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetLimits(hermes.BrokerLimits{
+		MaxQueueDepth:     1,
+		ServiceQueueDepth: map[string]int{"echo": 2},
+	})
+	startBusyWorker(t, broker, endpoint)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_1"))
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_2"))
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_3"))
+	expectErrorReply(t, recvFrames(t, client), "msg_3", hermes.ErrServiceQueueFull)
+
+	if queued := broker.GetServices()["echo"].Queued; queued != 2 {
+		t.Errorf("Expected the echo override to queue 2 requests, got %d", queued)
+	}
+}
+
+func TestBackpressure_RequestTTL(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetLimits(hermes.BrokerLimits{RequestTTL: 200 * time.Millisecond})
+	startBusyWorker(t, broker, endpoint)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	start := time.Now()
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_1"))
+	expectErrorReply(t, recvFrames(t, client), "msg_1", hermes.ErrRequestExpired)
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the request to wait out its TTL, expired after %v", elapsed)
+	}
+
+	stats := broker.GetStats()
+	if stats.Expired != 1 || stats.Queued != 0 {
+		t.Errorf("Expected 1 expired and no queued requests, got %+v", stats)
+	}
+}
+
+func TestBackpressure_ClientInflight(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetLimits(hermes.BrokerLimits{MaxClientInflight: 1})
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_1"))
+	recvFrames(t, worker)
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_2"))
+	expectErrorReply(t, recvFrames(t, client), "msg_2", hermes.ErrTooManyInflight)
+
+	// Another client is not held back by it
+	other := dialDealer(t, endpoint, "other-client")
+	sendFrames(t, other, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_3"))
+	time.Sleep(100 * time.Millisecond)
+	if stats := broker.GetStats(); stats.Rejected != 1 || stats.Queued != 1 {
+		t.Errorf("Expected the other client's request queued, got %+v", stats)
+	}
+
+	// Once answered, the client may send again
+	reply, _ := hermes.SerializeServiceResponse(hermes.CreateServiceResponse("msg_1", "echo", true, "HI", nil))
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, "raw-client", "", string(reply))
+	recvFrames(t, client)
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_4"))
+	time.Sleep(100 * time.Millisecond)
+	if stats := broker.GetStats(); stats.Rejected != 1 || stats.Queued != 1 {
+		t.Errorf("Expected msg_4 accepted, got %+v", stats)
+	}
+}
+
+func TestBackpressure_StuckHub(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetLimits(hermes.BrokerLimits{MaxWorkerInflight: 1})
+
+	stuck := dialDealer(t, endpoint, "hub_stuck")
+	healthy := dialDealer(t, endpoint, "hub_healthy")
+	sendFrames(t, stuck, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "hub.control")
+	sendFrames(t, healthy, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "hub.control")
+	waitForWorkers(t, broker, 2)
+	recvFrames(t, stuck)
+	recvFrames(t, healthy)
+
+	hubRequest := func(messageID, hubID string) string {
+		return fmt.Sprintf(`{"message_id":%q,"service":"hub.control","action":"status","payload":{},"nonce":"nonce_%s","hub_id":%q}`,
+			messageID, messageID, hubID)
+	}
+
+	// The stuck hub never answers, so its second request is turned away
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "hub.control", hubRequest("msg_1", "hub_stuck"))
+	recvFrames(t, stuck)
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "hub.control", hubRequest("msg_2", "hub_stuck"))
+	expectErrorReply(t, recvFrames(t, client), "msg_2", hermes.ErrWorkerBusy)
+
+	// The other hub is still served
+	request := hubRequest("msg_3", "hub_healthy")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "hub.control", request)
+	expectFrames(t, recvFrames(t, healthy), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "raw-client", "", request)
+
+	if !hermes.IsRetryable(&hermes.ServiceError{Service: "hub.control", Message: hermes.ErrWorkerBusy.Error()}) {
+		t.Error("Expected a busy hub to be worth retrying")
+	}
+	if hermes.IsRetryable(&hermes.ServiceError{Service: "hub.control", Message: "device not found: tv"}) {
+		t.Error("Expected a device error not to be retried")
+	}
+}
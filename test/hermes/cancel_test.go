@@ -0,0 +1,151 @@
+package hermes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"lucas/internal/hermes"
+)
+
+func startClient(t *testing.T, endpoint, identity string) *hermes.HermesClient {
+	t.Helper()
+	client := hermes.NewClient(endpoint, identity)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	t.Cleanup(func() { client.Stop() })
+	return client
+}
+
+func TestCancel_ForwardedToWorker(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	// Only workers that announce cancel are told
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo", `{"version":2,"capabilities":["cancel"]}`)
+	waitForWorkers(t, broker, 1)
+
+	client := startClient(t, endpoint, "cancel-client")
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Request(ctx, "echo", []byte(`{"message_id":"msg_slow","service":"echo","action":"echo","payload":"hi"}`))
+		done <- err
+	}()
+
+	// The deadline travels with the request as its timeout
+	request := recvFrames(t, worker)
+	if len(request) != 6 {
+		t.Fatalf("Expected 6 frames, got %q", request)
+	}
+	var req hermes.ServiceRequest
+	if err := json.Unmarshal(request[5], &req); err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+	if req.MessageID != "msg_slow" || req.Timeout != 1 {
+		t.Errorf("Expected msg_slow with a 1s timeout, got %+v", req)
+	}
+
+	// The worker never answers, so the client gives up and the worker is told
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_CANCEL, "cancel-client", "", "msg_slow")
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if stats := client.GetStats(); stats.RequestsTimeout != 1 {
+		t.Errorf("Expected 1 timed out request, got %+v", stats)
+	}
+	if stats := broker.GetStats(); stats.Cancelled != 1 {
+		t.Errorf("Expected 1 cancelled request, got %d", stats.Cancelled)
+	}
+}
+
+func TestCancel_QueuedRequest(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	// The only worker is busy, so the client's request waits in the queue
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	first := dialDealer(t, endpoint, "first-client")
+	sendFrames(t, first, "", hermes.HERMES_CLIENT, "echo", "one")
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "first-client", "", "one")
+
+	client := startClient(t, endpoint, "cancel-client")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Request(ctx, "echo", []byte(`{"message_id":"msg_queued","service":"echo","action":"echo","payload":"hi"}`))
+		done <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled error, got %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for broker.GetStats().Cancelled != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the queued request to be cancelled, got %+v", broker.GetStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The withdrawn request never reaches the worker once it is free
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, "first-client", "", "ONE")
+	expectFrames(t, recvFrames(t, first), "", hermes.HERMES_CLIENT, "echo", "ONE")
+	sendFrames(t, first, "", hermes.HERMES_CLIENT, "echo", "two")
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "first-client", "", "two")
+}
+
+// blockingHandler holds each request until its context ends and reports how it ended
+type blockingHandler struct {
+	ended chan error
+}
+
+func (h blockingHandler) Handle(request []byte) ([]byte, error) {
+	return h.HandleContext(context.Background(), request)
+}
+
+func (h blockingHandler) HandleContext(ctx context.Context, request []byte) ([]byte, error) {
+	<-ctx.Done()
+	h.ended <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func TestCancel_ContextRequestHandler(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	handler := blockingHandler{ended: make(chan error, 1)}
+	worker := hermes.NewWorker(endpoint, "echo", "context-worker", handler)
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	client := startClient(t, endpoint, "cancel-client")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := client.Request(ctx, "echo", []byte(`{"service":"echo","action":"echo","payload":"hi"}`)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled error, got %v", err)
+	}
+
+	select {
+	case err := <-handler.ended:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the handler's context to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the handler's context to end")
+	}
+}
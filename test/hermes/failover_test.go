@@ -0,0 +1,144 @@
+package hermes_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"lucas/internal/hermes"
+)
+
+func waitForBroker(t *testing.T, worker *hermes.HermesWorker, broker string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !worker.IsConnected() || worker.GetBroker() != broker {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the worker on %s, got %s (connected %v)", broker, worker.GetBroker(), worker.IsConnected())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWorkerFailover(t *testing.T) {
+	primaryEndpoint := freeEndpoint(t)
+	backup, backupEndpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(primaryEndpoint, "echo", "failover-worker", echoHandler{})
+	worker.SetBrokers([]string{primaryEndpoint, backupEndpoint})
+	worker.SetHeartbeat(100 * time.Millisecond)
+	worker.SetReconnectInterval(100 * time.Millisecond)
+	worker.SetPrimaryCheckInterval(200 * time.Millisecond)
+
+	// The primary is down, so the worker starts on the backup
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForBroker(t, worker, backupEndpoint)
+	waitForWorkers(t, backup, 1)
+
+	// Once the primary comes up the worker returns to it and leaves the backup
+	primary := hermes.NewBroker(primaryEndpoint)
+	if err := primary.Start(); err != nil {
+		t.Fatalf("Failed to start primary broker: %v", err)
+	}
+	waitForBroker(t, worker, primaryEndpoint)
+	waitForWorkers(t, primary, 1)
+	waitForWorkers(t, backup, 0)
+
+	// Losing the primary sends the worker back to the backup
+	primary.Stop()
+	waitForBroker(t, worker, backupEndpoint)
+	waitForWorkers(t, backup, 1)
+}
+
+func TestZMQTransportReachable(t *testing.T) {
+	var transport hermes.ZMQTransport
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	tcpEndpoint := "tcp://" + listener.Addr().String()
+	if !transport.Reachable(tcpEndpoint, time.Second) {
+		t.Error("Expected a listening tcp endpoint to be reachable")
+	}
+	listener.Close()
+	if transport.Reachable(tcpEndpoint, time.Second) {
+		t.Error("Expected a closed tcp endpoint to be unreachable")
+	}
+
+	path := filepath.Join(t.TempDir(), "broker.sock")
+	if transport.Reachable("ipc://"+path, time.Second) {
+		t.Error("Expected an ipc endpoint without a socket to be unreachable")
+	}
+	unixListener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer unixListener.Close()
+	if !transport.Reachable("ipc://"+path, time.Second) {
+		t.Error("Expected a listening ipc endpoint to be reachable")
+	}
+
+	// inproc endpoints cannot be probed, the worker finds out by dialing
+	if !transport.Reachable("inproc://broker", time.Second) {
+		t.Error("Expected an inproc endpoint to count as reachable")
+	}
+}
+
+// keyRecorder is a MemoryTransport that records the server key each endpoint was dialed with
+type keyRecorder struct {
+	*hermes.MemoryTransport
+	mutex sync.Mutex
+	keys  map[string]string
+}
+
+func (r *keyRecorder) Dial(ctx context.Context, endpoint, identity, serverKey string, maxRetries int) (hermes.Socket, error) {
+	r.mutex.Lock()
+	r.keys[endpoint] = serverKey
+	r.mutex.Unlock()
+	return r.MemoryTransport.Dial(ctx, endpoint, identity, serverKey, maxRetries)
+}
+
+func (r *keyRecorder) key(endpoint string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.keys[endpoint]
+}
+
+func TestWorkerFailoverServerKeys(t *testing.T) {
+	const primaryEndpoint, backupEndpoint = "inproc://primary", "inproc://backup"
+	transport := &keyRecorder{MemoryTransport: hermes.NewMemoryTransport(), keys: make(map[string]string)}
+	clock := hermes.NewManualClock(time.Unix(0, 0))
+
+	backup := hermes.NewBroker(backupEndpoint)
+	backup.SetTransport(transport)
+	backup.SetClock(clock)
+	if err := backup.Start(); err != nil {
+		t.Fatalf("Failed to start backup broker: %v", err)
+	}
+	defer backup.Stop()
+
+	worker := hermes.NewWorker(primaryEndpoint, "echo", "keyed-worker", echoHandler{})
+	worker.SetTransport(transport)
+	worker.SetClock(clock)
+	worker.SetBrokers([]string{primaryEndpoint, backupEndpoint})
+	worker.SetServerKeys(map[string]string{primaryEndpoint: "primary-key", backupEndpoint: "backup-key"})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+
+	// The primary is down, so the worker fails over to the backup with the backup's key
+	advanceUntil(t, clock, time.Second, time.Minute, hasWorker(backup, "keyed-worker"))
+	if key := transport.key(primaryEndpoint); key != "primary-key" {
+		t.Errorf("Expected the primary dialed with primary-key, got %q", key)
+	}
+	if key := transport.key(backupEndpoint); key != "backup-key" {
+		t.Errorf("Expected the backup dialed with backup-key, got %q", key)
+	}
+}
@@ -0,0 +1,61 @@
+package hermes_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/destiny/zmq4/v25"
+	"lucas/internal/hermes"
+)
+
+func TestHubControl_RoutesByHubID(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	hubA := dialDealer(t, endpoint, "hub_a")
+	hubB := dialDealer(t, endpoint, "hub_b")
+	sendFrames(t, hubA, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "hub.control")
+	sendFrames(t, hubB, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "hub.control")
+	waitForWorkers(t, broker, 2)
+
+	// Each hub is asked for its device list on READY
+	for _, hub := range []zmq4.Socket{hubA, hubB} {
+		if reply := recvFrames(t, hub); len(reply) < 3 || string(reply[2]) != hermes.HERMES_REQUEST {
+			t.Fatalf("Expected a device list request, got %q", reply)
+		}
+	}
+
+	client := dialDealer(t, endpoint, "raw-client")
+	request := `{"message_id":"msg_1","service":"hub.control","action":"status","payload":{},"nonce":"n1","hub_id":"b"}`
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "hub.control", request)
+	expectFrames(t, recvFrames(t, hubB), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "raw-client", "", request)
+
+	// A request for a hub that is not connected fails at once, echoing the nonce
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "hub.control",
+		`{"message_id":"msg_2","service":"hub.control","action":"status","payload":{},"nonce":"n2","hub_id":"hub_c"}`)
+	reply := recvFrames(t, client)
+	if len(reply) != 4 {
+		t.Fatalf("Expected 4 frames, got %q", reply)
+	}
+	var resp hermes.ServiceResponse
+	if err := json.Unmarshal(reply[3], &resp); err != nil {
+		t.Fatalf("Failed to parse reply: %v", err)
+	}
+	if resp.Success || resp.Error != "hub worker not available" || resp.Nonce != "n2" {
+		t.Errorf("Unexpected reply: %+v", resp)
+	}
+}
+
+func TestWorkerIdentity(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(endpoint, "echo", "named-worker", echoHandler{})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	if _, exists := broker.GetWorkers()["named-worker"]; !exists {
+		t.Errorf("Expected the broker to know the worker by its identity, got %v", broker.GetWorkers())
+	}
+}
@@ -0,0 +1,374 @@
+package hermes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/destiny/zmq4/v25"
+	"lucas/internal/hermes"
+)
+
+// The conformance tests drive the broker with raw sockets writing RFC 7/MDP 0.1 frames
+// byte for byte, the way MDP clients and workers in other languages do
+
+func freeEndpoint(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return "tcp://" + listener.Addr().String()
+}
+
+func startConformanceBroker(t *testing.T) (*hermes.Broker, string) {
+	t.Helper()
+	endpoint := freeEndpoint(t)
+	broker := hermes.NewBroker(endpoint)
+	if err := broker.Start(); err != nil {
+		t.Fatalf("Failed to start broker: %v", err)
+	}
+	t.Cleanup(func() { broker.Stop() })
+	return broker, endpoint
+}
+
+func dialDealer(t *testing.T, endpoint, identity string) zmq4.Socket {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	socket := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity(identity)))
+	if err := socket.Dial(endpoint); err != nil {
+		cancel()
+		t.Fatalf("Failed to dial broker: %v", err)
+	}
+	t.Cleanup(func() {
+		socket.Close()
+		cancel()
+	})
+	return socket
+}
+
+func sendFrames(t *testing.T, socket zmq4.Socket, frames ...string) {
+	t.Helper()
+	parts := make([][]byte, len(frames))
+	for i, frame := range frames {
+		parts[i] = []byte(frame)
+	}
+	if err := socket.Send(zmq4.NewMsgFrom(parts...)); err != nil {
+		t.Fatalf("Failed to send frames: %v", err)
+	}
+}
+
+func recvFrames(t *testing.T, socket zmq4.Socket) [][]byte {
+	t.Helper()
+	type result struct {
+		msg zmq4.Msg
+		err error
+	}
+	received := make(chan result, 1)
+	go func() {
+		msg, err := socket.Recv()
+		received <- result{msg, err}
+	}()
+
+	select {
+	case r := <-received:
+		if r.err != nil {
+			t.Fatalf("Failed to receive frames: %v", r.err)
+		}
+		return r.msg.Frames
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for frames")
+		return nil
+	}
+}
+
+func expectFrames(t *testing.T, got [][]byte, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d frames %q, got %d frames %q", len(want), want, len(got), got)
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], []byte(want[i])) {
+			t.Fatalf("Frame %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func waitForWorkers(t *testing.T, broker *hermes.Broker, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(broker.GetWorkers()) != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d workers, got %d", count, len(broker.GetWorkers()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// echoHandler answers service requests with the upper-cased payload
+type echoHandler struct{}
+
+func (echoHandler) Handle(request []byte) ([]byte, error) {
+	req, err := hermes.DeserializeServiceRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	var text string
+	if err := json.Unmarshal(req.Payload, &text); err != nil {
+		return nil, err
+	}
+	resp := hermes.CreateServiceResponseWithNonce(req.MessageID, req.Service, req.Nonce, true, strings.ToUpper(text), nil)
+	return hermes.SerializeServiceResponse(resp)
+}
+
+func echoRequest(t *testing.T, client *hermes.HermesClient, text string) string {
+	t.Helper()
+	req, err := hermes.CreateServiceRequest("echo", "echo", text)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Nonce = hermes.GenerateNonce()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := client.RequestWithNonce(ctx, "echo", body, req.Nonce)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var resp hermes.ServiceResponse
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("Failed to parse reply: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Request failed: %s", resp.Error)
+	}
+	return fmt.Sprint(resp.Data)
+}
+
+func TestMDPConformance_RequestReply(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	for i := 0; i < 3; i++ {
+		body := fmt.Sprintf("hello %d", i)
+		sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", body)
+
+		// The worker sees the client's address envelope followed by the body
+		request := recvFrames(t, worker)
+		expectFrames(t, request, "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "raw-client", "", body)
+
+		sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, "raw-client", "", strings.ToUpper(body))
+		reply := recvFrames(t, client)
+		expectFrames(t, reply, "", hermes.HERMES_CLIENT, "echo", strings.ToUpper(body))
+	}
+}
+
+func TestMDPConformance_QueuedRequest(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	// The service exists but its only worker is busy, so the next request waits for it
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	first := dialDealer(t, endpoint, "first-client")
+	second := dialDealer(t, endpoint, "second-client")
+	sendFrames(t, first, "", hermes.HERMES_CLIENT, "echo", "one")
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "first-client", "", "one")
+	sendFrames(t, second, "", hermes.HERMES_CLIENT, "echo", "two")
+
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, "first-client", "", "ONE")
+	expectFrames(t, recvFrames(t, first), "", hermes.HERMES_CLIENT, "echo", "ONE")
+
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "second-client", "", "two")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, "second-client", "", "TWO")
+	expectFrames(t, recvFrames(t, second), "", hermes.HERMES_CLIENT, "echo", "TWO")
+}
+
+func TestMDPConformance_Heartbeat(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_HEARTBEAT)
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_HEARTBEAT)
+}
+
+func TestMDPConformance_Disconnect(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_DISCONNECT)
+	waitForWorkers(t, broker, 0)
+}
+
+func TestMDPConformance_UnknownService(t *testing.T) {
+	_, endpoint := startConformanceBroker(t)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "missing", `{"message_id":"msg_1"}`)
+
+	reply := recvFrames(t, client)
+	if len(reply) != 4 {
+		t.Fatalf("Expected 4 frames, got %q", reply)
+	}
+	expectFrames(t, reply[:3], "", hermes.HERMES_CLIENT, "missing")
+
+	var resp hermes.ServiceResponse
+	if err := json.Unmarshal(reply[3], &resp); err != nil {
+		t.Fatalf("Failed to parse reply: %v", err)
+	}
+	if resp.Success || resp.MessageID != "msg_1" || !strings.Contains(resp.Error, "service not available") {
+		t.Errorf("Unexpected reply: %+v", resp)
+	}
+}
+
+func TestMDPConformance_Worker(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	// A Hermes worker registers with plain MDP frames
+	worker := hermes.NewWorker(endpoint, "echo", "hermes-worker", echoHandler{})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", `{"message_id":"msg_1","service":"echo","action":"echo","payload":"hi"}`)
+
+	reply := recvFrames(t, client)
+	if len(reply) != 4 {
+		t.Fatalf("Expected 4 frames, got %q", reply)
+	}
+	expectFrames(t, reply[:3], "", hermes.HERMES_CLIENT, "echo")
+	var resp hermes.ServiceResponse
+	if err := json.Unmarshal(reply[3], &resp); err != nil {
+		t.Fatalf("Failed to parse reply: %v", err)
+	}
+	if !resp.Success || resp.MessageID != "msg_1" || resp.Data != "HI" {
+		t.Errorf("Unexpected reply: %+v", resp)
+	}
+}
+
+func TestMDPConformance_Client(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	// Answer the Hermes client's request from the raw worker
+	go func() {
+		msg, err := worker.Recv()
+		if err != nil || len(msg.Frames) != 6 {
+			return
+		}
+		var req hermes.ServiceRequest
+		if json.Unmarshal(msg.Frames[5], &req) != nil {
+			return
+		}
+		resp, _ := hermes.SerializeServiceResponse(hermes.CreateServiceResponseWithNonce(req.MessageID, "echo", req.Nonce, true, "RAW", nil))
+		worker.Send(zmq4.NewMsgFrom([]byte(""), []byte(hermes.HERMES_WORKER), []byte(hermes.HERMES_REPLY), msg.Frames[3], []byte(""), resp))
+	}()
+
+	client := hermes.NewClient(endpoint, "hermes-client")
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Stop()
+
+	if got := echoRequest(t, client, "raw"); got != "RAW" {
+		t.Errorf("Expected RAW, got %s", got)
+	}
+}
+
+func TestMDPConformance_JSONCompatibility(t *testing.T) {
+	tests := []struct {
+		name          string
+		workerFraming hermes.Framing
+		clientFraming hermes.Framing
+	}{
+		{"json worker, mdp client", hermes.FramingJSON, hermes.FramingMDP},
+		{"mdp worker, json client", hermes.FramingMDP, hermes.FramingJSON},
+		{"json worker, json client", hermes.FramingJSON, hermes.FramingJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker, endpoint := startConformanceBroker(t)
+
+			worker := hermes.NewWorker(endpoint, "echo", "hermes-worker", echoHandler{})
+			worker.SetFraming(tt.workerFraming)
+			if err := worker.Start(); err != nil {
+				t.Fatalf("Failed to start worker: %v", err)
+			}
+			defer worker.Stop()
+			waitForWorkers(t, broker, 1)
+
+			client := hermes.NewClient(endpoint, "hermes-client")
+			client.SetFraming(tt.clientFraming)
+			if err := client.Start(); err != nil {
+				t.Fatalf("Failed to start client: %v", err)
+			}
+			defer client.Stop()
+
+			for _, text := range []string{"first", "second"} {
+				if got := echoRequest(t, client, text); got != strings.ToUpper(text) {
+					t.Errorf("Expected %s, got %s", strings.ToUpper(text), got)
+				}
+			}
+		})
+	}
+}
+
+func TestMDPConformance_JSONFrames(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	// A worker still sending single JSON frames is answered in kind
+	worker := dialDealer(t, endpoint, "json-worker")
+	ready, _ := hermes.SerializeMessage(&hermes.WorkerMessage{Protocol: hermes.HERMES_WORKER, Command: hermes.HERMES_READY, Service: "echo"})
+	sendFrames(t, worker, "", string(ready))
+	waitForWorkers(t, broker, 1)
+
+	heartbeat, _ := hermes.SerializeMessage(&hermes.WorkerMessage{Protocol: hermes.HERMES_WORKER, Command: hermes.HERMES_HEARTBEAT})
+	sendFrames(t, worker, "", string(heartbeat))
+	reply := recvFrames(t, worker)
+	if len(reply) != 2 {
+		t.Fatalf("Expected a single JSON frame, got %q", reply)
+	}
+	msg, err := hermes.DeserializeWorkerMessage(reply[1])
+	if err != nil || msg.Command != hermes.HERMES_HEARTBEAT {
+		t.Errorf("Expected a JSON heartbeat, got %q", reply[1])
+	}
+}
+
+func TestParseFraming(t *testing.T) {
+	for name, want := range map[string]hermes.Framing{"": hermes.FramingMDP, "mdp": hermes.FramingMDP, "json": hermes.FramingJSON} {
+		got, err := hermes.ParseFraming(name)
+		if err != nil || got != want {
+			t.Errorf("ParseFraming(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := hermes.ParseFraming("zmtp"); err == nil {
+		t.Error("Expected an error for an unknown framing")
+	}
+}
@@ -0,0 +1,257 @@
+package hermes_test
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/destiny/zmq4/v25"
+
+	"lucas/internal/hermes"
+)
+
+const memoryEndpoint = "inproc://broker"
+
+// startMemoryBroker starts a broker on the in-memory transport, driven by clock
+func startMemoryBroker(t *testing.T, transport *hermes.MemoryTransport, clock hermes.Clock) *hermes.Broker {
+	t.Helper()
+	broker := hermes.NewBroker(memoryEndpoint)
+	broker.SetTransport(transport)
+	broker.SetClock(clock)
+	if err := broker.Start(); err != nil {
+		t.Fatalf("Failed to start broker: %v", err)
+	}
+	t.Cleanup(func() { broker.Stop() })
+	return broker
+}
+
+// startMemoryWorker starts an echo worker on the in-memory transport, driven by clock
+func startMemoryWorker(t *testing.T, transport *hermes.MemoryTransport, clock hermes.Clock, identity string) *hermes.HermesWorker {
+	t.Helper()
+	worker := hermes.NewWorker(memoryEndpoint, "echo", identity, echoHandler{})
+	worker.SetTransport(transport)
+	worker.SetClock(clock)
+	// The socket reader polls on this interval in real time while disconnected
+	worker.SetReconnectInterval(100 * time.Millisecond)
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	t.Cleanup(func() { worker.Stop() })
+	return worker
+}
+
+// settle gives the peers' goroutines up to a second to act on what happened, reporting
+// whether cond came true. Only the manual clock moves Hermes time, so no timer fires meanwhile
+func settle(cond func() bool) bool {
+	return settleFor(cond, 1000)
+}
+
+func settleFor(cond func() bool, rounds int) bool {
+	for i := 0; i < rounds; i++ {
+		if cond() {
+			return true
+		}
+		runtime.Gosched()
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+// advanceUntil moves clock forward a step at a time until cond holds, returning how far it went
+func advanceUntil(t *testing.T, clock *hermes.ManualClock, step, limit time.Duration, cond func() bool) time.Duration {
+	t.Helper()
+	for elapsed := time.Duration(0); elapsed <= limit; elapsed += step {
+		if settleFor(cond, 20) {
+			return elapsed
+		}
+		clock.Advance(step)
+	}
+	t.Fatalf("Condition not met within %v of clock time", limit)
+	return 0
+}
+
+func hasWorker(broker *hermes.Broker, identity string) func() bool {
+	return func() bool {
+		_, exists := broker.GetWorkers()[identity]
+		return exists
+	}
+}
+
+func TestMemoryTransport_Conversation(t *testing.T) {
+	transport := hermes.NewMemoryTransport()
+	clock := hermes.NewManualClock(time.Unix(0, 0))
+	broker := startMemoryBroker(t, transport, clock)
+	startMemoryWorker(t, transport, clock, "memory-worker")
+	if !settle(hasWorker(broker, "memory-worker")) {
+		t.Fatal("Worker did not register")
+	}
+
+	client := hermes.NewClient(memoryEndpoint, "memory-client")
+	client.SetTransport(transport)
+	client.SetClock(clock)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Stop()
+
+	if got := echoRequest(t, client, "hello"); got != "HELLO" {
+		t.Errorf("Expected HELLO, got %q", got)
+	}
+	if info, err := client.Hello(time.Second); err != nil || info.Version != hermes.HERMES_PROTOCOL_VERSION {
+		t.Errorf("Expected the broker's hello, got %+v (%v)", info, err)
+	}
+}
+
+func TestMemoryTransport_DialWithoutBroker(t *testing.T) {
+	transport := hermes.NewMemoryTransport()
+	if _, err := transport.Dial(context.Background(), memoryEndpoint, "early-worker", "", 0); err == nil {
+		t.Error("Expected dialing an unbound endpoint to fail")
+	}
+	if transport.Reachable(memoryEndpoint, time.Second) {
+		t.Error("Expected an unbound endpoint to be unreachable")
+	}
+}
+
+func TestMemoryTransport_HeartbeatExpiry(t *testing.T) {
+	transport := hermes.NewMemoryTransport()
+	clock := hermes.NewManualClock(time.Unix(0, 0))
+	broker := startMemoryBroker(t, transport, clock)
+
+	// A worker that registers and then falls silent
+	socket, err := transport.Dial(context.Background(), memoryEndpoint, "silent-worker", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to dial broker: %v", err)
+	}
+	defer socket.Close()
+	socket.Send(zmq4.NewMsgFrom([]byte(""), []byte(hermes.HERMES_WORKER), []byte(hermes.HERMES_READY), []byte("echo")))
+	if !settle(hasWorker(broker, "silent-worker")) {
+		t.Fatal("Worker did not register")
+	}
+
+	// Ten heartbeat intervals of 7.5s and a 30s grace period pass before it is dropped
+	clock.BlockUntil(2)
+	gone := func() bool { return !hasWorker(broker, "silent-worker")() }
+	elapsed := advanceUntil(t, clock, hermes.GetMDPHeartbeatExpiry(), 5*time.Minute, gone)
+	if elapsed < 105*time.Second {
+		t.Errorf("Expected the worker to outlive its expiry and grace period, dropped after %v", elapsed)
+	}
+}
+
+func TestMemoryTransport_ReRegistration(t *testing.T) {
+	transport := hermes.NewMemoryTransport()
+	clock := hermes.NewManualClock(time.Unix(0, 0))
+	first := hermes.NewBroker(memoryEndpoint)
+	first.SetTransport(transport)
+	first.SetClock(clock)
+	if err := first.Start(); err != nil {
+		t.Fatalf("Failed to start broker: %v", err)
+	}
+	worker := startMemoryWorker(t, transport, clock, "memory-worker")
+	if !settle(hasWorker(first, "memory-worker")) {
+		t.Fatal("Worker did not register")
+	}
+
+	// The broker restarts in place: the worker's next heartbeat reaches a broker that
+	// does not know it, which asks it to register again
+	first.Stop()
+	second := startMemoryBroker(t, transport, clock)
+	advanceUntil(t, clock, time.Second, 5*time.Minute, hasWorker(second, "memory-worker"))
+
+	if stats := worker.GetStats(); stats.Reconnections == 0 {
+		t.Errorf("Expected the worker to have reconnected, got %+v", stats)
+	}
+}
+
+func TestMemoryTransport_ReconnectAfterSilence(t *testing.T) {
+	transport := hermes.NewMemoryTransport()
+	clock := hermes.NewManualClock(time.Unix(0, 0))
+	first := hermes.NewBroker(memoryEndpoint)
+	first.SetTransport(transport)
+	first.SetClock(clock)
+	if err := first.Start(); err != nil {
+		t.Fatalf("Failed to start broker: %v", err)
+	}
+	worker := startMemoryWorker(t, transport, clock, "memory-worker")
+	if !settle(hasWorker(first, "memory-worker")) {
+		t.Fatal("Worker did not register")
+	}
+
+	// With the broker gone the worker runs out of liveness and keeps trying to reconnect
+	first.Stop()
+	advanceUntil(t, clock, time.Second, 5*time.Minute, func() bool {
+		return worker.GetStats().Reconnections > 0
+	})
+	if worker.IsConnected() {
+		t.Error("Expected the worker to be disconnected while no broker listens")
+	}
+
+	// Once a broker listens again the worker registers with it
+	second := startMemoryBroker(t, transport, clock)
+	advanceUntil(t, clock, time.Second, 10*time.Minute, hasWorker(second, "memory-worker"))
+
+	client := hermes.NewClient(memoryEndpoint, "memory-client")
+	client.SetTransport(transport)
+	client.SetClock(clock)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Stop()
+
+	req, _ := hermes.CreateServiceRequest("echo", "echo", "back")
+	body, _ := json.Marshal(req)
+	response, err := client.Request(context.Background(), "echo", body)
+	if err != nil {
+		t.Fatalf("Request after reconnect failed: %v", err)
+	}
+	var resp hermes.ServiceResponse
+	if json.Unmarshal(response, &resp); resp.Data != "BACK" {
+		t.Errorf("Expected BACK, got %s", response)
+	}
+}
+
+func TestMemoryTransport_ClientExpiry(t *testing.T) {
+	transport := hermes.NewMemoryTransport()
+	clock := hermes.NewManualClock(time.Unix(0, 0))
+	broker := startMemoryBroker(t, transport, clock)
+	announced := func(clientID string) func() bool {
+		return func() bool {
+			_, ok := broker.GetClientInfo(clientID)
+			return ok
+		}
+	}
+
+	// A client that says hello and goes away without a word
+	socket, err := transport.Dial(context.Background(), memoryEndpoint, "gone-client", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to dial broker: %v", err)
+	}
+	defer socket.Close()
+	hello, _ := json.Marshal(hermes.PeerInfo{Version: hermes.HERMES_PROTOCOL_VERSION})
+	socket.Send(zmq4.NewMsgFrom([]byte(""), []byte(hermes.HERMES_CLIENT), []byte(hermes.HERMES_HELLO_SERVICE), hello))
+	if !settle(announced("gone-client")) {
+		t.Fatal("Broker did not record the client's hello")
+	}
+
+	// A client that stays connected but sends no requests
+	client := hermes.NewClient(memoryEndpoint, "idle-client")
+	client.SetTransport(transport)
+	client.SetClock(clock)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Stop()
+	if !settle(announced("idle-client")) {
+		t.Fatal("Broker did not record the idle client's hello")
+	}
+
+	gone := func() bool { return !announced("gone-client")() }
+	elapsed := advanceUntil(t, clock, time.Minute, 2*time.Hour, gone)
+	if elapsed < 30*time.Minute {
+		t.Errorf("Expected the client to be remembered for 30 minutes, forgotten after %v", elapsed)
+	}
+	if !announced("idle-client")() {
+		t.Error("Expected the idle client's repeated hello to keep it known")
+	}
+}
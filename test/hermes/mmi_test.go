@@ -0,0 +1,127 @@
+package hermes_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"lucas/internal/hermes"
+)
+
+func TestMMI_RawClient(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	client := dialDealer(t, endpoint, "raw-client")
+
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, "echo")
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, hermes.MMI_OK)
+
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, "missing")
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, hermes.MMI_NOT_FOUND)
+
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "mmi.unknown", "")
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, "mmi.unknown", hermes.MMI_NOT_IMPLEMENTED)
+
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, hermes.MMI_WORKERS, "")
+	reply := recvFrames(t, client)
+	if len(reply) != 4 {
+		t.Fatalf("Expected 4 frames, got %q", reply)
+	}
+	var workers []hermes.WorkerInfo
+	if err := json.Unmarshal(reply[3], &workers); err != nil {
+		t.Fatalf("Failed to parse workers: %v", err)
+	}
+	if len(workers) != 1 || workers[0].Identity != "raw-worker" || workers[0].Service != "echo" {
+		t.Errorf("Unexpected workers: %+v", workers)
+	}
+
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, hermes.MMI_WORKERS, "other")
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, hermes.MMI_WORKERS, "[]")
+
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, hermes.MMI_STATS, "")
+	reply = recvFrames(t, client)
+	if len(reply) != 4 {
+		t.Fatalf("Expected 4 frames, got %q", reply)
+	}
+	var stats hermes.BrokerStats
+	if err := json.Unmarshal(reply[3], &stats); err != nil {
+		t.Fatalf("Failed to parse stats: %v", err)
+	}
+	if stats.Workers != 1 || stats.Services != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestMMI_Authorizer(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetManagementAuthorizer(func(clientID, token string) bool {
+		return strings.HasPrefix(clientID, "hub_1/") && token == "secret"
+	})
+
+	allowed := dialDealer(t, endpoint, "hub_1/cli")
+	sendFrames(t, allowed, "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, `{"argument":"echo","token":"secret"}`)
+	expectFrames(t, recvFrames(t, allowed), "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, hermes.MMI_NOT_FOUND)
+
+	// The client ID alone is not enough
+	sendFrames(t, allowed, "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, "echo")
+	expectFrames(t, recvFrames(t, allowed), "", hermes.HERMES_CLIENT, hermes.MMI_SERVICE, hermes.MMI_UNAUTHORIZED)
+
+	denied := dialDealer(t, endpoint, "stranger")
+	sendFrames(t, denied, "", hermes.HERMES_CLIENT, hermes.MMI_STATS, `{"token":"secret"}`)
+	expectFrames(t, recvFrames(t, denied), "", hermes.HERMES_CLIENT, hermes.MMI_STATS, hermes.MMI_UNAUTHORIZED)
+}
+
+func TestMMI_Client(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(endpoint, "echo", "hermes-worker", echoHandler{})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	broker.SetManagementAuthorizer(func(clientID, token string) bool {
+		return token == "secret"
+	})
+
+	client := hermes.NewClient(endpoint, "hermes-client")
+	client.SetManagementToken("secret")
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Stop()
+
+	reply, err := client.Management(hermes.MMI_SERVICE, []byte("echo"), 5*time.Second)
+	if err != nil || string(reply) != hermes.MMI_OK {
+		t.Errorf("Expected %s, got %q (%v)", hermes.MMI_OK, reply, err)
+	}
+
+	reply, err = client.Management(hermes.MMI_WORKERS, nil, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Workers query failed: %v", err)
+	}
+	var workers []hermes.WorkerInfo
+	if err := json.Unmarshal(reply, &workers); err != nil || len(workers) != 1 {
+		t.Errorf("Unexpected workers reply: %s", reply)
+	}
+
+	// Regular requests still work alongside management requests
+	if got := echoRequest(t, client, "still here"); got != "STILL HERE" {
+		t.Errorf("Expected STILL HERE, got %s", got)
+	}
+
+	if _, err := client.Management("echo", nil, time.Second); err == nil {
+		t.Error("Expected an error for a service outside the management interface")
+	}
+
+	client.SetFraming(hermes.FramingJSON)
+	if _, err := client.Management(hermes.MMI_STATS, nil, time.Second); err == nil {
+		t.Error("Expected an error for management requests in JSON framing")
+	}
+}
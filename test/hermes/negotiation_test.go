@@ -0,0 +1,122 @@
+package hermes_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"lucas/internal/hermes"
+)
+
+func TestNegotiation_ReadyFrames(t *testing.T) {
+	info := &hermes.PeerInfo{Version: 2, Capabilities: []string{"cancel", "nonce"}, Schema: 1}
+	ready := &hermes.WorkerMessage{Protocol: hermes.HERMES_WORKER, Command: hermes.HERMES_READY, Service: "echo", Info: info}
+
+	for _, framing := range []hermes.Framing{hermes.FramingMDP, hermes.FramingJSON} {
+		frames, err := hermes.EncodeWorkerMessage(ready, framing)
+		if err != nil {
+			t.Fatalf("Failed to encode READY in %s framing: %v", framing, err)
+		}
+		msg, _, err := hermes.DecodeWorkerMessage(frames[1:])
+		if err != nil {
+			t.Fatalf("Failed to decode READY in %s framing: %v", framing, err)
+		}
+		if msg.Service != "echo" || !reflect.DeepEqual(msg.Info, info) {
+			t.Errorf("READY in %s framing: expected %+v, got %+v", framing, info, msg.Info)
+		}
+	}
+
+	// The info follows the service in a frame of its own, which plain MDP brokers ignore
+	frames, _ := hermes.EncodeWorkerMessage(ready, hermes.FramingMDP)
+	expectFrames(t, frames[:4], "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	if len(frames) != 5 {
+		t.Errorf("Expected the info in a fifth frame, got %q", frames)
+	}
+}
+
+func TestNegotiation_WorkerInfo(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(endpoint, "echo", "modern-worker", echoHandler{})
+	worker.SetCapabilities(hermes.CapabilityNonce)
+	worker.SetSchema(3)
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+
+	legacy := dialDealer(t, endpoint, "legacy-worker")
+	sendFrames(t, legacy, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 2)
+
+	workers := broker.GetWorkers()
+	modern := workers["modern-worker"]
+	if modern.Version != hermes.HERMES_PROTOCOL_VERSION || modern.Schema != 3 ||
+		!reflect.DeepEqual(modern.Capabilities, []string{hermes.CapabilityCancel, hermes.CapabilityCompression, hermes.CapabilityNonce}) {
+		t.Errorf("Unexpected modern worker: %+v", modern)
+	}
+	if old := workers["legacy-worker"]; old.Version != hermes.HERMES_LEGACY_VERSION || len(old.Capabilities) != 0 {
+		t.Errorf("Expected a legacy worker without capabilities, got %+v", old)
+	}
+}
+
+func TestNegotiation_NoCancelForLegacyWorker(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "legacy-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	client := startClient(t, endpoint, "cancel-client")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+	go client.Request(ctx, "echo", []byte(`{"message_id":"msg_1","service":"echo","action":"echo","payload":"hi"}`))
+	recvFrames(t, worker)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for broker.GetStats().Cancelled != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the request to be cancelled, got %+v", broker.GetStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The next thing the worker hears is the heartbeat reply, not a CANCEL it would not understand
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_HEARTBEAT)
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_HEARTBEAT)
+}
+
+func TestNegotiation_ClientHello(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	client := hermes.NewClient(endpoint, "hello-client")
+	client.SetCapabilities(hermes.CapabilityStreaming)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Stop()
+
+	info, err := client.Hello(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Hello failed: %v", err)
+	}
+	if info.Version != hermes.HERMES_PROTOCOL_VERSION || !info.HasCapability(hermes.CapabilityCancel) {
+		t.Errorf("Unexpected broker info: %+v", info)
+	}
+	if client.BrokerInfo() == nil {
+		t.Error("Expected the client to remember the broker's info")
+	}
+
+	announced, ok := broker.GetClientInfo("hello-client")
+	if !ok || announced.Version != hermes.HERMES_PROTOCOL_VERSION ||
+		!reflect.DeepEqual(announced.Capabilities, []string{hermes.CapabilityCancel, hermes.CapabilityCompression, hermes.CapabilityStreaming}) {
+		t.Errorf("Unexpected client info: %+v (%v)", announced, ok)
+	}
+	if info, ok := broker.GetClientInfo("unknown-client"); ok || info.Version != hermes.HERMES_LEGACY_VERSION {
+		t.Errorf("Expected an unknown client to be legacy, got %+v (%v)", info, ok)
+	}
+}
@@ -0,0 +1,235 @@
+package hermes_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"lucas/internal/hermes"
+)
+
+func gzipBody(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(body)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to compress body: %v", err)
+	}
+	return compressed.Bytes()
+}
+
+func gunzipBody(t *testing.T, body []byte) []byte {
+	t.Helper()
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected a compressed body, got %.40q: %v", body, err)
+	}
+	plain, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress body: %v", err)
+	}
+	return plain
+}
+
+func largeBody(messageID string, size int) string {
+	return fmt.Sprintf(`{"message_id":%q,"service":"echo","action":"echo","payload":%q,"nonce":"nonce_%s"}`,
+		messageID, strings.Repeat("lucas ", size/6), messageID)
+}
+
+// expectRefusal checks reply refuses messageID as too large. Only the start of a refused body
+// is read, so a nonce sent after a large payload is not echoed
+func expectRefusal(t *testing.T, reply []byte, messageID, nonce string) {
+	t.Helper()
+	var resp hermes.ServiceResponse
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("Failed to parse reply: %v", err)
+	}
+	if resp.Success || resp.MessageID != messageID || resp.Nonce != nonce ||
+		!strings.HasSuffix(resp.Error, hermes.ErrMessageTooLarge.Error()) {
+		t.Errorf("Expected %s refused as too large, got %+v", messageID, resp)
+	}
+}
+
+func TestPayload_CompressionNegotiated(t *testing.T) {
+	_, endpoint := startConformanceBroker(t)
+
+	// A worker announcing compression hears that the broker takes it too
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo", `{"version":2,"capabilities":["compression"]}`)
+	announce := recvFrames(t, worker)
+	expectFrames(t, announce[:3], "", hermes.HERMES_WORKER, hermes.HERMES_HEARTBEAT)
+	var info hermes.PeerInfo
+	if len(announce) != 4 || json.Unmarshal(announce[3], &info) != nil || !info.HasCapability(hermes.CapabilityCompression) {
+		t.Fatalf("Expected the broker's info with the heartbeat, got %q", announce)
+	}
+
+	client := startClient(t, endpoint, "large-client")
+	if _, err := client.Hello(5 * time.Second); err != nil {
+		t.Fatalf("Hello failed: %v", err)
+	}
+
+	type result struct {
+		reply []byte
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		reply, err := client.Request(ctx, "echo", []byte(largeBody("msg_large", 12000)))
+		done <- result{reply, err}
+	}()
+
+	// Large bodies cross both hops compressed
+	request := recvFrames(t, worker)
+	if len(request) != 6 {
+		t.Fatalf("Expected a request, got %d frames", len(request))
+	}
+	var req hermes.ServiceRequest
+	if err := json.Unmarshal(gunzipBody(t, request[5]), &req); err != nil || req.MessageID != "msg_large" {
+		t.Fatalf("Unexpected request: %+v (%v)", req, err)
+	}
+
+	data := strings.Repeat("LUCAS ", 2000)
+	reply, _ := hermes.SerializeServiceResponse(hermes.CreateServiceResponse("msg_large", "echo", true, data, nil))
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, string(request[3]), "", string(gzipBody(t, reply)))
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("Request failed: %v", r.err)
+	}
+	var resp hermes.ServiceResponse
+	if err := json.Unmarshal(r.reply, &resp); err != nil || resp.Data != data {
+		t.Errorf("Expected the decompressed reply, got %.80s (%v)", r.reply, err)
+	}
+}
+
+func TestPayload_PlainForPeersNotAnnouncing(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo", `{"version":2,"capabilities":["cancel"]}`)
+	waitForWorkers(t, broker, 1)
+
+	body := largeBody("msg_large", 12000)
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", body)
+
+	// Nothing announced, nothing compressed: the request is the first thing the worker hears
+	request := recvFrames(t, worker)
+	expectFrames(t, request[:3], "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST)
+	if string(request[5]) != body {
+		t.Fatalf("Expected the request as sent, got %.40q", request[5])
+	}
+
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, string(request[3]), "", body)
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, "echo", body)
+}
+
+func TestPayload_BrokerRefusesOversizedRequests(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetPayloadLimits(hermes.PayloadLimits{MaxFrameSize: 1024, MaxBodySize: 4096})
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", largeBody("msg_frame", 2000))
+	reply := recvFrames(t, client)
+	expectFrames(t, reply[:3], "", hermes.HERMES_CLIENT, "echo")
+	expectRefusal(t, reply[3], "msg_frame", "nonce_msg_frame")
+
+	// A small frame that inflates past the body limit is refused just the same
+	bomb := gzipBody(t, []byte(largeBody("msg_bomb", 100000)))
+	if len(bomb) > 1024 {
+		t.Fatalf("Expected the compressed body to fit a frame, got %d bytes", len(bomb))
+	}
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", string(bomb))
+	expectRefusal(t, recvFrames(t, client)[3], "msg_bomb", "")
+
+	if stats := broker.GetStats(); stats.Oversized != 2 || stats.Requests != 0 {
+		t.Errorf("Expected 2 oversized messages and no request routed, got %+v", stats)
+	}
+}
+
+func TestPayload_BrokerRefusesOversizedReply(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+	broker.SetPayloadLimits(hermes.PayloadLimits{MaxFrameSize: 1024})
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_1"))
+	request := recvFrames(t, worker)
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, string(request[3]), "", largeBody("msg_1", 2000))
+	expectRefusal(t, recvFrames(t, client)[3], "msg_1", "nonce_msg_1")
+
+	// The refused reply still freed the worker for the next request
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", echoBody("msg_2"))
+	expectFrames(t, recvFrames(t, worker)[:3], "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST)
+}
+
+func TestPayload_WorkerRefusesOversizedRequest(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(endpoint, "echo", "small-worker", echoHandler{})
+	worker.SetPayloadLimits(hermes.PayloadLimits{MaxBodySize: 1024})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	client := startClient(t, endpoint, "large-client")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.Request(ctx, "echo", []byte(largeBody("msg_large", 12000)))
+
+	var serviceErr *hermes.ServiceError
+	if !errors.As(err, &serviceErr) || !strings.HasSuffix(serviceErr.Message, hermes.ErrMessageTooLarge.Error()) {
+		t.Errorf("Expected the worker to refuse the request, got %v", err)
+	}
+}
+
+func TestPayload_ClientLimits(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo")
+	waitForWorkers(t, broker, 1)
+
+	client := startClient(t, endpoint, "small-client")
+	client.SetPayloadLimits(hermes.PayloadLimits{MaxBodySize: 1024})
+
+	// Too large to send: failed at once rather than retried
+	start := time.Now()
+	_, err := client.Request(context.Background(), "echo", []byte(largeBody("msg_large", 2000)))
+	if !errors.Is(err, hermes.ErrMessageTooLarge) || time.Since(start) > time.Second {
+		t.Errorf("Expected the request to fail at once as too large, got %v after %v", err, time.Since(start))
+	}
+
+	// Too large to take: the request waiting for it fails
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := client.Request(ctx, "echo", []byte(echoBody("msg_1")))
+		done <- err
+	}()
+	request := recvFrames(t, worker)
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, string(request[3]), "", largeBody("msg_1", 2000))
+	if err := <-done; !errors.Is(err, hermes.ErrMessageTooLarge) {
+		t.Errorf("Expected the reply to be refused as too large, got %v", err)
+	}
+}
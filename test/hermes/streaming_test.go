@@ -0,0 +1,198 @@
+package hermes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"lucas/internal/hermes"
+)
+
+// spellingHandler reports each letter of the payload as progress, then replies with the word
+type spellingHandler struct {
+	echoHandler
+}
+
+func (h spellingHandler) HandleContext(ctx context.Context, request []byte) ([]byte, error) {
+	return h.Handle(request)
+}
+
+func (h spellingHandler) HandleStream(ctx context.Context, request []byte, progress func(data interface{}) error) ([]byte, error) {
+	req, err := hermes.DeserializeServiceRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	var text string
+	json.Unmarshal(req.Payload, &text)
+	for _, letter := range text {
+		if err := progress(strings.ToUpper(string(letter))); err != nil {
+			return nil, err
+		}
+	}
+	return h.Handle(request)
+}
+
+func streamRequestBody(t *testing.T, text string) []byte {
+	t.Helper()
+	req, err := hermes.CreateServiceRequest("echo", "echo", text)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	body, _ := json.Marshal(req)
+	return body
+}
+
+// collectStream reads a stream until it closes
+func collectStream(t *testing.T, replies <-chan hermes.StreamReply) []hermes.StreamReply {
+	t.Helper()
+	var collected []hermes.StreamReply
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				return collected
+			}
+			collected = append(collected, reply)
+		case <-timeout:
+			t.Fatalf("Stream did not end, got %d replies", len(collected))
+		}
+	}
+}
+
+func TestStreaming_PartialReplies(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(endpoint, "echo", "streaming-worker", spellingHandler{})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	info := broker.GetWorkers()["streaming-worker"]
+	if !(&hermes.PeerInfo{Capabilities: info.Capabilities}).HasCapability(hermes.CapabilityStreaming) {
+		t.Errorf("Expected the worker to announce streaming, got %v", info.Capabilities)
+	}
+
+	client := startClient(t, endpoint, "stream-client")
+	replies, err := client.RequestStream(context.Background(), "echo", streamRequestBody(t, "abc"))
+	if err != nil {
+		t.Fatalf("RequestStream failed: %v", err)
+	}
+
+	collected := collectStream(t, replies)
+	if len(collected) != 4 {
+		t.Fatalf("Expected 3 partial replies and the final one, got %d", len(collected))
+	}
+	for i, reply := range collected {
+		if reply.Err != nil {
+			t.Fatalf("Reply %d failed: %v", i, reply.Err)
+		}
+		var resp hermes.ServiceResponse
+		if err := json.Unmarshal(reply.Body, &resp); err != nil {
+			t.Fatalf("Failed to parse reply %d: %v", i, err)
+		}
+		if i < 3 {
+			if !reply.Partial || !resp.Partial || resp.Sequence != i+1 || resp.Data != string("ABC"[i]) {
+				t.Errorf("Unexpected partial reply %d: %+v", i, resp)
+			}
+		} else if reply.Partial || resp.Partial || resp.Data != "ABC" {
+			t.Errorf("Unexpected final reply: %+v", resp)
+		}
+	}
+
+	if stats := broker.GetStats(); stats.Partials != 3 {
+		t.Errorf("Expected 3 partial replies forwarded, got %d", stats.Partials)
+	}
+}
+
+func TestStreaming_SlowReaderEndsStream(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(endpoint, "echo", "streaming-worker", spellingHandler{})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	client := startClient(t, endpoint, "stream-client")
+	replies, err := client.RequestStream(context.Background(), "echo", streamRequestBody(t, strings.Repeat("a", 400)))
+	if err != nil {
+		t.Fatalf("RequestStream failed: %v", err)
+	}
+
+	// Nothing is read until far more partial replies were sent than the stream buffers
+	deadline := time.Now().Add(10 * time.Second)
+	for broker.GetStats().Partials < 300 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the worker to send partial replies, got %d", broker.GetStats().Partials)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	collected := collectStream(t, replies)
+	last := collected[len(collected)-1]
+	if !errors.Is(last.Err, hermes.ErrPartialRepliesLost) {
+		t.Fatalf("Expected the stream to end with ErrPartialRepliesLost, got %+v", last)
+	}
+	if len(collected) > 401 {
+		t.Errorf("Expected the stream to end early, got %d replies", len(collected))
+	}
+}
+
+func TestStreaming_WorkerWithoutStreaming(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := hermes.NewWorker(endpoint, "echo", "plain-worker", echoHandler{})
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Failed to start worker: %v", err)
+	}
+	defer worker.Stop()
+	waitForWorkers(t, broker, 1)
+
+	client := startClient(t, endpoint, "stream-client")
+	replies, err := client.RequestStream(context.Background(), "echo", streamRequestBody(t, "abc"))
+	if err != nil {
+		t.Fatalf("RequestStream failed: %v", err)
+	}
+
+	collected := collectStream(t, replies)
+	if len(collected) != 1 || collected[0].Partial || collected[0].Err != nil {
+		t.Fatalf("Expected only the final reply, got %+v", collected)
+	}
+}
+
+func TestStreaming_BrokerKeepsWorkerBusy(t *testing.T) {
+	broker, endpoint := startConformanceBroker(t)
+
+	worker := dialDealer(t, endpoint, "raw-worker")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_READY, "echo", `{"version":2,"capabilities":["cancel","streaming"]}`)
+	waitForWorkers(t, broker, 1)
+
+	client := dialDealer(t, endpoint, "raw-client")
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", "one")
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "raw-client", "", "one")
+
+	// Partial replies reach the client in order while the worker keeps the request
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_PARTIAL, "raw-client", "", "o")
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_PARTIAL, "raw-client", "", "on")
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, "echo", "o")
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, "echo", "on")
+
+	sendFrames(t, client, "", hermes.HERMES_CLIENT, "echo", "two")
+	time.Sleep(100 * time.Millisecond)
+	if stats := broker.GetStats(); stats.Queued != 1 {
+		t.Errorf("Expected the second request to wait for the worker, got %+v", stats)
+	}
+
+	// Only the final reply frees the worker
+	sendFrames(t, worker, "", hermes.HERMES_WORKER, hermes.HERMES_REPLY, "raw-client", "", "ONE")
+	expectFrames(t, recvFrames(t, client), "", hermes.HERMES_CLIENT, "echo", "ONE")
+	expectFrames(t, recvFrames(t, worker), "", hermes.HERMES_WORKER, hermes.HERMES_REQUEST, "raw-client", "", "two")
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package homeassistant_test
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/device"
+	"lucas/internal/homeassistant"
+	"lucas/internal/hub"
+	"lucas/internal/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "long-lived-token"
+
+// serviceCall records a service call received by the fake server
+type serviceCall struct {
+	Domain  string
+	Service string
+	Data    map[string]interface{}
+}
+
+// fakeHomeAssistant serves the REST and WebSocket APIs used by Lucas
+type fakeHomeAssistant struct {
+	server     *httptest.Server
+	mutex      sync.Mutex
+	states     map[string]homeassistant.EntityState
+	calls      chan serviceCall
+	subscribed chan *websocket.Conn
+}
+
+func newFakeHomeAssistant(t *testing.T) *fakeHomeAssistant {
+	ha := &fakeHomeAssistant{
+		states: map[string]homeassistant.EntityState{
+			"light.kitchen": {
+				EntityID:   "light.kitchen",
+				State:      "off",
+				Attributes: map[string]interface{}{"friendly_name": "Kitchen Light"},
+			},
+			"media_player.lounge": {
+				EntityID:   "media_player.lounge",
+				State:      "playing",
+				Attributes: map[string]interface{}{"volume_level": 0.2, "is_volume_muted": false},
+			},
+		},
+		calls:      make(chan serviceCall, 10),
+		subscribed: make(chan *websocket.Conn, 1),
+	}
+	ha.server = httptest.NewServer(http.HandlerFunc(ha.serve))
+	t.Cleanup(ha.server.Close)
+	return ha
+}
+
+func (ha *fakeHomeAssistant) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == homeassistant.WebSocketPath {
+		ha.serveWebSocket(w, r)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ha.mutex.Lock()
+	defer ha.mutex.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == homeassistant.StatesPath:
+		states := make([]homeassistant.EntityState, 0, len(ha.states))
+		for _, state := range ha.states {
+			states = append(states, state)
+		}
+		json.NewEncoder(w).Encode(states)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, homeassistant.StatesPath+"/"):
+		state, exists := ha.states[strings.TrimPrefix(r.URL.Path, homeassistant.StatesPath+"/")]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(state)
+
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, homeassistant.ServicesPath+"/"):
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, homeassistant.ServicesPath+"/"), "/")
+		var data map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+		ha.calls <- serviceCall{Domain: parts[0], Service: parts[1], Data: data}
+
+		entityID, _ := data["entity_id"].(string)
+		state := ha.states[entityID]
+		switch parts[1] {
+		case "turn_on":
+			state.State = "on"
+		case "turn_off":
+			state.State = "off"
+		}
+		ha.states[entityID] = state
+		json.NewEncoder(w).Encode([]homeassistant.EntityState{state})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (ha *fakeHomeAssistant) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]interface{}{"type": "auth_required"})
+	var auth map[string]interface{}
+	if err := conn.ReadJSON(&auth); err != nil {
+		return
+	}
+	if auth["access_token"] != testToken {
+		conn.WriteJSON(map[string]interface{}{"type": "auth_invalid"})
+		return
+	}
+	conn.WriteJSON(map[string]interface{}{"type": "auth_ok"})
+
+	var subscribe map[string]interface{}
+	if err := conn.ReadJSON(&subscribe); err != nil || subscribe["event_type"] != "state_changed" {
+		return
+	}
+	conn.WriteJSON(map[string]interface{}{"id": subscribe["id"], "type": "result", "success": true})
+	ha.subscribed <- conn
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func process(t *testing.T, d device.Device, actionType device.ActionType, action string, params map[string]interface{}) *device.ActionResponse {
+	request, err := json.Marshal(device.ActionRequest{Type: actionType, Action: action, Parameters: params})
+	require.NoError(t, err)
+
+	response, err := d.Process(request)
+	require.NoError(t, err)
+	return response
+}
+
+func TestHomeAssistantImport(t *testing.T) {
+	ha := newFakeHomeAssistant(t)
+
+	devices, err := hub.ImportHomeAssistantEntities(ha.server.URL, testToken, []string{"light.kitchen"})
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "light_kitchen", devices[0].ID)
+	assert.Equal(t, "homeassistant", devices[0].Type)
+	assert.Equal(t, "Kitchen Light", devices[0].Model)
+	assert.Equal(t, testToken, devices[0].Credential)
+	assert.Equal(t, "light.kitchen", devices[0].Options["entity_id"])
+
+	config := &hub.Config{Devices: []hub.DeviceConfig{{ID: "light_kitchen"}}}
+	added, skipped := config.AddDevices(devices)
+	assert.Empty(t, added)
+	assert.Equal(t, []string{"light_kitchen"}, skipped)
+
+	_, err = hub.ImportHomeAssistantEntities(ha.server.URL, testToken, []string{"light.missing"})
+	assert.Error(t, err)
+
+	_, err = hub.ImportHomeAssistantEntities(ha.server.URL, "wrong-token", []string{"light.kitchen"})
+	assert.ErrorIs(t, err, homeassistant.ErrAuthInvalid)
+}
+
+func TestHomeAssistantEntityActions(t *testing.T) {
+	ha := newFakeHomeAssistant(t)
+	client := homeassistant.NewHAClient(ha.server.URL, testToken, internal.FnModeOptions{})
+	defer client.Close()
+
+	light, err := homeassistant.NewHAEntity(client, ha.server.URL, "light.kitchen")
+	require.NoError(t, err)
+
+	t.Run("power_on calls light.turn_on", func(t *testing.T) {
+		response := process(t, light, device.ActionTypeRemote, "power_on", nil)
+		require.True(t, response.Success, response.Error)
+
+		call := <-ha.calls
+		assert.Equal(t, "light", call.Domain)
+		assert.Equal(t, "turn_on", call.Service)
+		assert.Equal(t, "light.kitchen", call.Data["entity_id"])
+	})
+
+	t.Run("power_status reflects service result", func(t *testing.T) {
+		response := process(t, light, device.ActionTypeControl, "power_status", nil)
+		require.True(t, response.Success)
+		assert.Equal(t, device.PowerStatusData(device.PowerStatusActive), response.Data)
+	})
+
+	t.Run("call_service is restricted to the entity domain", func(t *testing.T) {
+		response := process(t, light, device.ActionTypeControl, "call_service", map[string]interface{}{
+			"service": "lock.unlock",
+		})
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "outside the light domain")
+
+		response = process(t, light, device.ActionTypeControl, "call_service", map[string]interface{}{
+			"service": "turn_on",
+			"data":    map[string]interface{}{"brightness": 128, "entity_id": "light.other"},
+		})
+		require.True(t, response.Success, response.Error)
+		call := <-ha.calls
+		assert.Equal(t, float64(128), call.Data["brightness"])
+		assert.Equal(t, "light.kitchen", call.Data["entity_id"])
+	})
+
+	t.Run("media player volume", func(t *testing.T) {
+		player, err := homeassistant.NewHAEntity(client, ha.server.URL, "media_player.lounge")
+		require.NoError(t, err)
+
+		response := process(t, player, device.ActionTypeControl, "set_volume", map[string]interface{}{"volume": 40})
+		require.True(t, response.Success, response.Error)
+		call := <-ha.calls
+		assert.Equal(t, "volume_set", call.Service)
+		assert.InDelta(t, 0.4, call.Data["volume_level"], 0.001)
+
+		response = process(t, light, device.ActionTypeControl, "set_volume", map[string]interface{}{"volume": 40})
+		assert.False(t, response.Success)
+	})
+
+	t.Run("invalid entity id", func(t *testing.T) {
+		_, err := homeassistant.NewHAEntity(client, ha.server.URL, "kitchen")
+		assert.Error(t, err)
+	})
+}
+
+func TestHomeAssistantStateEvents(t *testing.T) {
+	ha := newFakeHomeAssistant(t)
+	client := homeassistant.NewHAClient(ha.server.URL, testToken, internal.FnModeOptions{})
+	defer client.Close()
+
+	light, err := homeassistant.NewHAEntity(client, ha.server.URL, "light.kitchen")
+	require.NoError(t, err)
+	client.Start()
+
+	var conn *websocket.Conn
+	select {
+	case conn = <-ha.subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not subscribe to state_changed")
+	}
+
+	// The resync after subscribing marks the entity online
+	assert.Eventually(t, func() bool { return light.GetDeviceInfo().Status == "online" }, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"id":   1,
+		"type": "event",
+		"event": map[string]interface{}{
+			"event_type": "state_changed",
+			"data": map[string]interface{}{
+				"entity_id": "light.kitchen",
+				"new_state": map[string]interface{}{"entity_id": "light.kitchen", "state": "unavailable"},
+			},
+		},
+	}))
+
+	assert.Eventually(t, func() bool { return light.GetDeviceInfo().Status == "offline" }, 2*time.Second, 10*time.Millisecond)
+
+	response := process(t, light, device.ActionTypeControl, "power_status", nil)
+	assert.Equal(t, device.PowerStatusData(device.PowerStatusStandby), response.Data)
+}
+
+func TestHomeAssistantTestMode(t *testing.T) {
+	client := homeassistant.NewHAClient("http://192.0.2.1:8123", "", internal.FnModeOptions{Test: true})
+	defer client.Close()
+
+	fan, err := homeassistant.NewHAEntity(client, "http://192.0.2.1:8123", "fan.bedroom")
+	require.NoError(t, err)
+
+	response := process(t, fan, device.ActionTypeRemote, "power", nil)
+	require.True(t, response.Success, response.Error)
+
+	response = process(t, fan, device.ActionTypeControl, "power_status", nil)
+	assert.Equal(t, device.PowerStatusData(device.PowerStatusActive), response.Data)
+	assert.Equal(t, "online", fan.GetDeviceInfo().Status)
+}
@@ -0,0 +1,71 @@
+package hub_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"lucas/internal/hub"
+)
+
+func TestGatewayFailoverConfig(t *testing.T) {
+	config := hub.NewDefaultConfig()
+	config.AddFailoverGateway("tcp://backup-1:5555", "key_1")
+	config.AddFailoverGateway("tcp://backup-2:5555", "key_2")
+
+	// Re-adding a gateway updates its key in place
+	config.AddFailoverGateway("tcp://backup-1:5555", "key_1b")
+	config.AddFailoverGateway(config.Gateway.Endpoint, "primary_key")
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected a valid config: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "hub.yml")
+	if err := hub.SaveConfig(config, path); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	loaded, err := hub.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	want := []hub.GatewayEndpoint{
+		{Endpoint: "tcp://gateway.example.com:5555", PublicKey: "primary_key"},
+		{Endpoint: "tcp://backup-1:5555", PublicKey: "key_1b"},
+		{Endpoint: "tcp://backup-2:5555", PublicKey: "key_2"},
+	}
+	endpoints := loaded.Gateway.Endpoints()
+	if len(endpoints) != len(want) {
+		t.Fatalf("Expected %d gateways, got %+v", len(want), endpoints)
+	}
+	for i := range want {
+		if endpoints[i] != want[i] {
+			t.Errorf("Gateway %d: expected %+v, got %+v", i, want[i], endpoints[i])
+		}
+	}
+}
+
+func TestGatewayFailoverValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		failover []hub.GatewayEndpoint
+	}{
+		{"missing endpoint", []hub.GatewayEndpoint{{PublicKey: "key"}}},
+		{"missing key", []hub.GatewayEndpoint{{Endpoint: "tcp://backup:5555"}}},
+		{"duplicate of primary", []hub.GatewayEndpoint{{Endpoint: "tcp://gateway.example.com:5555", PublicKey: "key"}}},
+		{"duplicate failover", []hub.GatewayEndpoint{
+			{Endpoint: "tcp://backup:5555", PublicKey: "key"},
+			{Endpoint: "tcp://backup:5555", PublicKey: "key"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := hub.NewDefaultConfig()
+			config.Gateway.Failover = tt.failover
+			if err := config.Validate(); err == nil {
+				t.Error("Expected a validation error")
+			}
+		})
+	}
+}
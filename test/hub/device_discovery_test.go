@@ -0,0 +1,121 @@
+package hub_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"lucas/internal/cast"
+	"lucas/internal/hub"
+	"lucas/internal/ssdp"
+)
+
+// descriptionServer serves a UPnP device description from a loopback address of its own
+func descriptionServer(t *testing.T, host, name, model string) string {
+	listener, err := net.Listen("tcp", host+":0")
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", host, err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0"?><root xmlns="urn:schemas-upnp-org:device-1-0"><device>`+
+			`<friendlyName>%s</friendlyName><manufacturer>Test</manufacturer><modelName>%s</modelName>`+
+			`<UDN>uuid:%s</UDN></device></root>`, name, model, host)
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return server.URL + "/description.xml"
+}
+
+func TestDeviceDiscovery(t *testing.T) {
+	tvLocation := descriptionServer(t, "127.0.0.2", "Living Room TV", "KD-55X85J")
+	castLocation := descriptionServer(t, "127.0.0.3", "Kitchen speaker", "Google Nest Mini")
+
+	group := fmt.Sprintf("239.255.255.250:%d", 30000+time.Now().Nanosecond()%10000)
+	responder, err := ssdp.NewResponder(group,
+		ssdp.Service{ST: hub.SearchTargetBravia, USN: "uuid:tv::" + hub.SearchTargetBravia, Location: tvLocation},
+		ssdp.Service{ST: hub.SearchTargetDIAL, USN: "uuid:tv::" + hub.SearchTargetDIAL, Location: tvLocation},
+		ssdp.Service{ST: hub.SearchTargetDIAL, USN: "uuid:speaker::" + hub.SearchTargetDIAL, Location: castLocation},
+		ssdp.Service{ST: "urn:schemas-upnp-org:device:Printer:1", USN: "uuid:printer", Location: "http://127.0.0.4/"},
+	)
+	if err != nil {
+		t.Skipf("loopback multicast not available: %v", err)
+	}
+	defer responder.Close()
+
+	config := &hub.Config{Devices: []hub.DeviceConfig{{ID: "kitchen", Type: "wol", Address: "127.0.0.3", MAC: "02:00:00:00:00:01"}}}
+	dm := hub.NewDeviceManager(config)
+	if err := dm.Initialize(false, true); err != nil {
+		t.Fatalf("Failed to initialize devices: %v", err)
+	}
+	defer dm.Shutdown()
+	dm.Discovery().SetSearchOptions(ssdp.SearchOptions{Address: responder.Address(), LocalAddress: "127.0.0.1:0", Timeout: 300 * time.Millisecond})
+
+	discovered, err := dm.DiscoverDevices()
+	if err != nil {
+		t.Fatalf("Discovery failed: %v", err)
+	}
+	if len(discovered) != 2 {
+		t.Fatalf("Expected the TV and the speaker, got %+v", discovered)
+	}
+
+	tv, speaker := discovered[0], discovered[1]
+	if tv.Type != "bravia" || tv.ID != "tv" || tv.Name != "Living Room TV" || tv.Model != "KD-55X85J" || tv.Address != "127.0.0.2" {
+		t.Errorf("Expected the TV typed by its most specific service, got %+v", tv)
+	}
+	if tv.Configured {
+		t.Error("Expected the TV not to be configured")
+	}
+	if speaker.Type != "cast" || !speaker.Configured || speaker.DeviceID != "kitchen" {
+		t.Errorf("Expected the speaker to match the configured device at its address, got %+v", speaker)
+	}
+
+	t.Run("adopting a discovered device", func(t *testing.T) {
+		adopted, err := dm.AdoptDevice("tv", "", "0000")
+		if err != nil {
+			t.Fatalf("Adopt failed: %v", err)
+		}
+		if adopted.ID != "bravia_living_room_tv" || adopted.Address != "127.0.0.2" || adopted.Model != "KD-55X85J" {
+			t.Errorf("Unexpected adopted configuration: %+v", adopted)
+		}
+		if len(adopted.Capabilities) == 0 {
+			t.Error("Expected capabilities to be filled in from the device")
+		}
+		if _, err := dm.GetDevice("bravia_living_room_tv"); err != nil {
+			t.Errorf("Expected the adopted device to be running: %v", err)
+		}
+		if len(config.Devices) != 2 {
+			t.Errorf("Expected the adopted device in the configuration, got %d devices", len(config.Devices))
+		}
+
+		if _, err := dm.AdoptDevice("tv", "", "0000"); err == nil {
+			t.Error("Expected adopting the same device twice to fail")
+		}
+		if _, err := dm.AdoptDevice("speaker", "", ""); err == nil {
+			t.Error("Expected adopting a configured device to fail")
+		}
+		if _, err := dm.AdoptDevice("missing", "", ""); err == nil {
+			t.Error("Expected adopting an unknown device to fail")
+		}
+	})
+}
+
+func TestDiscoveredDevice_Config(t *testing.T) {
+	found := hub.DiscoveredDevice{Type: "cast", Name: "Kitchen speaker", Address: "192.0.2.5", Location: "http://192.0.2.5:8443/ssdp/device-desc.xml", MAC: "aa:bb:cc:dd:ee:ff"}
+	config := found.Config("", "")
+	if config.ID != "cast_kitchen_speaker" || config.MAC != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("Unexpected configuration: %+v", config)
+	}
+	if config.Options[cast.OptionDIALPort] != "8443" {
+		t.Errorf("Expected the DIAL port from the location, got %v", config.Options)
+	}
+
+	found.Location = fmt.Sprintf("http://192.0.2.5:%d/ssdp/device-desc.xml", cast.DefaultDIALPort)
+	if config := found.Config("speaker", ""); config.ID != "speaker" || config.Options != nil {
+		t.Errorf("Expected no options for the default DIAL port, got %+v", config)
+	}
+}
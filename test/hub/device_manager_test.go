@@ -0,0 +1,74 @@
+package hub_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lucas/internal/bravia"
+	"lucas/internal/hub"
+)
+
+func TestReloadWhileRenewingCookie(t *testing.T) {
+	// The TV refuses the notification connections with an expired cookie and holds
+	// the re-registration until released
+	registering := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sony/accessControl" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		select {
+		case registering <- struct{}{}:
+		default:
+		}
+		<-release
+		http.SetCookie(w, &http.Cookie{Name: "auth", Value: "renewed"})
+		w.Write([]byte(`{"result": [], "id": 8}`))
+	}))
+	defer server.Close()
+
+	config := &hub.Config{Devices: []hub.DeviceConfig{{
+		ID:         "tv",
+		Type:       "bravia",
+		Address:    strings.TrimPrefix(server.URL, "http://"),
+		Credential: bravia.CookieCredential(bravia.NewClientID(), "expired"),
+	}}}
+	path := filepath.Join(t.TempDir(), "hub.yml")
+	if err := hub.SaveConfig(config, path); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	dm := hub.NewDeviceManager(config)
+	dm.SetConfigPath(path)
+	if err := dm.Initialize(false, false); err != nil {
+		t.Fatalf("Failed to initialize device manager: %v", err)
+	}
+	defer dm.Shutdown()
+
+	select {
+	case <-registering:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the device to re-register after its cookie was refused")
+	}
+
+	// The reload waits for the notification goroutine, which saves the renewed cookie
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- dm.Reload(&hub.Config{}, false, false)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatalf("Failed to reload device manager: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload deadlocked with the cookie renewal")
+	}
+}
@@ -1,6 +1,7 @@
 package hub_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -323,3 +324,23 @@ func TestNonceCachePerformCleanup(t *testing.T) {
 		t.Error("Expected expired nonces to be cleaned up")
 	}
 }
+
+func TestNonceDeduplicationSurvivesReload(t *testing.T) {
+	dm := hub.NewDeviceManager(&hub.Config{})
+	if err := dm.Initialize(false, true); err != nil {
+		t.Fatalf("Failed to initialize device manager: %v", err)
+	}
+	defer dm.Shutdown()
+
+	nonce := hub.GenerateNonce()
+	first, _ := dm.ProcessDeviceActionWithNonce(context.Background(), "tv", nonce, []byte(`{"type":"power"}`))
+
+	if err := dm.Reload(&hub.Config{}, false, true); err != nil {
+		t.Fatalf("Failed to reload device manager: %v", err)
+	}
+
+	second, _ := dm.ProcessDeviceActionWithNonce(context.Background(), "tv", nonce, []byte(`{"type":"power"}`))
+	if second != first {
+		t.Error("Expected a nonce seen before the reload to return the cached response")
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samsung_test
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/device"
+	"lucas/internal/samsung"
+	"lucas/internal/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore records settings saved by a device
+type memoryStore struct {
+	mutex      sync.Mutex
+	credential string
+	options    map[string]string
+}
+
+func (s *memoryStore) SetCredential(credential string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.credential = credential
+	return nil
+}
+
+func (s *memoryStore) SetOption(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.options == nil {
+		s.options = make(map[string]string)
+	}
+	s.options[key] = value
+	return nil
+}
+
+func (s *memoryStore) Credential() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.credential
+}
+
+// fakeTV accepts remote channel connections like a Tizen TV
+type fakeTV struct {
+	server   *httptest.Server
+	commands chan samsung.SamsungCommand
+	tokens   chan string
+	reject   bool
+}
+
+func newFakeTV(t *testing.T) *fakeTV {
+	tv := &fakeTV{
+		commands: make(chan samsung.SamsungCommand, 16),
+		tokens:   make(chan string, 4),
+	}
+	tv.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, samsung.RemoteChannelPath, r.URL.Path)
+		tv.tokens <- r.URL.Query().Get("token")
+
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if tv.reject {
+			conn.WriteJSON(map[string]interface{}{"event": samsung.EventUnauthorized})
+			return
+		}
+		conn.WriteJSON(map[string]interface{}{
+			"event": samsung.EventConnect,
+			"data":  map[string]interface{}{"token": "issued-token"},
+		})
+
+		for {
+			var command samsung.SamsungCommand
+			if err := conn.ReadJSON(&command); err != nil {
+				return
+			}
+			tv.commands <- command
+			if command.Method == samsung.MethodChannelEmit {
+				conn.WriteJSON(map[string]interface{}{
+					"event": samsung.EventInstalledApp,
+					"data":  map[string]interface{}{"data": []interface{}{map[string]interface{}{"name": "YouTube"}}},
+				})
+			}
+		}
+	}))
+	t.Cleanup(tv.server.Close)
+	return tv
+}
+
+func (tv *fakeTV) address() string {
+	return strings.TrimPrefix(tv.server.URL, "https://")
+}
+
+func TestSamsungRemote_PairingAndKeys(t *testing.T) {
+	tv := newFakeTV(t)
+	store := &memoryStore{}
+
+	remote := samsung.NewSamsungRemote(tv.address(), "", "", store, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "volume_up"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+
+	select {
+	case command := <-tv.commands:
+		assert.Equal(t, samsung.MethodRemoteControl, command.Method)
+		assert.Equal(t, "KEY_VOLUP", command.Params["DataOfCmd"])
+		assert.Equal(t, "Click", command.Params["Cmd"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("TV did not receive key")
+	}
+
+	assert.Equal(t, "", <-tv.tokens)
+	assert.Equal(t, "issued-token", store.Credential())
+	assert.Equal(t, "issued-token", remote.Client().Token())
+
+	// Subsequent keys reuse the paired connection
+	response, err = remote.Process([]byte(`{"type": "remote", "action": "hdmi2"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+	command := <-tv.commands
+	assert.Equal(t, "KEY_HDMI2", command.Params["DataOfCmd"])
+}
+
+func TestSamsungRemote_PowerOnWithWakeOnLAN(t *testing.T) {
+	tv := newFakeTV(t)
+	remote := samsung.NewSamsungRemote(tv.address(), "", "aa:bb:cc:dd:ee:ff", &memoryStore{}, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	// A TV that is awake enough to answer still gets the key after the magic packet
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "power_on"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+	select {
+	case command := <-tv.commands:
+		assert.Equal(t, "KEY_POWERON", command.Params["DataOfCmd"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("TV did not receive the power on key")
+	}
+
+	// A TV still booting from the magic packet does not fail the wake
+	tv.server.Close()
+	offline := samsung.NewSamsungRemote(tv.address(), "", "aa:bb:cc:dd:ee:ff", &memoryStore{}, &internal.FnModeOptions{})
+	response, err = offline.Process([]byte(`{"type": "remote", "action": "power_on"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+}
+
+func TestSamsungRemote_SendsStoredToken(t *testing.T) {
+	tv := newFakeTV(t)
+
+	remote := samsung.NewSamsungRemote(tv.address(), "issued-token", "", nil, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "home"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+	assert.Equal(t, "issued-token", <-tv.tokens)
+}
+
+func TestSamsungRemote_PairingRejected(t *testing.T) {
+	tv := newFakeTV(t)
+	tv.reject = true
+
+	remote := samsung.NewSamsungRemote(tv.address(), "", "", nil, &internal.FnModeOptions{})
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "mute"}`))
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "pairing rejected")
+}
+
+func TestSamsungRemote_AppList(t *testing.T) {
+	tv := newFakeTV(t)
+
+	remote := samsung.NewSamsungRemote(tv.address(), "issued-token", "", nil, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	response, err := remote.Process([]byte(`{"type": "control", "action": "app_list"}`))
+	require.NoError(t, err)
+	require.True(t, response.Success, response.Error)
+
+	data, err := json.Marshal(response.Data)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "YouTube")
+}
+
+func TestSamsungRemote_UnsupportedActions(t *testing.T) {
+	remote := samsung.NewSamsungRemote("192.168.1.101", "", "", nil, &internal.FnModeOptions{Test: true})
+
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "teleport"}`))
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "unsupported remote action")
+
+	response, err = remote.Process([]byte(`{"type": "control", "action": "set_volume", "parameters": {"volume": 10}}`))
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "unsupported control action")
+}
+
+func TestSamsungRemote_TestMode(t *testing.T) {
+	store := &memoryStore{}
+	remote := samsung.NewSamsungRemote("192.168.1.101", "", "", store, &internal.FnModeOptions{Test: true})
+
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "power_off"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success)
+	assert.Equal(t, "test-token", store.Credential())
+
+	response, err = remote.Process([]byte(`{"type": "control", "action": "power_status"}`))
+	require.NoError(t, err)
+	require.True(t, response.Success)
+	assert.Equal(t, device.PowerStatusData(device.PowerStatusStandby), response.Data)
+
+	response, err = remote.Process([]byte(`{"type": "remote", "action": "power_on"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success)
+
+	response, err = remote.Process([]byte(`{"type": "control", "action": "power_status"}`))
+	require.NoError(t, err)
+	assert.Equal(t, device.PowerStatusData(device.PowerStatusActive), response.Data)
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssdp_test
+
+import (
+	"fmt"
+	"lucas/internal/ssdp"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var services = []ssdp.Service{
+	{
+		ST:       "urn:schemas-sony-com:service:ScalarWebAPI:1",
+		USN:      "uuid:bravia-1::urn:schemas-sony-com:service:ScalarWebAPI:1",
+		Location: "http://192.0.2.10:52323/dmr.xml",
+		Server:   "Linux/4.9 UPnP/1.0 Sony-BRAVIA/1.0",
+	},
+	{
+		ST:       "urn:dial-multiscreen-org:service:dial:1",
+		USN:      "uuid:bravia-1::urn:dial-multiscreen-org:service:dial:1",
+		Location: "http://192.0.2.10:8008/ssdp/device-desc.xml",
+	},
+}
+
+// startResponder joins a multicast group on loopback, skipping where that is not possible
+func startResponder(t *testing.T, services ...ssdp.Service) *ssdp.Responder {
+	group := fmt.Sprintf("239.255.255.250:%d", 20000+time.Now().Nanosecond()%10000)
+	responder, err := ssdp.NewResponder(group, services...)
+	if err != nil {
+		t.Skipf("loopback multicast not available: %v", err)
+	}
+	t.Cleanup(responder.Close)
+	return responder
+}
+
+func TestSearch_Multicast(t *testing.T) {
+	responder := startResponder(t, services...)
+	options := ssdp.SearchOptions{
+		Address:      responder.Address(),
+		LocalAddress: "127.0.0.1:0",
+		Timeout:      300 * time.Millisecond,
+	}
+
+	t.Run("ssdp:all finds every service", func(t *testing.T) {
+		responses, err := ssdp.Search(options)
+		require.NoError(t, err)
+		require.Len(t, responses, 2)
+		for _, response := range responses {
+			assert.Equal(t, "bravia-1", response.UUID())
+			assert.Equal(t, "127.0.0.1", response.Address)
+		}
+	})
+
+	t.Run("a target only finds its service", func(t *testing.T) {
+		options.Targets = []string{services[0].ST}
+		responses, err := ssdp.Search(options)
+		require.NoError(t, err)
+		require.Len(t, responses, 1)
+		assert.Equal(t, services[0].Location, responses[0].Location)
+		assert.Equal(t, services[0].Server, responses[0].Server)
+	})
+
+	t.Run("no answer", func(t *testing.T) {
+		options.Targets = []string{"urn:schemas-upnp-org:device:Printer:1"}
+		responses, err := ssdp.Search(options)
+		require.NoError(t, err)
+		assert.Empty(t, responses)
+	})
+}
+
+func TestSearch_Unicast(t *testing.T) {
+	responder, err := ssdp.NewResponder("127.0.0.1:0", services[0])
+	require.NoError(t, err)
+	defer responder.Close()
+
+	responses, err := ssdp.Search(ssdp.SearchOptions{Address: responder.Address(), Timeout: 200 * time.Millisecond})
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, 1, responder.Searches())
+}
+
+func TestDescribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <friendlyName>Living Room TV</friendlyName>
+    <manufacturer>Sony Corporation</manufacturer>
+    <modelName>KD-55X85J</modelName>
+    <UDN>uuid:bravia-1</UDN>
+  </device>
+</root>`))
+	}))
+	defer server.Close()
+
+	description, err := ssdp.Describe(server.Client(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, ssdp.Description{
+		FriendlyName: "Living Room TV",
+		Manufacturer: "Sony Corporation",
+		ModelName:    "KD-55X85J",
+		UDN:          "bravia-1",
+	}, *description)
+
+	_, err = ssdp.Describe(server.Client(), "http://127.0.0.1:1/missing.xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,283 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webos_test
+
+import (
+	"encoding/json"
+	"lucas/internal"
+	"lucas/internal/device"
+	"lucas/internal/webos"
+	"lucas/internal/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore records settings saved by a device
+type memoryStore struct {
+	mutex      sync.Mutex
+	credential string
+}
+
+func (s *memoryStore) SetCredential(credential string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.credential = credential
+	return nil
+}
+
+func (s *memoryStore) SetOption(key, value string) error {
+	return nil
+}
+
+func (s *memoryStore) Credential() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.credential
+}
+
+// fakeTV speaks SSAP like an LG webOS TV
+type fakeTV struct {
+	server    *httptest.Server
+	requests  chan webos.WebOSMessage
+	buttons   chan string
+	clientKey chan string
+}
+
+func newFakeTV(t *testing.T) *fakeTV {
+	tv := &fakeTV{
+		requests:  make(chan webos.WebOSMessage, 16),
+		buttons:   make(chan string, 16),
+		clientKey: make(chan string, 4),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var message webos.WebOSMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+
+			switch message.Type {
+			case webos.MessageRegister:
+				var payload map[string]interface{}
+				json.Unmarshal(message.Payload, &payload)
+				key, _ := payload["client-key"].(string)
+				tv.clientKey <- key
+				if key == "" {
+					conn.WriteJSON(map[string]interface{}{
+						"type":    webos.MessageResponse,
+						"id":      message.ID,
+						"payload": map[string]interface{}{"pairingType": "PROMPT", "returnValue": true},
+					})
+					key = "issued-key"
+				}
+				conn.WriteJSON(map[string]interface{}{
+					"type":    webos.MessageRegistered,
+					"id":      message.ID,
+					"payload": map[string]interface{}{"client-key": key},
+				})
+
+			case webos.MessageRequest:
+				tv.requests <- message
+				payload := map[string]interface{}{"returnValue": true}
+				switch webos.WebOSURI(message.URI) {
+				case webos.GetPointerInput:
+					payload["socketPath"] = "ws://" + r.Host + "/pointer"
+				case webos.GetVolume:
+					payload["volume"] = 12
+				case webos.GetPowerState:
+					payload["state"] = "Active"
+				case webos.ChannelUp:
+					conn.WriteJSON(map[string]interface{}{
+						"type":  webos.MessageError,
+						"id":    message.ID,
+						"error": "401 insufficient permissions",
+					})
+					continue
+				}
+				conn.WriteJSON(map[string]interface{}{
+					"type":    webos.MessageResponse,
+					"id":      message.ID,
+					"payload": payload,
+				})
+			}
+		}
+	})
+	mux.HandleFunc("/pointer", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			tv.buttons <- string(data)
+		}
+	})
+
+	tv.server = httptest.NewServer(mux)
+	t.Cleanup(tv.server.Close)
+	return tv
+}
+
+func (tv *fakeTV) address() string {
+	return strings.TrimPrefix(tv.server.URL, "http://")
+}
+
+func (tv *fakeTV) nextRequest(t *testing.T) webos.WebOSMessage {
+	select {
+	case message := <-tv.requests:
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatal("TV did not receive request")
+		return webos.WebOSMessage{}
+	}
+}
+
+func TestWebOSRemote_PairingAndRequests(t *testing.T) {
+	tv := newFakeTV(t)
+	store := &memoryStore{}
+
+	remote := webos.NewWebOSRemote(tv.address(), "", "", store, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	response, err := remote.Process([]byte(`{"type": "remote", "action": "volume_up"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+
+	assert.Equal(t, "", <-tv.clientKey)
+	assert.Equal(t, string(webos.VolumeUp), tv.nextRequest(t).URI)
+	assert.Equal(t, "issued-key", store.Credential())
+	assert.Equal(t, "issued-key", remote.Client().ClientKey())
+
+	response, err = remote.Process([]byte(`{"type": "remote", "action": "hdmi3"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+	request := tv.nextRequest(t)
+	assert.Equal(t, string(webos.SwitchInput), request.URI)
+	assert.JSONEq(t, `{"inputId": "HDMI_3"}`, string(request.Payload))
+}
+
+func TestWebOSRemote_SendsStoredClientKey(t *testing.T) {
+	tv := newFakeTV(t)
+
+	remote := webos.NewWebOSRemote(tv.address(), "issued-key", "", nil, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	response, err := remote.Process([]byte(`{"type": "control", "action": "volume_info"}`))
+	require.NoError(t, err)
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, "issued-key", <-tv.clientKey)
+	assert.Equal(t, float64(12), response.Data.(map[string]interface{})["volume"])
+}
+
+func TestWebOSRemote_Buttons(t *testing.T) {
+	tv := newFakeTV(t)
+
+	remote := webos.NewWebOSRemote(tv.address(), "issued-key", "", nil, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	for _, action := range []string{"up", "confirm"} {
+		response, err := remote.Process([]byte(`{"type": "remote", "action": "` + action + `"}`))
+		require.NoError(t, err)
+		assert.True(t, response.Success, response.Error)
+	}
+
+	assert.Equal(t, "type:button\nname:UP\n\n", <-tv.buttons)
+	assert.Equal(t, "type:button\nname:ENTER\n\n", <-tv.buttons)
+}
+
+func TestWebOSRemote_ControlActions(t *testing.T) {
+	tv := newFakeTV(t)
+
+	remote := webos.NewWebOSRemote(tv.address(), "issued-key", "", nil, &internal.FnModeOptions{})
+	defer remote.Client().Close()
+
+	t.Run("set_volume sends validated payload", func(t *testing.T) {
+		response, err := remote.Process([]byte(`{"type": "control", "action": "set_volume", "parameters": {"volume": "25"}}`))
+		require.NoError(t, err)
+		assert.True(t, response.Success, response.Error)
+		request := tv.nextRequest(t)
+		assert.Equal(t, string(webos.SetVolume), request.URI)
+		assert.JSONEq(t, `{"volume": 25}`, string(request.Payload))
+	})
+
+	t.Run("set_volume rejects out of range", func(t *testing.T) {
+		response, err := remote.Process([]byte(`{"type": "control", "action": "set_volume", "parameters": {"volume": 150}}`))
+		require.NoError(t, err)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "invalid parameters")
+	})
+
+	t.Run("power_status reports active", func(t *testing.T) {
+		response, err := remote.Process([]byte(`{"type": "control", "action": "power_status"}`))
+		require.NoError(t, err)
+		assert.Equal(t, device.PowerStatusData(device.PowerStatusActive), response.Data)
+	})
+
+	t.Run("error replies are surfaced", func(t *testing.T) {
+		response, err := remote.Process([]byte(`{"type": "remote", "action": "channel_up"}`))
+		require.NoError(t, err)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "insufficient permissions")
+	})
+}
+
+func TestWebOSRemote_TestMode(t *testing.T) {
+	store := &memoryStore{}
+	remote := webos.NewWebOSRemote("192.168.1.102", "", "", store, &internal.FnModeOptions{Test: true})
+
+	response, err := remote.Process([]byte(`{"type": "control", "action": "set_volume", "parameters": {"volume": 30}}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+	assert.Equal(t, "test-client-key", store.Credential())
+
+	response, err = remote.Process([]byte(`{"type": "control", "action": "volume_info"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 30, response.Data.(map[string]interface{})["volume"])
+
+	response, err = remote.Process([]byte(`{"type": "remote", "action": "power_off"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success)
+
+	response, err = remote.Process([]byte(`{"type": "control", "action": "power_status"}`))
+	require.NoError(t, err)
+	assert.Equal(t, device.PowerStatusData(device.PowerStatusStandby), response.Data)
+
+	response, err = remote.Process([]byte(`{"type": "remote", "action": "power_on"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success)
+
+	response, err = remote.Process([]byte(`{"type": "control", "action": "power_status"}`))
+	require.NoError(t, err)
+	assert.Equal(t, device.PowerStatusData(device.PowerStatusActive), response.Data)
+}
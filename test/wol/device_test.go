@@ -0,0 +1,263 @@
+// Copyright 2025 Arion Yau
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wol_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"lucas/internal"
+	"lucas/internal/device"
+	"lucas/internal/wol"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func powerStatus(t *testing.T, response *device.ActionResponse) string {
+	require.True(t, response.Success, response.Error)
+	data, ok := response.Data.(map[string]interface{})
+	require.True(t, ok)
+	result, ok := data["result"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, result, 1)
+	return result[0].(map[string]interface{})["status"].(string)
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Run("applies defaults", func(t *testing.T) {
+		config, err := wol.ParseConfig("00:11:22:33:44:55", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "00:11:22:33:44:55", config.MAC.String())
+		assert.Equal(t, "255.255.255.255:9", config.Broadcast)
+		assert.Equal(t, wol.ProbeTCP, config.Probe)
+		assert.Equal(t, wol.ShutdownNone, config.Shutdown.Method)
+	})
+
+	t.Run("parses options", func(t *testing.T) {
+		config, err := wol.ParseConfig("00-11-22-33-44-55", map[string]string{
+			"broadcast":     "192.168.1.255",
+			"probe":         "icmp",
+			"probe_ports":   "22, 8080",
+			"probe_timeout": "500ms",
+			"shutdown":      "http",
+			"shutdown_url":  "http://host/shutdown",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "192.168.1.255:9", config.Broadcast)
+		assert.Equal(t, wol.ProbeICMP, config.Probe)
+		assert.Equal(t, []int{22, 8080}, config.ProbePorts)
+		assert.Equal(t, 500*time.Millisecond, config.ProbeTimeout)
+		assert.Equal(t, wol.ShutdownHTTP, config.Shutdown.Method)
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		_, err := wol.ParseConfig("", nil)
+		assert.Error(t, err)
+
+		_, err = wol.ParseConfig("not-a-mac", nil)
+		assert.Error(t, err)
+
+		_, err = wol.ParseConfig("00:11:22:33:44:55", map[string]string{"shutdown": "http"})
+		assert.Error(t, err)
+
+		_, err = wol.ParseConfig("00:11:22:33:44:55", map[string]string{"shutdown": "ssh", "ssh_user": "root"})
+		assert.Error(t, err)
+
+		// The host key must be pinned so the credentials only go to the real host
+		_, err = wol.ParseConfig("00:11:22:33:44:55", map[string]string{"shutdown": "ssh", "ssh_user": "root", "ssh_password": "secret"})
+		assert.Error(t, err)
+
+		_, err = wol.ParseConfig("00:11:22:33:44:55", map[string]string{
+			"shutdown": "ssh", "ssh_user": "root", "ssh_password": "secret", "ssh_host_key": "not-a-fingerprint",
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestMagicPacket(t *testing.T) {
+	mac, err := net.ParseMAC("01:23:45:67:89:ab")
+	require.NoError(t, err)
+
+	packet := wol.MagicPacket(mac)
+	require.Len(t, packet, 102)
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, byte(0xFF), packet[i])
+	}
+	for i := 0; i < 16; i++ {
+		assert.Equal(t, []byte(mac), packet[6+i*6:12+i*6])
+	}
+}
+
+func TestWOLDevice_PowerOn(t *testing.T) {
+	listener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	config, err := wol.ParseConfig("01:23:45:67:89:ab", map[string]string{
+		"broadcast": listener.LocalAddr().String(),
+	})
+	require.NoError(t, err)
+
+	dev := wol.NewWOLDevice("127.0.0.1", *config, &internal.FnModeOptions{})
+	response, err := dev.Process([]byte(`{"type": "remote", "action": "power_on"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success, response.Error)
+
+	buf := make([]byte, 256)
+	require.NoError(t, listener.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, wol.MagicPacket(config.MAC), buf[:n])
+}
+
+func TestWOLDevice_PowerStatus(t *testing.T) {
+	t.Run("reports active when a probe port accepts", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		port := listener.Addr().(*net.TCPAddr).Port
+		config, err := wol.ParseConfig("01:23:45:67:89:ab", map[string]string{
+			"probe_ports": strconv.Itoa(port),
+		})
+		require.NoError(t, err)
+
+		dev := wol.NewWOLDevice("127.0.0.1", *config, &internal.FnModeOptions{})
+		response, err := dev.Process([]byte(`{"type": "control", "action": "power_status"}`))
+		require.NoError(t, err)
+		assert.Equal(t, device.PowerStatusActive, powerStatus(t, response))
+	})
+}
+
+func TestWOLDevice_PowerOff(t *testing.T) {
+	t.Run("calls http shutdown hook", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		config, err := wol.ParseConfig("01:23:45:67:89:ab", map[string]string{
+			"shutdown":       "http",
+			"shutdown_url":   server.URL,
+			"shutdown_token": "secret",
+		})
+		require.NoError(t, err)
+
+		dev := wol.NewWOLDevice("127.0.0.1", *config, &internal.FnModeOptions{})
+		assert.Contains(t, dev.GetDeviceInfo().Capabilities, "shutdown")
+
+		response, err := dev.Process([]byte(`{"type": "remote", "action": "power_off"}`))
+		require.NoError(t, err)
+		assert.True(t, response.Success, response.Error)
+		assert.True(t, called)
+	})
+
+	t.Run("refuses an ssh host with another host key", func(t *testing.T) {
+		_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		signer, err := ssh.NewSignerFromKey(hostKey)
+		require.NoError(t, err)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		authenticated := false
+		serverConfig := &ssh.ServerConfig{
+			PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+				authenticated = true
+				return nil, nil
+			},
+		}
+		serverConfig.AddHostKey(signer)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			ssh.NewServerConn(conn, serverConfig)
+		}()
+
+		config, err := wol.ParseConfig("01:23:45:67:89:ab", map[string]string{
+			"shutdown":     "ssh",
+			"ssh_user":     "root",
+			"ssh_password": "secret",
+			"ssh_port":     strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
+			"ssh_host_key": "SHA256:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU",
+		})
+		require.NoError(t, err)
+
+		dev := wol.NewWOLDevice("127.0.0.1", *config, &internal.FnModeOptions{})
+		response, err := dev.Process([]byte(`{"type": "remote", "action": "power_off"}`))
+		require.NoError(t, err)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "does not match")
+		assert.False(t, authenticated, "credentials must not be sent to an unverified host")
+	})
+
+	t.Run("fails without a shutdown hook", func(t *testing.T) {
+		config, err := wol.ParseConfig("01:23:45:67:89:ab", nil)
+		require.NoError(t, err)
+
+		dev := wol.NewWOLDevice("127.0.0.1", *config, &internal.FnModeOptions{})
+		response, err := dev.Process([]byte(`{"type": "remote", "action": "power_off"}`))
+		require.NoError(t, err)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "no shutdown hook")
+	})
+}
+
+func TestWOLDevice_TestMode(t *testing.T) {
+	config, err := wol.ParseConfig("01:23:45:67:89:ab", map[string]string{
+		"shutdown":     "http",
+		"shutdown_url": "http://unused/shutdown",
+	})
+	require.NoError(t, err)
+
+	dev := wol.NewWOLDevice("192.0.2.1", *config, &internal.FnModeOptions{Test: true})
+	status := `{"type": "control", "action": "power_status"}`
+
+	response, err := dev.Process([]byte(status))
+	require.NoError(t, err)
+	assert.Equal(t, device.PowerStatusStandby, powerStatus(t, response))
+
+	response, err = dev.Process([]byte(`{"type": "remote", "action": "power"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success)
+
+	response, err = dev.Process([]byte(status))
+	require.NoError(t, err)
+	assert.Equal(t, device.PowerStatusActive, powerStatus(t, response))
+
+	response, err = dev.Process([]byte(`{"type": "remote", "action": "power"}`))
+	require.NoError(t, err)
+	assert.True(t, response.Success)
+
+	response, err = dev.Process([]byte(status))
+	require.NoError(t, err)
+	assert.Equal(t, device.PowerStatusStandby, powerStatus(t, response))
+}